@@ -8,14 +8,22 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/anthropics/anthropic-sdk-go/option"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/bot"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/config"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/conversation"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/db"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/holiday"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/module/debt"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/module/expense"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/module/project"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/module/todo"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/nlp"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/notify"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/pending"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/reminder"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/undo"
 	tele "gopkg.in/telebot.v4"
 )
 
@@ -39,6 +47,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Load the holiday calendar; rules with X-NONWORKDAY consult it
+	holidays, err := holiday.Load(cfg.HolidaysFile)
+	if err != nil {
+		slog.Error("failed to load holidays", "file", cfg.HolidaysFile, "error", err)
+		os.Exit(1)
+	}
+	if year := time.Now().In(loc).Year(); !holidays.Covers(year) {
+		slog.Warn("holiday calendar does not cover this year; only fixed-date holidays are known", "year", year, "last_year", holidays.LastYear())
+	}
+
 	// Connect to database
 	database, err := db.Connect(cfg.DatabaseURL)
 	if err != nil {
@@ -67,28 +85,61 @@ func main() {
 	reminderRepo := reminder.NewRepository(database)
 	todoRepo := todo.NewRepository(database)
 	expenseRepo := expense.NewRepository(database)
+	debtRepo := debt.NewRepository(database)
 	projectRepo := project.NewRepository(database)
+	convRepo := conversation.NewRepository(database, cfg.ConversationWindow, time.Duration(cfg.ConversationTTLMin)*time.Minute)
+	pendingRepo := pending.NewRepository(database, time.Duration(cfg.PendingTTLMin)*time.Minute)
+	journal := undo.NewRepository(database, time.Duration(cfg.UndoWindowMin)*time.Minute)
+	settingsRepo := settings.NewRepository(database)
+	outboundRepo := notify.NewRepository(database)
 
 	// Initialize services
-	nlpSvc := nlp.NewService(cfg.AnthropicAPIKey, loc)
+	settingsSvc := settings.NewService(settingsRepo, loc, cfg.DefaultReminderHour)
+	var nlpOpts []option.RequestOption
+	if cfg.AnthropicBaseURL != "" {
+		nlpOpts = append(nlpOpts, option.WithBaseURL(cfg.AnthropicBaseURL))
+	}
+	nlpSvc := nlp.NewService(cfg.AnthropicAPIKey, loc, nlpOpts...)
+	ruleParser := nlp.NewRuleParser()
+	var parser nlp.IntentParser
+	if cfg.ParserOrder == "llm_first" {
+		parser = nlp.NewChainParser(nlpSvc, ruleParser)
+	} else {
+		parser = nlp.NewChainParser(ruleParser, nlpSvc)
+	}
+	// Notification queue: proactive messages held back during quiet hours
+	// or do-not-disturb are sent once it ends. Services alert through it
+	// too, so it is created before them.
+	schedulerInterval := time.Duration(cfg.SchedulerIntervalSec) * time.Second
+	queue := notify.NewQueue(outboundRepo, b, settingsSvc, schedulerInterval)
+
 	todoSvc := todo.NewService(todoRepo, reminderRepo, loc)
-	expenseSvc := expense.NewService(expenseRepo, loc)
+	expenseSvc := expense.NewService(expenseRepo, queue, loc)
+	debtSvc := debt.NewService(debtRepo, reminderRepo, loc)
 	projectSvc := project.NewService(projectRepo, reminderRepo, loc)
+	reminderSvc := reminder.NewService(reminderRepo, loc, holidays)
 
 	// Register bot handlers
-	handler := bot.NewHandler(nlpSvc, todoSvc, expenseSvc, projectSvc, reminderRepo, loc)
+	handler := bot.NewHandler(parser, nlp.NewNormalizer(loc, holidays), todoSvc, expenseSvc, debtSvc, projectSvc, reminderSvc, reminderRepo, convRepo, pendingRepo, journal, settingsSvc, holidays, cfg.AdminUserIDs, loc)
 	handler.Register(b)
 
+	// Start notification queue
+	go queue.Start()
+
 	// Start reminder scheduler
-	schedulerInterval := time.Duration(cfg.SchedulerIntervalSec) * time.Second
-	scheduler := reminder.NewScheduler(reminderRepo, b, schedulerInterval, loc)
+	catchUp := time.Duration(cfg.ReminderCatchUpMin) * time.Minute
+	scheduler := reminder.NewScheduler(reminderRepo, queue, schedulerInterval, catchUp, settingsSvc, holidays)
 	go scheduler.Start()
 
-	// Start daily briefing scheduler (sends daily briefing at 07:30 WIB)
-	dailyScheduler := bot.NewDailyScheduler(b, todoRepo, todoSvc, expenseSvc, reminderRepo, loc)
+	// Start daily scheduler (briefing, overdue follow-ups and monthly report at
+	// each user's configured times)
+	dailyScheduler := bot.NewDailyScheduler(queue, todoRepo, todoSvc, expenseSvc, reminderRepo, settingsSvc, holidays)
 	go dailyScheduler.Start()
 
-	// Start todo cleanup scheduler (runs every hour, soft-deletes completed todos older than 1 day)
+	// Start cleanup scheduler (runs every hour, soft-deletes completed todos older than 1 day,
+	// purges the trash past its retention and drops expired conversation context,
+	// pending button actions, undo entries and old reminder deliveries)
+	trashRetention := time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour
 	cleanupStopCh := make(chan struct{})
 	go func() {
 		ticker := time.NewTicker(1 * time.Hour)
@@ -102,6 +153,23 @@ func main() {
 				} else {
 					slog.Info("todo cleanup completed")
 				}
+				if n, err := todoSvc.PurgeTrash(context.Background(), trashRetention); err != nil {
+					slog.Error("purge todo trash failed", "error", err)
+				} else if n > 0 {
+					slog.Info("todo trash purged", "count", n)
+				}
+				if err := convRepo.DeleteExpired(context.Background()); err != nil {
+					slog.Error("cleanup expired conversations failed", "error", err)
+				}
+				if err := pendingRepo.DeleteExpired(context.Background()); err != nil {
+					slog.Error("cleanup expired pending actions failed", "error", err)
+				}
+				if err := journal.DeleteExpired(context.Background()); err != nil {
+					slog.Error("cleanup expired undo entries failed", "error", err)
+				}
+				if err := reminderRepo.DeleteDeliveriesBefore(context.Background(), time.Now().AddDate(0, 0, -30)); err != nil {
+					slog.Error("cleanup old reminder deliveries failed", "error", err)
+				}
 			case <-cleanupStopCh:
 				slog.Info("todo cleanup scheduler stopped")
 				return
@@ -118,6 +186,7 @@ func main() {
 
 		scheduler.Stop()
 		dailyScheduler.Stop()
+		queue.Stop()
 		close(cleanupStopCh)
 		b.Stop()
 		database.Close()
@@ -125,6 +194,6 @@ func main() {
 		os.Exit(0)
 	}()
 
-	slog.Info("bot started", "timezone", cfg.Timezone, "scheduler_interval", schedulerInterval)
+	slog.Info("bot started", "timezone", cfg.Timezone, "scheduler_interval", schedulerInterval, "parser_order", cfg.ParserOrder)
 	b.Start()
 }
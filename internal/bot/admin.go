@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// adminOnly guards a command so that only ADMIN_USER_IDS can run it.
+func (h *Handler) adminOnly(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if !h.admins[c.Sender().ID] {
+			slog.Warn("admin command denied", "user_id", c.Sender().ID, "text", c.Text())
+			return c.Send("ℹ️ Perintah ini khusus admin.")
+		}
+		return next(c)
+	}
+}
+
+func (h *Handler) handleDeadLetters(c tele.Context) error {
+	ctx := h.userContext(context.Background(), c.Sender().ID)
+	dead, err := h.reminderRepo.ListDeadDeliveries(ctx)
+	if err != nil {
+		slog.Error("list dead deliveries failed", "error", err)
+		return c.Send("⚠️ Gagal mengambil daftar dead letter.")
+	}
+	return c.Send(FormatDeadLetters(dead, h.loc(ctx)))
+}
+
+// handleRequeue puts dead letters back in the queue: "/requeue 12 15" or
+// "/requeue all".
+func (h *Handler) handleRequeue(c tele.Context) error {
+	ctx := context.Background()
+	args := strings.Fields(c.Message().Payload)
+	if len(args) == 0 {
+		return c.Send("ℹ️ Gunakan /requeue <id> [id...] atau /requeue all.")
+	}
+
+	var ids []int
+	if !(len(args) == 1 && strings.EqualFold(args[0], "all")) {
+		for _, a := range args {
+			id, err := strconv.Atoi(a)
+			if err != nil {
+				return c.Send(fmt.Sprintf("❌ ID \"%s\" tidak valid.", a))
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	n, err := h.reminderRepo.RequeueDead(ctx, ids)
+	if err != nil {
+		slog.Error("requeue dead deliveries failed", "error", err)
+		return c.Send("⚠️ Gagal requeue dead letter.")
+	}
+	slog.Info("dead deliveries requeued", "count", n, "ids", ids, "admin_id", c.Sender().ID)
+	if n == 0 {
+		return c.Send("ℹ️ Tidak ada dead letter yang cocok.")
+	}
+	return c.Send(fmt.Sprintf("🔁 %d reminder dimasukkan kembali ke antrean.", n))
+}
@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/nlp"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/pending"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/undo"
+	tele "gopkg.in/telebot.v4"
+)
+
+// confirmUnique is the callback endpoint for confirm/cancel buttons.
+const confirmUnique = "confirm"
+
+// Candidate values carried by the confirm and cancel buttons.
+const (
+	confirmNo  = 0
+	confirmYes = 1
+)
+
+// isDestructive reports whether an intent wipes data in bulk and must be
+// confirmed before it runs.
+func isDestructive(intent string) bool {
+	switch intent {
+	case "clear_todo", "clear_expense", "delete_project":
+		return true
+	}
+	return false
+}
+
+// askConfirm previews a destructive intent and stores it as a pending
+// confirmation. When there is nothing to delete it returns the service's
+// answer without buttons.
+func (h *Handler) askConfirm(ctx context.Context, markup *tele.ReplyMarkup, userID int64, intent *nlp.ParsedIntent) (string, []tele.Row, error) {
+	var preview string
+	var ok bool
+	var err error
+
+	switch intent.Intent {
+	case "clear_todo":
+		preview, ok, err = h.todoSvc.PreviewClearAll(ctx, userID)
+	case "clear_expense":
+		var year int
+		preview, year, err = h.expenseSvc.PreviewClearByMonth(ctx, userID, intent.Month, intent.Year)
+		intent.Year = year
+		ok = year != 0
+	case "delete_project":
+		preview, ok, err = h.projectSvc.PreviewDelete(ctx, userID, intent.Project)
+	}
+	if err != nil || !ok {
+		return preview, nil, err
+	}
+
+	payload, err := json.Marshal(intent)
+	if err != nil {
+		return "", nil, err
+	}
+	actionID, err := h.pendingRepo.Create(ctx, userID, pending.KindConfirm, payload, []int{confirmYes, confirmNo})
+	if err != nil {
+		return "", nil, err
+	}
+
+	id := strconv.Itoa(actionID)
+	row := markup.Row(
+		markup.Data("✅ Ya, hapus", confirmUnique, id, strconv.Itoa(confirmYes)),
+		markup.Data("❌ Batal", confirmUnique, id, strconv.Itoa(confirmNo)),
+	)
+	return preview + "\n\nLanjutkan? Tekan tombol atau balas \"ya\" / \"batal\".", []tele.Row{row}, nil
+}
+
+// handleConfirm runs or discards a pending destructive intent from its buttons.
+func (h *Handler) handleConfirm(c tele.Context) error {
+	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
+
+	actionID, choice, ok := parsePickData(c.Data())
+	if !ok {
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Tombol tidak valid."})
+	}
+
+	payload, err := h.pendingRepo.Take(ctx, userID, pending.KindConfirm, actionID, choice)
+	if err != nil {
+		slog.Error("take pending confirmation failed", "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Maaf, terjadi kesalahan. Coba lagi nanti."})
+	}
+	if payload == nil {
+		return c.Respond(&tele.CallbackResponse{Text: "⌛ Konfirmasi ini sudah kedaluwarsa. Kirim ulang perintahnya.", ShowAlert: true})
+	}
+
+	if err := c.Respond(); err != nil {
+		slog.Warn("respond to callback failed", "error", err)
+	}
+	if choice != confirmYes {
+		return c.Edit("👌 Dibatalkan, tidak ada yang dihapus.")
+	}
+
+	resp, err := h.runConfirmed(ctx, userID, payload)
+	if err != nil {
+		slog.Error("confirmed action failed", "error", err)
+		resp = "⚠️ Maaf, terjadi kesalahan saat memproses permintaan kamu."
+	}
+	return c.Edit(resp)
+}
+
+// confirmLatest runs the user's latest pending confirmation ("ya").
+func (h *Handler) confirmLatest(ctx context.Context, userID int64) (string, error) {
+	payload, err := h.pendingRepo.TakeLatest(ctx, userID, pending.KindConfirm)
+	if err != nil {
+		return "", err
+	}
+	if payload == nil {
+		return "ℹ️ Tidak ada aksi yang menunggu konfirmasi.", nil
+	}
+	return h.runConfirmed(ctx, userID, payload)
+}
+
+// cancelLatest discards the user's latest pending confirmation ("batal").
+func (h *Handler) cancelLatest(ctx context.Context, userID int64) (string, error) {
+	payload, err := h.pendingRepo.TakeLatest(ctx, userID, pending.KindConfirm)
+	if err != nil {
+		return "", err
+	}
+	if payload == nil {
+		return "ℹ️ Tidak ada aksi yang menunggu konfirmasi.", nil
+	}
+	return "👌 Dibatalkan, tidak ada yang dihapus.", nil
+}
+
+func (h *Handler) runConfirmed(ctx context.Context, userID int64, payload []byte) (string, error) {
+	var intent nlp.ParsedIntent
+	if err := json.Unmarshal(payload, &intent); err != nil {
+		return "", err
+	}
+	slog.Info("destructive action confirmed", "user_id", userID, "intent", intent.Intent)
+	return h.route(ctx, userID, &intent)
+}
+
+// undoLast reverts the user's last destructive operation. A still-pending
+// confirmation is cancelled instead, since nothing has been deleted yet.
+func (h *Handler) undoLast(ctx context.Context, userID int64) (string, error) {
+	if payload, err := h.pendingRepo.TakeLatest(ctx, userID, pending.KindConfirm); err != nil {
+		return "", err
+	} else if payload != nil {
+		return "👌 Dibatalkan, tidak ada yang dihapus.", nil
+	}
+
+	entry, err := h.journal.Latest(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		return "ℹ️ Tidak ada penghapusan yang bisa dibatalkan.", nil
+	}
+
+	slog.Info("undoing destructive action", "user_id", userID, "kind", entry.Kind)
+
+	// The entry is only dropped once the restore went through, so the user
+	// can try again after an error.
+	var resp string
+	switch entry.Kind {
+	case undo.KindClearTodo:
+		resp, err = h.todoSvc.UndoClear(ctx, userID, entry.Payload)
+	case undo.KindClearExpense:
+		resp, err = h.expenseSvc.UndoClear(ctx, entry.Payload)
+	case undo.KindDeleteProject:
+		resp, err = h.projectSvc.UndoDelete(ctx, entry.Payload)
+	default:
+		slog.Warn("unknown undo entry kind", "kind", entry.Kind)
+		resp = "ℹ️ Tidak ada penghapusan yang bisa dibatalkan."
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := h.journal.Delete(ctx, entry.ID); err != nil {
+		return "", err
+	}
+	return resp, nil
+}
@@ -3,67 +3,77 @@ package bot
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"sync"
 	"time"
 
+	"github.com/zhafrantharif/personal-assistant-bot/internal/holiday"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/module/expense"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/module/todo"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/notify"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/reminder"
-	tele "gopkg.in/telebot.v4"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
 )
 
+// holidayLookaheadDays is how far ahead the daily briefing lists holidays.
+const holidayLookaheadDays = 14
+
+// scheduledTask is a per-user message sent at the time in the user's settings.
 type scheduledTask struct {
-	hour   int
-	minute int
-	name   string
-	fn     func()
+	message settings.Message
+	monthly bool // only on the 1st of the month
+	fn      func(ctx context.Context, userID int64, loc *time.Location)
 }
 
+// DailyScheduler checks every minute which users are due a briefing, overdue
+// follow-up or monthly report in their own timezone. Messages go out through
+// the notification queue, which holds them back during quiet hours.
 type DailyScheduler struct {
-	bot          *tele.Bot
+	queue        *notify.Queue
 	todoRepo     *todo.Repository
 	todoSvc      *todo.Service
 	expenseSvc   *expense.Service
 	reminderRepo *reminder.Repository
-	timezone     *time.Location
+	settingsSvc  *settings.Service
+	holidays     *holiday.Calendar
 	stopCh       chan struct{}
 	once         sync.Once
 }
 
-func NewDailyScheduler(bot *tele.Bot, todoRepo *todo.Repository, todoSvc *todo.Service, expenseSvc *expense.Service, reminderRepo *reminder.Repository, timezone *time.Location) *DailyScheduler {
+func NewDailyScheduler(queue *notify.Queue, todoRepo *todo.Repository, todoSvc *todo.Service, expenseSvc *expense.Service, reminderRepo *reminder.Repository, settingsSvc *settings.Service, holidays *holiday.Calendar) *DailyScheduler {
 	return &DailyScheduler{
-		bot:          bot,
+		queue:        queue,
 		todoRepo:     todoRepo,
 		todoSvc:      todoSvc,
 		expenseSvc:   expenseSvc,
 		reminderRepo: reminderRepo,
-		timezone:     timezone,
+		settingsSvc:  settingsSvc,
+		holidays:     holidays,
 		stopCh:       make(chan struct{}),
 	}
 }
 
 func (s *DailyScheduler) Start() {
-	slog.Info("daily scheduler started", "briefing", "07:30", "overdue", "19:00", "monthly_report", "1st 08:00")
+	slog.Info("daily scheduler started",
+		"default_briefing", settings.DefaultBriefingAt.String(),
+		"default_overdue", settings.DefaultOverdueAt.String(),
+		"default_monthly_report", "1st "+settings.DefaultMonthlyReportAt.String())
 
 	tasks := []scheduledTask{
-		{hour: 7, minute: 30, name: "daily_briefing", fn: s.sendBriefing},
-		{hour: 8, minute: 0, name: "monthly_report", fn: s.sendMonthlyReport},
-		{hour: 19, minute: 0, name: "overdue_followup", fn: s.sendOverdueFollowups},
+		{message: settings.Briefing, fn: s.sendBriefing},
+		{message: settings.MonthlyReport, monthly: true, fn: s.sendMonthlyReport},
+		{message: settings.Overdue, fn: s.sendOverdueFollowup},
 	}
 
-	for {
-		now := time.Now().In(s.timezone)
-		nextTask, waitDuration := s.findNextTask(now, tasks)
-
-		slog.Info("daily scheduler next run",
-			"task", nextTask.name,
-			"at", now.Add(waitDuration).Format("2006-01-02 15:04"),
-			"in", waitDuration.Round(time.Second),
-		)
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
 
+	last := time.Now()
+	for {
 		select {
-		case <-time.After(waitDuration):
-			nextTask.fn()
+		case now := <-ticker.C:
+			s.runDue(last, now, tasks)
+			last = now
 		case <-s.stopCh:
 			slog.Info("daily scheduler stopped")
 			return
@@ -71,151 +81,139 @@ func (s *DailyScheduler) Start() {
 	}
 }
 
-func (s *DailyScheduler) findNextTask(now time.Time, tasks []scheduledTask) (scheduledTask, time.Duration) {
-	var best scheduledTask
-	var bestDuration time.Duration
-	first := true
-
-	for _, t := range tasks {
-		// Monthly report only runs on the 1st
-		if t.name == "monthly_report" {
-			target := s.nextFirstOfMonth(now, t.hour, t.minute)
-			d := target.Sub(now)
-			if first || d < bestDuration {
-				best = t
-				bestDuration = d
-				first = false
+// runDue sends every task whose time, in each user's settings, falls in
+// (from, to].
+func (s *DailyScheduler) runDue(from, to time.Time, tasks []scheduledTask) {
+	ctx := context.Background()
+
+	userIDs, err := s.users(ctx)
+	if err != nil {
+		slog.Error("daily scheduler: failed to list users", "error", err)
+		return
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+	all, err := s.settingsSvc.ForUsers(ctx, userIDs)
+	if err != nil {
+		slog.Error("daily scheduler: failed to load settings", "error", err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		us := all[userID]
+		userCtx := settings.WithUser(ctx, us)
+		for _, t := range tasks {
+			at, enabled := us.Schedule(t.message)
+			if enabled && dueBetween(at, t.monthly, us.Location(), from, to) {
+				t.fn(userCtx, userID, us.Location())
 			}
-			continue
 		}
+	}
+}
 
-		target := time.Date(now.Year(), now.Month(), now.Day(), t.hour, t.minute, 0, 0, s.timezone)
-		if !target.After(now) {
-			target = target.AddDate(0, 0, 1)
-		}
-		d := target.Sub(now)
-		if first || d < bestDuration {
-			best = t
-			bestDuration = d
-			first = false
+// users returns everyone a scheduled message may be for: users with todos,
+// standalone reminders, expenses or incomes, or settings of their own.
+func (s *DailyScheduler) users(ctx context.Context) ([]int64, error) {
+	var all []int64
+	for _, list := range []func(context.Context) ([]int64, error){
+		s.todoRepo.ListActiveUserIDs,
+		s.reminderRepo.ListActiveUserIDs,
+		s.expenseSvc.UserIDs,
+		s.settingsSvc.UserIDs,
+	} {
+		ids, err := list(ctx)
+		if err != nil {
+			return nil, err
 		}
+		all = append(all, ids...)
 	}
-
-	return best, bestDuration
+	slices.Sort(all)
+	return slices.Compact(all), nil
 }
 
-func (s *DailyScheduler) nextFirstOfMonth(now time.Time, hour, minute int) time.Time {
-	// If today is the 1st and the time hasn't passed yet, use today
-	if now.Day() == 1 {
-		target := time.Date(now.Year(), now.Month(), 1, hour, minute, 0, 0, s.timezone)
-		if target.After(now) {
-			return target
+// dueBetween reports whether the wall-clock time at, in loc, falls in
+// (from, to]. Ticks are a minute apart, so only the local days of from and to
+// need checking.
+func dueBetween(at settings.Clock, monthly bool, loc *time.Location, from, to time.Time) bool {
+	for _, day := range []time.Time{from, to} {
+		target := at.On(day, loc)
+		if monthly && target.Day() != 1 {
+			continue
+		}
+		if target.After(from) && !target.After(to) {
+			return true
 		}
 	}
-	// Otherwise, the 1st of next month
-	return time.Date(now.Year(), now.Month()+1, 1, hour, minute, 0, 0, s.timezone)
+	return false
 }
 
 func (s *DailyScheduler) Stop() {
 	s.once.Do(func() { close(s.stopCh) })
 }
 
-func (s *DailyScheduler) sendBriefing() {
-	ctx := context.Background()
-
-	userIDs, err := s.todoRepo.ListActiveUserIDs(ctx)
+func (s *DailyScheduler) sendBriefing(ctx context.Context, userID int64, loc *time.Location) {
+	todos, err := s.todoSvc.List(ctx, userID, "pending")
 	if err != nil {
-		slog.Error("daily briefing: failed to list users", "error", err)
+		slog.Error("daily briefing: failed to list todos", "user_id", userID, "error", err)
 		return
 	}
 
-	for _, userID := range userIDs {
-		todos, err := s.todoSvc.List(ctx, userID, "pending")
-		if err != nil {
-			slog.Error("daily briefing: failed to list todos", "user_id", userID, "error", err)
-			continue
-		}
-
-		reminders, err := s.reminderRepo.ListActiveByUser(ctx, userID)
-		if err != nil {
-			slog.Error("daily briefing: failed to list reminders", "user_id", userID, "error", err)
-			reminders = nil
-		}
-
-		msg := FormatDailyBriefing(todos, s.timezone, reminders)
-		user := &tele.User{ID: userID}
-		if _, err := s.bot.Send(user, msg); err != nil {
-			slog.Error("daily briefing: failed to send", "user_id", userID, "error", err)
-			continue
-		}
+	reminders, err := s.reminderRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		slog.Error("daily briefing: failed to list reminders", "user_id", userID, "error", err)
+		reminders = nil
+	}
 
-		slog.Info("daily briefing sent", "user_id", userID)
+	msg := FormatDailyBriefing(todos, loc, reminders, s.holidays.Upcoming(time.Now().In(loc), holidayLookaheadDays))
+	if _, err := s.queue.Send(ctx, userID, notify.KindBriefing, msg, nil); err != nil {
+		slog.Error("daily briefing: failed to send", "user_id", userID, "error", err)
+		return
 	}
-}
 
-func (s *DailyScheduler) sendOverdueFollowups() {
-	ctx := context.Background()
+	slog.Info("daily briefing sent", "user_id", userID)
+}
 
-	userIDs, err := s.todoRepo.ListActiveUserIDs(ctx)
+func (s *DailyScheduler) sendOverdueFollowup(ctx context.Context, userID int64, loc *time.Location) {
+	overdueTodos, err := s.todoRepo.ListOverdueByUser(ctx, userID, loc)
 	if err != nil {
-		slog.Error("overdue followup: failed to list users", "error", err)
+		slog.Error("overdue followup: failed to list overdue", "user_id", userID, "error", err)
 		return
 	}
 
-	for _, userID := range userIDs {
-		overdueTodos, err := s.todoRepo.ListOverdueByUser(ctx, userID, s.timezone)
-		if err != nil {
-			slog.Error("overdue followup: failed to list overdue", "user_id", userID, "error", err)
-			continue
-		}
+	if len(overdueTodos) == 0 {
+		return
+	}
 
-		if len(overdueTodos) == 0 {
+	for _, t := range overdueTodos {
+		msg := FormatOverdueNotification(t, loc)
+		if _, err := s.queue.Send(ctx, userID, notify.KindOverdue, msg, nil); err != nil {
+			slog.Error("overdue followup: failed to send", "user_id", userID, "todo_id", t.ID, "error", err)
 			continue
 		}
-
-		user := &tele.User{ID: userID}
-		for _, t := range overdueTodos {
-			msg := FormatOverdueNotification(t, s.timezone)
-			if _, err := s.bot.Send(user, msg); err != nil {
-				slog.Error("overdue followup: failed to send", "user_id", userID, "todo_id", t.ID, "error", err)
-				continue
-			}
-		}
-
-		slog.Info("overdue followup sent", "user_id", userID, "count", len(overdueTodos))
 	}
+
+	slog.Info("overdue followup sent", "user_id", userID, "count", len(overdueTodos))
 }
 
-func (s *DailyScheduler) sendMonthlyReport() {
-	ctx := context.Background()
-	now := time.Now().In(s.timezone)
+func (s *DailyScheduler) sendMonthlyReport(ctx context.Context, userID int64, loc *time.Location) {
+	now := time.Now().In(loc)
 
 	// Report for previous month
 	prevMonth := now.AddDate(0, -1, 0)
 	year := prevMonth.Year()
 	month := prevMonth.Month()
 
-	slog.Info("monthly expense report triggered", "year", year, "month", month)
-
-	userIDs, err := s.todoRepo.ListActiveUserIDs(ctx)
+	report, err := s.expenseSvc.MonthlyReport(ctx, userID, year, month)
 	if err != nil {
-		slog.Error("monthly report: failed to list users", "error", err)
+		slog.Error("monthly report: failed to generate", "user_id", userID, "error", err)
 		return
 	}
 
-	for _, userID := range userIDs {
-		report, err := s.expenseSvc.MonthlyReport(ctx, userID, year, month)
-		if err != nil {
-			slog.Error("monthly report: failed to generate", "user_id", userID, "error", err)
-			continue
-		}
-
-		user := &tele.User{ID: userID}
-		if _, err := s.bot.Send(user, report); err != nil {
-			slog.Error("monthly report: failed to send", "user_id", userID, "error", err)
-			continue
-		}
-
-		slog.Info("monthly report sent", "user_id", userID, "month", month)
+	if _, err := s.queue.Send(ctx, userID, notify.KindMonthlyReport, report, nil); err != nil {
+		slog.Error("monthly report: failed to send", "user_id", userID, "error", err)
+		return
 	}
+
+	slog.Info("monthly report sent", "user_id", userID, "month", month)
 }
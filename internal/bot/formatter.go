@@ -5,8 +5,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/zhafrantharif/personal-assistant-bot/internal/holiday"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/module/todo"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/recurrence"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/reminder"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
 )
 
 var indonesianMonths = [...]string{
@@ -47,26 +50,25 @@ func recurringLabel(rule *string) string {
 	if rule == nil {
 		return ""
 	}
-	r := *rule
-	switch {
-	case r == "daily":
-		return "Harian"
-	case strings.HasPrefix(r, "weekly:"):
-		return "Mingguan"
-	case strings.HasPrefix(r, "monthly:"):
-		return "Bulanan"
-	case strings.HasPrefix(r, "yearly:"):
-		return "Tahunan"
-	default:
+	r, err := recurrence.Parse(*rule)
+	if err != nil {
 		return ""
 	}
+	return r.Label()
 }
 
-// buildReminderMap creates a lookup map from todoID to its active reminder info.
+// buildReminderMap creates a lookup map from todoID to its next active
+// reminder. reminders must be sorted by remind_at. Standalone and paused
+// reminders are left out.
 func buildReminderMap(reminders []reminder.TodoReminder) map[int]reminder.TodoReminder {
 	m := make(map[int]reminder.TodoReminder, len(reminders))
 	for _, r := range reminders {
-		m[r.TodoID] = r
+		if r.TodoID == nil || r.PausedAt != nil {
+			continue
+		}
+		if _, ok := m[*r.TodoID]; !ok {
+			m[*r.TodoID] = r
+		}
 	}
 	return m
 }
@@ -175,6 +177,9 @@ func FormatTodoList(todos []todo.Todo, filter string, loc *time.Location, remind
 // ⚡ Overdue
 // 🔘 Bayar pajak — 10 Feb ⚠️
 //
+// ⏰ Reminder Hari Ini
+// 08:00 · Minum obat 🔁
+//
 // ─────────────
 //
 // 🗓 Reminder Bulan Ini — Februari 2026
@@ -183,9 +188,16 @@ func FormatTodoList(todos []todo.Todo, filter string, loc *time.Location, remind
 //	25 Feb · Bayar internet 🔁
 //
 // ─────────────
+//
+// 🎌 Libur Mendatang
+//
+//	18 Mar · Hari Suci Nyepi (cuti bersama)
+//	20–24 Mar · Idul Fitri 1447 H (termasuk cuti bersama)
+//
+// ─────────────
 // 📊 Hari ini: 2 todo
 // 📊 Bulan ini: 3 reminder tersisa
-func FormatDailyBriefing(todos []todo.Todo, loc *time.Location, reminders []reminder.TodoReminder) string {
+func FormatDailyBriefing(todos []todo.Todo, loc *time.Location, reminders []reminder.TodoReminder, holidays []holiday.Holiday) string {
 	now := time.Now().In(loc)
 
 	var lines []string
@@ -259,10 +271,31 @@ func FormatDailyBriefing(todos []todo.Todo, loc *time.Location, reminders []remi
 		}
 	}
 
+	// ⏰ Standalone reminders due today, kept apart from the todos
+	tomorrow := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, loc)
+	var today []reminder.TodoReminder
+	for _, r := range reminders {
+		if r.TodoID == nil && r.PausedAt == nil && r.RemindAt.Before(tomorrow) {
+			today = append(today, r)
+		}
+	}
+	if len(today) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "⏰ Reminder Hari Ini")
+		for _, r := range today {
+			line := fmt.Sprintf("%s · %s", formatTime(r.RemindAt.In(loc)), r.TodoTitle)
+			if r.IsRecurring {
+				line += " 🔁"
+			}
+			lines = append(lines, line)
+		}
+	}
+
 	// 🔁 Recurring reminders section — show ALL active recurring reminders
+	// of todos, except paused ones
 	var recurringReminders []reminder.TodoReminder
 	for _, r := range reminders {
-		if r.IsRecurring {
+		if r.IsRecurring && r.TodoID != nil && r.PausedAt == nil {
 			recurringReminders = append(recurringReminders, r)
 		}
 	}
@@ -287,6 +320,15 @@ func FormatDailyBriefing(todos []todo.Todo, loc *time.Location, reminders []remi
 		lines = append(lines, " Tidak ada reminder rutin aktif")
 	}
 
+	// 🎌 Upcoming holidays and cuti bersama
+	if len(holidays) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "─────────────")
+		lines = append(lines, "")
+		lines = append(lines, "🎌 Libur Mendatang\n")
+		lines = append(lines, holidayLines(holidays)...)
+	}
+
 	// Summary footer
 	lines = append(lines, "")
 	lines = append(lines, "─────────────")
@@ -296,55 +338,113 @@ func FormatDailyBriefing(todos []todo.Todo, loc *time.Location, reminders []remi
 	if len(overdue) > 0 {
 		lines = append(lines, fmt.Sprintf("📊 Overdue: %d todo", len(overdue)))
 	}
+	if len(today) > 0 {
+		lines = append(lines, fmt.Sprintf("📊 Reminder hari ini: %d", len(today)))
+	}
 	lines = append(lines, fmt.Sprintf("📊 Reminder rutin: %d aktif", len(recurringReminders)))
 
 	return strings.Join(lines, "\n")
 }
 
-// FormatReminderList formats all active reminders.
+// holidayLines lists holidays one per line, joining consecutive days of
+// the same holiday ("20–24 Mar · Idul Fitri 1447 H").
+func holidayLines(holidays []holiday.Holiday) []string {
+	var lines []string
+	for i := 0; i < len(holidays); {
+		first := holidays[i]
+		last, cuti, all := first, first.CutiBersama, first.CutiBersama
+		j := i + 1
+		for ; j < len(holidays); j++ {
+			h := holidays[j]
+			if h.Name != first.Name || !h.Date.Equal(last.Date.AddDate(0, 0, 1)) {
+				break
+			}
+			last = h
+			cuti = cuti || h.CutiBersama
+			all = all && h.CutiBersama
+		}
+		i = j
+
+		date := formatDateShort(first.Date)
+		if !last.Date.Equal(first.Date) {
+			if last.Date.Month() == first.Date.Month() {
+				date = fmt.Sprintf("%d–%s", first.Date.Day(), formatDateShort(last.Date))
+			} else {
+				date += "–" + formatDateShort(last.Date)
+			}
+		}
+		line := fmt.Sprintf(" %s · %s", date, first.Name)
+		switch {
+		case all:
+			line += " (cuti bersama)"
+		case cuti:
+			line += " (termasuk cuti bersama)"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// FormatReminderList formats all active reminders, standalone ones first
+// and those of todos after.
 //
 // 🔔 Daftar Reminder Aktif
 //
-// 🔁 Bayar wifi
+// ⏰ Reminder
+// 🔁 #9 Minum obat
+//
+//	Harian · setiap hari
+//	Berikutnya: 5 Mar 2026 08:00
 //
-//	Bulanan · tanggal 5
+// 📌 Reminder Todo
+// 🔁 #3 Bayar wifi
+//
+//	Bulanan · setiap tanggal 5
 //	Berikutnya: 5 Mar 2026 07:00
 //
-// 🔁 Bayar listrik
+// 🔔 #7 Laporan
+//
+//	6 Mar 2026 16:00 · 1 jam sebelum deadline
 //
-//	Bulanan · tanggal 17
-//	Berikutnya: 17 Mar 2026 07:00
+// ⏸ #8 Bayar listrik
+//
+//	Bulanan · setiap tanggal 20
+//	Dijeda · jadwal: 20 Mar 2026 07:00
 //
 // ─────────────
-// 🔔 2  🔁 2
+// 🔔 1  🔁 2
 func FormatReminderList(reminders []reminder.TodoReminder, loc *time.Location) string {
 	if len(reminders) == 0 {
 		return "🔔 Tidak ada reminder aktif."
 	}
 
-	var lines []string
-	lines = append(lines, "🔔 Daftar Reminder Aktif\n")
-
+	var standalone, ofTodos []reminder.TodoReminder
 	var countRecurring, countOnce int
 	for _, r := range reminders {
-		rt := r.RemindAt.In(loc)
-		nextStr := fmt.Sprintf("%d %s %d %02d:%02d",
-			rt.Day(), indonesianMonths[rt.Month()-1], rt.Year(), rt.Hour(), rt.Minute())
-
+		if r.TodoID == nil {
+			standalone = append(standalone, r)
+		} else {
+			ofTodos = append(ofTodos, r)
+		}
 		if r.IsRecurring {
 			countRecurring++
-			label := recurringLabel(r.RecurrenceRule)
-			detail := recurringRuleDetail(r.RecurrenceRule)
-			line := fmt.Sprintf("🔁 %s", r.TodoTitle)
-			lines = append(lines, line)
-			if detail != "" {
-				lines = append(lines, fmt.Sprintf("   %s · %s", label, detail))
-			}
-			lines = append(lines, fmt.Sprintf("   Berikutnya: %s", nextStr))
 		} else {
 			countOnce++
-			lines = append(lines, fmt.Sprintf("🔔 %s", r.TodoTitle))
-			lines = append(lines, fmt.Sprintf("   %s", nextStr))
+		}
+	}
+
+	var lines []string
+	lines = append(lines, "🔔 Daftar Reminder Aktif")
+	if len(standalone) > 0 {
+		lines = append(lines, "", "⏰ Reminder")
+		for _, r := range standalone {
+			lines = append(lines, reminderEntry(r, loc)...)
+		}
+	}
+	if len(ofTodos) > 0 {
+		lines = append(lines, "", "📌 Reminder Todo")
+		for _, r := range ofTodos {
+			lines = append(lines, reminderEntry(r, loc)...)
 		}
 	}
 
@@ -361,51 +461,158 @@ func FormatReminderList(reminders []reminder.TodoReminder, loc *time.Location) s
 	return strings.Join(lines, "\n")
 }
 
-// recurringRuleDetail returns a human-readable detail of the recurrence rule.
+// reminderEntry formats one reminder of FormatReminderList.
+func reminderEntry(r reminder.TodoReminder, loc *time.Location) []string {
+	rt := r.RemindAt.In(loc)
+	nextStr := fmt.Sprintf("%d %s %d %02d:%02d",
+		rt.Day(), indonesianMonths[rt.Month()-1], rt.Year(), rt.Hour(), rt.Minute())
+
+	icon := "🔔"
+	if r.IsRecurring {
+		icon = "🔁"
+	}
+	if r.PausedAt != nil {
+		icon = "⏸"
+	}
+	lines := []string{fmt.Sprintf("%s #%d %s", icon, r.ID, r.TodoTitle)}
+
+	if r.IsRecurring {
+		if detail := recurringRuleDetail(r.RecurrenceRule); detail != "" {
+			lines = append(lines, fmt.Sprintf("   %s · %s", recurringLabel(r.RecurrenceRule), detail))
+		}
+		if r.PausedAt != nil {
+			return append(lines, fmt.Sprintf("   Dijeda · jadwal: %s", nextStr))
+		}
+		return append(lines, fmt.Sprintf("   Berikutnya: %s", nextStr))
+	}
+	if r.OffsetMinutes != nil {
+		nextStr += " · " + reminder.DescribeOffset(*r.OffsetMinutes)
+	}
+	if r.PausedAt != nil {
+		return append(lines, fmt.Sprintf("   Dijeda · jadwal: %s", nextStr))
+	}
+	return append(lines, fmt.Sprintf("   %s", nextStr))
+}
+
+// recurringRuleDetail returns a human-readable detail of the recurrence rule,
+// e.g. "setiap 2 minggu hari Jumat sampai 31 Des 2026".
 func recurringRuleDetail(rule *string) string {
 	if rule == nil {
 		return ""
 	}
-	r := *rule
-	switch {
-	case r == "daily":
-		return "setiap hari"
-	case strings.HasPrefix(r, "weekly:"):
-		day := strings.TrimPrefix(r, "weekly:")
-		switch strings.ToUpper(day) {
-		case "MON", "SENIN":
-			return "setiap Senin"
-		case "TUE", "SELASA":
-			return "setiap Selasa"
-		case "WED", "RABU":
-			return "setiap Rabu"
-		case "THU", "KAMIS":
-			return "setiap Kamis"
-		case "FRI", "JUMAT":
-			return "setiap Jumat"
-		case "SAT", "SABTU":
-			return "setiap Sabtu"
-		case "SUN", "MINGGU":
-			return "setiap Minggu"
-		default:
-			return fmt.Sprintf("setiap %s", day)
+	r, err := recurrence.Parse(*rule)
+	if err != nil {
+		return ""
+	}
+	return r.Describe()
+}
+
+// FormatTrashList formats soft-deleted todos, most recently deleted first.
+//
+// 🗑️ Sampah
+//
+//  1. Beli susu ✅
+//     Dihapus 3 Mar
+//  2. Bikin wireframe (Laundry App)
+//     Dihapus 1 Mar
+//
+// Ketik "pulihkan todo <nama>" untuk mengembalikan.
+func FormatTrashList(todos []todo.TrashedTodo, loc *time.Location) string {
+	if len(todos) == 0 {
+		return "🗑️ Sampah kosong."
+	}
+
+	var lines []string
+	lines = append(lines, "🗑️ Sampah\n")
+	for i, t := range todos {
+		line := fmt.Sprintf("%d. %s", i+1, t.Title)
+		if t.ProjectName != nil {
+			line += fmt.Sprintf(" (%s)", *t.ProjectName)
 		}
-	case strings.HasPrefix(r, "monthly:"):
-		d := strings.TrimPrefix(r, "monthly:")
-		return fmt.Sprintf("tanggal %s", d)
-	case strings.HasPrefix(r, "yearly:"):
-		parts := strings.Split(strings.TrimPrefix(r, "yearly:"), "-")
-		if len(parts) == 2 {
-			m := 0
-			fmt.Sscanf(parts[0], "%d", &m)
-			if m >= 1 && m <= 12 {
-				return fmt.Sprintf("%s %s", parts[1], indonesianMonthsFull[m-1])
-			}
+		if t.IsCompleted {
+			line += " ✅"
 		}
-		return "setiap tahun"
-	default:
-		return ""
+		lines = append(lines, line)
+		lines = append(lines, fmt.Sprintf("   Dihapus %s", formatDateShort(t.DeletedAt.In(loc))))
 	}
+	lines = append(lines, "\nKetik \"pulihkan todo <nama>\" untuk mengembalikan.")
+
+	return strings.Join(lines, "\n")
+}
+
+// FormatDeadLetters formats reminder deliveries that gave up, for admins.
+//
+// ☠️ Dead letter reminder (1)
+//
+// #12 · Beli susu (user 123456)
+//
+//	📅 3 Mar 09:00 · 6x percobaan
+//	⚠️ telegram: bot was blocked by the user (403)
+//
+// Ketik /requeue <id> atau /requeue all.
+func FormatDeadLetters(dead []reminder.DeadDelivery, loc *time.Location) string {
+	if len(dead) == 0 {
+		return "✅ Tidak ada dead letter."
+	}
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("☠️ Dead letter reminder (%d)\n", len(dead)))
+	for _, d := range dead {
+		at := d.ScheduledFor.In(loc)
+		lines = append(lines, fmt.Sprintf("#%d · %s (user %d)", d.ID, d.TodoTitle, d.UserID))
+		lines = append(lines, fmt.Sprintf("   📅 %s %02d:%02d · %dx percobaan", formatDateShort(at), at.Hour(), at.Minute(), d.Attempts))
+		if d.LastError != nil {
+			lines = append(lines, fmt.Sprintf("   ⚠️ %s", *d.LastError))
+		}
+	}
+	lines = append(lines, "\nKetik /requeue <id> atau /requeue all.")
+
+	return strings.Join(lines, "\n")
+}
+
+// FormatSettings formats a user's settings.
+//
+// ⚙️ Pengaturan
+//
+// 🌏 Timezone: Asia/Jakarta (WIB)
+// ⏰ Jam default reminder: 07:00
+// 🔔 Daily briefing: 07:30
+// 🔔 Pengingat overdue: 19:00
+// 🔕 Laporan bulanan: mati
+// 🌙 Jam tenang: 22:00–06:00
+// ⛔ Jangan ganggu sampai 15:00
+//
+// Ketik untuk mengubah, misal "ganti briefing jam 6 pagi".
+func FormatSettings(us *settings.Settings) string {
+	loc := us.Location()
+
+	var lines []string
+	lines = append(lines, "⚙️ Pengaturan\n")
+	lines = append(lines, fmt.Sprintf("🌏 Timezone: %s (%s)", loc.String(), settings.ZoneName(loc)))
+	lines = append(lines, fmt.Sprintf("⏰ Jam default reminder: %02d:00", us.ReminderHour))
+	for _, m := range settings.Messages {
+		at, enabled := us.Schedule(m)
+		label := m.Label()
+		if m == settings.MonthlyReport {
+			label += " (tgl 1)"
+		}
+		if enabled {
+			lines = append(lines, fmt.Sprintf("🔔 %s: %s", label, at))
+		} else {
+			lines = append(lines, fmt.Sprintf("🔕 %s: mati", label))
+		}
+	}
+	if us.QuietEnabled {
+		lines = append(lines, fmt.Sprintf("🌙 Jam tenang: %s–%s", us.QuietStart, us.QuietEnd))
+	} else {
+		lines = append(lines, "🌙 Jam tenang: mati")
+	}
+	if us.DNDUntil != nil && us.DNDUntil.After(time.Now()) {
+		lines = append(lines, fmt.Sprintf("⛔ Jangan ganggu sampai %s", us.DNDUntil.In(loc).Format("15:04")))
+	}
+	lines = append(lines, "\nKetik untuk mengubah, misal \"ganti briefing jam 6 pagi\" atau \"matikan laporan bulanan\".")
+
+	return strings.Join(lines, "\n")
 }
 
 // FormatOverdueNotification formats a single overdue todo follow-up.
@@ -2,34 +2,69 @@ package bot
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/zhafrantharif/personal-assistant-bot/internal/conversation"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/holiday"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/module/debt"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/module/expense"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/module/project"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/module/todo"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/nlp"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/pending"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/reminder"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/undo"
 	tele "gopkg.in/telebot.v4"
 )
 
 type Handler struct {
-	nlpSvc       *nlp.Service
+	parser       nlp.IntentParser
+	normalizer   *nlp.Normalizer
 	todoSvc      *todo.Service
 	expenseSvc   *expense.Service
+	debtSvc      *debt.Service
 	projectSvc   *project.Service
+	reminderSvc  *reminder.Service
 	reminderRepo *reminder.Repository
+	convRepo     *conversation.Repository
+	pendingRepo  *pending.Repository
+	journal      *undo.Repository
+	settingsSvc  *settings.Service
+	holidays     *holiday.Calendar
+	admins       map[int64]bool
 	timezone     *time.Location
 }
 
-func NewHandler(nlpSvc *nlp.Service, todoSvc *todo.Service, expenseSvc *expense.Service, projectSvc *project.Service, reminderRepo *reminder.Repository, timezone *time.Location) *Handler {
+// pickUnique is the callback endpoint for disambiguation buttons.
+const pickUnique = "pick"
+
+func NewHandler(parser nlp.IntentParser, normalizer *nlp.Normalizer, todoSvc *todo.Service, expenseSvc *expense.Service, debtSvc *debt.Service, projectSvc *project.Service, reminderSvc *reminder.Service, reminderRepo *reminder.Repository, convRepo *conversation.Repository, pendingRepo *pending.Repository, journal *undo.Repository, settingsSvc *settings.Service, holidays *holiday.Calendar, admins []int64, timezone *time.Location) *Handler {
+	adminSet := make(map[int64]bool, len(admins))
+	for _, id := range admins {
+		adminSet[id] = true
+	}
 	return &Handler{
-		nlpSvc:       nlpSvc,
+		parser:       parser,
+		normalizer:   normalizer,
 		todoSvc:      todoSvc,
 		expenseSvc:   expenseSvc,
+		debtSvc:      debtSvc,
 		projectSvc:   projectSvc,
+		reminderSvc:  reminderSvc,
 		reminderRepo: reminderRepo,
+		convRepo:     convRepo,
+		pendingRepo:  pendingRepo,
+		journal:      journal,
+		settingsSvc:  settingsSvc,
+		holidays:     holidays,
+		admins:       adminSet,
 		timezone:     timezone,
 	}
 }
@@ -41,46 +76,253 @@ func (h *Handler) Register(b *tele.Bot) {
 	b.Handle("/todos", h.handleTodos)
 	b.Handle("/daily", h.handleDaily)
 	b.Handle("/expenses", h.handleExpenses)
+	b.Handle("/budget", h.handleBudget)
+	b.Handle("/saldo", h.handleBalance)
+	b.Handle("/hutang", h.handleDebts)
 	b.Handle("/projects", h.handleProjects)
 	b.Handle("/reminders", h.handleReminders)
+	b.Handle("/settings", h.handleSettings)
+	b.Handle("/deadletters", h.adminOnly(h.handleDeadLetters))
+	b.Handle("/requeue", h.adminOnly(h.handleRequeue))
+	b.Handle("\f"+pickUnique, h.handlePick)
+	b.Handle("\f"+reminder.ActionUnique, h.handleReminderAction)
+	b.Handle("\f"+confirmUnique, h.handleConfirm)
+	b.Handle("\f"+settingsUnique, h.handleSettingsAction)
 }
 
 func (h *Handler) handleText(c tele.Context) error {
-	ctx := context.Background()
 	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
 	text := c.Text()
 
 	slog.Info("received message", "user_id", userID, "text", text)
 
-	intents, err := h.nlpSvc.Parse(ctx, text)
+	conv := h.loadConversation(ctx, userID)
+	ctx, tracker := conversation.WithTracker(ctx)
+
+	intents, err := h.parser.Parse(ctx, text, conv)
 	if err != nil {
 		slog.Error("nlp parse failed", "error", err)
+		var fieldErr *nlp.FieldError
+		if errors.As(err, &fieldErr) {
+			return c.Send(fmt.Sprintf("⚠️ Maaf, pesan kamu belum bisa diproses (%s). Coba tulis ulang dengan lebih jelas.", fieldErr))
+		}
 		return c.Send("⚠️ Maaf, terjadi kesalahan. Coba lagi nanti.")
 	}
 
 	slog.Info("parsed intents", "count", len(intents), "user_id", userID)
 
 	var responses []string
+	markup := &tele.ReplyMarkup{}
+	var rows []tele.Row
 	for _, intent := range intents {
-		resp, err := h.route(ctx, userID, &intent)
+		if intent.Raw == "" {
+			intent.Raw = text
+		}
+
+		corrections, err := h.normalizer.Normalize(ctx, &intent)
+		if err != nil {
+			var rejection *nlp.RejectionError
+			if errors.As(err, &rejection) {
+				slog.Info("intent rejected by normalizer", "intent", intent.Intent, "field", rejection.Field)
+				responses = append(responses, "❌ "+rejection.Message)
+				continue
+			}
+			slog.Error("normalize intent failed", "intent", intent.Intent, "error", err)
+			responses = append(responses, "⚠️ Maaf, terjadi kesalahan saat memproses permintaan kamu.")
+			continue
+		}
+
+		if intent.Intent == "show_settings" {
+			us := settings.FromContext(ctx)
+			rows = append(rows, settingsRows(markup, us)...)
+			responses = append(responses, withCorrections(FormatSettings(us), corrections))
+			continue
+		}
+
+		if isDestructive(intent.Intent) {
+			resp, confirmRows, err := h.askConfirm(ctx, markup, userID, &intent)
+			if err != nil {
+				slog.Error("prepare confirmation failed", "intent", intent.Intent, "error", err)
+				responses = append(responses, "⚠️ Maaf, terjadi kesalahan saat memproses permintaan kamu.")
+				continue
+			}
+			rows = append(rows, confirmRows...)
+			responses = append(responses, withCorrections(resp, corrections))
+			continue
+		}
+
+		intentCtx, collector := pending.WithCollector(ctx)
+		resp, err := h.route(intentCtx, userID, &intent)
 		if err != nil {
 			slog.Error("handler error", "intent", intent.Intent, "error", err)
 			responses = append(responses, "⚠️ Maaf, terjadi kesalahan saat memproses permintaan kamu.")
 			continue
 		}
-		responses = append(responses, resp)
+		if choices := collector.Choices(); len(choices) > 0 {
+			rows = append(rows, h.choiceRows(ctx, markup, userID, &intent, choices)...)
+		}
+		responses = append(responses, withCorrections(resp, corrections))
+	}
+
+	reply := strings.Join(responses, "\n\n")
+	if err := h.convRepo.Record(ctx, userID, text, reply, tracker.Entities()); err != nil {
+		slog.Error("record conversation failed", "user_id", userID, "error", err)
+	}
+
+	if len(rows) > 0 {
+		markup.Inline(rows...)
+		return c.Send(reply, markup)
+	}
+	return c.Send(reply)
+}
+
+// withCorrections appends what the normalizer changed in the intent, so the
+// user can see, e.g., a date that was corrected.
+func withCorrections(resp string, corrections []string) string {
+	if len(corrections) == 0 {
+		return resp
+	}
+	return resp + "\n\n🛠 " + strings.Join(corrections, "\n🛠 ")
+}
+
+// choiceRows stores the intent as a pending action and returns one button row
+// per candidate. Returns nil when the action cannot be stored, leaving the
+// typed "id 123" fallback in the prompt text.
+func (h *Handler) choiceRows(ctx context.Context, markup *tele.ReplyMarkup, userID int64, intent *nlp.ParsedIntent, choices []pending.Choice) []tele.Row {
+	payload, err := json.Marshal(intent)
+	if err != nil {
+		slog.Error("marshal pending intent failed", "intent", intent.Intent, "error", err)
+		return nil
+	}
+	ids := make([]int, len(choices))
+	for i, ch := range choices {
+		ids[i] = ch.ID
+	}
+	actionID, err := h.pendingRepo.Create(ctx, userID, pending.KindPick, payload, ids)
+	if err != nil {
+		slog.Error("create pending action failed", "intent", intent.Intent, "error", err)
+		return nil
+	}
+
+	rows := make([]tele.Row, 0, len(choices))
+	for _, ch := range choices {
+		rows = append(rows, markup.Row(markup.Data(ch.Label, pickUnique, strconv.Itoa(actionID), strconv.Itoa(ch.ID))))
+	}
+	return rows
+}
+
+// handlePick runs a pending action on the exact row chosen via its button.
+func (h *Handler) handlePick(c tele.Context) error {
+	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
+
+	actionID, targetID, ok := parsePickData(c.Data())
+	if !ok {
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Tombol tidak valid."})
+	}
+
+	payload, err := h.pendingRepo.Take(ctx, userID, pending.KindPick, actionID, targetID)
+	if err != nil {
+		slog.Error("take pending action failed", "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Maaf, terjadi kesalahan. Coba lagi nanti."})
+	}
+	if payload == nil {
+		return c.Respond(&tele.CallbackResponse{Text: "⌛ Pilihan ini sudah kedaluwarsa. Kirim ulang perintahnya.", ShowAlert: true})
+	}
+
+	var intent nlp.ParsedIntent
+	if err := json.Unmarshal(payload, &intent); err != nil {
+		slog.Error("unmarshal pending intent failed", "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Maaf, terjadi kesalahan. Coba lagi nanti."})
+	}
+	switch intent.Intent {
+	case "pay_expense", "delete_expense", "edit_expense", "set_expense_category":
+		intent.ExpenseID = targetID
+	case "delete_income":
+		intent.IncomeID = targetID
+	case "complete_goal", "delete_goal":
+		intent.GoalID = targetID
+	case "edit_reminder", "delete_reminder", "pause_reminder", "resume_reminder", "cancel_reminder", "skip_reminder":
+		intent.ReminderID = targetID
+	}
+
+	slog.Info("pending action picked", "user_id", userID, "intent", intent.Intent, "target_id", targetID)
+
+	ctx, tracker := conversation.WithTracker(ctx)
+	resp, err := h.route(ctx, userID, &intent)
+	if err != nil {
+		slog.Error("handler error", "intent", intent.Intent, "error", err)
+		resp = "⚠️ Maaf, terjadi kesalahan saat memproses permintaan kamu."
+	}
+	if err := h.convRepo.Record(ctx, userID, fmt.Sprintf("pilih #%d", targetID), resp, tracker.Entities()); err != nil {
+		slog.Error("record conversation failed", "user_id", userID, "error", err)
+	}
+
+	if err := c.Respond(); err != nil {
+		slog.Warn("respond to callback failed", "error", err)
+	}
+	return c.Edit(resp)
+}
+
+// parsePickData splits "<actionID>|<targetID>" from a pick button.
+func parsePickData(data string) (actionID, targetID int, ok bool) {
+	a, t, found := strings.Cut(data, "|")
+	if !found {
+		return 0, 0, false
+	}
+	actionID, err := strconv.Atoi(a)
+	if err != nil {
+		return 0, 0, false
+	}
+	targetID, err = strconv.Atoi(t)
+	if err != nil {
+		return 0, 0, false
+	}
+	return actionID, targetID, true
+}
+
+// userContext attaches the user's settings to ctx so services, the parser
+// and formatters use their timezone and preferences.
+func (h *Handler) userContext(ctx context.Context, userID int64) context.Context {
+	return settings.WithUser(ctx, h.settingsSvc.Resolve(ctx, userID))
+}
+
+// loc returns the timezone of the user in ctx.
+func (h *Handler) loc(ctx context.Context) *time.Location {
+	return settings.Location(ctx, h.timezone)
+}
+
+// loadConversation returns the user's recent history for the parser, or nil
+// when there is none. Failures are logged and treated as no history.
+func (h *Handler) loadConversation(ctx context.Context, userID int64) *nlp.Conversation {
+	hist, err := h.convRepo.Load(ctx, userID)
+	if err != nil {
+		slog.Error("load conversation failed", "user_id", userID, "error", err)
+		return nil
+	}
+	if len(hist.Messages) == 0 && len(hist.Entities) == 0 {
+		return nil
 	}
 
-	return c.Send(strings.Join(responses, "\n\n"))
+	conv := &nlp.Conversation{}
+	for _, m := range hist.Messages {
+		conv.Turns = append(conv.Turns, nlp.Turn{Role: m.Role, Text: m.Content})
+	}
+	for _, e := range hist.Entities {
+		conv.Entities = append(conv.Entities, nlp.EntityRef{Kind: e.Kind, ID: e.ID, Label: e.Label})
+	}
+	return conv
 }
 
 func (h *Handler) route(ctx context.Context, userID int64, intent *nlp.ParsedIntent) (string, error) {
 	switch intent.Intent {
 	// === Todo ===
 	case "add_todo":
-		remindAt, _ := intent.ParseRemindAt(h.timezone)
-		dueDate, _ := intent.ParseDueDate(h.timezone)
-		msg, err := h.todoSvc.Add(ctx, userID, intent.Title, dueDate, intent.Reminder, remindAt, intent.Recurring)
+		remindAt, _ := intent.ParseRemindAt(h.loc(ctx))
+		dueDate, _ := intent.ParseDueDate(h.loc(ctx))
+		leads, _ := intent.ParseLeadTimes()
+		msg, err := h.todoSvc.Add(ctx, userID, intent.Title, dueDate, intent.Reminder, remindAt, intent.Recurring, leads, nagFromIntent(intent))
 		if err != nil {
 			return "", err
 		}
@@ -103,7 +345,7 @@ func (h *Handler) route(ctx context.Context, userID int64, intent *nlp.ParsedInt
 			slog.Error("list reminders for todo list failed", "error", err)
 			reminders = nil
 		}
-		return FormatTodoList(todos, filter, h.timezone, reminders), nil
+		return FormatTodoList(todos, filter, h.loc(ctx), reminders), nil
 
 	case "daily_briefing":
 		return h.dailyBriefing(ctx, userID)
@@ -119,8 +361,8 @@ func (h *Handler) route(ctx context.Context, userID int64, intent *nlp.ParsedInt
 		return h.todoListResponse(ctx, userID)
 
 	case "edit_todo":
-		dueDate, _ := intent.ParseDueDate(h.timezone)
-		remindAt, _ := intent.ParseRemindAt(h.timezone)
+		dueDate, _ := intent.ParseDueDate(h.loc(ctx))
+		remindAt, _ := intent.ParseRemindAt(h.loc(ctx))
 		msg, err := h.todoSvc.Edit(ctx, userID, intent.Search, intent.Title, dueDate, remindAt)
 		if err != nil {
 			return "", err
@@ -150,17 +392,27 @@ func (h *Handler) route(ctx context.Context, userID int64, intent *nlp.ParsedInt
 		}
 		return h.todoListResponse(ctx, userID)
 
+	case "list_trash":
+		todos, err := h.todoSvc.ListTrash(ctx, userID)
+		if err != nil {
+			return "", err
+		}
+		return FormatTrashList(todos, h.loc(ctx)), nil
+
+	case "restore_todo":
+		return h.todoSvc.RestoreBySearch(ctx, userID, intent.Search)
+
 	// === Expense ===
 	case "add_expense":
 		isPaid := true
 		if intent.IsPaid != nil {
 			isPaid = *intent.IsPaid
 		}
-		return h.expenseSvc.Add(ctx, userID, intent.Description, intent.Amount, isPaid)
+		return h.expenseSvc.Add(ctx, userID, intent.Description, intent.Amount, isPaid, intent.Category, intent.Account)
 
 	case "pay_expense":
-		date, _ := intent.ParseDate(h.timezone)
-		return h.expenseSvc.PayExpense(ctx, userID, intent.Search, intent.Amount, date)
+		date, _ := intent.ParseDate(h.loc(ctx))
+		return h.expenseSvc.PayExpense(ctx, userID, intent.ExpenseID, intent.Search, intent.Amount, date)
 
 	case "list_expense":
 		filter := intent.Filter
@@ -170,19 +422,67 @@ func (h *Handler) route(ctx context.Context, userID int64, intent *nlp.ParsedInt
 		return h.expenseSvc.List(ctx, userID, filter)
 
 	case "delete_expense":
-		date, _ := intent.ParseDate(h.timezone)
+		date, _ := intent.ParseDate(h.loc(ctx))
 		return h.expenseSvc.Delete(ctx, userID, intent.ExpenseID, intent.Search, intent.Amount, date)
 
 	case "edit_expense":
-		date, _ := intent.ParseDate(h.timezone)
+		date, _ := intent.ParseDate(h.loc(ctx))
 		return h.expenseSvc.Edit(ctx, userID, intent.ExpenseID, intent.Search, intent.Amount, date, intent.NewTitle, intent.NewIsPaid)
 
+	case "set_expense_category":
+		date, _ := intent.ParseDate(h.loc(ctx))
+		return h.expenseSvc.SetCategory(ctx, userID, intent.ExpenseID, intent.Search, intent.Amount, date, intent.Category)
+
+	case "set_budget":
+		return h.expenseSvc.SetBudget(ctx, userID, intent.Category, intent.Amount)
+
+	case "delete_budget":
+		return h.expenseSvc.RemoveBudget(ctx, userID, intent.Category)
+
+	case "show_budget":
+		return h.expenseSvc.BudgetStatus(ctx, userID)
+
+	case "add_income":
+		return h.expenseSvc.AddIncome(ctx, userID, intent.Description, intent.Amount, intent.Source, intent.Account)
+
+	case "delete_income":
+		return h.expenseSvc.DeleteIncome(ctx, userID, intent.IncomeID, intent.Search, intent.Amount)
+
+	case "list_income":
+		filter := intent.Filter
+		if filter == "" {
+			filter = "this_month"
+		}
+		return h.expenseSvc.ListIncome(ctx, userID, filter)
+
+	case "set_account":
+		return h.expenseSvc.SetAccount(ctx, userID, intent.Account, intent.Amount)
+
+	case "transfer":
+		return h.expenseSvc.Transfer(ctx, userID, intent.Account, intent.ToAccount, intent.Amount)
+
+	case "show_balance":
+		return h.expenseSvc.Balance(ctx, userID, intent.Account)
+
+	case "add_debt":
+		dueDate, _ := intent.ParseDueDate(h.loc(ctx))
+		return h.debtSvc.Add(ctx, userID, intent.Person, intent.Amount, intent.Direction, intent.Description, dueDate)
+
+	case "pay_debt":
+		return h.debtSvc.Pay(ctx, userID, intent.Person, intent.Amount, intent.Direction)
+
+	case "list_debt":
+		if intent.Person != "" {
+			return h.debtSvc.Show(ctx, userID, intent.Person)
+		}
+		return h.debtSvc.List(ctx, userID, intent.Direction)
+
 	case "clear_expense":
 		return h.expenseSvc.ClearByMonth(ctx, userID, intent.Month, intent.Year)
 
 	// === Project ===
 	case "add_project":
-		dueDate, _ := intent.ParseDueDate(h.timezone)
+		dueDate, _ := intent.ParseDueDate(h.loc(ctx))
 		var desc *string
 		if intent.Description != "" {
 			desc = &intent.Description
@@ -190,12 +490,13 @@ func (h *Handler) route(ctx context.Context, userID int64, intent *nlp.ParsedInt
 		return h.projectSvc.Add(ctx, userID, intent.Name, desc, dueDate)
 
 	case "add_goal":
-		remindAt, _ := intent.ParseRemindAt(h.timezone)
-		dueDate, _ := intent.ParseDueDate(h.timezone)
-		return h.projectSvc.AddGoal(ctx, userID, intent.Project, intent.Title, dueDate, intent.Reminder, remindAt, intent.Recurring)
+		remindAt, _ := intent.ParseRemindAt(h.loc(ctx))
+		dueDate, _ := intent.ParseDueDate(h.loc(ctx))
+		leads, _ := intent.ParseLeadTimes()
+		return h.projectSvc.AddGoal(ctx, userID, intent.Project, intent.Title, dueDate, intent.Reminder, remindAt, intent.Recurring, leads)
 
 	case "complete_goal":
-		return h.projectSvc.CompleteGoal(ctx, userID, intent.Project, intent.Search)
+		return h.projectSvc.CompleteGoal(ctx, userID, intent.GoalID, intent.Project, intent.Search)
 
 	case "list_project":
 		return h.projectSvc.List(ctx, userID)
@@ -207,7 +508,7 @@ func (h *Handler) route(ctx context.Context, userID int64, intent *nlp.ParsedInt
 		return h.projectSvc.Delete(ctx, userID, intent.Project)
 
 	case "delete_goal":
-		return h.projectSvc.DeleteGoal(ctx, userID, intent.Project, intent.Search)
+		return h.projectSvc.DeleteGoal(ctx, userID, intent.GoalID, intent.Project, intent.Search)
 
 	// === Reminder ===
 	case "list_reminder":
@@ -215,7 +516,65 @@ func (h *Handler) route(ctx context.Context, userID int64, intent *nlp.ParsedInt
 		if err != nil {
 			return "", err
 		}
-		return FormatReminderList(reminders, h.timezone), nil
+		return FormatReminderList(reminders, h.loc(ctx)), nil
+
+	case "add_reminder":
+		remindAt, _ := intent.ParseRemindAt(h.loc(ctx))
+		leads, _ := intent.ParseLeadTimes()
+		return h.todoSvc.AddReminder(ctx, userID, intent.Search, remindAt, intent.Recurring, leads)
+
+	case "create_reminder":
+		remindAt, _ := intent.ParseRemindAt(h.loc(ctx))
+		if remindAt == nil {
+			return "❌ Sebutkan kapan reminder dikirim, misal \"ingetin minum obat jam 9\".", nil
+		}
+		return h.reminderSvc.Add(ctx, userID, intent.Title, *remindAt, intent.Recurring, nagFromIntent(intent))
+
+	case "edit_reminder":
+		remindAt, _ := intent.ParseRemindAt(h.loc(ctx))
+		return h.reminderSvc.Edit(ctx, userID, intent.ReminderID, intent.Search, intent.Title, remindAt, intent.Recurring)
+
+	case "delete_reminder":
+		return h.reminderSvc.Delete(ctx, userID, intent.ReminderID, intent.Search)
+
+	case "pause_reminder":
+		return h.reminderSvc.Pause(ctx, userID, intent.ReminderID, intent.Search)
+
+	case "resume_reminder":
+		return h.reminderSvc.Resume(ctx, userID, intent.ReminderID, intent.Search)
+
+	case "cancel_reminder":
+		return h.reminderSvc.Cancel(ctx, userID, intent.ReminderID, intent.Search)
+
+	case "skip_reminder":
+		return h.reminderSvc.SkipNext(ctx, userID, intent.ReminderID, intent.Search)
+
+	case "set_reminder_catchup":
+		// Standalone reminders first; otherwise the search names a todo.
+		if msg, ok, err := h.reminderSvc.SetCatchUp(ctx, userID, intent.Search, intent.CatchUp); ok || err != nil {
+			return msg, err
+		}
+		return h.todoSvc.SetReminderCatchUp(ctx, userID, intent.Search, intent.CatchUp)
+
+	case "set_reminder_nag":
+		if msg, ok, err := h.reminderSvc.SetNag(ctx, userID, intent.Search, nagFromIntent(intent)); ok || err != nil {
+			return msg, err
+		}
+		return h.todoSvc.SetReminderNag(ctx, userID, intent.Search, nagFromIntent(intent))
+
+	// === Confirmation & undo ===
+	case "confirm":
+		return h.confirmLatest(ctx, userID)
+
+	case "cancel":
+		return h.cancelLatest(ctx, userID)
+
+	case "undo":
+		return h.undoLast(ctx, userID)
+
+	// === Settings ===
+	case "update_setting":
+		return h.updateSetting(ctx, userID, intent)
 
 	// === Help ===
 	case "help":
@@ -232,8 +591,8 @@ func (h *Handler) handleHelp(c tele.Context) error {
 }
 
 func (h *Handler) handleTodos(c tele.Context) error {
-	ctx := context.Background()
 	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
 	todos, err := h.todoSvc.List(ctx, userID, "all")
 	if err != nil {
 		slog.Error("list todos failed", "error", err)
@@ -244,12 +603,12 @@ func (h *Handler) handleTodos(c tele.Context) error {
 		slog.Error("list reminders failed", "error", err)
 		reminders = nil
 	}
-	return c.Send(FormatTodoList(todos, "all", h.timezone, reminders))
+	return c.Send(FormatTodoList(todos, "all", h.loc(ctx), reminders))
 }
 
 func (h *Handler) handleDaily(c tele.Context) error {
-	ctx := context.Background()
 	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
 	resp, err := h.dailyBriefing(ctx, userID)
 	if err != nil {
 		slog.Error("daily briefing failed", "error", err)
@@ -267,12 +626,13 @@ func (h *Handler) dailyBriefing(ctx context.Context, userID int64) (string, erro
 	if err != nil {
 		return "", err
 	}
-	return FormatDailyBriefing(todos, h.timezone, reminders), nil
+	loc := h.loc(ctx)
+	return FormatDailyBriefing(todos, loc, reminders, h.holidays.Upcoming(time.Now().In(loc), holidayLookaheadDays)), nil
 }
 
 func (h *Handler) handleExpenses(c tele.Context) error {
-	ctx := context.Background()
 	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
 	resp, err := h.expenseSvc.List(ctx, userID, "this_month")
 	if err != nil {
 		slog.Error("list expenses failed", "error", err)
@@ -281,9 +641,42 @@ func (h *Handler) handleExpenses(c tele.Context) error {
 	return c.Send(resp)
 }
 
+func (h *Handler) handleBudget(c tele.Context) error {
+	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
+	resp, err := h.expenseSvc.BudgetStatus(ctx, userID)
+	if err != nil {
+		slog.Error("budget status failed", "error", err)
+		return c.Send("⚠️ Gagal mengambil sisa budget.")
+	}
+	return c.Send(resp)
+}
+
+func (h *Handler) handleBalance(c tele.Context) error {
+	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
+	resp, err := h.expenseSvc.Balance(ctx, userID, "")
+	if err != nil {
+		slog.Error("account balance failed", "error", err)
+		return c.Send("⚠️ Gagal mengambil saldo akun.")
+	}
+	return c.Send(resp)
+}
+
+func (h *Handler) handleDebts(c tele.Context) error {
+	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
+	resp, err := h.debtSvc.List(ctx, userID, "")
+	if err != nil {
+		slog.Error("list debts failed", "error", err)
+		return c.Send("⚠️ Gagal mengambil daftar hutang piutang.")
+	}
+	return c.Send(resp)
+}
+
 func (h *Handler) handleProjects(c tele.Context) error {
-	ctx := context.Background()
 	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
 	resp, err := h.projectSvc.List(ctx, userID)
 	if err != nil {
 		slog.Error("list projects failed", "error", err)
@@ -293,14 +686,14 @@ func (h *Handler) handleProjects(c tele.Context) error {
 }
 
 func (h *Handler) handleReminders(c tele.Context) error {
-	ctx := context.Background()
 	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
 	reminders, err := h.reminderRepo.ListActiveByUser(ctx, userID)
 	if err != nil {
 		slog.Error("list reminders failed", "error", err)
 		return c.Send("⚠️ Gagal mengambil daftar reminder.")
 	}
-	return c.Send(FormatReminderList(reminders, h.timezone))
+	return c.Send(FormatReminderList(reminders, h.loc(ctx)))
 }
 
 // todoListResponse fetches the full todo list with reminders and returns it formatted.
@@ -314,7 +707,23 @@ func (h *Handler) todoListResponse(ctx context.Context, userID int64) (string, e
 		slog.Error("list reminders for todo list failed", "error", err)
 		reminders = nil
 	}
-	return FormatTodoList(todos, "all", h.timezone, reminders), nil
+	return FormatTodoList(todos, "all", h.loc(ctx), reminders), nil
+}
+
+// nagFromIntent returns the nagging the user asked for, with defaults for
+// what they left out, or nil when they did not ask for it.
+func nagFromIntent(intent *nlp.ParsedIntent) *reminder.Nag {
+	if !intent.Nag {
+		return nil
+	}
+	nag := &reminder.Nag{Every: reminder.DefaultNagEvery, Max: reminder.DefaultNagMax}
+	if intent.NagEvery > 0 {
+		nag.Every = time.Duration(intent.NagEvery) * time.Minute
+	}
+	if intent.NagMax > 0 {
+		nag.Max = intent.NagMax
+	}
+	return nag
 }
 
 // isNonSuccessMsg returns true when the message is an error or info notice
@@ -333,10 +742,29 @@ func helpText() string {
 • "edit todo beli susu jadi beli madu"
 • "ingetin bayar listrik besok"
 • "ingetin bayar wifi tiap tanggal 5"
+• "ingetin standup tiap hari kerja jam 9"
+• "ingetin gajian tiap tanggal 25, kalau libur maju ke hari kerja sebelumnya"
+• "ingetin bayar kos tiap akhir bulan sampai Desember"
+• "reminder minum obat kalau kelewat skip aja"
+• "ingetin minum obat jam 8 terus sampai aku bilang done"
+• "jangan ganggu jam 10 malam sampai 6 pagi" (pesan otomatis dikirim sekaligus setelahnya)
+• "jangan ganggu sampai jam 3"
+• "tambah todo laporan deadline jumat 17:00, ingetin 1 jam dan 1 hari sebelumnya"
+• "ingetin beli kado 2 hari sebelum deadline"
+• "hapus reminder #12" (nomor dari /reminders)
+• "hapus reminder minum obat"
+• "ubah reminder minum obat jadi jam 8"
+• "ubah reminder bayar wifi jadi tiap tanggal 10"
+• "jeda reminder bayar wifi" / "lanjutkan reminder bayar wifi"
+• "skip reminder bayar wifi bulan ini"
+• "stop reminder bayar wifi" (todo-nya tetap ada)
 • "list todo"
 • "selesaiin todo beli susu"
 • "hapus todo beli susu"
 • "hapus todo A, selesaikan todo B" (bulk)
+• "kosongkan todo" (minta konfirmasi dulu)
+• "lihat sampah"
+• "pulihkan todo beli susu"
 
 💰 Pengeluaran:
 • "catat makan siang 35rb"
@@ -347,12 +775,39 @@ func helpText() string {
 • "hapus beli kecap 14 feb" (filter by tanggal)
 • "ganti nama bensin jadi bensin motor"
 • "tandai beli kecap 20rb sudah lunas"
+• "ubah kategori kado jadi Belanja" (kategori otomatis belajar dari koreksi)
 • "kosongkan februari 2026"
 • "pengeluaran hari ini"
 • "pengeluaran bulan ini"
 • "semua pengeluaran"
 • "hapus pengeluaran parkir"
 
+💼 Pemasukan:
+• "catat gaji 8jt"
+• "terima transfer 500rb dari Budi"
+• "pemasukan bulan ini"
+• "hapus pemasukan bonus"
+
+🎯 Budget:
+• "budget makan 2jt per bulan"
+• "sisa budget" atau /budget
+• "hapus budget makan"
+
+💳 Akun:
+• "saldo awal BCA 5jt"
+• "catat bensin 50rb pakai gopay"
+• "catat gaji 8jt masuk ke BCA"
+• "topup gopay 200rb dari BCA"
+• "saldo" atau /saldo
+
+🤝 Hutang Piutang:
+• "Budi pinjam 200rb"
+• "aku hutang ke Sari 1jt, bayar tanggal 25" (diingatkan saat jatuh tempo)
+• "Budi bayar 50rb" / "aku bayar Sari 500rb"
+• "siapa saja yang hutang ke aku"
+• "hutang piutang" atau /hutang
+• "hutang Budi" (riwayat dengan Budi)
+
 📁 Project:
 • "buat project Laundry App deadline April"
 • "tambah goal di Laundry App: bikin wireframe"
@@ -360,11 +815,24 @@ func helpText() string {
 • "progress Laundry App"
 • "hapus project Laundry App"
 
+⚙️ Pengaturan:
+• "pengaturan" atau /settings
+• "ganti briefing jam 6 pagi"
+• "matikan laporan bulanan"
+• "ganti timezone ke WITA"
+
+↩️ Batalkan:
+• "undo" / "batalkan" — kembalikan penghapusan massal terakhir
+
 ⌨️ Shortcut Commands:
 /todos — List semua todo
 /daily — Daily briefing + reminder rutin
 /reminders — List semua reminder aktif
 /expenses — Pengeluaran bulan ini
+/budget — Sisa budget bulan ini
+/saldo — Saldo semua akun
+/hutang — Hutang piutang
 /projects — List semua project
+/settings — Pengaturan timezone & pesan terjadwal
 /help — Tampilkan bantuan ini`
 }
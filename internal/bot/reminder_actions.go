@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/reminder"
+	tele "gopkg.in/telebot.v4"
+)
+
+// handleReminderAction handles the Done / Oke / Snooze / Skip buttons on a fired
+// reminder. The reminder must belong to the user pressing the button.
+func (h *Handler) handleReminderAction(c tele.Context) error {
+	userID := c.Sender().ID
+	ctx := h.userContext(context.Background(), userID)
+
+	action, idStr, _ := strings.Cut(c.Data(), "|")
+	reminderID, err := strconv.Atoi(idStr)
+	if err != nil {
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Tombol tidak valid."})
+	}
+
+	rem, err := h.reminderRepo.GetForUser(ctx, reminderID, userID)
+	if err != nil {
+		slog.Error("get reminder for action failed", "reminder_id", reminderID, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Maaf, terjadi kesalahan. Coba lagi nanti."})
+	}
+	if rem == nil {
+		slog.Warn("reminder action rejected", "reminder_id", reminderID, "user_id", userID)
+		return c.Respond(&tele.CallbackResponse{Text: "❌ Reminder tidak ditemukan.", ShowAlert: true})
+	}
+
+	result, err := h.applyReminderAction(ctx, userID, action, rem)
+	if err != nil {
+		slog.Error("reminder action failed", "action", action, "reminder_id", reminderID, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Maaf, terjadi kesalahan. Coba lagi nanti."})
+	}
+	if result == "" {
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Tombol tidak valid."})
+	}
+
+	slog.Info("reminder action applied", "action", action, "reminder_id", reminderID, "user_id", userID)
+
+	if err := c.Respond(); err != nil {
+		slog.Warn("respond to callback failed", "error", err)
+	}
+	// Editing without markup drops the buttons so they can't be pressed twice.
+	return c.Edit(c.Message().Text + "\n\n" + result)
+}
+
+// applyReminderAction performs the action and returns the line appended to
+// the notification, or "" for an unknown action.
+func (h *Handler) applyReminderAction(ctx context.Context, userID int64, action string, rem *reminder.ReminderWithTodo) (string, error) {
+	loc := h.loc(ctx)
+	now := time.Now().In(loc)
+
+	// Any button means the user has seen the reminder, so it stops nagging.
+	if rem.NagAt != nil {
+		if err := h.reminderRepo.StopNag(ctx, rem.ID); err != nil {
+			return "", err
+		}
+	}
+
+	if at, ok := reminder.SnoozeUntil(action, now); ok {
+		if err := h.reminderRepo.Snooze(ctx, rem, at); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("💤 Diingatkan lagi %s", at.Format("2 Jan 2006 15:04 MST")), nil
+	}
+
+	switch action {
+	case reminder.ActionDone:
+		// A standalone reminder has nothing to complete; nagging has
+		// already stopped above.
+		if rem.Standalone() {
+			return "✅ Sip, sudah dilakukan.", nil
+		}
+		return h.todoSvc.CompleteByID(ctx, userID, *rem.TodoID)
+
+	case reminder.ActionAck:
+		return "👍 Oke, tidak diingatkan lagi untuk yang ini.", nil
+
+	case reminder.ActionSkip:
+		if !rem.IsRecurring || rem.RecurrenceRule == nil {
+			return "ℹ️ Reminder ini tidak berulang.", nil
+		}
+		next, err := reminder.NextOccurrence(rem.RemindAt, *rem.RecurrenceRule, loc, now, h.holidays)
+		if err != nil {
+			return "", err
+		}
+		if err := h.reminderRepo.Advance(ctx, rem.ID, next); err != nil {
+			return "", err
+		}
+		if next == nil {
+			return "⏭ Dilewati. Ini reminder terakhir dari seri ini.", nil
+		}
+		return fmt.Sprintf("⏭ Dilewati. Berikutnya: %s", next.RemindAt.In(loc).Format("2 Jan 2006 15:04 MST")), nil
+	}
+	return "", nil
+}
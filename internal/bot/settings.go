@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/nlp"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+	tele "gopkg.in/telebot.v4"
+)
+
+// settingsUnique is the callback endpoint for the /settings keyboard.
+const settingsUnique = "set"
+
+// Settings keyboard actions, sent as "<action>|<value>".
+const (
+	settingsToggle   = "toggle"
+	settingsTimezone = "tz"
+)
+
+// settingsZones are the timezones offered as buttons; others can be typed.
+var settingsZones = []string{"WIB", "WITA", "WIT"}
+
+func (h *Handler) handleSettings(c tele.Context) error {
+	ctx := h.userContext(context.Background(), c.Sender().ID)
+	us := settings.FromContext(ctx)
+
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(settingsRows(markup, us)...)
+	return c.Send(FormatSettings(us), markup)
+}
+
+// handleSettingsAction applies a settings button and redraws the panel, so
+// the keyboard stays usable for further changes.
+func (h *Handler) handleSettingsAction(c tele.Context) error {
+	ctx := context.Background()
+	userID := c.Sender().ID
+
+	action, value, _ := strings.Cut(c.Data(), "|")
+	var result string
+	var err error
+	switch action {
+	case settingsToggle:
+		m := settings.Message(value)
+		current := h.settingsSvc.Resolve(ctx, userID)
+		_, enabled := current.Schedule(m)
+		result, err = h.settingsSvc.SetEnabled(ctx, userID, m, !enabled)
+	case settingsTimezone:
+		result, err = h.settingsSvc.SetTimezone(ctx, userID, value)
+	default:
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Tombol tidak valid."})
+	}
+	if err != nil {
+		slog.Error("settings action failed", "action", action, "value", value, "error", err)
+		return c.Respond(&tele.CallbackResponse{Text: "⚠️ Maaf, terjadi kesalahan. Coba lagi nanti."})
+	}
+
+	slog.Info("settings action applied", "action", action, "value", value, "user_id", userID)
+
+	if err := c.Respond(&tele.CallbackResponse{Text: result}); err != nil {
+		slog.Warn("respond to callback failed", "error", err)
+	}
+	us := h.settingsSvc.Resolve(ctx, userID)
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(settingsRows(markup, us)...)
+	return c.Edit(FormatSettings(us), markup)
+}
+
+// settingsRows builds one toggle per scheduled message and a row of
+// timezone shortcuts, marking the current state.
+func settingsRows(markup *tele.ReplyMarkup, us *settings.Settings) []tele.Row {
+	var toggles []tele.Btn
+	for _, m := range settings.Messages {
+		_, enabled := us.Schedule(m)
+		state := "🔕"
+		if enabled {
+			state = "🔔"
+		}
+		toggles = append(toggles, markup.Data(state+" "+m.Label(), settingsUnique, settingsToggle, string(m)))
+	}
+
+	current := settings.ZoneName(us.Location())
+	var zones []tele.Btn
+	for _, z := range settingsZones {
+		label := z
+		if z == current {
+			label = "✓ " + z
+		}
+		zones = append(zones, markup.Data(label, settingsUnique, settingsTimezone, z))
+	}
+
+	rows := make([]tele.Row, 0, len(toggles)+1)
+	for _, btn := range toggles {
+		rows = append(rows, markup.Row(btn))
+	}
+	return append(rows, markup.Row(zones...))
+}
+
+// updateSetting applies an update_setting intent.
+func (h *Handler) updateSetting(ctx context.Context, userID int64, intent *nlp.ParsedIntent) (string, error) {
+	switch intent.Setting {
+	case "timezone":
+		return h.settingsSvc.SetTimezone(ctx, userID, intent.Timezone)
+	case "reminder_hour":
+		at, err := settings.ParseClock(intent.Time)
+		if err != nil {
+			return fmt.Sprintf("❌ Jam \"%s\" tidak valid.", intent.Time), nil
+		}
+		return h.settingsSvc.SetReminderHour(ctx, userID, at.Hour)
+	case "quiet_hours":
+		if intent.Time == "" {
+			if intent.Enabled == nil {
+				return "❌ Sebutkan jam tenang. Contoh: \"jangan ganggu jam 10 malam sampai 6 pagi\".", nil
+			}
+			return h.settingsSvc.SetQuietEnabled(ctx, userID, *intent.Enabled)
+		}
+		start, err := settings.ParseClock(intent.Time)
+		if err != nil {
+			return fmt.Sprintf("❌ Jam \"%s\" tidak valid.", intent.Time), nil
+		}
+		end, err := settings.ParseClock(intent.EndTime)
+		if err != nil {
+			return fmt.Sprintf("❌ Jam \"%s\" tidak valid.", intent.EndTime), nil
+		}
+		return h.settingsSvc.SetQuietHours(ctx, userID, start, end)
+	case "dnd":
+		if intent.Time == "" {
+			if intent.Enabled != nil && !*intent.Enabled {
+				return h.settingsSvc.ClearDND(ctx, userID)
+			}
+			return "❌ Sebutkan sampai jam berapa. Contoh: \"jangan ganggu sampai jam 3\".", nil
+		}
+		at, err := settings.ParseClock(intent.Time)
+		if err != nil {
+			return fmt.Sprintf("❌ Jam \"%s\" tidak valid.", intent.Time), nil
+		}
+		// The next time the clock shows at, today or tomorrow.
+		now, loc := time.Now(), h.loc(ctx)
+		until := at.On(now, loc)
+		if !until.After(now) {
+			until = at.On(now.AddDate(0, 0, 1), loc)
+		}
+		return h.settingsSvc.SetDND(ctx, userID, until)
+	}
+
+	m := settings.Message(intent.Setting)
+	if intent.Time != "" {
+		at, err := settings.ParseClock(intent.Time)
+		if err != nil {
+			return fmt.Sprintf("❌ Jam \"%s\" tidak valid.", intent.Time), nil
+		}
+		return h.settingsSvc.SetTime(ctx, userID, m, at)
+	}
+	if intent.Enabled != nil {
+		return h.settingsSvc.SetEnabled(ctx, userID, m, *intent.Enabled)
+	}
+	return "❌ Sebutkan jam baru atau nyalakan/matikan. Contoh: \"ganti briefing jam 6 pagi\".", nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -12,9 +13,19 @@ type Config struct {
 	TelegramBotToken     string
 	DatabaseURL          string
 	AnthropicAPIKey      string
+	AnthropicBaseURL     string
 	Timezone             string
 	DefaultReminderHour  int
 	SchedulerIntervalSec int
+	ReminderCatchUpMin   int
+	ParserOrder          string
+	ConversationWindow   int
+	ConversationTTLMin   int
+	PendingTTLMin        int
+	UndoWindowMin        int
+	TrashRetentionDays   int
+	HolidaysFile         string
+	AdminUserIDs         []int64
 }
 
 func Load() (*Config, error) {
@@ -24,7 +35,9 @@ func Load() (*Config, error) {
 		TelegramBotToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
 		DatabaseURL:      os.Getenv("DATABASE_URL"),
 		AnthropicAPIKey:  os.Getenv("ANTHROPIC_API_KEY"),
+		AnthropicBaseURL: os.Getenv("ANTHROPIC_BASE_URL"),
 		Timezone:         os.Getenv("TIMEZONE"),
+		HolidaysFile:     os.Getenv("HOLIDAYS_FILE"),
 	}
 
 	if cfg.TelegramBotToken == "" {
@@ -40,6 +53,15 @@ func Load() (*Config, error) {
 		cfg.Timezone = "Asia/Jakarta"
 	}
 
+	cfg.ParserOrder = os.Getenv("PARSER_ORDER")
+	switch cfg.ParserOrder {
+	case "":
+		cfg.ParserOrder = "rules_first"
+	case "rules_first", "llm_first":
+	default:
+		return nil, fmt.Errorf("invalid PARSER_ORDER: %s (want rules_first or llm_first)", cfg.ParserOrder)
+	}
+
 	if v := os.Getenv("DEFAULT_REMINDER_HOUR"); v != "" {
 		h, err := strconv.Atoi(v)
 		if err != nil {
@@ -60,5 +82,75 @@ func Load() (*Config, error) {
 		cfg.SchedulerIntervalSec = 30
 	}
 
+	if v := os.Getenv("REMINDER_CATCHUP_MIN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REMINDER_CATCHUP_MIN: %w", err)
+		}
+		cfg.ReminderCatchUpMin = n
+	} else {
+		cfg.ReminderCatchUpMin = 30
+	}
+
+	if v := os.Getenv("CONVERSATION_WINDOW"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONVERSATION_WINDOW: %w", err)
+		}
+		cfg.ConversationWindow = n
+	} else {
+		cfg.ConversationWindow = 10
+	}
+
+	if v := os.Getenv("CONVERSATION_TTL_MIN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CONVERSATION_TTL_MIN: %w", err)
+		}
+		cfg.ConversationTTLMin = n
+	} else {
+		cfg.ConversationTTLMin = 30
+	}
+
+	if v := os.Getenv("PENDING_TTL_MIN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PENDING_TTL_MIN: %w", err)
+		}
+		cfg.PendingTTLMin = n
+	} else {
+		cfg.PendingTTLMin = 10
+	}
+
+	if v := os.Getenv("UNDO_WINDOW_MIN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid UNDO_WINDOW_MIN: %w", err)
+		}
+		cfg.UndoWindowMin = n
+	} else {
+		cfg.UndoWindowMin = 15
+	}
+
+	if v := os.Getenv("TRASH_RETENTION_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRASH_RETENTION_DAYS: %w", err)
+		}
+		cfg.TrashRetentionDays = n
+	} else {
+		cfg.TrashRetentionDays = 30
+	}
+
+	if v := os.Getenv("ADMIN_USER_IDS"); v != "" {
+		for _, f := range strings.Split(v, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(f), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid ADMIN_USER_IDS: %w", err)
+			}
+			cfg.AdminUserIDs = append(cfg.AdminUserIDs, id)
+		}
+	}
+
 	return cfg, nil
 }
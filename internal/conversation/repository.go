@@ -0,0 +1,165 @@
+package conversation
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+
+	KindTodo     = "todo"
+	KindGoal     = "goal"
+	KindExpense  = "expense"
+	KindIncome   = "income"
+	KindDebt     = "debt"
+	KindProject  = "project"
+	KindReminder = "reminder"
+)
+
+// maxStoredReply caps how much of a bot reply is kept as history.
+const maxStoredReply = 600
+
+type Message struct {
+	Role      string
+	Content   string
+	CreatedAt time.Time
+}
+
+type Entity struct {
+	Kind      string
+	ID        int
+	Label     string
+	TouchedAt time.Time
+}
+
+// History is the recent, non-expired conversation state for one user.
+type History struct {
+	Messages []Message
+	Entities []Entity
+}
+
+// Repository stores a bounded, expiring window of each user's conversation.
+type Repository struct {
+	db     *sql.DB
+	window int
+	ttl    time.Duration
+}
+
+func NewRepository(db *sql.DB, window int, ttl time.Duration) *Repository {
+	return &Repository{db: db, window: window, ttl: ttl}
+}
+
+// Load returns up to `window` messages and the recently touched entities
+// that are younger than the TTL, oldest message first.
+func (r *Repository) Load(ctx context.Context, userID int64) (*History, error) {
+	since := time.Now().Add(-r.ttl)
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT role, content, created_at FROM (
+		     SELECT id, role, content, created_at FROM conversation_messages
+		     WHERE user_id = $1 AND created_at >= $2
+		     ORDER BY created_at DESC, id DESC LIMIT $3
+		 ) recent ORDER BY created_at ASC, id ASC`,
+		userID, since, r.window,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load conversation messages: %w", err)
+	}
+	defer rows.Close()
+
+	h := &History{}
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(&m.Role, &m.Content, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan conversation message: %w", err)
+		}
+		h.Messages = append(h.Messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entRows, err := r.db.QueryContext(ctx,
+		`SELECT kind, entity_id, label, touched_at FROM conversation_entities
+		 WHERE user_id = $1 AND touched_at >= $2
+		 ORDER BY touched_at DESC LIMIT $3`,
+		userID, since, r.window,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("load conversation entities: %w", err)
+	}
+	defer entRows.Close()
+
+	for entRows.Next() {
+		var e Entity
+		if err := entRows.Scan(&e.Kind, &e.ID, &e.Label, &e.TouchedAt); err != nil {
+			return nil, fmt.Errorf("scan conversation entity: %w", err)
+		}
+		h.Entities = append(h.Entities, e)
+	}
+	return h, entRows.Err()
+}
+
+// Record stores one exchange (user message + bot reply) and the entities it
+// touched, then trims the user's history back to the window size.
+func (r *Repository) Record(ctx context.Context, userID int64, userText, reply string, entities []Entity) error {
+	if runes := []rune(reply); len(runes) > maxStoredReply {
+		reply = string(runes[:maxStoredReply]) + "…"
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin record conversation: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO conversation_messages (user_id, role, content, created_at)
+		 VALUES ($1, $2, $3, NOW()), ($1, $4, $5, NOW() + INTERVAL '1 millisecond')`,
+		userID, RoleUser, userText, RoleAssistant, reply,
+	)
+	if err != nil {
+		return fmt.Errorf("insert conversation messages: %w", err)
+	}
+
+	for _, e := range entities {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO conversation_entities (user_id, kind, entity_id, label, touched_at)
+			 VALUES ($1, $2, $3, $4, NOW())
+			 ON CONFLICT (user_id, kind, entity_id) DO UPDATE SET label = EXCLUDED.label, touched_at = NOW()`,
+			userID, e.Kind, e.ID, e.Label,
+		)
+		if err != nil {
+			return fmt.Errorf("upsert conversation entity: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM conversation_messages WHERE user_id = $1 AND id NOT IN (
+		     SELECT id FROM conversation_messages WHERE user_id = $1
+		     ORDER BY created_at DESC, id DESC LIMIT $2
+		 )`,
+		userID, r.window,
+	)
+	if err != nil {
+		return fmt.Errorf("trim conversation messages: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// DeleteExpired removes messages and entities older than the TTL.
+func (r *Repository) DeleteExpired(ctx context.Context) error {
+	before := time.Now().Add(-r.ttl)
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM conversation_messages WHERE created_at < $1`, before); err != nil {
+		return fmt.Errorf("delete expired conversation messages: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM conversation_entities WHERE touched_at < $1`, before); err != nil {
+		return fmt.Errorf("delete expired conversation entities: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package conversation
+
+import (
+	"context"
+	"sync"
+)
+
+type trackerKey struct{}
+
+// Tracker collects the entities touched while handling one message so they
+// can be offered to the parser as referents ("yang tadi") on the next turn.
+type Tracker struct {
+	mu       sync.Mutex
+	entities []Entity
+}
+
+// WithTracker returns a context carrying a fresh Tracker.
+func WithTracker(ctx context.Context) (context.Context, *Tracker) {
+	t := &Tracker{}
+	return context.WithValue(ctx, trackerKey{}, t), t
+}
+
+// Touch records an entity on the context's Tracker. It is a no-op when the
+// context has none, so services can call it unconditionally.
+func Touch(ctx context.Context, kind string, id int, label string) {
+	t, ok := ctx.Value(trackerKey{}).(*Tracker)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entities = append(t.entities, Entity{Kind: kind, ID: id, Label: label})
+}
+
+func (t *Tracker) Entities() []Entity {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Entity(nil), t.entities...)
+}
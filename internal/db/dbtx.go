@@ -0,0 +1,14 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx, so helpers can run either
+// standalone or inside a caller's transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
@@ -0,0 +1,142 @@
+// Package holiday knows Indonesia's national holidays (hari libur nasional)
+// and collective leave days (cuti bersama). They are read from a JSON file:
+// a copy is bundled with the binary, and a newer one can be loaded instead
+// once the government publishes the next year's dates.
+//
+// The file lists holidays on the same date every year once, under "fixed",
+// and all others by date:
+//
+//	{
+//	  "fixed":    [{"date": "08-17", "name": "Hari Kemerdekaan RI"}],
+//	  "holidays": [{"date": "2026-03-20", "name": "Idul Fitri 1447 H", "cuti_bersama": true}]
+//	}
+package holiday
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+//go:embed holidays.json
+var bundled []byte
+
+// Holiday is a day off. Date is at midnight UTC; only its year, month and
+// day matter.
+type Holiday struct {
+	Date        time.Time
+	Name        string
+	CutiBersama bool
+}
+
+type file struct {
+	Fixed []struct {
+		Date string `json:"date"`
+		Name string `json:"name"`
+	} `json:"fixed"`
+	Holidays []struct {
+		Date        string `json:"date"`
+		Name        string `json:"name"`
+		CutiBersama bool   `json:"cuti_bersama"`
+	} `json:"holidays"`
+}
+
+type fixedHoliday struct {
+	month time.Month
+	day   int
+	name  string
+}
+
+// Calendar answers which days are holidays.
+type Calendar struct {
+	fixed    []fixedHoliday
+	byDate   map[time.Time][]Holiday
+	years    map[int]bool
+	lastYear int
+}
+
+// Load reads the calendar from path, or the bundled one when path is empty.
+func Load(path string) (*Calendar, error) {
+	if path == "" {
+		return Parse(bundled)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read holidays file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse reads a calendar in the format described in the package doc.
+func Parse(data []byte) (*Calendar, error) {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse holidays: %w", err)
+	}
+
+	c := &Calendar{byDate: make(map[time.Time][]Holiday), years: make(map[int]bool)}
+	for _, h := range f.Fixed {
+		t, err := time.Parse("01-02", h.Date)
+		if err != nil {
+			return nil, fmt.Errorf("fixed holiday %q: invalid date %q", h.Name, h.Date)
+		}
+		c.fixed = append(c.fixed, fixedHoliday{month: t.Month(), day: t.Day(), name: h.Name})
+	}
+	for _, h := range f.Holidays {
+		t, err := time.Parse("2006-01-02", h.Date)
+		if err != nil {
+			return nil, fmt.Errorf("holiday %q: invalid date %q", h.Name, h.Date)
+		}
+		c.byDate[t] = append(c.byDate[t], Holiday{Date: t, Name: h.Name, CutiBersama: h.CutiBersama})
+		c.years[t.Year()] = true
+		c.lastYear = max(c.lastYear, t.Year())
+	}
+	return c, nil
+}
+
+// Covers reports whether the calendar lists the holidays of year. For other
+// years only the fixed-date holidays are known.
+func (c *Calendar) Covers(year int) bool {
+	return c.years[year]
+}
+
+// LastYear is the last year the calendar lists holidays for.
+func (c *Calendar) LastYear() int {
+	return c.lastYear
+}
+
+// On returns the holidays on a date, fixed-date ones first.
+func (c *Calendar) On(year int, month time.Month, day int) []Holiday {
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	var hs []Holiday
+	for _, f := range c.fixed {
+		if f.month == month && f.day == day {
+			hs = append(hs, Holiday{Date: date, Name: f.name})
+		}
+	}
+	return append(hs, c.byDate[date]...)
+}
+
+// IsHoliday reports whether a date is a national holiday or cuti bersama.
+func (c *Calendar) IsHoliday(year int, month time.Month, day int) bool {
+	return len(c.On(year, month, day)) > 0
+}
+
+// Upcoming returns the holidays from the date of from through the following
+// days days, in date order. A date with several names is listed once per
+// name.
+func (c *Calendar) Upcoming(from time.Time, days int) []Holiday {
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	var hs []Holiday
+	for d := start; !d.After(start.AddDate(0, 0, days)); d = d.AddDate(0, 0, 1) {
+		hs = append(hs, c.On(d.Year(), d.Month(), d.Day())...)
+	}
+	// A fixed holiday also listed by date (e.g. with a year-specific name)
+	// shows once.
+	return slices.CompactFunc(hs, func(a, b Holiday) bool {
+		return a.Date.Equal(b.Date) && a.Name == b.Name
+	})
+}
@@ -0,0 +1,144 @@
+package holiday
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBundledCalendar(t *testing.T) {
+	c, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2025-03-28", true},
+		{"2025-03-31", true},
+		{"2025-04-01", true},
+		{"2025-04-04", true},
+		{"2025-04-05", false}, // a weekend, not a holiday
+		{"2025-04-07", true},
+		{"2025-04-08", false},
+		{"2026-03-17", false},
+		{"2026-03-18", true},
+		{"2026-03-20", true},
+		{"2026-03-24", true},
+		{"2026-03-25", false},
+		{"2030-08-17", true}, // fixed, in a year not listed
+		{"2030-03-20", false},
+	}
+	for _, tt := range tests {
+		d, err := time.Parse("2006-01-02", tt.date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.IsHoliday(d.Year(), d.Month(), d.Day()); got != tt.want {
+			t.Errorf("IsHoliday(%s) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+
+	if !c.Covers(2025) || !c.Covers(2026) || c.Covers(2030) {
+		t.Errorf("Covers 2025, 2026, 2030 = %v, %v, %v; want true, true, false", c.Covers(2025), c.Covers(2026), c.Covers(2030))
+	}
+	if c.LastYear() < 2026 {
+		t.Errorf("LastYear = %d, want at least 2026", c.LastYear())
+	}
+}
+
+func TestOn(t *testing.T) {
+	c, err := Parse([]byte(`{
+		"fixed":    [{"date": "08-17", "name": "Hari Kemerdekaan RI"}],
+		"holidays": [
+			{"date": "2025-08-17", "name": "HUT RI ke-80"},
+			{"date": "2026-03-20", "name": "Idul Fitri 1447 H", "cuti_bersama": true}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		date time.Time
+		want []Holiday
+	}{
+		{d(2025, 8, 17), []Holiday{
+			{Date: d(2025, 8, 17), Name: "Hari Kemerdekaan RI"},
+			{Date: d(2025, 8, 17), Name: "HUT RI ke-80"},
+		}},
+		{d(2031, 8, 17), []Holiday{{Date: d(2031, 8, 17), Name: "Hari Kemerdekaan RI"}}},
+		{d(2026, 3, 20), []Holiday{{Date: d(2026, 3, 20), Name: "Idul Fitri 1447 H", CutiBersama: true}}},
+		{d(2026, 3, 25), nil},
+	}
+	for _, tt := range tests {
+		if got := c.On(tt.date.Year(), tt.date.Month(), tt.date.Day()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("On(%s) = %+v, want %+v", tt.date.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestUpcoming(t *testing.T) {
+	c, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Early morning in Jakarta is still the previous day in UTC; the dates
+	// are those of from's own timezone.
+	got := c.Upcoming(time.Date(2026, 3, 18, 5, 0, 0, 0, jakarta), 6)
+	var dates []string
+	for _, h := range got {
+		dates = append(dates, h.Date.Format("01-02")+" "+h.Name)
+	}
+	want := []string{
+		"03-18 Hari Suci Nyepi",
+		"03-19 Hari Suci Nyepi (Tahun Baru Saka 1948)",
+		"03-20 Idul Fitri 1447 H",
+		"03-21 Idul Fitri 1447 H",
+		"03-22 Idul Fitri 1447 H",
+		"03-23 Idul Fitri 1447 H",
+		"03-24 Idul Fitri 1447 H",
+	}
+	if !reflect.DeepEqual(dates, want) {
+		t.Errorf("Upcoming =\n%v\nwant\n%v", dates, want)
+	}
+}
+
+func TestUpcomingListsFixedHolidayOnce(t *testing.T) {
+	c, err := Parse([]byte(`{
+		"fixed":    [{"date": "08-17", "name": "Hari Kemerdekaan RI"}],
+		"holidays": [{"date": "2025-08-17", "name": "Hari Kemerdekaan RI"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Upcoming(time.Date(2025, 8, 16, 0, 0, 0, 0, time.UTC), 3); len(got) != 1 {
+		t.Errorf("Upcoming = %+v, want one holiday", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"invalid JSON":       `{"fixed": [`,
+		"invalid fixed date": `{"fixed": [{"date": "17-08", "name": "Hari Kemerdekaan RI"}]}`,
+		"invalid date":       `{"holidays": [{"date": "2026-3-20", "name": "Idul Fitri 1447 H"}]}`,
+	}
+	for name, data := range tests {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: Parse succeeded, want an error", name)
+		}
+	}
+	if _, err := Load("testdata/missing.json"); err == nil {
+		t.Error("Load of a missing file succeeded, want an error")
+	}
+}
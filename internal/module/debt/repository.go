@@ -0,0 +1,166 @@
+package debt
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Entry is one movement in the ledger with a person. Amount is signed from
+// the user's side: positive when the person owes more, negative when the
+// user does.
+type Entry struct {
+	ID         int
+	UserID     int64
+	Person     string
+	Amount     int64
+	Note       *string
+	DueDate    *time.Time
+	ReminderID *int
+	RecordedAt time.Time
+}
+
+// Balance is what is owed between the user and a person: positive when the
+// person owes the user (piutang), negative when the user owes them (hutang).
+// DueDate is the latest due date of their entries, if any has one; earlier
+// ones usually belong to debts already paid.
+type Balance struct {
+	Person  string
+	Amount  int64
+	DueDate *time.Time
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(ctx context.Context, userID int64, person string, amount int64, note *string, dueDate *time.Time) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO debt_entries (user_id, person, amount, note, due_date) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		userID, person, amount, note, dueDate,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("create debt entry: %w", err)
+	}
+	return id, nil
+}
+
+// SetReminder links an entry to the reminder of its due date.
+func (r *Repository) SetReminder(ctx context.Context, id, reminderID int) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE debt_entries SET reminder_id = $1 WHERE id = $2`,
+		reminderID, id,
+	)
+	if err != nil {
+		return fmt.Errorf("set debt reminder: %w", err)
+	}
+	return nil
+}
+
+// balanceQuery sums the ledger per person, ignoring the case of the name.
+// The name is shown as it was first written.
+const balanceQuery = `
+	SELECT (ARRAY_AGG(person ORDER BY recorded_at, id))[1], SUM(amount), MAX(due_date)
+	FROM debt_entries
+	WHERE user_id = $1 %s
+	GROUP BY LOWER(person)`
+
+// Balances returns the balance with every person the user has a ledger
+// with, settled ones included, biggest amounts first.
+func (r *Repository) Balances(ctx context.Context, userID int64) ([]Balance, error) {
+	rows, err := r.db.QueryContext(ctx,
+		fmt.Sprintf(balanceQuery, "")+` ORDER BY ABS(SUM(amount)) DESC, LOWER(person)`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list debt balances: %w", err)
+	}
+	return scanBalances(rows)
+}
+
+// FindPeople returns the balances of the people whose name contains search,
+// taken literally so "%" or "_" in a name match only themselves. An exact
+// match, ignoring case, is returned alone.
+func (r *Repository) FindPeople(ctx context.Context, userID int64, search string) ([]Balance, error) {
+	rows, err := r.db.QueryContext(ctx,
+		fmt.Sprintf(balanceQuery, `AND strpos(LOWER(person), LOWER($2)) > 0`)+` ORDER BY LOWER(person)`,
+		userID, search,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("find debt people: %w", err)
+	}
+	people, err := scanBalances(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range people {
+		if strings.EqualFold(p.Person, search) {
+			return []Balance{p}, nil
+		}
+	}
+	return people, nil
+}
+
+func scanBalances(rows *sql.Rows) ([]Balance, error) {
+	defer rows.Close()
+	var balances []Balance
+	for rows.Next() {
+		var b Balance
+		if err := rows.Scan(&b.Person, &b.Amount, &b.DueDate); err != nil {
+			return nil, fmt.Errorf("scan debt balance: %w", err)
+		}
+		balances = append(balances, b)
+	}
+	return balances, rows.Err()
+}
+
+// ListByPerson returns the ledger with a person, oldest first.
+func (r *Repository) ListByPerson(ctx context.Context, userID int64, person string) ([]Entry, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, person, amount, note, due_date, reminder_id, recorded_at
+		 FROM debt_entries WHERE user_id = $1 AND LOWER(person) = LOWER($2)
+		 ORDER BY recorded_at, id`,
+		userID, person,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list debt entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Person, &e.Amount, &e.Note, &e.DueDate, &e.ReminderID, &e.RecordedAt); err != nil {
+			return nil, fmt.Errorf("scan debt entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ReminderIDs returns the due-date reminders of the ledger with a person.
+func (r *Repository) ReminderIDs(ctx context.Context, userID int64, person string) ([]int, error) {
+	var ids []int64
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COALESCE(ARRAY_AGG(reminder_id), '{}') FROM debt_entries
+		 WHERE user_id = $1 AND LOWER(person) = LOWER($2) AND reminder_id IS NOT NULL`,
+		userID, person,
+	).Scan(pq.Array(&ids))
+	if err != nil {
+		return nil, fmt.Errorf("list debt reminders: %w", err)
+	}
+	out := make([]int, len(ids))
+	for i, id := range ids {
+		out[i] = int(id)
+	}
+	return out, nil
+}
@@ -0,0 +1,380 @@
+package debt
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/conversation"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/module/expense"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/reminder"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+)
+
+// Directions of a debt: whose debt it is.
+const (
+	TheyOwe = "they_owe" // the person owes the user (piutang)
+	IOwe    = "i_owe"    // the user owes the person (hutang)
+)
+
+var indonesianMonths = [...]string{
+	"Jan", "Feb", "Mar", "Apr", "Mei", "Jun",
+	"Jul", "Agu", "Sep", "Okt", "Nov", "Des",
+}
+
+type Service struct {
+	repo         *Repository
+	reminderRepo *reminder.Repository
+	timezone     *time.Location
+}
+
+func NewService(repo *Repository, reminderRepo *reminder.Repository, timezone *time.Location) *Service {
+	return &Service{repo: repo, reminderRepo: reminderRepo, timezone: timezone}
+}
+
+// defaultReminderHour is used for due-date reminders when the context
+// carries no user settings.
+const defaultReminderHour = 7
+
+// loc returns the timezone of the user being served.
+func (s *Service) loc(ctx context.Context) *time.Location {
+	return settings.Location(ctx, s.timezone)
+}
+
+// Add records money lent to a person ("Budi pinjam 200rb", direction
+// TheyOwe) or borrowed from them ("aku hutang ke Sari 1jt", IOwe). With a
+// due date, a standalone reminder is set for it.
+func (s *Service) Add(ctx context.Context, userID int64, person string, amount int64, direction, note string, dueDate *time.Time) (string, error) {
+	person = strings.TrimSpace(person)
+	if person == "" {
+		return "❌ Sebutkan namanya, contoh: \"Budi pinjam 200rb\".", nil
+	}
+	if amount <= 0 {
+		return "❌ Nominal hutang harus lebih dari 0.", nil
+	}
+	signed := amount
+	switch direction {
+	case TheyOwe:
+	case IOwe:
+		signed = -amount
+	default:
+		return "❌ Siapa yang hutang? Contoh: \"Budi pinjam 200rb\" atau \"aku hutang ke Sari 1jt\".", nil
+	}
+
+	var notePtr *string
+	if note = strings.TrimSpace(note); note != "" {
+		notePtr = &note
+	}
+	id, err := s.repo.Create(ctx, userID, person, signed, notePtr, dueDate)
+	if err != nil {
+		return "", err
+	}
+
+	conversation.Touch(ctx, conversation.KindDebt, id, describeEntry(person, signed))
+
+	lines := []string{"📒 Dicatat: " + describeEntry(person, signed)}
+	if notePtr != nil {
+		lines = append(lines, "📝 "+note)
+	}
+	if dueDate != nil {
+		lines = append(lines, s.remindDue(ctx, userID, id, person, signed, *dueDate))
+	}
+
+	people, err := s.repo.FindPeople(ctx, userID, person)
+	if err != nil {
+		return "", err
+	}
+	if len(people) == 1 {
+		lines = append(lines, "", "💰 "+describeBalance(people[0].Person, people[0].Amount))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// remindDue sets a reminder on the due date of entry id and returns the line
+// describing it. A due date already past gets no reminder.
+func (s *Service) remindDue(ctx context.Context, userID int64, id int, person string, amount int64, due time.Time) string {
+	loc := s.loc(ctx)
+	at := reminder.RelativeTime(due, 0, loc, settings.ReminderHour(ctx, defaultReminderHour))
+	dueStr := formatDate(due, loc, true)
+	if !at.After(time.Now()) {
+		return fmt.Sprintf("⏰ Jatuh tempo: %s (sudah lewat, tanpa reminder)", dueStr)
+	}
+
+	title := fmt.Sprintf("Tagih hutang %s (%s)", person, expense.FormatRupiah(amount))
+	if amount < 0 {
+		title = fmt.Sprintf("Bayar hutang ke %s (%s)", person, expense.FormatRupiah(-amount))
+	}
+	reminderID, err := s.reminderRepo.CreateStandalone(ctx, userID, title, at, "", nil)
+	if err != nil {
+		slog.Error("create debt reminder failed", "user_id", userID, "debt_entry_id", id, "error", err)
+		return fmt.Sprintf("⏰ Jatuh tempo: %s (reminder gagal dibuat)", dueStr)
+	}
+	if err := s.repo.SetReminder(ctx, id, reminderID); err != nil {
+		slog.Error("link debt reminder failed", "user_id", userID, "debt_entry_id", id, "error", err)
+	}
+	return fmt.Sprintf("⏰ Jatuh tempo: %s, diingatkan jam %s", dueStr, at.In(loc).Format("15:04"))
+}
+
+// Pay records a payment on a debt: "Budi bayar 50rb" pays off what Budi owes
+// (direction TheyOwe), "aku bayar Sari 500rb" what the user owes (IOwe). An
+// amount of 0 pays off all of it. Once the balance is settled the due-date
+// reminders with the person are cancelled.
+func (s *Service) Pay(ctx context.Context, userID int64, person string, amount int64, direction string) (string, error) {
+	if direction != TheyOwe && direction != IOwe {
+		return "❌ Siapa yang bayar? Contoh: \"Budi bayar 50rb\" atau \"aku bayar Sari 500rb\".", nil
+	}
+	if amount < 0 {
+		return "❌ Nominal pembayaran tidak boleh negatif.", nil
+	}
+	b, msg, err := s.resolvePerson(ctx, userID, person)
+	if err != nil || msg != "" {
+		return msg, err
+	}
+
+	owed := b.Amount
+	if direction == IOwe {
+		owed = -b.Amount
+	}
+	if owed <= 0 {
+		if direction == IOwe {
+			return fmt.Sprintf("ℹ️ Kamu tidak sedang hutang ke %s. Saat ini: %s.", b.Person, describeBalance(b.Person, b.Amount)), nil
+		}
+		return fmt.Sprintf("ℹ️ %s tidak sedang hutang ke kamu. Saat ini: %s.", b.Person, describeBalance(b.Person, b.Amount)), nil
+	}
+	if amount == 0 {
+		amount = owed
+	}
+	if amount > owed {
+		if direction == IOwe {
+			return fmt.Sprintf("❌ Hutangmu ke %s cuma %s. Kalau sudah lunas, bilang \"lunasi hutang ke %s\".",
+				b.Person, expense.FormatRupiah(owed), strings.ToLower(b.Person)), nil
+		}
+		return fmt.Sprintf("❌ %s cuma hutang %s ke kamu. Kalau sudah lunas, bilang \"%s lunas\".",
+			b.Person, expense.FormatRupiah(owed), strings.ToLower(b.Person)), nil
+	}
+
+	// A payment moves the balance back towards zero.
+	signed := -amount
+	if direction == IOwe {
+		signed = amount
+	}
+	if _, err := s.repo.Create(ctx, userID, b.Person, signed, nil, nil); err != nil {
+		return "", err
+	}
+
+	header := fmt.Sprintf("💵 %s bayar %s", b.Person, expense.FormatRupiah(amount))
+	if direction == IOwe {
+		header = fmt.Sprintf("💵 Kamu bayar %s ke %s", expense.FormatRupiah(amount), b.Person)
+	}
+	if amount < owed {
+		return fmt.Sprintf("%s\n💰 Sisa: %s", header, describeBalance(b.Person, b.Amount+signed)), nil
+	}
+
+	lines := []string{header, fmt.Sprintf("✅ Hutang dengan %s lunas!", b.Person)}
+	ids, err := s.repo.ReminderIDs(ctx, userID, b.Person)
+	if err != nil {
+		slog.Error("list debt reminders failed", "user_id", userID, "error", err)
+	}
+	for _, id := range ids {
+		if err := s.reminderRepo.Cancel(ctx, id); err != nil {
+			slog.Error("cancel debt reminder failed", "user_id", userID, "reminder_id", id, "error", err)
+		}
+	}
+	if len(ids) > 0 {
+		lines = append(lines, "⏰ Reminder jatuh temponya dihentikan.")
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// List summarizes who owes whom, leaving out settled people. direction
+// limits it to one side ("siapa saja yang hutang ke aku" is TheyOwe); empty
+// shows both.
+//
+// 📒 Hutang Piutang
+//
+// 💚 Hutang ke kamu
+// • Budi: Rp 150.000 · ⏰ 25 Okt
+// • Andi: Rp 50.000
+// Total: Rp 200.000
+//
+// 🔴 Hutang kamu
+// • Sari: Rp 1.000.000 · ⏰ 1 Okt (lewat)
+// Total: Rp 1.000.000
+func (s *Service) List(ctx context.Context, userID int64, direction string) (string, error) {
+	balances, err := s.repo.Balances(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	loc := s.loc(ctx)
+
+	var theirs, mine []Balance
+	for _, b := range balances {
+		switch {
+		case b.Amount > 0:
+			theirs = append(theirs, b)
+		case b.Amount < 0:
+			mine = append(mine, b)
+		}
+	}
+
+	switch direction {
+	case TheyOwe:
+		if len(theirs) == 0 {
+			return "📭 Tidak ada yang hutang ke kamu.", nil
+		}
+		return strings.Join(append([]string{"💚 Yang Hutang ke Kamu\n"}, balanceLines(theirs, loc)...), "\n"), nil
+	case IOwe:
+		if len(mine) == 0 {
+			return "📭 Kamu tidak punya hutang.", nil
+		}
+		return strings.Join(append([]string{"🔴 Hutang Kamu\n"}, balanceLines(mine, loc)...), "\n"), nil
+	}
+
+	if len(theirs) == 0 && len(mine) == 0 {
+		return "📭 Tidak ada hutang piutang. Catat dengan \"Budi pinjam 200rb\" atau \"aku hutang ke Sari 1jt\".", nil
+	}
+	lines := []string{"📒 Hutang Piutang"}
+	if len(theirs) > 0 {
+		lines = append(lines, "", "💚 Hutang ke kamu")
+		lines = append(lines, balanceLines(theirs, loc)...)
+	}
+	if len(mine) > 0 {
+		lines = append(lines, "", "🔴 Hutang kamu")
+		lines = append(lines, balanceLines(mine, loc)...)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Show lists the ledger with one person and the balance after each entry.
+//
+// 📒 Hutang Piutang — Budi
+//
+// 📤 1 Okt · Rp 200.000 ke Budi · Budi hutang Rp 200.000
+// 📥 5 Okt · Rp 50.000 dari Budi · Budi hutang Rp 150.000
+// ─────────────
+// 💰 Budi hutang ke kamu Rp 150.000
+func (s *Service) Show(ctx context.Context, userID int64, person string) (string, error) {
+	b, msg, err := s.resolvePerson(ctx, userID, person)
+	if err != nil || msg != "" {
+		return msg, err
+	}
+	entries, err := s.repo.ListByPerson(ctx, userID, b.Person)
+	if err != nil {
+		return "", err
+	}
+	loc := s.loc(ctx)
+
+	lines := []string{fmt.Sprintf("📒 Hutang Piutang — %s\n", b.Person)}
+	var running int64
+	for _, e := range entries {
+		running += e.Amount
+		// The sign of an entry is which way the money went.
+		line := fmt.Sprintf("📤 %s · %s ke %s", formatDate(e.RecordedAt, loc, false), expense.FormatRupiah(e.Amount), b.Person)
+		if e.Amount < 0 {
+			line = fmt.Sprintf("📥 %s · %s dari %s", formatDate(e.RecordedAt, loc, false), expense.FormatRupiah(-e.Amount), b.Person)
+		}
+		if e.Note != nil {
+			line += fmt.Sprintf(" (%s)", *e.Note)
+		}
+		line += " · " + shortBalance(b.Person, running)
+		if e.DueDate != nil {
+			line += " · ⏰ " + formatDate(*e.DueDate, loc, false)
+		}
+		lines = append(lines, line)
+	}
+	lines = append(lines, "─────────────")
+	lines = append(lines, "💰 "+describeBalance(b.Person, b.Amount))
+	return strings.Join(lines, "\n"), nil
+}
+
+// resolvePerson finds the person whose ledger the user means. When there is
+// no such person, or several, it returns a message instead.
+func (s *Service) resolvePerson(ctx context.Context, userID int64, person string) (*Balance, string, error) {
+	person = strings.TrimSpace(person)
+	if person == "" {
+		return nil, "❌ Sebutkan namanya.", nil
+	}
+	people, err := s.repo.FindPeople(ctx, userID, person)
+	if err != nil {
+		return nil, "", err
+	}
+	switch len(people) {
+	case 0:
+		return nil, fmt.Sprintf("❌ Belum ada catatan hutang dengan \"%s\".", person), nil
+	case 1:
+		return &people[0], "", nil
+	}
+	names := make([]string, len(people))
+	for i, p := range people {
+		names[i] = p.Person
+	}
+	return nil, fmt.Sprintf("🔍 Ada beberapa nama \"%s\": %s.\nSebutkan nama lengkapnya.", person, strings.Join(names, ", ")), nil
+}
+
+// balanceLines lists balances of one side with their total. A due date is
+// marked when it has passed.
+func balanceLines(balances []Balance, loc *time.Location) []string {
+	today := time.Now().In(loc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+
+	var lines []string
+	var total int64
+	for _, b := range balances {
+		amount := b.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+		total += amount
+		line := fmt.Sprintf("• %s: %s", b.Person, expense.FormatRupiah(amount))
+		if b.DueDate != nil {
+			line += " · ⏰ " + formatDate(*b.DueDate, loc, false)
+			if b.DueDate.Before(today) {
+				line += " (lewat)"
+			}
+		}
+		lines = append(lines, line)
+	}
+	return append(lines, "Total: "+expense.FormatRupiah(total))
+}
+
+// describeEntry describes a new debt: "Budi pinjam Rp 200.000".
+func describeEntry(person string, amount int64) string {
+	if amount < 0 {
+		return fmt.Sprintf("kamu hutang ke %s %s", person, expense.FormatRupiah(-amount))
+	}
+	return fmt.Sprintf("%s pinjam %s", person, expense.FormatRupiah(amount))
+}
+
+// describeBalance describes a balance in a sentence.
+func describeBalance(person string, amount int64) string {
+	switch {
+	case amount > 0:
+		return fmt.Sprintf("%s hutang ke kamu %s", person, expense.FormatRupiah(amount))
+	case amount < 0:
+		return fmt.Sprintf("kamu hutang ke %s %s", person, expense.FormatRupiah(-amount))
+	default:
+		return "lunas"
+	}
+}
+
+// shortBalance is describeBalance for a ledger line.
+func shortBalance(person string, amount int64) string {
+	switch {
+	case amount > 0:
+		return fmt.Sprintf("%s hutang %s", person, expense.FormatRupiah(amount))
+	case amount < 0:
+		return fmt.Sprintf("kamu hutang %s", expense.FormatRupiah(-amount))
+	default:
+		return "lunas"
+	}
+}
+
+func formatDate(t time.Time, loc *time.Location, withYear bool) string {
+	t = t.In(loc)
+	if withYear {
+		return fmt.Sprintf("%d %s %d", t.Day(), indonesianMonths[t.Month()-1], t.Year())
+	}
+	return fmt.Sprintf("%d %s", t.Day(), indonesianMonths[t.Month()-1])
+}
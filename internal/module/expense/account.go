@@ -0,0 +1,226 @@
+package expense
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Account is where money is paid from or lands: cash, a bank account or an
+// e-wallet. Balance is computed by the repository; see balanceQuery.
+type Account struct {
+	ID             int
+	UserID         int64
+	Name           string
+	OpeningBalance int64
+	Balance        int64
+}
+
+// noAccount is how expenses and incomes without an account are shown.
+const noAccount = "Tanpa akun"
+
+// SetAccount creates an account with an opening balance, or changes the
+// opening balance of an existing one ("saldo awal BCA 5jt").
+func (s *Service) SetAccount(ctx context.Context, userID int64, name string, openingBalance int64) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "❌ Sebutkan nama akunnya, contoh: \"saldo awal BCA 5jt\".", nil
+	}
+	if openingBalance < 0 {
+		return "❌ Saldo awal tidak boleh negatif.", nil
+	}
+	existing, err := s.repo.FindAccountByName(ctx, userID, name)
+	if err != nil {
+		return "", err
+	}
+	if existing == nil {
+		if _, err := s.repo.CreateAccount(ctx, userID, name, openingBalance); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("💳 Akun %s dibuat dengan saldo awal %s.\nCatat pengeluaran darinya, misal \"catat bensin 50rb pakai %s\".",
+			name, FormatRupiah(openingBalance), strings.ToLower(name)), nil
+	}
+
+	if err := s.repo.SetOpeningBalance(ctx, existing.ID, openingBalance); err != nil {
+		return "", err
+	}
+	balance := existing.Balance - existing.OpeningBalance + openingBalance
+	return fmt.Sprintf("💳 Saldo awal %s diubah jadi %s.\nSaldo sekarang: %s",
+		existing.Name, FormatRupiah(openingBalance), formatBalance(balance)), nil
+}
+
+// Transfer moves money between two accounts, e.g. "topup gopay 200rb dari
+// BCA".
+func (s *Service) Transfer(ctx context.Context, userID int64, fromName, toName string, amount int64) (string, error) {
+	if amount <= 0 {
+		return "❌ Nominal transfer harus lebih dari 0.", nil
+	}
+	from, msg, err := s.resolveAccount(ctx, userID, fromName)
+	if err != nil || msg != "" {
+		return msg, err
+	}
+	to, msg, err := s.resolveAccount(ctx, userID, toName)
+	if err != nil || msg != "" {
+		return msg, err
+	}
+	if from.ID == to.ID {
+		return "❌ Akun asal dan tujuan sama.", nil
+	}
+
+	if err := s.repo.CreateTransfer(ctx, userID, from.ID, to.ID, amount); err != nil {
+		return "", err
+	}
+	lines := []string{
+		fmt.Sprintf("🔁 Transfer %s: %s → %s\n", FormatRupiah(amount), from.Name, to.Name),
+		fmt.Sprintf("💳 %s: %s", from.Name, formatBalance(from.Balance-amount)),
+		fmt.Sprintf("💳 %s: %s", to.Name, formatBalance(to.Balance+amount)),
+	}
+	if from.Balance-amount < 0 {
+		lines = append(lines, fmt.Sprintf("\n⚠️ Saldo %s jadi minus. Cek lagi saldo awalnya dengan \"saldo awal %s <nominal>\".", from.Name, strings.ToLower(from.Name)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Balance shows the balance of one account, or of all of them when name is
+// empty.
+//
+// 💳 Saldo Akun
+//
+// Cash: Rp 350.000
+// BCA: Rp 4.800.000
+// GoPay: Rp 180.000
+// ─────────────
+// 💰 Total: Rp 5.330.000
+func (s *Service) Balance(ctx context.Context, userID int64, name string) (string, error) {
+	if name != "" {
+		account, msg, err := s.resolveAccount(ctx, userID, name)
+		if err != nil || msg != "" {
+			return msg, err
+		}
+		return fmt.Sprintf("💳 Saldo %s: %s", account.Name, formatBalance(account.Balance)), nil
+	}
+
+	accounts, err := s.repo.ListAccounts(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(accounts) == 0 {
+		return "ℹ️ Belum ada akun. Buat dengan saldo awalnya, contoh: \"saldo awal BCA 5jt\".", nil
+	}
+
+	lines := []string{"💳 Saldo Akun\n"}
+	var total int64
+	for _, a := range accounts {
+		lines = append(lines, fmt.Sprintf("%s: %s", a.Name, formatBalance(a.Balance)))
+		total += a.Balance
+	}
+	lines = append(lines, "─────────────")
+	lines = append(lines, fmt.Sprintf("💰 Total: %s", formatBalance(total)))
+	return strings.Join(lines, "\n"), nil
+}
+
+// resolveAccount finds the user's account called name. When there is none,
+// it returns a message listing the accounts there are instead.
+func (s *Service) resolveAccount(ctx context.Context, userID int64, name string) (*Account, string, error) {
+	account, err := s.repo.FindAccountByName(ctx, userID, strings.TrimSpace(name))
+	if err != nil {
+		return nil, "", err
+	}
+	if account != nil {
+		return account, "", nil
+	}
+	accounts, err := s.repo.ListAccounts(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(accounts) == 0 {
+		return nil, fmt.Sprintf("❌ Akun \"%s\" belum ada. Buat dulu, contoh: \"saldo awal %s 100rb\".", name, strings.ToLower(name)), nil
+	}
+	names := make([]string, len(accounts))
+	for i, a := range accounts {
+		names[i] = a.Name
+	}
+	return nil, fmt.Sprintf("❌ Akun \"%s\" belum ada. Akunmu: %s.\nBuat dulu dengan \"saldo awal %s <nominal>\".",
+		name, strings.Join(names, ", "), strings.ToLower(name)), nil
+}
+
+// accountBreakdown lists money out and in per account. It returns nothing
+// when no expense or income names an account, since a single "Tanpa akun"
+// line says nothing.
+//
+//	🏦 Per Akun
+//	BCA: keluar Rp 1.200.000 · masuk Rp 8.000.000
+//	GoPay: keluar Rp 300.000
+//	Tanpa akun: keluar Rp 50.000
+func accountBreakdown(expenses []Expense, incomes []Income, accounts []Account, indent string) []string {
+	names := make(map[int]string, len(accounts))
+	for _, a := range accounts {
+		names[a.ID] = a.Name
+	}
+	nameOf := func(id *int) string {
+		if id == nil {
+			return noAccount
+		}
+		if name, ok := names[*id]; ok {
+			return name
+		}
+		return noAccount
+	}
+
+	out := make(map[string]int64)
+	in := make(map[string]int64)
+	named := false
+	for _, e := range expenses {
+		out[nameOf(e.AccountID)] += e.Amount
+		named = named || e.AccountID != nil
+	}
+	for _, i := range incomes {
+		in[nameOf(i.AccountID)] += i.Amount
+		named = named || i.AccountID != nil
+	}
+	if !named {
+		return nil
+	}
+
+	var keys []string
+	for name := range out {
+		keys = append(keys, name)
+	}
+	for name := range in {
+		if _, ok := out[name]; !ok {
+			keys = append(keys, name)
+		}
+	}
+	// Biggest spender first; "Tanpa akun" last.
+	sort.Slice(keys, func(i, j int) bool {
+		if (keys[i] == noAccount) != (keys[j] == noAccount) {
+			return keys[j] == noAccount
+		}
+		if out[keys[i]] != out[keys[j]] {
+			return out[keys[i]] > out[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	lines := []string{indent + "🏦 Per Akun"}
+	for _, name := range keys {
+		var parts []string
+		if out[name] > 0 {
+			parts = append(parts, "keluar "+FormatRupiah(out[name]))
+		}
+		if in[name] > 0 {
+			parts = append(parts, "masuk "+FormatRupiah(in[name]))
+		}
+		lines = append(lines, fmt.Sprintf("%s%s: %s", indent, name, strings.Join(parts, " · ")))
+	}
+	return lines
+}
+
+// formatBalance formats a balance, which unlike an amount can be negative.
+func formatBalance(balance int64) string {
+	if balance < 0 {
+		return "-" + FormatRupiah(-balance)
+	}
+	return FormatRupiah(balance)
+}
@@ -0,0 +1,274 @@
+package expense
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/notify"
+)
+
+// Budget is a user's monthly spending limit for a category.
+type Budget struct {
+	CategoryID int
+	Amount     int64
+}
+
+// budgetThresholds are the shares of a budget, in percent, that are alerted
+// once a month each as spending crosses them.
+var budgetThresholds = []int{50, 80, 100}
+
+// SetBudget sets the monthly budget of a category, e.g. "budget makan 2jt
+// per bulan".
+func (s *Service) SetBudget(ctx context.Context, userID int64, categoryName string, amount int64) (string, error) {
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return "", err
+	}
+	category := findCategory(categories, categoryName)
+	if category == nil {
+		return fmt.Sprintf("❌ Kategori \"%s\" tidak ada. Pilihan: %s.", categoryName, categoryNames(categories)), nil
+	}
+	if amount <= 0 {
+		return "❌ Nominal budget harus lebih dari 0.", nil
+	}
+	if err := s.repo.SetBudget(ctx, userID, category.ID, amount); err != nil {
+		return "", err
+	}
+
+	loc := s.loc(ctx)
+	now := time.Now().In(loc)
+	sums, err := s.repo.SumByCategory(ctx, userID, now.Year(), now.Month(), loc)
+	if err != nil {
+		return "", err
+	}
+	spent := sums[category.ID]
+	return fmt.Sprintf("🎯 Budget %s %s: %s per bulan.\nBulan ini terpakai %s (%d%%) · %s.",
+		category.Emoji, category.Name, FormatRupiah(amount),
+		FormatRupiah(spent), budgetPercent(spent, amount), budgetLeft(spent, amount)), nil
+}
+
+// RemoveBudget removes the monthly budget of a category.
+func (s *Service) RemoveBudget(ctx context.Context, userID int64, categoryName string) (string, error) {
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return "", err
+	}
+	category := findCategory(categories, categoryName)
+	if category == nil {
+		return fmt.Sprintf("❌ Kategori \"%s\" tidak ada. Pilihan: %s.", categoryName, categoryNames(categories)), nil
+	}
+	found, err := s.repo.DeleteBudget(ctx, userID, category.ID)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return fmt.Sprintf("ℹ️ Belum ada budget untuk %s %s.", category.Emoji, category.Name), nil
+	}
+	return fmt.Sprintf("🗑️ Budget %s %s dihapus.", category.Emoji, category.Name), nil
+}
+
+// BudgetStatus shows what is left of each budget this month ("sisa budget").
+//
+// 🎯 Sisa Budget — Oktober 2026
+//
+// 🍜 Makan
+//
+//	Rp 1.200.000 / Rp 2.000.000 (60%)
+//	Sisa Rp 800.000 · ±Rp 50.000/hari
+//
+// 🚗 Transport 🔴
+//
+//	Rp 900.000 / Rp 800.000 (112%)
+//	Lewat Rp 100.000
+//
+// ─────────────
+// 💵 Total: Rp 2.100.000 / Rp 2.800.000
+// ⏳ 16 hari lagi
+func (s *Service) BudgetStatus(ctx context.Context, userID int64) (string, error) {
+	budgets, err := s.repo.ListBudgets(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if len(budgets) == 0 {
+		return "ℹ️ Belum ada budget. Contoh: \"budget makan 2jt per bulan\".", nil
+	}
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return "", err
+	}
+	loc := s.loc(ctx)
+	now := time.Now().In(loc)
+	sums, err := s.repo.SumByCategory(ctx, userID, now.Year(), now.Month(), loc)
+	if err != nil {
+		return "", err
+	}
+	daysLeft := daysLeftInMonth(now)
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("🎯 Sisa Budget — %s %d\n", indonesianMonthsFull[now.Month()-1], now.Year()))
+
+	var totalSpent, totalBudget int64
+	for _, b := range budgets {
+		c := categoryOf(categories, Expense{CategoryID: &b.CategoryID})
+		spent := sums[b.CategoryID]
+		totalSpent += spent
+		totalBudget += b.Amount
+
+		header := fmt.Sprintf("%s %s", c.Emoji, c.Name)
+		if icon := budgetIcon(spent, b.Amount); icon != "✅" {
+			header += " " + icon
+		}
+		lines = append(lines, header)
+		lines = append(lines, fmt.Sprintf("   %s / %s (%d%%)", FormatRupiah(spent), FormatRupiah(b.Amount), budgetPercent(spent, b.Amount)))
+		if spent < b.Amount {
+			lines = append(lines, fmt.Sprintf("   Sisa %s · ±%s/hari", FormatRupiah(b.Amount-spent), FormatRupiah((b.Amount-spent)/int64(daysLeft))))
+		} else if spent == b.Amount {
+			lines = append(lines, "   Habis")
+		} else {
+			lines = append(lines, "   Lewat "+FormatRupiah(spent-b.Amount))
+		}
+		lines = append(lines, "")
+	}
+
+	lines = append(lines, "─────────────")
+	lines = append(lines, fmt.Sprintf("💵 Total: %s / %s", FormatRupiah(totalSpent), FormatRupiah(totalBudget)))
+	lines = append(lines, fmt.Sprintf("⏳ %d hari lagi", daysLeft))
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// checkBudget runs after an expense is added to category. It returns a line
+// on what is left of the category's budget for the Add reply, or "" when
+// the category has no budget, and alerts the user the first time this month
+// spending crosses one of budgetThresholds.
+func (s *Service) checkBudget(ctx context.Context, userID int64, category Category) string {
+	budgets, err := s.repo.ListBudgets(ctx, userID)
+	if err != nil {
+		slog.Error("list budgets failed", "user_id", userID, "error", err)
+		return ""
+	}
+	var budget *Budget
+	for i, b := range budgets {
+		if b.CategoryID == category.ID {
+			budget = &budgets[i]
+		}
+	}
+	if budget == nil {
+		return ""
+	}
+
+	loc := s.loc(ctx)
+	now := time.Now().In(loc)
+	sums, err := s.repo.SumByCategory(ctx, userID, now.Year(), now.Month(), loc)
+	if err != nil {
+		slog.Error("sum expenses by category failed", "user_id", userID, "error", err)
+		return ""
+	}
+	spent := sums[category.ID]
+	pct := budgetPercent(spent, budget.Amount)
+	info := fmt.Sprintf("🎯 Budget %s: %s / %s (%d%%) · %s",
+		category.Name, FormatRupiah(spent), FormatRupiah(budget.Amount), pct, budgetLeft(spent, budget.Amount))
+
+	crossed := 0
+	for _, t := range budgetThresholds {
+		if pct >= t {
+			crossed = t
+		}
+	}
+	if crossed == 0 {
+		return info
+	}
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	claimed, err := s.repo.ClaimBudgetAlert(ctx, userID, category.ID, month, crossed)
+	if err != nil {
+		slog.Error("claim budget alert failed", "user_id", userID, "category_id", category.ID, "error", err)
+		return info
+	}
+	if claimed {
+		alert := formatBudgetAlert(category, spent, budget.Amount, crossed, daysLeftInMonth(now))
+		if _, err := s.queue.Send(ctx, userID, notify.KindBudget, alert, nil); err != nil {
+			slog.Error("send budget alert failed", "user_id", userID, "category_id", category.ID, "error", err)
+		}
+	}
+	return info
+}
+
+// formatBudgetAlert formats the alert for a crossed threshold.
+//
+// ⚠️ Budget 🍜 Makan bulan ini sudah terpakai 84%
+//
+// Rp 1.680.000 dari Rp 2.000.000 · sisa Rp 320.000 untuk 16 hari lagi.
+func formatBudgetAlert(category Category, spent, amount int64, threshold, daysLeft int) string {
+	pct := budgetPercent(spent, amount)
+	if threshold >= 100 {
+		return fmt.Sprintf("🚨 Budget %s %s bulan ini habis!\n\n%s dari %s (%d%%) · lewat %s, masih %d hari lagi.",
+			category.Emoji, category.Name, FormatRupiah(spent), FormatRupiah(amount), pct, FormatRupiah(spent-amount), daysLeft)
+	}
+	icon := "🎯"
+	if threshold >= 80 {
+		icon = "⚠️"
+	}
+	return fmt.Sprintf("%s Budget %s %s bulan ini sudah terpakai %d%%\n\n%s dari %s · sisa %s untuk %d hari lagi.",
+		icon, category.Emoji, category.Name, pct, FormatRupiah(spent), FormatRupiah(amount), FormatRupiah(amount-spent), daysLeft)
+}
+
+// budgetLines compares each budget with the month's spending, for the
+// monthly report. Budgets are not versioned, so a past month is compared
+// with the current ones.
+//
+//	🎯 Budget vs Aktual
+//	🍜 Makan: Rp 1.200.000 / Rp 2.000.000 (60%) ✅
+//	🚗 Transport: Rp 900.000 / Rp 800.000 (112%) 🔴
+func budgetLines(expenses []Expense, budgets []Budget, categories []Category, indent string) []string {
+	spent := make(map[int]int64)
+	for _, e := range expenses {
+		if e.CategoryID != nil {
+			spent[*e.CategoryID] += e.Amount
+		}
+	}
+	lines := []string{indent + "🎯 Budget vs Aktual"}
+	for _, b := range budgets {
+		c := categoryOf(categories, Expense{CategoryID: &b.CategoryID})
+		lines = append(lines, fmt.Sprintf("%s%s %s: %s / %s (%d%%) %s",
+			indent, c.Emoji, c.Name, FormatRupiah(spent[b.CategoryID]), FormatRupiah(b.Amount),
+			budgetPercent(spent[b.CategoryID], b.Amount), budgetIcon(spent[b.CategoryID], b.Amount)))
+	}
+	return lines
+}
+
+// budgetPercent is the share of amount spent, rounded down so a threshold
+// counts as crossed only once it really is.
+func budgetPercent(spent, amount int64) int {
+	return int(spent * 100 / amount)
+}
+
+// budgetIcon marks a budget as fine, nearly used up or overspent.
+func budgetIcon(spent, amount int64) string {
+	switch pct := budgetPercent(spent, amount); {
+	case pct >= 100:
+		return "🔴"
+	case pct >= 80:
+		return "⚠️"
+	default:
+		return "✅"
+	}
+}
+
+// budgetLeft describes what is left of a budget, or by how much it was
+// overspent.
+func budgetLeft(spent, amount int64) string {
+	if spent > amount {
+		return "lewat " + FormatRupiah(spent-amount)
+	}
+	return "sisa " + FormatRupiah(amount-spent)
+}
+
+// daysLeftInMonth counts the days from now to the end of its month, today
+// included.
+func daysLeftInMonth(now time.Time) int {
+	last := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location())
+	return last.Day() - now.Day() + 1
+}
@@ -0,0 +1,199 @@
+package expense
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Category groups expenses in reports. The defaults (Makan, Transport,
+// Tagihan, Belanja, Hiburan, Kesehatan) are seeded by the migration.
+type Category struct {
+	ID    int
+	Name  string
+	Emoji string
+}
+
+// uncategorized is how expenses without a category are shown.
+var uncategorized = Category{Name: "Lainnya", Emoji: "📦"}
+
+// builtinKeywords maps the default categories to words that give them away,
+// for when neither the user's own rules nor the NLP picked one.
+var builtinKeywords = map[string][]string{
+	"Makan": {
+		"makan", "makanan", "minum", "kopi", "teh", "nasi", "bakso", "mie", "soto", "sate",
+		"ayam", "gorengan", "jajan", "snack", "sarapan", "lunch", "dinner", "resto", "warteg",
+		"cafe", "gofood", "grabfood", "shopeefood",
+	},
+	"Transport": {
+		"bensin", "pertalite", "pertamax", "solar", "parkir", "tol", "ojek", "ojol", "gojek",
+		"gocar", "grab", "grabcar", "taksi", "taxi", "krl", "mrt", "lrt", "transjakarta",
+		"busway", "kereta", "bus", "tiket", "pesawat", "servis", "bengkel",
+	},
+	"Tagihan": {
+		"listrik", "pln", "token", "pdam", "air", "wifi", "internet", "indihome", "pulsa",
+		"kuota", "bpjs", "asuransi", "cicilan", "kredit", "kos", "kost", "sewa", "kontrakan",
+		"iuran", "pajak", "tagihan",
+	},
+	"Belanja": {
+		"belanja", "sabun", "shampo", "odol", "deterjen", "baju", "celana", "sepatu", "tas",
+		"indomaret", "alfamart", "supermarket", "pasar", "sayur", "buah", "beras", "minyak",
+		"telur", "galon", "gas", "shopee", "tokopedia", "lazada",
+	},
+	"Hiburan": {
+		"nonton", "bioskop", "film", "netflix", "spotify", "youtube", "disney", "game",
+		"steam", "konser", "karaoke", "liburan", "wisata", "hotel", "staycation",
+	},
+	"Kesehatan": {
+		"obat", "dokter", "apotek", "apotik", "vitamin", "klinik", "rumah sakit", "lab",
+		"periksa", "gigi", "masker", "suplemen",
+	},
+}
+
+// stopwords carry no hint of a category and are never learned.
+var stopwords = map[string]bool{
+	"beli": true, "bayar": true, "buat": true, "untuk": true, "dan": true, "yang": true,
+	"dari": true, "dengan": true, "pakai": true, "pake": true, "sama": true, "lagi": true,
+	"hutang": true, "utang": true, "bulan": true, "minggu": true, "hari": true, "ini": true,
+}
+
+// keywords splits a description into the lowercase words worth matching:
+// no numbers, no stopwords and nothing shorter than three letters.
+func keywords(description string) []string {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	var out []string
+	seen := make(map[string]bool)
+	for _, w := range words {
+		if len([]rune(w)) < 3 || stopwords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		out = append(out, w)
+	}
+	return out
+}
+
+// builtinCategory returns the default category whose keywords the
+// description mentions most, or "" when it mentions none.
+func builtinCategory(description string) string {
+	desc := " " + strings.Join(keywords(description), " ") + " "
+	best, bestHits := "", 0
+	names := make([]string, 0, len(builtinKeywords))
+	for name := range builtinKeywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hits := 0
+		for _, k := range builtinKeywords[name] {
+			if strings.Contains(desc, " "+k+" ") {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = name, hits
+		}
+	}
+	return best
+}
+
+// findCategory looks a category up by name, ignoring case.
+func findCategory(categories []Category, name string) *Category {
+	for i, c := range categories {
+		if strings.EqualFold(c.Name, name) {
+			return &categories[i]
+		}
+	}
+	return nil
+}
+
+// categoryOf returns the category an expense is filed under.
+func categoryOf(categories []Category, e Expense) Category {
+	if e.CategoryID != nil {
+		for _, c := range categories {
+			if c.ID == *e.CategoryID {
+				return c
+			}
+		}
+	}
+	return uncategorized
+}
+
+// categorize picks the category of a new expense. The user's learned rules
+// come first, since they record corrections of earlier guesses; then the
+// NLP's suggestion, then the built-in keywords. It returns nil when none of
+// them applies.
+func (s *Service) categorize(ctx context.Context, userID int64, description, suggested string, categories []Category) *Category {
+	learned, err := s.repo.MatchKeywords(ctx, userID, keywords(description))
+	if err != nil {
+		slog.Error("match expense keywords failed", "user_id", userID, "error", err)
+	}
+	if learned != nil {
+		for i, c := range categories {
+			if c.ID == *learned {
+				return &categories[i]
+			}
+		}
+	}
+	if c := findCategory(categories, suggested); c != nil {
+		return c
+	}
+	return findCategory(categories, builtinCategory(description))
+}
+
+// categoryNames lists the categories for a message, e.g. "Makan, Transport".
+func categoryNames(categories []Category) string {
+	names := make([]string, len(categories))
+	for i, c := range categories {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// categoryBreakdown lists the spending per category, biggest first, with
+// its share of the total:
+//
+//	📂 Per Kategori
+//	🍜 Makan: Rp 450.000 (45%)
+//	🚗 Transport: Rp 300.000 (30%)
+func categoryBreakdown(expenses []Expense, categories []Category, indent string) []string {
+	totals := make(map[string]int64)
+	counts := make(map[string]int)
+	byName := make(map[string]Category)
+	var total int64
+	for _, e := range expenses {
+		c := categoryOf(categories, e)
+		totals[c.Name] += e.Amount
+		counts[c.Name]++
+		byName[c.Name] = c
+		total += e.Amount
+	}
+
+	names := make([]string, 0, len(totals))
+	for name := range totals {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if totals[names[i]] != totals[names[j]] {
+			return totals[names[i]] > totals[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	lines := []string{indent + "📂 Per Kategori"}
+	for _, name := range names {
+		c := byName[name]
+		pct := 0
+		if total > 0 {
+			pct = int((totals[name]*100 + total/2) / total)
+		}
+		lines = append(lines, fmt.Sprintf("%s%s %s: %s (%d%%) · %d item",
+			indent, c.Emoji, c.Name, FormatRupiah(totals[name]), pct, counts[name]))
+	}
+	return lines
+}
@@ -0,0 +1,213 @@
+package expense
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/conversation"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/pending"
+)
+
+// AddIncome records money coming in, e.g. "catat gaji 8jt" or "terima
+// transfer 500rb dari Budi". source and account, the account it landed in,
+// may be empty.
+func (s *Service) AddIncome(ctx context.Context, userID int64, description string, amount int64, source, account string) (string, error) {
+	var paidTo *Account
+	var accountID *int
+	if account != "" {
+		found, msg, err := s.resolveAccount(ctx, userID, account)
+		if err != nil || msg != "" {
+			return msg, err
+		}
+		paidTo, accountID = found, &found.ID
+	}
+
+	var sourcePtr *string
+	if source = strings.TrimSpace(source); source != "" {
+		sourcePtr = &source
+	}
+	id, err := s.repo.CreateIncome(ctx, userID, description, amount, sourcePtr, accountID)
+	if err != nil {
+		return "", err
+	}
+	in := Income{ID: id, Description: description, Amount: amount, Source: sourcePtr, AccountID: accountID}
+	conversation.Touch(ctx, conversation.KindIncome, id, incomeLabel(in))
+
+	loc := s.loc(ctx)
+	now := time.Now().In(loc)
+	dateStr := fmt.Sprintf("%d %s %d", now.Day(), indonesianMonths[now.Month()-1], now.Year())
+
+	lines := []string{"✅ Pemasukan dicatat!\n", "📝 " + description, "💵 " + FormatRupiah(amount)}
+	if sourcePtr != nil {
+		lines = append(lines, "👤 Dari: "+source)
+	}
+	if paidTo != nil {
+		lines = append(lines, fmt.Sprintf("💳 Ke: %s (saldo %s)", paidTo.Name, formatBalance(paidTo.Balance+amount)))
+	}
+	lines = append(lines, "📅 "+dateStr)
+
+	incomes, err := s.repo.ListIncomesByMonth(ctx, userID, now.Year(), now.Month(), loc)
+	if err != nil {
+		return strings.Join(lines, "\n"), nil
+	}
+	spent, err := s.repo.SumByMonth(ctx, userID, now.Year(), now.Month(), loc)
+	if err != nil {
+		spent = 0
+	}
+	income := sumIncomes(incomes)
+	lines = append(lines, "", fmt.Sprintf("Pemasukan bulan ini: %s", FormatRupiah(income)))
+	lines = append(lines, fmt.Sprintf("Selisih bulan ini: %s", formatSignedRupiah(income-spent)))
+	return strings.Join(lines, "\n"), nil
+}
+
+// ListIncome returns the incomes of a period; filter takes the same values
+// as List.
+//
+// 💼 Pemasukan Bulan Ini
+//
+// 📥 1 Okt · Gaji · Rp 8.000.000
+// 📥 5 Okt · transfer dari Budi · Rp 500.000
+// ─────────────
+// 💵 Total: Rp 8.500.000
+func (s *Service) ListIncome(ctx context.Context, userID int64, filter string) (string, error) {
+	loc := s.loc(ctx)
+	incomes, err := s.repo.ListIncomes(ctx, userID, filter, loc)
+	if err != nil {
+		return "", err
+	}
+	if len(incomes) == 0 {
+		return fmt.Sprintf("📭 Tidak ada pemasukan %s.", filterLabel(filter)), nil
+	}
+
+	lines := []string{fmt.Sprintf("💼 Pemasukan %s\n", filterLabel(filter))}
+	for _, in := range incomes {
+		lines = append(lines, incomeLine(in, loc))
+	}
+	lines = append(lines, "─────────────")
+	lines = append(lines, fmt.Sprintf("💵 Total: %s", FormatRupiah(sumIncomes(incomes))))
+	return strings.Join(lines, "\n"), nil
+}
+
+// DeleteIncome removes an income.
+// incomeID: if > 0, look up directly by ID (bypasses search).
+// amount is an optional disambiguator when several incomes match.
+func (s *Service) DeleteIncome(ctx context.Context, userID int64, incomeID int, search string, amount int64) (string, error) {
+	var income *Income
+
+	if incomeID > 0 {
+		found, err := s.repo.FindIncomeByID(ctx, userID, incomeID)
+		if err != nil {
+			return "", err
+		}
+		if found == nil {
+			return fmt.Sprintf("❌ Pemasukan dengan ID #%d tidak ditemukan.", incomeID), nil
+		}
+		income = found
+	} else {
+		matches, err := s.repo.FindAllIncomesBySearch(ctx, userID, search)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) == 0 {
+			return fmt.Sprintf("❌ Pemasukan \"%s\" tidak ditemukan.", search), nil
+		}
+
+		if len(matches) == 1 {
+			income = &matches[0]
+		} else if amount > 0 {
+			var byAmount []Income
+			for _, in := range matches {
+				if in.Amount == amount {
+					byAmount = append(byAmount, in)
+				}
+			}
+			if len(byAmount) == 1 {
+				income = &byAmount[0]
+			}
+		}
+		if income == nil {
+			return s.formatIncomeDisambiguation(ctx, search, matches), nil
+		}
+	}
+
+	if err := s.repo.DeleteIncome(ctx, income.ID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🗑️ Dihapus: \"%s\" — %s", incomeDescription(*income), FormatRupiah(income.Amount)), nil
+}
+
+// formatIncomeDisambiguation lists the matching incomes with their IDs and
+// offers each as a button choice.
+func (s *Service) formatIncomeDisambiguation(ctx context.Context, search string, matches []Income) string {
+	loc := s.loc(ctx)
+	lines := []string{
+		fmt.Sprintf("🔍 Ada %d pemasukan \"%s\":\n", len(matches), search),
+	}
+	for _, in := range matches {
+		t := in.RecordedAt.In(loc)
+		lines = append(lines, fmt.Sprintf("#%d · 📅 %d %s %d · %s · %s",
+			in.ID, t.Day(), indonesianMonths[t.Month()-1], t.Year(), incomeDescription(in), FormatRupiah(in.Amount)))
+		pending.Offer(ctx, pending.Choice{
+			ID:    in.ID,
+			Label: fmt.Sprintf("#%d · %d %s · %s", in.ID, t.Day(), indonesianMonths[t.Month()-1], FormatRupiah(in.Amount)),
+		})
+	}
+
+	lines = append(lines, "\nPilih tombol di bawah, atau sebutkan ID-nya, contoh:")
+	for _, in := range matches {
+		lines = append(lines, fmt.Sprintf("• \"hapus pemasukan id %d\"", in.ID))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// incomeLine formats an income as a list entry, next to the ✅/🔴 lines of
+// expenses.
+func incomeLine(in Income, loc *time.Location) string {
+	t := in.RecordedAt.In(loc)
+	return fmt.Sprintf("📥 %d %s · %s · %s",
+		t.Day(), indonesianMonths[t.Month()-1], incomeDescription(in), FormatRupiah(in.Amount))
+}
+
+// incomeDescription is the description with the source, if known:
+// "transfer dari Budi".
+func incomeDescription(in Income) string {
+	if in.Source == nil {
+		return in.Description
+	}
+	return fmt.Sprintf("%s dari %s", in.Description, *in.Source)
+}
+
+func incomeLabel(in Income) string {
+	return fmt.Sprintf("%s (%s)", incomeDescription(in), FormatRupiah(in.Amount))
+}
+
+func sumIncomes(incomes []Income) int64 {
+	var total int64
+	for _, in := range incomes {
+		total += in.Amount
+	}
+	return total
+}
+
+// formatSignedRupiah formats a net amount with its sign: "+Rp 3.000.000",
+// "-Rp 500.000".
+func formatSignedRupiah(amount int64) string {
+	if amount < 0 {
+		return "-" + FormatRupiah(-amount)
+	}
+	return "+" + FormatRupiah(amount)
+}
+
+// savingsRate describes the share of income left after spending.
+func savingsRate(income, spent int64) string {
+	if income <= 0 {
+		return "-"
+	}
+	pct := (income - spent) * 100 / income
+	if pct < 0 {
+		return fmt.Sprintf("%d%% (pengeluaran melebihi pemasukan)", pct)
+	}
+	return fmt.Sprintf("%d%% dari pemasukan", pct)
+}
@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/undo"
 )
 
 type Expense struct {
@@ -14,6 +17,19 @@ type Expense struct {
 	Amount      int64
 	IsPaid      bool
 	RecordedAt  time.Time
+	CategoryID  *int
+	AccountID   *int
+}
+
+// Income is money coming in. Source, when known, is who it came from.
+type Income struct {
+	ID          int
+	UserID      int64
+	Description string
+	Amount      int64
+	Source      *string
+	RecordedAt  time.Time
+	AccountID   *int
 }
 
 type Repository struct {
@@ -24,11 +40,11 @@ func NewRepository(db *sql.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) Create(ctx context.Context, userID int64, description string, amount int64, isPaid bool) (int, error) {
+func (r *Repository) Create(ctx context.Context, userID int64, description string, amount int64, isPaid bool, categoryID, accountID *int) (int, error) {
 	var id int
 	err := r.db.QueryRowContext(ctx,
-		`INSERT INTO expenses (user_id, description, amount, is_paid) VALUES ($1, $2, $3, $4) RETURNING id`,
-		userID, description, amount, isPaid,
+		`INSERT INTO expenses (user_id, description, amount, is_paid, category_id, account_id) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		userID, description, amount, isPaid, categoryID, accountID,
 	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("create expense: %w", err)
@@ -36,42 +52,36 @@ func (r *Repository) Create(ctx context.Context, userID int64, description strin
 	return id, nil
 }
 
-func (r *Repository) List(ctx context.Context, userID int64, filter string, loc *time.Location) ([]Expense, error) {
-	now := time.Now().In(loc)
-	var query string
-	var args []interface{}
+// ListUserIDs returns the users who recorded an expense or income.
+func (r *Repository) ListUserIDs(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT user_id FROM expenses UNION SELECT user_id FROM incomes`)
+	if err != nil {
+		return nil, fmt.Errorf("list expense user ids: %w", err)
+	}
+	defer rows.Close()
 
-	switch filter {
-	case "today":
-		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
-		endOfDay := startOfDay.AddDate(0, 0, 1)
-		query = `SELECT id, user_id, description, amount, is_paid, recorded_at FROM expenses
-				 WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3
-				 ORDER BY recorded_at ASC`
-		args = []interface{}{userID, startOfDay, endOfDay}
-	case "this_week":
-		weekday := int(now.Weekday())
-		if weekday == 0 {
-			weekday = 7
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan user id: %w", err)
 		}
-		startOfWeek := time.Date(now.Year(), now.Month(), now.Day()-(weekday-1), 0, 0, 0, 0, loc)
-		endOfWeek := startOfWeek.AddDate(0, 0, 7)
-		query = `SELECT id, user_id, description, amount, is_paid, recorded_at FROM expenses
-				 WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3
-				 ORDER BY recorded_at ASC`
-		args = []interface{}{userID, startOfWeek, endOfWeek}
-	case "this_month":
-		startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
-		endOfMonth := startOfMonth.AddDate(0, 1, 0)
-		query = `SELECT id, user_id, description, amount, is_paid, recorded_at FROM expenses
-				 WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3
-				 ORDER BY recorded_at ASC`
-		args = []interface{}{userID, startOfMonth, endOfMonth}
-	default: // "all"
-		query = `SELECT id, user_id, description, amount, is_paid, recorded_at FROM expenses
-				 WHERE user_id = $1
-				 ORDER BY recorded_at ASC`
-		args = []interface{}{userID}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *Repository) List(ctx context.Context, userID int64, filter string, loc *time.Location) ([]Expense, error) {
+	query := `SELECT id, user_id, description, amount, is_paid, recorded_at, category_id, account_id FROM expenses
+		 WHERE user_id = $1
+		 ORDER BY recorded_at ASC`
+	args := []interface{}{userID}
+	if start, end, ok := filterRange(filter, loc); ok {
+		query = `SELECT id, user_id, description, amount, is_paid, recorded_at, category_id, account_id FROM expenses
+			 WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3
+			 ORDER BY recorded_at ASC`
+		args = append(args, start, end)
 	}
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
@@ -87,7 +97,7 @@ func (r *Repository) ListByMonth(ctx context.Context, userID int64, year int, mo
 	startOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, loc)
 	endOfMonth := startOfMonth.AddDate(0, 1, 0)
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, user_id, description, amount, is_paid, recorded_at FROM expenses
+		`SELECT id, user_id, description, amount, is_paid, recorded_at, category_id, account_id FROM expenses
 		 WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3
 		 ORDER BY recorded_at ASC`,
 		userID, startOfMonth, endOfMonth,
@@ -100,33 +110,11 @@ func (r *Repository) ListByMonth(ctx context.Context, userID int64, year int, mo
 }
 
 func (r *Repository) Sum(ctx context.Context, userID int64, filter string, loc *time.Location) (int64, error) {
-	now := time.Now().In(loc)
-	var query string
-	var args []interface{}
-
-	switch filter {
-	case "today":
-		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
-		endOfDay := startOfDay.AddDate(0, 0, 1)
-		query = `SELECT COALESCE(SUM(amount), 0) FROM expenses WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3`
-		args = []interface{}{userID, startOfDay, endOfDay}
-	case "this_week":
-		weekday := int(now.Weekday())
-		if weekday == 0 {
-			weekday = 7
-		}
-		startOfWeek := time.Date(now.Year(), now.Month(), now.Day()-(weekday-1), 0, 0, 0, 0, loc)
-		endOfWeek := startOfWeek.AddDate(0, 0, 7)
-		query = `SELECT COALESCE(SUM(amount), 0) FROM expenses WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3`
-		args = []interface{}{userID, startOfWeek, endOfWeek}
-	case "this_month":
-		startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
-		endOfMonth := startOfMonth.AddDate(0, 1, 0)
-		query = `SELECT COALESCE(SUM(amount), 0) FROM expenses WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3`
-		args = []interface{}{userID, startOfMonth, endOfMonth}
-	default:
-		query = `SELECT COALESCE(SUM(amount), 0) FROM expenses WHERE user_id = $1`
-		args = []interface{}{userID}
+	query := `SELECT COALESCE(SUM(amount), 0) FROM expenses WHERE user_id = $1`
+	args := []interface{}{userID}
+	if start, end, ok := filterRange(filter, loc); ok {
+		query += ` AND recorded_at >= $2 AND recorded_at < $3`
+		args = append(args, start, end)
 	}
 
 	var total int64
@@ -154,11 +142,11 @@ func (r *Repository) SumByMonth(ctx context.Context, userID int64, year int, mon
 func (r *Repository) FindBySearch(ctx context.Context, userID int64, search string) (*Expense, error) {
 	var e Expense
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id, user_id, description, amount, is_paid, recorded_at FROM expenses
+		`SELECT id, user_id, description, amount, is_paid, recorded_at, category_id, account_id FROM expenses
 		 WHERE user_id = $1 AND description ILIKE '%' || $2 || '%'
 		 ORDER BY recorded_at DESC LIMIT 1`,
 		userID, search,
-	).Scan(&e.ID, &e.UserID, &e.Description, &e.Amount, &e.IsPaid, &e.RecordedAt)
+	).Scan(&e.ID, &e.UserID, &e.Description, &e.Amount, &e.IsPaid, &e.RecordedAt, &e.CategoryID, &e.AccountID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -170,7 +158,7 @@ func (r *Repository) FindBySearch(ctx context.Context, userID int64, search stri
 
 func (r *Repository) FindAllBySearch(ctx context.Context, userID int64, search string) ([]Expense, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT id, user_id, description, amount, is_paid, recorded_at FROM expenses
+		`SELECT id, user_id, description, amount, is_paid, recorded_at, category_id, account_id FROM expenses
 		 WHERE user_id = $1 AND description ILIKE '%' || $2 || '%'
 		 ORDER BY recorded_at DESC`,
 		userID, search,
@@ -185,10 +173,10 @@ func (r *Repository) FindAllBySearch(ctx context.Context, userID int64, search s
 func (r *Repository) FindByID(ctx context.Context, userID int64, id int) (*Expense, error) {
 	var e Expense
 	err := r.db.QueryRowContext(ctx,
-		`SELECT id, user_id, description, amount, is_paid, recorded_at FROM expenses
+		`SELECT id, user_id, description, amount, is_paid, recorded_at, category_id, account_id FROM expenses
 		 WHERE id = $1 AND user_id = $2`,
 		id, userID,
-	).Scan(&e.ID, &e.UserID, &e.Description, &e.Amount, &e.IsPaid, &e.RecordedAt)
+	).Scan(&e.ID, &e.UserID, &e.Description, &e.Amount, &e.IsPaid, &e.RecordedAt, &e.CategoryID, &e.AccountID)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -239,18 +227,60 @@ func (r *Repository) ListYearsForMonth(ctx context.Context, userID int64, month
 	return years, rows.Err()
 }
 
-// ClearByMonth deletes all expenses for a user in the given year/month. Returns count deleted.
-func (r *Repository) ClearByMonth(ctx context.Context, userID int64, year int, month time.Month, loc *time.Location) (int64, error) {
+// ClearByMonth deletes all expenses for a user in the given year/month and
+// journals them for undo in the same transaction. Returns the deleted rows.
+func (r *Repository) ClearByMonth(ctx context.Context, userID int64, year int, month time.Month, loc *time.Location) ([]Expense, error) {
 	start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
 	end := start.AddDate(0, 1, 0)
-	res, err := r.db.ExecContext(ctx,
-		`DELETE FROM expenses WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3`,
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin clear expenses: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`DELETE FROM expenses WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3
+		 RETURNING id, user_id, description, amount, is_paid, recorded_at, category_id, account_id`,
 		userID, start, end,
 	)
 	if err != nil {
-		return 0, fmt.Errorf("clear expenses by month: %w", err)
+		return nil, fmt.Errorf("clear expenses by month: %w", err)
+	}
+	deleted, err := scanExpenses(rows)
+	rows.Close()
+	if err != nil || len(deleted) == 0 {
+		return nil, err
+	}
+
+	if err := undo.Record(ctx, tx, userID, undo.KindClearExpense, deleted); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// Restore re-inserts deleted expenses with their original IDs.
+func (r *Repository) Restore(ctx context.Context, expenses []Expense) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin restore expenses: %w", err)
 	}
-	return res.RowsAffected()
+	defer tx.Rollback()
+
+	for _, e := range expenses {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO expenses (id, user_id, description, amount, is_paid, recorded_at, category_id, account_id)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (id) DO NOTHING`,
+			e.ID, e.UserID, e.Description, e.Amount, e.IsPaid, e.RecordedAt, e.CategoryID, e.AccountID,
+		)
+		if err != nil {
+			return fmt.Errorf("restore expense: %w", err)
+		}
+	}
+	return tx.Commit()
 }
 
 // UpdateExpense updates description and/or is_paid for a specific expense.
@@ -276,11 +306,402 @@ func (r *Repository) UpdateExpense(ctx context.Context, id int, newDescription *
 	return err
 }
 
+// SetCategory files an expense under a category.
+func (r *Repository) SetCategory(ctx context.Context, id int, categoryID int) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE expenses SET category_id = $1 WHERE id = $2`, categoryID, id)
+	if err != nil {
+		return fmt.Errorf("set expense category: %w", err)
+	}
+	return nil
+}
+
+// ListCategories returns all expense categories in the order they were added.
+func (r *Repository) ListCategories(ctx context.Context) ([]Category, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, name, emoji FROM expense_categories ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list expense categories: %w", err)
+	}
+	defer rows.Close()
+	var categories []Category
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.Emoji); err != nil {
+			return nil, fmt.Errorf("scan expense category: %w", err)
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// LearnKeywords points each keyword of the user's at categoryID, counting
+// one more hit when it already did.
+func (r *Repository) LearnKeywords(ctx context.Context, userID int64, keywords []string, categoryID int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin learn keywords: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, k := range keywords {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO expense_category_rules (user_id, keyword, category_id) VALUES ($1, $2, $3)
+			 ON CONFLICT (user_id, keyword) DO UPDATE SET
+			   hits = CASE WHEN expense_category_rules.category_id = EXCLUDED.category_id
+			               THEN expense_category_rules.hits + 1 ELSE 1 END,
+			   category_id = EXCLUDED.category_id,
+			   updated_at = NOW()`,
+			userID, k, categoryID,
+		)
+		if err != nil {
+			return fmt.Errorf("learn keyword: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// MatchKeywords returns the category the user's learned rules give the
+// keywords, or nil when none of them is known. When the keywords point at
+// different categories, the one with the most hits wins.
+func (r *Repository) MatchKeywords(ctx context.Context, userID int64, keywords []string) (*int, error) {
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+	var id int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT category_id FROM expense_category_rules
+		 WHERE user_id = $1 AND keyword = ANY($2)
+		 GROUP BY category_id
+		 ORDER BY SUM(hits) DESC, MAX(updated_at) DESC LIMIT 1`,
+		userID, pq.Array(keywords),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("match keywords: %w", err)
+	}
+	return &id, nil
+}
+
+// SumByCategory returns the user's spending per category in the given
+// month. Uncategorized expenses are left out.
+func (r *Repository) SumByCategory(ctx context.Context, userID int64, year int, month time.Month, loc *time.Location) (map[int]int64, error) {
+	startOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	endOfMonth := startOfMonth.AddDate(0, 1, 0)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT category_id, SUM(amount) FROM expenses
+		 WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3 AND category_id IS NOT NULL
+		 GROUP BY category_id`,
+		userID, startOfMonth, endOfMonth,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sum expenses by category: %w", err)
+	}
+	defer rows.Close()
+	sums := make(map[int]int64)
+	for rows.Next() {
+		var id int
+		var total int64
+		if err := rows.Scan(&id, &total); err != nil {
+			return nil, fmt.Errorf("scan category sum: %w", err)
+		}
+		sums[id] = total
+	}
+	return sums, rows.Err()
+}
+
+// SetBudget sets the user's monthly budget for a category. Changing it
+// forgets the alerts already sent, since the thresholds moved.
+func (r *Repository) SetBudget(ctx context.Context, userID int64, categoryID int, amount int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO expense_budgets (user_id, category_id, amount) VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id, category_id) DO UPDATE SET
+		   amount = EXCLUDED.amount, alerted_month = NULL, alerted_pct = 0, updated_at = NOW()`,
+		userID, categoryID, amount,
+	)
+	if err != nil {
+		return fmt.Errorf("set budget: %w", err)
+	}
+	return nil
+}
+
+// DeleteBudget removes the user's budget for a category. It reports whether
+// there was one.
+func (r *Repository) DeleteBudget(ctx context.Context, userID int64, categoryID int) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM expense_budgets WHERE user_id = $1 AND category_id = $2`, userID, categoryID)
+	if err != nil {
+		return false, fmt.Errorf("delete budget: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete budget: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListBudgets returns the user's budgets in category order.
+func (r *Repository) ListBudgets(ctx context.Context, userID int64) ([]Budget, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT category_id, amount FROM expense_budgets WHERE user_id = $1 ORDER BY category_id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list budgets: %w", err)
+	}
+	defer rows.Close()
+	var budgets []Budget
+	for rows.Next() {
+		var b Budget
+		if err := rows.Scan(&b.CategoryID, &b.Amount); err != nil {
+			return nil, fmt.Errorf("scan budget: %w", err)
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+// ClaimBudgetAlert records that the pct threshold of a budget was alerted
+// for the month starting at month. It reports false when that threshold, or
+// a higher one, was already alerted this month, so each alert is sent once
+// even when two expenses are added at the same time.
+func (r *Repository) ClaimBudgetAlert(ctx context.Context, userID int64, categoryID int, month time.Time, pct int) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE expense_budgets SET alerted_month = $3, alerted_pct = $4
+		 WHERE user_id = $1 AND category_id = $2
+		   AND (alerted_month IS DISTINCT FROM $3 OR alerted_pct < $4)`,
+		userID, categoryID, month.Format("2006-01-02"), pct,
+	)
+	if err != nil {
+		return false, fmt.Errorf("claim budget alert: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim budget alert: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (r *Repository) CreateIncome(ctx context.Context, userID int64, description string, amount int64, source *string, accountID *int) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO incomes (user_id, description, amount, source, account_id) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		userID, description, amount, source, accountID,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("create income: %w", err)
+	}
+	return id, nil
+}
+
+// ListIncomes returns the user's incomes in the period of filter, which
+// takes the same values as List.
+func (r *Repository) ListIncomes(ctx context.Context, userID int64, filter string, loc *time.Location) ([]Income, error) {
+	start, end, ok := filterRange(filter, loc)
+	if !ok {
+		rows, err := r.db.QueryContext(ctx,
+			`SELECT id, user_id, description, amount, source, recorded_at, account_id FROM incomes
+			 WHERE user_id = $1
+			 ORDER BY recorded_at ASC`,
+			userID,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("list incomes: %w", err)
+		}
+		defer rows.Close()
+		return scanIncomes(rows)
+	}
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, description, amount, source, recorded_at, account_id FROM incomes
+		 WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3
+		 ORDER BY recorded_at ASC`,
+		userID, start, end,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list incomes: %w", err)
+	}
+	defer rows.Close()
+	return scanIncomes(rows)
+}
+
+func (r *Repository) ListIncomesByMonth(ctx context.Context, userID int64, year int, month time.Month, loc *time.Location) ([]Income, error) {
+	startOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	endOfMonth := startOfMonth.AddDate(0, 1, 0)
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, description, amount, source, recorded_at, account_id FROM incomes
+		 WHERE user_id = $1 AND recorded_at >= $2 AND recorded_at < $3
+		 ORDER BY recorded_at ASC`,
+		userID, startOfMonth, endOfMonth,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list incomes by month: %w", err)
+	}
+	defer rows.Close()
+	return scanIncomes(rows)
+}
+
+// FindAllIncomesBySearch matches the search against the description and the
+// source.
+func (r *Repository) FindAllIncomesBySearch(ctx context.Context, userID int64, search string) ([]Income, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, user_id, description, amount, source, recorded_at, account_id FROM incomes
+		 WHERE user_id = $1 AND (description ILIKE '%' || $2 || '%' OR source ILIKE '%' || $2 || '%')
+		 ORDER BY recorded_at DESC`,
+		userID, search,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("find all incomes: %w", err)
+	}
+	defer rows.Close()
+	return scanIncomes(rows)
+}
+
+func (r *Repository) FindIncomeByID(ctx context.Context, userID int64, id int) (*Income, error) {
+	var in Income
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, description, amount, source, recorded_at, account_id FROM incomes
+		 WHERE id = $1 AND user_id = $2`,
+		id, userID,
+	).Scan(&in.ID, &in.UserID, &in.Description, &in.Amount, &in.Source, &in.RecordedAt, &in.AccountID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find income by id: %w", err)
+	}
+	return &in, nil
+}
+
+func (r *Repository) DeleteIncome(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM incomes WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete income: %w", err)
+	}
+	return nil
+}
+
+// balanceQuery computes account balances: the opening balance plus
+// incomes, minus paid expenses (an unpaid one has not left the account
+// yet), plus transfers in and minus transfers out.
+const balanceQuery = `SELECT a.id, a.user_id, a.name, a.opening_balance,
+	a.opening_balance
+	+ COALESCE((SELECT SUM(amount) FROM incomes WHERE account_id = a.id), 0)
+	- COALESCE((SELECT SUM(amount) FROM expenses WHERE account_id = a.id AND is_paid), 0)
+	+ COALESCE((SELECT SUM(amount) FROM account_transfers WHERE to_account_id = a.id), 0)
+	- COALESCE((SELECT SUM(amount) FROM account_transfers WHERE from_account_id = a.id), 0)
+	FROM accounts a`
+
+// ListAccounts returns the user's accounts with their balances, in the
+// order they were added.
+func (r *Repository) ListAccounts(ctx context.Context, userID int64) ([]Account, error) {
+	rows, err := r.db.QueryContext(ctx, balanceQuery+` WHERE a.user_id = $1 ORDER BY a.id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list accounts: %w", err)
+	}
+	defer rows.Close()
+	var accounts []Account
+	for rows.Next() {
+		var a Account
+		if err := rows.Scan(&a.ID, &a.UserID, &a.Name, &a.OpeningBalance, &a.Balance); err != nil {
+			return nil, fmt.Errorf("scan account: %w", err)
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// FindAccountByName returns the user's account with that name, ignoring
+// case, or nil.
+func (r *Repository) FindAccountByName(ctx context.Context, userID int64, name string) (*Account, error) {
+	var a Account
+	err := r.db.QueryRowContext(ctx,
+		balanceQuery+` WHERE a.user_id = $1 AND LOWER(a.name) = LOWER($2)`,
+		userID, name,
+	).Scan(&a.ID, &a.UserID, &a.Name, &a.OpeningBalance, &a.Balance)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find account: %w", err)
+	}
+	return &a, nil
+}
+
+func (r *Repository) CreateAccount(ctx context.Context, userID int64, name string, openingBalance int64) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO accounts (user_id, name, opening_balance) VALUES ($1, $2, $3) RETURNING id`,
+		userID, name, openingBalance,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("create account: %w", err)
+	}
+	return id, nil
+}
+
+func (r *Repository) SetOpeningBalance(ctx context.Context, id int, openingBalance int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE accounts SET opening_balance = $1 WHERE id = $2`, openingBalance, id)
+	if err != nil {
+		return fmt.Errorf("set opening balance: %w", err)
+	}
+	return nil
+}
+
+// CreateTransfer records money moved between two of the user's accounts.
+func (r *Repository) CreateTransfer(ctx context.Context, userID int64, fromID, toID int, amount int64) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO account_transfers (user_id, from_account_id, to_account_id, amount) VALUES ($1, $2, $3, $4)`,
+		userID, fromID, toID, amount,
+	)
+	if err != nil {
+		return fmt.Errorf("create transfer: %w", err)
+	}
+	return nil
+}
+
+// filterRange returns the period of a list filter ("today", "this_week",
+// "this_month"). ok is false for "all", which has no bounds.
+func filterRange(filter string, loc *time.Location) (start, end time.Time, ok bool) {
+	now := time.Now().In(loc)
+	switch filter {
+	case "today":
+		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 1), true
+	case "this_week":
+		weekday := int(now.Weekday())
+		if weekday == 0 {
+			weekday = 7
+		}
+		start = time.Date(now.Year(), now.Month(), now.Day()-(weekday-1), 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 7), true
+	case "this_month":
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0), true
+	default:
+		return time.Time{}, time.Time{}, false
+	}
+}
+
+func scanIncomes(rows *sql.Rows) ([]Income, error) {
+	var incomes []Income
+	for rows.Next() {
+		var in Income
+		if err := rows.Scan(&in.ID, &in.UserID, &in.Description, &in.Amount, &in.Source, &in.RecordedAt, &in.AccountID); err != nil {
+			return nil, fmt.Errorf("scan income: %w", err)
+		}
+		incomes = append(incomes, in)
+	}
+	return incomes, rows.Err()
+}
+
 func scanExpenses(rows *sql.Rows) ([]Expense, error) {
 	var expenses []Expense
 	for rows.Next() {
 		var e Expense
-		if err := rows.Scan(&e.ID, &e.UserID, &e.Description, &e.Amount, &e.IsPaid, &e.RecordedAt); err != nil {
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Description, &e.Amount, &e.IsPaid, &e.RecordedAt, &e.CategoryID, &e.AccountID); err != nil {
 			return nil, fmt.Errorf("scan expense: %w", err)
 		}
 		expenses = append(expenses, e)
@@ -2,10 +2,17 @@ package expense
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/conversation"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/notify"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/pending"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
 )
 
 var indonesianMonths = [...]string{
@@ -20,21 +27,61 @@ var indonesianMonthsFull = [...]string{
 
 type Service struct {
 	repo     *Repository
+	queue    *notify.Queue
 	timezone *time.Location
 }
 
-func NewService(repo *Repository, timezone *time.Location) *Service {
-	return &Service{repo: repo, timezone: timezone}
+func NewService(repo *Repository, queue *notify.Queue, timezone *time.Location) *Service {
+	return &Service{repo: repo, queue: queue, timezone: timezone}
+}
+
+// loc returns the timezone of the user being served.
+func (s *Service) loc(ctx context.Context) *time.Location {
+	return settings.Location(ctx, s.timezone)
+}
+
+// UserIDs returns the users with expenses or incomes to report on.
+func (s *Service) UserIDs(ctx context.Context) ([]int64, error) {
+	return s.repo.ListUserIDs(ctx)
 }
 
 // Add records an expense and returns a formatted notification (Template 3).
-func (s *Service) Add(ctx context.Context, userID int64, description string, amount int64, isPaid bool) (string, error) {
-	_, err := s.repo.Create(ctx, userID, description, amount, isPaid)
+// category is the NLP's guess and may be empty; see categorize. account,
+// if not empty, names the account it was paid from.
+func (s *Service) Add(ctx context.Context, userID int64, description string, amount int64, isPaid bool, category, account string) (string, error) {
+	var paidFrom *Account
+	if account != "" {
+		found, msg, err := s.resolveAccount(ctx, userID, account)
+		if err != nil || msg != "" {
+			return msg, err
+		}
+		paidFrom = found
+	}
+
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return "", err
+	}
+	picked := s.categorize(ctx, userID, description, category, categories)
+	var categoryID *int
+	if picked != nil {
+		categoryID = &picked.ID
+	} else {
+		picked = &uncategorized
+	}
+
+	var accountID *int
+	if paidFrom != nil {
+		accountID = &paidFrom.ID
+	}
+	id, err := s.repo.Create(ctx, userID, description, amount, isPaid, categoryID, accountID)
 	if err != nil {
 		return "", err
 	}
+	conversation.Touch(ctx, conversation.KindExpense, id, expenseLabel(description, amount))
 
-	now := time.Now().In(s.timezone)
+	loc := s.loc(ctx)
+	now := time.Now().In(loc)
 	dateStr := fmt.Sprintf("%d %s %d", now.Day(), indonesianMonths[now.Month()-1], now.Year())
 
 	status := "Lunas"
@@ -43,46 +90,87 @@ func (s *Service) Add(ctx context.Context, userID int64, description string, amo
 	}
 
 	// Get monthly total
-	monthTotal, err := s.repo.SumByMonth(ctx, userID, now.Year(), now.Month(), s.timezone)
+	monthTotal, err := s.repo.SumByMonth(ctx, userID, now.Year(), now.Month(), loc)
 	if err != nil {
 		monthTotal = 0
 	}
 
-	return fmt.Sprintf("✅ Pengeluaran dicatat!\n\n📝 %s\n💵 %s\n📅 %s\n📊 Status: %s\n\nTotal bulan ini: %s",
-		description, FormatRupiah(amount), dateStr, status, FormatRupiah(monthTotal)), nil
+	msg := fmt.Sprintf("✅ Pengeluaran dicatat!\n\n📝 %s\n💵 %s\n🏷 %s %s\n📅 %s\n📊 Status: %s\n\nTotal bulan ini: %s",
+		description, FormatRupiah(amount), picked.Emoji, picked.Name, dateStr, status, FormatRupiah(monthTotal))
+	if paidFrom != nil {
+		balance := paidFrom.Balance
+		if isPaid {
+			balance -= amount
+		}
+		msg += fmt.Sprintf("\n💳 Saldo %s: %s", paidFrom.Name, formatBalance(balance))
+	}
+	if categoryID != nil {
+		if info := s.checkBudget(ctx, userID, *picked); info != "" {
+			msg += "\n" + info
+		}
+	}
+	return msg + fmt.Sprintf("\nSalah kategori? Ketik \"ubah kategori id %d jadi <kategori>\".", id), nil
 }
 
-// List returns a formatted expense list based on the filter.
+// List returns a formatted expense list based on the filter, with the
+// incomes of the same period next to it.
 func (s *Service) List(ctx context.Context, userID int64, filter string) (string, error) {
-	expenses, err := s.repo.List(ctx, userID, filter, s.timezone)
+	loc := s.loc(ctx)
+	expenses, err := s.repo.List(ctx, userID, filter, loc)
+	if err != nil {
+		return "", err
+	}
+	incomes, err := s.repo.ListIncomes(ctx, userID, filter, loc)
 	if err != nil {
 		return "", err
 	}
 
-	if len(expenses) == 0 {
+	if len(expenses) == 0 && len(incomes) == 0 {
 		return fmt.Sprintf("📭 Tidak ada pengeluaran %s.", filterLabel(filter)), nil
 	}
 
 	if filter == "all" {
-		return s.formatAllExpenses(expenses), nil
+		return s.formatAllExpenses(expenses, incomes, loc), nil
 	}
-	return s.formatMonthlyExpenses(expenses, filter), nil
-}
-
-// PayExpense marks an expense as paid.
-// amount and date are optional disambiguators when multiple expenses share the same description.
-func (s *Service) PayExpense(ctx context.Context, userID int64, search string, amount int64, date *time.Time) (string, error) {
-	matches, err := s.repo.FindAllBySearch(ctx, userID, search)
+	categories, err := s.repo.ListCategories(ctx)
 	if err != nil {
 		return "", err
 	}
-	if len(matches) == 0 {
-		return fmt.Sprintf("❌ Pengeluaran \"%s\" tidak ditemukan.", search), nil
+	accounts, err := s.repo.ListAccounts(ctx, userID)
+	if err != nil {
+		return "", err
 	}
+	return s.formatMonthlyExpenses(expenses, incomes, filter, categories, accounts, loc), nil
+}
 
-	expense := s.pickExpense(matches, amount, date)
-	if expense == nil {
-		return s.formatDisambiguation(search, matches, "lunasi"), nil
+// PayExpense marks an expense as paid.
+// expenseID: if > 0, look up directly by ID (bypasses search).
+// amount and date are optional disambiguators when multiple expenses share the same description.
+func (s *Service) PayExpense(ctx context.Context, userID int64, expenseID int, search string, amount int64, date *time.Time) (string, error) {
+	var expense *Expense
+
+	if expenseID > 0 {
+		found, err := s.repo.FindByID(ctx, userID, expenseID)
+		if err != nil {
+			return "", err
+		}
+		if found == nil {
+			return fmt.Sprintf("❌ Pengeluaran dengan ID #%d tidak ditemukan.", expenseID), nil
+		}
+		expense = found
+	} else {
+		matches, err := s.repo.FindAllBySearch(ctx, userID, search)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) == 0 {
+			return fmt.Sprintf("❌ Pengeluaran \"%s\" tidak ditemukan.", search), nil
+		}
+
+		expense = s.pickExpense(matches, amount, date, s.loc(ctx))
+		if expense == nil {
+			return s.formatDisambiguation(ctx, search, matches, "lunasi", ""), nil
+		}
 	}
 
 	if expense.IsPaid {
@@ -92,6 +180,7 @@ func (s *Service) PayExpense(ctx context.Context, userID int64, search string, a
 	if err := s.repo.MarkPaid(ctx, expense.ID); err != nil {
 		return "", err
 	}
+	conversation.Touch(ctx, conversation.KindExpense, expense.ID, expenseLabel(expense.Description, expense.Amount))
 
 	return fmt.Sprintf("✅ Lunas: \"%s\" — %s", expense.Description, FormatRupiah(expense.Amount)), nil
 }
@@ -120,9 +209,9 @@ func (s *Service) Delete(ctx context.Context, userID int64, expenseID int, searc
 			return fmt.Sprintf("❌ Pengeluaran \"%s\" tidak ditemukan.", search), nil
 		}
 
-		exp = s.pickExpense(matches, amount, date)
+		exp = s.pickExpense(matches, amount, date, s.loc(ctx))
 		if exp == nil {
-			return s.formatDisambiguation(search, matches, "hapus"), nil
+			return s.formatDisambiguation(ctx, search, matches, "hapus", ""), nil
 		}
 	}
 
@@ -161,9 +250,9 @@ func (s *Service) Edit(ctx context.Context, userID int64, expenseID int, search
 			return fmt.Sprintf("❌ Pengeluaran \"%s\" tidak ditemukan.", search), nil
 		}
 
-		expense = s.pickExpense(matches, amount, date)
+		expense = s.pickExpense(matches, amount, date, s.loc(ctx))
 		if expense == nil {
-			return s.formatDisambiguation(search, matches, "edit"), nil
+			return s.formatDisambiguation(ctx, search, matches, "edit", ""), nil
 		}
 	}
 
@@ -179,6 +268,7 @@ func (s *Service) Edit(ctx context.Context, userID int64, expenseID int, search
 	if newTitle != "" {
 		displayDesc = newTitle
 	}
+	conversation.Touch(ctx, conversation.KindExpense, expense.ID, expenseLabel(displayDesc, expense.Amount))
 	statusStr := ""
 	if newIsPaid != nil {
 		if *newIsPaid {
@@ -190,58 +280,159 @@ func (s *Service) Edit(ctx context.Context, userID int64, expenseID int, search
 	return fmt.Sprintf("✏️ Pengeluaran diperbarui: \"%s\" — %s%s", displayDesc, FormatRupiah(expense.Amount), statusStr), nil
 }
 
-// ClearByMonth deletes all expenses for a specific year/month.
-// If year is 0 and the month exists across multiple years, returns a disambiguation prompt.
-func (s *Service) ClearByMonth(ctx context.Context, userID int64, month, year int) (string, error) {
-	if month < 1 || month > 12 {
-		return "❌ Bulan tidak valid.", nil
+// SetCategory files an expense under another category, and learns the
+// words of its description so later expenses like it land there too.
+// expenseID: if > 0, look up directly by ID (bypasses search).
+// amount and date are optional disambiguators.
+func (s *Service) SetCategory(ctx context.Context, userID int64, expenseID int, search string, amount int64, date *time.Time, categoryName string) (string, error) {
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return "", err
+	}
+	category := findCategory(categories, categoryName)
+	if category == nil {
+		return fmt.Sprintf("❌ Kategori \"%s\" tidak ada. Pilihan: %s.", categoryName, categoryNames(categories)), nil
 	}
 
-	if year == 0 {
-		years, err := s.repo.ListYearsForMonth(ctx, userID, month, s.timezone)
+	var expense *Expense
+
+	if expenseID > 0 {
+		found, err := s.repo.FindByID(ctx, userID, expenseID)
 		if err != nil {
 			return "", err
 		}
-		if len(years) == 0 {
-			return fmt.Sprintf("📭 Tidak ada pengeluaran di %s.", indonesianMonthsFull[month-1]), nil
+		if found == nil {
+			return fmt.Sprintf("❌ Pengeluaran dengan ID #%d tidak ditemukan.", expenseID), nil
 		}
-		if len(years) > 1 {
-			lines := []string{fmt.Sprintf("🔍 Pengeluaran \"%s\" ada di beberapa tahun:\n", indonesianMonthsFull[month-1])}
-			for _, y := range years {
-				lines = append(lines, fmt.Sprintf("• %s %d", indonesianMonthsFull[month-1], y))
-			}
-			lines = append(lines, "\nSebutkan tahunnya, contoh:")
-			lines = append(lines, fmt.Sprintf("• \"kosongkan %s %d\"", indonesianMonthsFull[month-1], years[len(years)-1]))
-			return strings.Join(lines, "\n"), nil
+		expense = found
+	} else {
+		matches, err := s.repo.FindAllBySearch(ctx, userID, search)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) == 0 {
+			return fmt.Sprintf("❌ Pengeluaran \"%s\" tidak ditemukan.", search), nil
+		}
+
+		expense = s.pickExpense(matches, amount, date, s.loc(ctx))
+		if expense == nil {
+			return s.formatDisambiguation(ctx, search, matches, "ubah kategori", " jadi "+category.Name), nil
 		}
-		year = years[0]
 	}
 
-	count, err := s.repo.ClearByMonth(ctx, userID, year, time.Month(month), s.timezone)
+	if err := s.repo.SetCategory(ctx, expense.ID, category.ID); err != nil {
+		return "", err
+	}
+	if err := s.repo.LearnKeywords(ctx, userID, keywords(expense.Description), category.ID); err != nil {
+		slog.Error("learn expense keywords failed", "user_id", userID, "error", err)
+	}
+	conversation.Touch(ctx, conversation.KindExpense, expense.ID, expenseLabel(expense.Description, expense.Amount))
+
+	return fmt.Sprintf("🏷 \"%s\" — %s sekarang masuk %s %s.\nPengeluaran serupa berikutnya otomatis masuk kategori ini.",
+		expense.Description, FormatRupiah(expense.Amount), category.Emoji, category.Name), nil
+}
+
+// PreviewClearByMonth describes what ClearByMonth would delete and resolves
+// the year. year is 0 when there is nothing to confirm, in which case msg is
+// the final answer (not found, invalid month, or ask for the year).
+func (s *Service) PreviewClearByMonth(ctx context.Context, userID int64, month, year int) (msg string, resolvedYear int, err error) {
+	loc := s.loc(ctx)
+	year, msg, err = s.resolveClearYear(ctx, userID, month, year)
+	if err != nil || year == 0 {
+		return msg, 0, err
+	}
+
+	expenses, err := s.repo.ListByMonth(ctx, userID, year, time.Month(month), loc)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(expenses) == 0 {
+		return fmt.Sprintf("📭 Tidak ada pengeluaran di %s %d.", indonesianMonthsFull[month-1], year), 0, nil
+	}
+	var total int64
+	for _, e := range expenses {
+		total += e.Amount
+	}
+	return fmt.Sprintf("⚠️ %d pengeluaran di %s %d (total %s) akan dihapus.",
+		len(expenses), indonesianMonthsFull[month-1], year, FormatRupiah(total)), year, nil
+}
+
+// ClearByMonth deletes all expenses for a specific year/month.
+// If year is 0 and the month exists across multiple years, returns a disambiguation prompt.
+func (s *Service) ClearByMonth(ctx context.Context, userID int64, month, year int) (string, error) {
+	loc := s.loc(ctx)
+	year, msg, err := s.resolveClearYear(ctx, userID, month, year)
+	if err != nil || year == 0 {
+		return msg, err
+	}
+
+	deleted, err := s.repo.ClearByMonth(ctx, userID, year, time.Month(month), loc)
 	if err != nil {
 		return "", err
 	}
-	if count == 0 {
+	if len(deleted) == 0 {
 		return fmt.Sprintf("📭 Tidak ada pengeluaran di %s %d.", indonesianMonthsFull[month-1], year), nil
 	}
-	return fmt.Sprintf("🗑️ %d pengeluaran di %s %d dihapus.", count, indonesianMonthsFull[month-1], year), nil
+	return fmt.Sprintf("🗑️ %d pengeluaran di %s %d dihapus.\nKetik \"undo\" untuk membatalkan.", len(deleted), indonesianMonthsFull[month-1], year), nil
+}
+
+// UndoClear restores expenses removed by ClearByMonth from an undo journal payload.
+func (s *Service) UndoClear(ctx context.Context, payload []byte) (string, error) {
+	var expenses []Expense
+	if err := json.Unmarshal(payload, &expenses); err != nil {
+		return "", fmt.Errorf("unmarshal expense snapshot: %w", err)
+	}
+	if err := s.repo.Restore(ctx, expenses); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("↩️ %d pengeluaran dikembalikan.", len(expenses)), nil
+}
+
+// resolveClearYear validates the month and fills in the year when the user
+// omitted it. It returns year 0 with a message when it cannot continue.
+func (s *Service) resolveClearYear(ctx context.Context, userID int64, month, year int) (int, string, error) {
+	loc := s.loc(ctx)
+	if month < 1 || month > 12 {
+		return 0, "❌ Bulan tidak valid.", nil
+	}
+	if year != 0 {
+		return year, "", nil
+	}
+
+	years, err := s.repo.ListYearsForMonth(ctx, userID, month, loc)
+	if err != nil {
+		return 0, "", err
+	}
+	if len(years) == 0 {
+		return 0, fmt.Sprintf("📭 Tidak ada pengeluaran di %s.", indonesianMonthsFull[month-1]), nil
+	}
+	if len(years) > 1 {
+		lines := []string{fmt.Sprintf("🔍 Pengeluaran \"%s\" ada di beberapa tahun:\n", indonesianMonthsFull[month-1])}
+		for _, y := range years {
+			lines = append(lines, fmt.Sprintf("• %s %d", indonesianMonthsFull[month-1], y))
+		}
+		lines = append(lines, "\nSebutkan tahunnya, contoh:")
+		lines = append(lines, fmt.Sprintf("• \"kosongkan %s %d\"", indonesianMonthsFull[month-1], years[len(years)-1]))
+		return 0, strings.Join(lines, "\n"), nil
+	}
+	return years[0], "", nil
 }
 
 // pickExpense returns the single matching expense.
 // Filters by amount (if > 0) and by recorded date (if non-nil). Returns nil when ambiguous.
-func (s *Service) pickExpense(matches []Expense, amount int64, date *time.Time) *Expense {
+func (s *Service) pickExpense(matches []Expense, amount int64, date *time.Time, loc *time.Location) *Expense {
 	if len(matches) == 1 {
 		return &matches[0]
 	}
 
 	filtered := matches
 	if date != nil {
-		d := date.In(s.timezone)
-		dayStart := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, s.timezone)
+		d := date.In(loc)
+		dayStart := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, loc)
 		dayEnd := dayStart.AddDate(0, 0, 1)
 		var byDate []Expense
 		for _, e := range filtered {
-			t := e.RecordedAt.In(s.timezone)
+			t := e.RecordedAt.In(loc)
 			if !t.Before(dayStart) && t.Before(dayEnd) {
 				byDate = append(byDate, e)
 			}
@@ -265,12 +456,15 @@ func (s *Service) pickExpense(matches []Expense, amount int64, date *time.Time)
 }
 
 // formatDisambiguation builds a disambiguation message listing all matching expenses with their IDs.
-func (s *Service) formatDisambiguation(search string, matches []Expense, action string) string {
+// Each match is also offered as a button choice.
+// suffix, if any, follows the ID in the example commands.
+func (s *Service) formatDisambiguation(ctx context.Context, search string, matches []Expense, action, suffix string) string {
+	loc := s.loc(ctx)
 	lines := []string{
 		fmt.Sprintf("🔍 Ada %d pengeluaran \"%s\":\n", len(matches), search),
 	}
 	for _, e := range matches {
-		t := e.RecordedAt.In(s.timezone)
+		t := e.RecordedAt.In(loc)
 		statusIcon := "✅"
 		statusLabel := "Lunas"
 		if !e.IsPaid {
@@ -283,12 +477,16 @@ func (s *Service) formatDisambiguation(search string, matches []Expense, action
 			FormatRupiah(e.Amount),
 			statusIcon, statusLabel,
 		))
+		pending.Offer(ctx, pending.Choice{
+			ID:    e.ID,
+			Label: fmt.Sprintf("#%d · %d %s · %s %s", e.ID, t.Day(), indonesianMonths[t.Month()-1], FormatRupiah(e.Amount), statusIcon),
+		})
 	}
 
 	// Build example commands using ID
-	lines = append(lines, fmt.Sprintf("\nSebutkan ID-nya untuk diproses, contoh:"))
+	lines = append(lines, "\nPilih tombol di bawah, atau sebutkan ID-nya, contoh:")
 	for _, e := range matches {
-		lines = append(lines, fmt.Sprintf("• \"%s id %d\"", action, e.ID))
+		lines = append(lines, fmt.Sprintf("• \"%s id %d%s\"", action, e.ID, suffix))
 	}
 
 	return strings.Join(lines, "\n")
@@ -311,21 +509,38 @@ func formatRupiahShort(amount int64) string {
 
 // MonthlyReport generates a full monthly report (Template 4).
 func (s *Service) MonthlyReport(ctx context.Context, userID int64, year int, month time.Month) (string, error) {
-	expenses, err := s.repo.ListByMonth(ctx, userID, year, month, s.timezone)
+	loc := s.loc(ctx)
+	expenses, err := s.repo.ListByMonth(ctx, userID, year, month, loc)
 	if err != nil {
 		return "", err
 	}
-	if len(expenses) == 0 {
+	incomes, err := s.repo.ListIncomesByMonth(ctx, userID, year, month, loc)
+	if err != nil {
+		return "", err
+	}
+	if len(expenses) == 0 && len(incomes) == 0 {
 		monthName := fmt.Sprintf("%s %d", indonesianMonthsFull[month-1], year)
 		return fmt.Sprintf("📭 Tidak ada pengeluaran di %s.", monthName), nil
 	}
 
-	return s.formatMonthlyReport(expenses, year, month), nil
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		return "", err
+	}
+	budgets, err := s.repo.ListBudgets(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	accounts, err := s.repo.ListAccounts(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	return s.formatMonthlyReport(expenses, incomes, year, month, categories, budgets, accounts, loc), nil
 }
 
-// formatAllExpenses formats all expenses grouped by month (Template 1).
-func (s *Service) formatAllExpenses(expenses []Expense) string {
-	now := time.Now().In(s.timezone)
+// formatAllExpenses formats all expenses and incomes grouped by month (Template 1).
+func (s *Service) formatAllExpenses(expenses []Expense, incomes []Income, loc *time.Location) string {
+	now := time.Now().In(loc)
 
 	// Group by year-month
 	type monthKey struct {
@@ -333,11 +548,12 @@ func (s *Service) formatAllExpenses(expenses []Expense) string {
 		month time.Month
 	}
 	grouped := make(map[monthKey][]Expense)
+	groupedIncomes := make(map[monthKey][]Income)
 	var keys []monthKey
 	seen := make(map[monthKey]bool)
 
 	for _, e := range expenses {
-		t := e.RecordedAt.In(s.timezone)
+		t := e.RecordedAt.In(loc)
 		k := monthKey{t.Year(), t.Month()}
 		if !seen[k] {
 			keys = append(keys, k)
@@ -345,6 +561,15 @@ func (s *Service) formatAllExpenses(expenses []Expense) string {
 		}
 		grouped[k] = append(grouped[k], e)
 	}
+	for _, in := range incomes {
+		t := in.RecordedAt.In(loc)
+		k := monthKey{t.Year(), t.Month()}
+		if !seen[k] {
+			keys = append(keys, k)
+			seen[k] = true
+		}
+		groupedIncomes[k] = append(groupedIncomes[k], in)
+	}
 
 	// Sort keys descending (newest first)
 	sort.Slice(keys, func(i, j int) bool {
@@ -357,7 +582,7 @@ func (s *Service) formatAllExpenses(expenses []Expense) string {
 	var lines []string
 	lines = append(lines, fmt.Sprintf("💰 Pengeluaran %d\n", now.Year()))
 
-	var grandTotal int64
+	var grandTotal, grandIncome int64
 
 	for _, k := range keys {
 		monthExpenses := grouped[k]
@@ -366,7 +591,7 @@ func (s *Service) formatAllExpenses(expenses []Expense) string {
 		var monthTotal int64
 		var unpaidCount int
 		for _, e := range monthExpenses {
-			t := e.RecordedAt.In(s.timezone)
+			t := e.RecordedAt.In(loc)
 			icon := "✅"
 			if !e.IsPaid {
 				icon = "🔴"
@@ -376,26 +601,39 @@ func (s *Service) formatAllExpenses(expenses []Expense) string {
 				icon, t.Day(), indonesianMonths[t.Month()-1], e.Description, FormatRupiah(e.Amount)))
 			monthTotal += e.Amount
 		}
+		var monthIncome int64
+		for _, in := range groupedIncomes[k] {
+			lines = append(lines, incomeLine(in, loc))
+			monthIncome += in.Amount
+		}
 
 		monthShort := indonesianMonths[k.month-1]
 		suffix := ""
 		if unpaidCount > 0 {
 			suffix = fmt.Sprintf(" (%d belum lunas)", unpaidCount)
 		}
+		if monthIncome > 0 {
+			suffix += " · 📥 " + FormatRupiah(monthIncome)
+		}
 		lines = append(lines, fmt.Sprintf("── %s: %s%s ──\n", monthShort, FormatRupiah(monthTotal), suffix))
 
 		grandTotal += monthTotal
+		grandIncome += monthIncome
 	}
 
 	lines = append(lines, "─────────────")
 	lines = append(lines, fmt.Sprintf("💵 Total: %s", FormatRupiah(grandTotal)))
+	if grandIncome > 0 {
+		lines = append(lines, fmt.Sprintf("📥 Pemasukan: %s", FormatRupiah(grandIncome)))
+		lines = append(lines, fmt.Sprintf("💰 Selisih: %s", formatSignedRupiah(grandIncome-grandTotal)))
+	}
 
 	return strings.Join(lines, "\n")
 }
 
 // formatMonthlyExpenses formats expenses for a single month/period (Template 2).
-func (s *Service) formatMonthlyExpenses(expenses []Expense, filter string) string {
-	now := time.Now().In(s.timezone)
+func (s *Service) formatMonthlyExpenses(expenses []Expense, incomes []Income, filter string, categories []Category, accounts []Account, loc *time.Location) string {
+	now := time.Now().In(loc)
 
 	var lines []string
 	lines = append(lines, fmt.Sprintf("💰 %s %d\n", indonesianMonthsFull[now.Month()-1], now.Year()))
@@ -404,7 +642,7 @@ func (s *Service) formatMonthlyExpenses(expenses []Expense, filter string) strin
 	var paidCount, unpaidCount int
 
 	for _, e := range expenses {
-		t := e.RecordedAt.In(s.timezone)
+		t := e.RecordedAt.In(loc)
 		icon := "✅"
 		if !e.IsPaid {
 			icon = "🔴"
@@ -418,6 +656,12 @@ func (s *Service) formatMonthlyExpenses(expenses []Expense, filter string) strin
 			icon, t.Day(), indonesianMonths[t.Month()-1], e.Description, FormatRupiah(e.Amount)))
 		total += e.Amount
 	}
+	if len(incomes) > 0 {
+		lines = append(lines, "\n💼 Pemasukan")
+		for _, in := range incomes {
+			lines = append(lines, incomeLine(in, loc))
+		}
+	}
 
 	lines = append(lines, "\n─────────────")
 	lines = append(lines, fmt.Sprintf("💵 Total: %s", FormatRupiah(total)))
@@ -427,12 +671,25 @@ func (s *Service) formatMonthlyExpenses(expenses []Expense, filter string) strin
 	if unpaidCount > 0 {
 		lines = append(lines, fmt.Sprintf("🔴 Belum: %s (%d)", FormatRupiah(unpaidTotal), unpaidCount))
 	}
+	if len(incomes) > 0 {
+		income := sumIncomes(incomes)
+		lines = append(lines, fmt.Sprintf("📥 Pemasukan: %s (%d)", FormatRupiah(income), len(incomes)))
+		lines = append(lines, fmt.Sprintf("💰 Selisih: %s", formatSignedRupiah(income-total)))
+	}
+	if len(expenses) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, categoryBreakdown(expenses, categories, "")...)
+	}
+	if byAccount := accountBreakdown(expenses, incomes, accounts, ""); len(byAccount) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, byAccount...)
+	}
 
 	return strings.Join(lines, "\n")
 }
 
 // formatMonthlyReport generates a detailed monthly report (Template 4).
-func (s *Service) formatMonthlyReport(expenses []Expense, year int, month time.Month) string {
+func (s *Service) formatMonthlyReport(expenses []Expense, incomes []Income, year int, month time.Month, categories []Category, budgets []Budget, accounts []Account, loc *time.Location) string {
 	monthName := fmt.Sprintf("%s %d", indonesianMonthsFull[month-1], year)
 
 	var lines []string
@@ -460,7 +717,7 @@ func (s *Service) formatMonthlyReport(expenses []Expense, year int, month time.M
 			lines = append(lines, fmt.Sprintf("  ... dan %d lainnya", len(paid)-maxShow))
 			break
 		}
-		t := e.RecordedAt.In(s.timezone)
+		t := e.RecordedAt.In(loc)
 		lines = append(lines, fmt.Sprintf("  %d %s · %s · %s",
 			t.Day(), indonesianMonths[t.Month()-1], e.Description, FormatRupiah(e.Amount)))
 	}
@@ -470,12 +727,23 @@ func (s *Service) formatMonthlyReport(expenses []Expense, year int, month time.M
 		lines = append(lines, "")
 		lines = append(lines, fmt.Sprintf("🔴 Belum Lunas (%d item)", len(unpaid)))
 		for _, e := range unpaid {
-			t := e.RecordedAt.In(s.timezone)
+			t := e.RecordedAt.In(loc)
 			lines = append(lines, fmt.Sprintf("  %d %s · %s · %s",
 				t.Day(), indonesianMonths[t.Month()-1], e.Description, FormatRupiah(e.Amount)))
 		}
 	}
 
+	// Income section
+	if len(incomes) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("💼 Pemasukan (%d item)", len(incomes)))
+		for _, in := range incomes {
+			t := in.RecordedAt.In(loc)
+			lines = append(lines, fmt.Sprintf("  %d %s · %s · %s",
+				t.Day(), indonesianMonths[t.Month()-1], incomeDescription(in), FormatRupiah(in.Amount)))
+		}
+	}
+
 	lines = append(lines, "\n━━━━━━━━━━━━━━━━━━━━\n")
 	lines = append(lines, "📊 Ringkasan\n")
 
@@ -485,6 +753,26 @@ func (s *Service) formatMonthlyReport(expenses []Expense, year int, month time.M
 	if unpaidTotal > 0 {
 		lines = append(lines, fmt.Sprintf("  🔴 Belum      : %s", FormatRupiah(unpaidTotal)))
 	}
+	if len(incomes) > 0 {
+		income := sumIncomes(incomes)
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("  💼 Pemasukan  : %s", FormatRupiah(income)))
+		lines = append(lines, fmt.Sprintf("  💰 Arus kas   : %s", formatSignedRupiah(income-grandTotal)))
+		lines = append(lines, fmt.Sprintf("  🏦 Tabungan   : %s", savingsRate(income, grandTotal)))
+	}
+
+	if len(expenses) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, categoryBreakdown(expenses, categories, "  ")...)
+	}
+	if len(budgets) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, budgetLines(expenses, budgets, categories, "  ")...)
+	}
+	if byAccount := accountBreakdown(expenses, incomes, accounts, "  "); len(byAccount) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, byAccount...)
+	}
 
 	// Top 3 biggest expenses
 	sorted := make([]Expense, len(expenses))
@@ -493,14 +781,16 @@ func (s *Service) formatMonthlyReport(expenses []Expense, year int, month time.M
 		return sorted[i].Amount > sorted[j].Amount
 	})
 
-	lines = append(lines, "")
-	lines = append(lines, "  Item terbesar :")
-	topN := 3
-	if len(sorted) < topN {
-		topN = len(sorted)
-	}
-	for i := 0; i < topN; i++ {
-		lines = append(lines, fmt.Sprintf("  %d. %s — %s", i+1, sorted[i].Description, FormatRupiah(sorted[i].Amount)))
+	if len(sorted) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, "  Item terbesar :")
+		topN := 3
+		if len(sorted) < topN {
+			topN = len(sorted)
+		}
+		for i := 0; i < topN; i++ {
+			lines = append(lines, fmt.Sprintf("  %d. %s — %s", i+1, sorted[i].Description, FormatRupiah(sorted[i].Amount)))
+		}
 	}
 
 	lines = append(lines, "")
@@ -513,6 +803,10 @@ func (s *Service) formatMonthlyReport(expenses []Expense, year int, month time.M
 	return strings.Join(lines, "\n")
 }
 
+func expenseLabel(description string, amount int64) string {
+	return fmt.Sprintf("%s (%s)", description, FormatRupiah(amount))
+}
+
 func FormatRupiah(amount int64) string {
 	s := fmt.Sprintf("%d", amount)
 	n := len(s)
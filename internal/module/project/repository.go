@@ -5,6 +5,9 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/module/todo"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/undo"
 )
 
 type Project struct {
@@ -39,6 +42,13 @@ type GoalWithProject struct {
 	ProjectName string
 }
 
+// Snapshot holds a hard-deleted project and its goals so the deletion can be
+// undone.
+type Snapshot struct {
+	Project Project
+	Goals   *todo.Snapshot
+}
+
 type Repository struct {
 	db *sql.DB
 }
@@ -109,12 +119,84 @@ func (r *Repository) FindByName(ctx context.Context, userID int64, name string)
 	return &p, nil
 }
 
-func (r *Repository) Delete(ctx context.Context, id int) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM projects WHERE id = $1`, id)
+// Delete removes a project (its goals cascade), journals it for undo in the
+// same transaction and returns what was deleted.
+func (r *Repository) Delete(ctx context.Context, id int) (*Snapshot, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("delete project: %w", err)
+		return nil, fmt.Errorf("begin delete project: %w", err)
 	}
-	return nil
+	defer tx.Rollback()
+
+	var snap Snapshot
+	p := &snap.Project
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, user_id, name, description, due_date, is_active, created_at, updated_at
+		 FROM projects WHERE id = $1`,
+		id,
+	).Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &p.DueDate, &p.IsActive, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot project: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM todos WHERE project_id = $1`, id)
+	if err != nil {
+		return nil, fmt.Errorf("list goals to delete: %w", err)
+	}
+	var goalIDs []int
+	for rows.Next() {
+		var gid int
+		if err := rows.Scan(&gid); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan goal id: %w", err)
+		}
+		goalIDs = append(goalIDs, gid)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	snap.Goals, err = todo.SnapshotByIDs(ctx, tx, goalIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM projects WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("delete project: %w", err)
+	}
+	if err := undo.Record(ctx, tx, p.UserID, undo.KindDeleteProject, &snap); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// Restore re-inserts a snapshot taken by Delete.
+func (r *Repository) Restore(ctx context.Context, snap *Snapshot) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin restore project: %w", err)
+	}
+	defer tx.Rollback()
+
+	p := snap.Project
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO projects (id, user_id, name, description, due_date, is_active, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW()) ON CONFLICT (id) DO NOTHING`,
+		p.ID, p.UserID, p.Name, p.Description, p.DueDate, p.IsActive, p.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("restore project: %w", err)
+	}
+	if snap.Goals != nil {
+		if err := todo.RestoreSnapshot(ctx, tx, snap.Goals); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *Repository) GetGoals(ctx context.Context, projectID int) ([]Goal, error) {
@@ -178,11 +260,29 @@ func (r *Repository) FindGoalAcrossProjects(ctx context.Context, userID int64, s
 	return goals, rows.Err()
 }
 
+func (r *Repository) FindGoalByID(ctx context.Context, userID int64, id int) (*GoalWithProject, error) {
+	var g GoalWithProject
+	err := r.db.QueryRowContext(ctx,
+		`SELECT t.id, t.project_id, t.title, t.is_completed, t.completed_at, t.due_date, t.created_at, p.name
+		 FROM todos t
+		 JOIN projects p ON p.id = t.project_id
+		 WHERE t.id = $1 AND p.user_id = $2 AND t.deleted_at IS NULL`,
+		id, userID,
+	).Scan(&g.ID, &g.ProjectID, &g.Title, &g.IsCompleted, &g.CompletedAt, &g.DueDate, &g.CreatedAt, &g.ProjectName)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find goal by id: %w", err)
+	}
+	return &g, nil
+}
+
 func (r *Repository) FindGoalBySearch(ctx context.Context, projectID int, search string) (*Goal, error) {
 	var g Goal
 	err := r.db.QueryRowContext(ctx,
 		`SELECT id, project_id, title, is_completed, completed_at, due_date, created_at
-		 FROM todos WHERE project_id = $1 AND title ILIKE '%' || $2 || '%'
+		 FROM todos WHERE project_id = $1 AND deleted_at IS NULL AND title ILIKE '%' || $2 || '%'
 		 ORDER BY created_at DESC LIMIT 1`,
 		projectID, search,
 	).Scan(&g.ID, &g.ProjectID, &g.Title, &g.IsCompleted, &g.CompletedAt, &g.DueDate, &g.CreatedAt)
@@ -206,8 +306,10 @@ func (r *Repository) CompleteGoal(ctx context.Context, id int) error {
 	return nil
 }
 
+// DeleteGoal moves a goal to the trash; it keeps its project link and
+// reminders so it can be restored.
 func (r *Repository) DeleteGoal(ctx context.Context, id int) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM todos WHERE id = $1`, id)
+	_, err := r.db.ExecContext(ctx, `UPDATE todos SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1`, id)
 	if err != nil {
 		return fmt.Errorf("delete goal: %w", err)
 	}
@@ -2,10 +2,15 @@ package project
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/zhafrantharif/personal-assistant-bot/internal/conversation"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/pending"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/recurrence"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/reminder"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
 )
 
 type Service struct {
@@ -22,20 +27,32 @@ func NewService(repo *Repository, reminderRepo *reminder.Repository, timezone *t
 	}
 }
 
+// defaultReminderHour is used for relative reminders on a deadline without a
+// time of day when the context carries no user settings.
+const defaultReminderHour = 7
+
+// loc returns the timezone of the user being served.
+func (s *Service) loc(ctx context.Context) *time.Location {
+	return settings.Location(ctx, s.timezone)
+}
+
 func (s *Service) Add(ctx context.Context, userID int64, name string, description *string, dueDate *time.Time) (string, error) {
-	_, err := s.repo.Create(ctx, userID, name, description, dueDate)
+	loc := s.loc(ctx)
+	id, err := s.repo.Create(ctx, userID, name, description, dueDate)
 	if err != nil {
 		return "", err
 	}
+	conversation.Touch(ctx, conversation.KindProject, id, name)
 
 	resp := fmt.Sprintf("📁 Project dibuat: \"%s\"", name)
 	if dueDate != nil {
-		resp += fmt.Sprintf("\n📅 Deadline: %s", dueDate.In(s.timezone).Format("2 Jan 2006"))
+		resp += fmt.Sprintf("\n📅 Deadline: %s", dueDate.In(loc).Format("2 Jan 2006"))
 	}
 	return resp, nil
 }
 
 func (s *Service) List(ctx context.Context, userID int64) (string, error) {
+	loc := s.loc(ctx)
 	projects, err := s.repo.List(ctx, userID)
 	if err != nil {
 		return "", err
@@ -50,7 +67,7 @@ func (s *Service) List(ctx context.Context, userID int64) (string, error) {
 		progress := fmt.Sprintf("%d/%d goals ✓", p.CompletedGoals, p.TotalGoals)
 		deadline := ""
 		if p.DueDate != nil {
-			deadline = fmt.Sprintf(" — deadline %s", p.DueDate.In(s.timezone).Format("2 Jan 2006"))
+			deadline = fmt.Sprintf(" — deadline %s", p.DueDate.In(loc).Format("2 Jan 2006"))
 		}
 		resp += fmt.Sprintf("%d. %s (%s)%s\n", i+1, p.Name, progress, deadline)
 	}
@@ -58,6 +75,7 @@ func (s *Service) List(ctx context.Context, userID int64) (string, error) {
 }
 
 func (s *Service) Show(ctx context.Context, userID int64, projectName string) (string, error) {
+	loc := s.loc(ctx)
 	proj, err := s.repo.FindByName(ctx, userID, projectName)
 	if err != nil {
 		return "", err
@@ -70,6 +88,7 @@ func (s *Service) Show(ctx context.Context, userID int64, projectName string) (s
 	if err != nil {
 		return "", err
 	}
+	conversation.Touch(ctx, conversation.KindProject, proj.ID, proj.Name)
 
 	total := len(goals)
 	completed := 0
@@ -101,7 +120,7 @@ func (s *Service) Show(ctx context.Context, userID int64, projectName string) (s
 		resp += fmt.Sprintf("📝 %s\n", *proj.Description)
 	}
 	if proj.DueDate != nil {
-		resp += fmt.Sprintf("📅 Deadline: %s\n", proj.DueDate.In(s.timezone).Format("2 Jan 2006"))
+		resp += fmt.Sprintf("📅 Deadline: %s\n", proj.DueDate.In(loc).Format("2 Jan 2006"))
 	}
 	resp += fmt.Sprintf("📊 Progress: %d/%d goals %s\n", completed, total, progressBar)
 
@@ -110,7 +129,7 @@ func (s *Service) Show(ctx context.Context, userID int64, projectName string) (s
 		return resp, nil
 	}
 
-	now := time.Now().In(s.timezone)
+	now := time.Now().In(loc)
 	resp += "\nGoals:\n"
 	for i, g := range goals {
 		if g.IsCompleted {
@@ -118,7 +137,7 @@ func (s *Service) Show(ctx context.Context, userID int64, projectName string) (s
 		} else {
 			line := fmt.Sprintf("%d. ☐ %s", i+1, g.Title)
 			if g.DueDate != nil {
-				d := g.DueDate.In(s.timezone)
+				d := g.DueDate.In(loc)
 				dateStr := d.Format("2 Jan 2006")
 				if d.Before(now) {
 					dateStr += " ⚠️"
@@ -132,7 +151,10 @@ func (s *Service) Show(ctx context.Context, userID int64, projectName string) (s
 	return resp, nil
 }
 
-func (s *Service) AddGoal(ctx context.Context, userID int64, projectName, title string, dueDate *time.Time, hasReminder bool, remindAt *time.Time, recurring string) (string, error) {
+// AddGoal adds a goal to a project. leads are reminders relative to dueDate,
+// e.g. -1d for one day before; they are ignored without a due date.
+func (s *Service) AddGoal(ctx context.Context, userID int64, projectName, title string, dueDate *time.Time, hasReminder bool, remindAt *time.Time, recurring string, leads []time.Duration) (string, error) {
+	loc := s.loc(ctx)
 	proj, err := s.repo.FindByName(ctx, userID, projectName)
 	if err != nil {
 		return "", err
@@ -146,10 +168,13 @@ func (s *Service) AddGoal(ctx context.Context, userID int64, projectName, title
 		return "", err
 	}
 
+	conversation.Touch(ctx, conversation.KindProject, proj.ID, proj.Name)
+	conversation.Touch(ctx, conversation.KindGoal, goalID, title)
+
 	resp := fmt.Sprintf("✅ Goal ditambahkan ke %s: \"%s\"", proj.Name, title)
 
 	if dueDate != nil {
-		resp += fmt.Sprintf("\n📅 Deadline: %s", dueDate.In(s.timezone).Format("2 Jan 2006"))
+		resp += fmt.Sprintf("\n📅 Deadline: %s", dueDate.In(loc).Format("2 Jan 2006"))
 	}
 
 	if hasReminder && remindAt != nil {
@@ -157,16 +182,48 @@ func (s *Service) AddGoal(ctx context.Context, userID int64, projectName, title
 		if err != nil {
 			return "", fmt.Errorf("create goal reminder: %w", err)
 		}
-		resp += fmt.Sprintf("\n⏰ Reminder: %s", remindAt.In(s.timezone).Format("2 Jan 2006 15:04 WIB"))
-		if recurring != "" {
-			resp += fmt.Sprintf(" (recurring: %s)", recurring)
+		resp += fmt.Sprintf("\n⏰ Reminder: %s", remindAt.In(loc).Format("2 Jan 2006 15:04 MST"))
+		if rule, err := recurrence.Parse(recurring); err == nil {
+			resp += fmt.Sprintf(" (🔁 %s)", rule.Describe())
+		}
+	}
+
+	if dueDate != nil {
+		hour := settings.ReminderHour(ctx, defaultReminderHour)
+		for _, lead := range leads {
+			offset := int(lead / time.Minute)
+			at := reminder.RelativeTime(*dueDate, offset, loc, hour)
+			if err := s.reminderRepo.CreateRelative(ctx, goalID, offset, at); err != nil {
+				return "", fmt.Errorf("create goal reminder: %w", err)
+			}
+			resp += fmt.Sprintf("\n⏰ Reminder: %s (%s)", at.In(loc).Format("2 Jan 2006 15:04 MST"), reminder.DescribeOffset(offset))
 		}
 	}
 
 	return resp, nil
 }
 
-func (s *Service) CompleteGoal(ctx context.Context, userID int64, projectName, search string) (string, error) {
+// CompleteGoal marks a goal completed.
+// goalID: if > 0, look up directly by ID (bypasses project and search).
+func (s *Service) CompleteGoal(ctx context.Context, userID int64, goalID int, projectName, search string) (string, error) {
+	if goalID > 0 {
+		g, err := s.repo.FindGoalByID(ctx, userID, goalID)
+		if err != nil {
+			return "", err
+		}
+		if g == nil {
+			return fmt.Sprintf("❌ Goal dengan ID #%d tidak ditemukan.", goalID), nil
+		}
+		if g.IsCompleted {
+			return fmt.Sprintf("ℹ️ Goal \"%s\" sudah selesai sebelumnya.", g.Title), nil
+		}
+		if err := s.repo.CompleteGoal(ctx, g.ID); err != nil {
+			return "", err
+		}
+		conversation.Touch(ctx, conversation.KindGoal, g.ID, g.Title)
+		return fmt.Sprintf("✅ Goal selesai di %s: \"%s\"", g.ProjectName, g.Title), nil
+	}
+
 	// If project not specified, search across all projects
 	if projectName == "" {
 		return s.completeGoalAcrossProjects(ctx, userID, search)
@@ -194,6 +251,7 @@ func (s *Service) CompleteGoal(ctx context.Context, userID int64, projectName, s
 	if err := s.repo.CompleteGoal(ctx, goal.ID); err != nil {
 		return "", err
 	}
+	conversation.Touch(ctx, conversation.KindGoal, goal.ID, goal.Title)
 
 	return fmt.Sprintf("✅ Goal selesai di %s: \"%s\"", proj.Name, goal.Title), nil
 }
@@ -215,9 +273,28 @@ func (s *Service) completeGoalAcrossProjects(ctx context.Context, userID int64,
 		if err := s.repo.CompleteGoal(ctx, g.ID); err != nil {
 			return "", err
 		}
+		conversation.Touch(ctx, conversation.KindGoal, g.ID, g.Title)
 		return fmt.Sprintf("✅ Goal selesai di %s: \"%s\"", g.ProjectName, g.Title), nil
 	}
-	return formatGoalDisambiguation("selesaikan", search, matches), nil
+	return formatGoalDisambiguation(ctx, "selesaikan", search, matches), nil
+}
+
+// PreviewDelete describes what Delete would remove. ok is false when the
+// project does not exist, in which case msg is the final answer.
+func (s *Service) PreviewDelete(ctx context.Context, userID int64, projectName string) (msg string, ok bool, err error) {
+	proj, err := s.repo.FindByName(ctx, userID, projectName)
+	if err != nil {
+		return "", false, err
+	}
+	if proj == nil {
+		return fmt.Sprintf("❌ Project \"%s\" tidak ditemukan.", projectName), false, nil
+	}
+
+	goals, err := s.repo.GetGoals(ctx, proj.ID)
+	if err != nil {
+		return "", false, err
+	}
+	return fmt.Sprintf("⚠️ Project \"%s\" beserta %d goals akan dihapus.", proj.Name, len(goals)), true, nil
 }
 
 func (s *Service) Delete(ctx context.Context, userID int64, projectName string) (string, error) {
@@ -229,14 +306,42 @@ func (s *Service) Delete(ctx context.Context, userID int64, projectName string)
 		return fmt.Sprintf("❌ Project \"%s\" tidak ditemukan.", projectName), nil
 	}
 
-	if err := s.repo.Delete(ctx, proj.ID); err != nil {
+	if _, err := s.repo.Delete(ctx, proj.ID); err != nil {
 		return "", err
 	}
 
-	return fmt.Sprintf("🗑️ Project dihapus: \"%s\" (beserta semua goals)", proj.Name), nil
+	return fmt.Sprintf("🗑️ Project dihapus: \"%s\" (beserta semua goals)\nKetik \"undo\" untuk membatalkan.", proj.Name), nil
+}
+
+// UndoDelete restores a project removed by Delete from an undo journal payload.
+func (s *Service) UndoDelete(ctx context.Context, payload []byte) (string, error) {
+	var snap Snapshot
+	if err := json.Unmarshal(payload, &snap); err != nil {
+		return "", fmt.Errorf("unmarshal project snapshot: %w", err)
+	}
+	if err := s.repo.Restore(ctx, &snap); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("↩️ Project dikembalikan: \"%s\"", snap.Project.Name), nil
 }
 
-func (s *Service) DeleteGoal(ctx context.Context, userID int64, projectName, search string) (string, error) {
+// DeleteGoal removes a goal.
+// goalID: if > 0, look up directly by ID (bypasses project and search).
+func (s *Service) DeleteGoal(ctx context.Context, userID int64, goalID int, projectName, search string) (string, error) {
+	if goalID > 0 {
+		g, err := s.repo.FindGoalByID(ctx, userID, goalID)
+		if err != nil {
+			return "", err
+		}
+		if g == nil {
+			return fmt.Sprintf("❌ Goal dengan ID #%d tidak ditemukan.", goalID), nil
+		}
+		if err := s.repo.DeleteGoal(ctx, g.ID); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("🗑️ Goal dihapus dari %s: \"%s\"", g.ProjectName, g.Title), nil
+	}
+
 	// If project not specified, search across all projects
 	if projectName == "" {
 		return s.deleteGoalAcrossProjects(ctx, userID, search)
@@ -280,7 +385,7 @@ func (s *Service) deleteGoalAcrossProjects(ctx context.Context, userID int64, se
 		}
 		return fmt.Sprintf("🗑️ Goal dihapus dari %s: \"%s\"", g.ProjectName, g.Title), nil
 	}
-	return formatGoalDisambiguation("hapus", search, matches), nil
+	return formatGoalDisambiguation(ctx, "hapus", search, matches), nil
 }
 
 // allSameProject returns true if all GoalWithProject entries belong to the same project.
@@ -298,7 +403,8 @@ func allSameProject(goals []GoalWithProject) bool {
 }
 
 // formatGoalDisambiguation builds a message asking the user to specify the project.
-func formatGoalDisambiguation(action, search string, matches []GoalWithProject) string {
+// Each matching goal is also offered as a button choice.
+func formatGoalDisambiguation(ctx context.Context, action, search string, matches []GoalWithProject) string {
 	// Collect unique project names
 	seen := make(map[string]bool)
 	var projectNames []string
@@ -307,13 +413,14 @@ func formatGoalDisambiguation(action, search string, matches []GoalWithProject)
 			seen[g.ProjectName] = true
 			projectNames = append(projectNames, g.ProjectName)
 		}
+		pending.Offer(ctx, pending.Choice{ID: g.ID, Label: fmt.Sprintf("%s: %s", g.ProjectName, g.Title)})
 	}
 
 	msg := fmt.Sprintf("🔍 Goal \"%s\" ditemukan di %d project:\n", search, len(projectNames))
 	for i, name := range projectNames {
 		msg += fmt.Sprintf("%d. %s\n", i+1, name)
 	}
-	msg += fmt.Sprintf("\nSebutkan projectnya, contoh:\n\"")
+	msg += fmt.Sprintf("\nPilih tombol di bawah, atau sebutkan projectnya, contoh:\n\"")
 	msg += fmt.Sprintf("%s goal %s di %s\"", action, search, projectNames[0])
 	return msg
 }
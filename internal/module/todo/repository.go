@@ -5,6 +5,11 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/db"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/reminder"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/undo"
 )
 
 type Todo struct {
@@ -21,6 +26,13 @@ type Todo struct {
 	UpdatedAt   time.Time
 }
 
+// Snapshot holds hard-deleted todos and their reminders so the deletion can
+// be undone, e.g. goals removed together with their project.
+type Snapshot struct {
+	Todos     []Todo
+	Reminders []reminder.Reminder
+}
+
 type Repository struct {
 	db *sql.DB
 }
@@ -127,9 +139,11 @@ func (r *Repository) Update(ctx context.Context, id int, title string, dueDate *
 	return nil
 }
 
+// Delete moves a todo to the trash. Its reminders and project link are kept
+// so Restore can bring it back intact.
 func (r *Repository) Delete(ctx context.Context, id int) error {
 	_, err := r.db.ExecContext(ctx,
-		`DELETE FROM todos WHERE id = $1`,
+		`UPDATE todos SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1`,
 		id,
 	)
 	if err != nil {
@@ -138,22 +152,190 @@ func (r *Repository) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-func (r *Repository) DeleteAll(ctx context.Context, userID int64) (int64, error) {
-	res, err := r.db.ExecContext(ctx,
-		`DELETE FROM todos WHERE user_id = $1 AND project_id IS NULL AND deleted_at IS NULL`,
+func (r *Repository) CountActive(ctx context.Context, userID int64) (int, error) {
+	var n int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM todos WHERE user_id = $1 AND project_id IS NULL AND deleted_at IS NULL`,
 		userID,
-	)
+	).Scan(&n)
 	if err != nil {
-		return 0, fmt.Errorf("delete all todos: %w", err)
+		return 0, fmt.Errorf("count todos: %w", err)
 	}
-	n, _ := res.RowsAffected()
 	return n, nil
 }
 
+// DeleteAll moves the user's todos (not goals) to the trash, journals their
+// IDs for undo in the same transaction and returns them.
+func (r *Repository) DeleteAll(ctx context.Context, userID int64) ([]int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin delete all todos: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`UPDATE todos SET deleted_at = NOW(), updated_at = NOW()
+		 WHERE user_id = $1 AND project_id IS NULL AND deleted_at IS NULL
+		 RETURNING id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("delete all todos: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan todo id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if err := undo.Record(ctx, tx, userID, undo.KindClearTodo, ids); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// TrashedTodo is a soft-deleted todo or goal, with its project name for goals.
+type TrashedTodo struct {
+	Todo
+	ProjectName *string
+}
+
+func (r *Repository) ListTrash(ctx context.Context, userID int64) ([]TrashedTodo, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT t.id, t.user_id, t.project_id, t.title, t.description, t.is_completed, t.completed_at, t.due_date, t.deleted_at, t.created_at, t.updated_at,
+		        p.name
+		 FROM todos t
+		 LEFT JOIN projects p ON p.id = t.project_id
+		 WHERE t.user_id = $1 AND t.deleted_at IS NOT NULL
+		 ORDER BY t.deleted_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list trash: %w", err)
+	}
+	defer rows.Close()
+
+	var todos []TrashedTodo
+	for rows.Next() {
+		var t TrashedTodo
+		err := rows.Scan(&t.ID, &t.UserID, &t.ProjectID, &t.Title, &t.Description, &t.IsCompleted, &t.CompletedAt, &t.DueDate, &t.DeletedAt, &t.CreatedAt, &t.UpdatedAt,
+			&t.ProjectName)
+		if err != nil {
+			return nil, fmt.Errorf("scan trashed todo: %w", err)
+		}
+		todos = append(todos, t)
+	}
+	return todos, rows.Err()
+}
+
+// FindTrashedBySearch returns the most recently deleted todo or goal matching
+// search.
+func (r *Repository) FindTrashedBySearch(ctx context.Context, userID int64, search string) (*Todo, error) {
+	var t Todo
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, project_id, title, description, is_completed, completed_at, due_date, deleted_at, created_at, updated_at
+		 FROM todos WHERE user_id = $1 AND deleted_at IS NOT NULL AND title ILIKE '%' || $2 || '%'
+		 ORDER BY deleted_at DESC LIMIT 1`,
+		userID, search,
+	).Scan(&t.ID, &t.UserID, &t.ProjectID, &t.Title, &t.Description, &t.IsCompleted, &t.CompletedAt, &t.DueDate, &t.DeletedAt, &t.CreatedAt, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find trashed todo: %w", err)
+	}
+	return &t, nil
+}
+
+// Restore takes the user's todos with the given IDs out of the trash and
+// returns how many were restored.
+func (r *Repository) Restore(ctx context.Context, userID int64, ids []int) (int, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE todos SET deleted_at = NULL, updated_at = NOW()
+		 WHERE user_id = $1 AND id = ANY($2) AND deleted_at IS NOT NULL`,
+		userID, pq.Array(ids),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("restore todos: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("restore todos: %w", err)
+	}
+	return int(n), nil
+}
+
+// PurgeDeletedBefore permanently removes todos trashed before the given time;
+// their reminders go with them via ON DELETE CASCADE.
+func (r *Repository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx,
+		`DELETE FROM todos WHERE deleted_at IS NOT NULL AND deleted_at <= $1`,
+		before,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("purge trashed todos: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// SnapshotByIDs loads todos (including goals) and their reminders by ID.
+func SnapshotByIDs(ctx context.Context, q db.DBTX, ids []int) (*Snapshot, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, user_id, project_id, title, description, is_completed, completed_at, due_date, deleted_at, created_at, updated_at
+		 FROM todos WHERE id = ANY($1) ORDER BY id`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot todos: %w", err)
+	}
+	todos, err := scanTodos(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	reminders, err := reminder.SnapshotByTodoIDs(ctx, q, ids)
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{Todos: todos, Reminders: reminders}, nil
+}
+
+// RestoreSnapshot re-inserts todos and their reminders with their original IDs.
+func RestoreSnapshot(ctx context.Context, q db.DBTX, snap *Snapshot) error {
+	for _, t := range snap.Todos {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO todos (id, user_id, project_id, title, description, is_completed, completed_at, due_date, deleted_at, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW()) ON CONFLICT (id) DO NOTHING`,
+			t.ID, t.UserID, t.ProjectID, t.Title, t.Description, t.IsCompleted, t.CompletedAt, t.DueDate, t.DeletedAt, t.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("restore todo: %w", err)
+		}
+	}
+	return reminder.RestoreSnapshot(ctx, q, snap.Reminders)
+}
+
+// SoftDeleteCompletedOlderThan trashes completed todos. Checking updated_at
+// too keeps a todo just restored from the trash from being swept right back.
 func (r *Repository) SoftDeleteCompletedOlderThan(ctx context.Context, before time.Time) error {
 	_, err := r.db.ExecContext(ctx,
 		`UPDATE todos SET deleted_at = NOW(), updated_at = NOW()
-		 WHERE is_completed = TRUE AND completed_at <= $1 AND deleted_at IS NULL AND project_id IS NULL`,
+		 WHERE is_completed = TRUE AND completed_at <= $1 AND updated_at <= $1 AND deleted_at IS NULL AND project_id IS NULL`,
 		before,
 	)
 	if err != nil {
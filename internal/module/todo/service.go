@@ -2,10 +2,14 @@ package todo
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/zhafrantharif/personal-assistant-bot/internal/conversation"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/recurrence"
 	"github.com/zhafrantharif/personal-assistant-bot/internal/reminder"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
 )
 
 type Service struct {
@@ -22,16 +26,31 @@ func NewService(repo *Repository, reminderRepo *reminder.Repository, timezone *t
 	}
 }
 
-func (s *Service) Add(ctx context.Context, userID int64, title string, dueDate *time.Time, hasReminder bool, remindAt *time.Time, recurring string) (string, error) {
+// loc returns the timezone of the user being served.
+func (s *Service) loc(ctx context.Context) *time.Location {
+	return settings.Location(ctx, s.timezone)
+}
+
+// defaultReminderHour is used for relative reminders on a deadline without a
+// time of day when the context carries no user settings.
+const defaultReminderHour = 7
+
+// Add creates a todo. leads are reminders relative to dueDate, e.g. -1h for
+// one hour before; they are ignored without a due date. nag, when set, makes
+// the reminders repeat until acknowledged.
+func (s *Service) Add(ctx context.Context, userID int64, title string, dueDate *time.Time, hasReminder bool, remindAt *time.Time, recurring string, leads []time.Duration, nag *reminder.Nag) (string, error) {
+	loc := s.loc(ctx)
 	todoID, err := s.repo.Create(ctx, userID, title, dueDate)
 	if err != nil {
 		return "", err
 	}
 
+	conversation.Touch(ctx, conversation.KindTodo, todoID, title)
+
 	resp := fmt.Sprintf("✅ Todo ditambahkan: \"%s\"", title)
 
 	if dueDate != nil {
-		resp += fmt.Sprintf("\n📅 Deadline: %s", dueDate.In(s.timezone).Format("2 Jan 2006"))
+		resp += fmt.Sprintf("\n📅 Deadline: %s", dueDate.In(loc).Format("2 Jan 2006"))
 	}
 
 	if hasReminder && remindAt != nil {
@@ -39,17 +58,73 @@ func (s *Service) Add(ctx context.Context, userID int64, title string, dueDate *
 		if err != nil {
 			return "", fmt.Errorf("create reminder: %w", err)
 		}
-		resp += fmt.Sprintf("\n⏰ Reminder: %s", remindAt.In(s.timezone).Format("2 Jan 2006 15:04 WIB"))
-		if recurring != "" {
-			resp += fmt.Sprintf(" (recurring: %s)", recurring)
+		resp += fmt.Sprintf("\n⏰ Reminder: %s", remindAt.In(loc).Format("2 Jan 2006 15:04 MST"))
+		if rule, err := recurrence.Parse(recurring); err == nil {
+			resp += fmt.Sprintf(" (🔁 %s)", rule.Describe())
+		}
+	}
+
+	if dueDate != nil && len(leads) > 0 {
+		lines, err := s.addRelativeReminders(ctx, todoID, *dueDate, leads)
+		if err != nil {
+			return "", err
+		}
+		resp += lines
+	}
+
+	if nag != nil {
+		n, err := s.reminderRepo.SetNag(ctx, todoID, nag)
+		if err != nil {
+			return "", err
+		}
+		if n > 0 {
+			resp += fmt.Sprintf("\n🔂 Diulang %s sampai ditandai", nag.Describe())
 		}
 	}
 
 	return resp, nil
 }
 
+// addRelativeReminders creates a reminder per lead time relative to due and
+// returns the response lines describing them.
+func (s *Service) addRelativeReminders(ctx context.Context, todoID int, due time.Time, leads []time.Duration) (string, error) {
+	loc := s.loc(ctx)
+	hour := settings.ReminderHour(ctx, defaultReminderHour)
+	var resp string
+	for _, lead := range leads {
+		offset := int(lead / time.Minute)
+		at := reminder.RelativeTime(due, offset, loc, hour)
+		if err := s.reminderRepo.CreateRelative(ctx, todoID, offset, at); err != nil {
+			return "", err
+		}
+		resp += fmt.Sprintf("\n⏰ Reminder: %s (%s)", at.In(loc).Format("2 Jan 2006 15:04 MST"), reminder.DescribeOffset(offset))
+		if !at.After(time.Now()) {
+			resp += " ⚠️ sudah lewat"
+		}
+	}
+	return resp, nil
+}
+
+// rescheduleRelative moves a todo's reminders that are relative to its due
+// date along with it and returns how many were moved.
+func (s *Service) rescheduleRelative(ctx context.Context, todoID int, due time.Time) (int, error) {
+	rems, err := s.reminderRepo.ListRelative(ctx, todoID)
+	if err != nil {
+		return 0, err
+	}
+	loc := s.loc(ctx)
+	hour := settings.ReminderHour(ctx, defaultReminderHour)
+	for _, rm := range rems {
+		at := reminder.RelativeTime(due, *rm.OffsetMinutes, loc, hour)
+		if err := s.reminderRepo.Reschedule(ctx, rm.ID, at); err != nil {
+			return 0, err
+		}
+	}
+	return len(rems), nil
+}
+
 func (s *Service) List(ctx context.Context, userID int64, filter string) ([]Todo, error) {
-	return s.repo.List(ctx, userID, filter, s.timezone)
+	return s.repo.List(ctx, userID, filter, s.loc(ctx))
 }
 
 func (s *Service) Complete(ctx context.Context, userID int64, search string) (string, error) {
@@ -67,11 +142,34 @@ func (s *Service) Complete(ctx context.Context, userID int64, search string) (st
 	if err := s.repo.Complete(ctx, todo.ID); err != nil {
 		return "", err
 	}
+	conversation.Touch(ctx, conversation.KindTodo, todo.ID, todo.Title)
+
+	return fmt.Sprintf("✅ Todo selesai: \"%s\"", todo.Title), nil
+}
+
+// CompleteByID completes the exact todo, e.g. from a reminder button.
+func (s *Service) CompleteByID(ctx context.Context, userID int64, todoID int) (string, error) {
+	todo, err := s.repo.GetByID(ctx, todoID)
+	if err != nil {
+		return "", err
+	}
+	if todo == nil || todo.UserID != userID {
+		return "❌ Todo tidak ditemukan.", nil
+	}
+	if todo.IsCompleted {
+		return fmt.Sprintf("ℹ️ Todo \"%s\" sudah selesai sebelumnya.", todo.Title), nil
+	}
+
+	if err := s.repo.Complete(ctx, todo.ID); err != nil {
+		return "", err
+	}
+	conversation.Touch(ctx, conversation.KindTodo, todo.ID, todo.Title)
 
 	return fmt.Sprintf("✅ Todo selesai: \"%s\"", todo.Title), nil
 }
 
 func (s *Service) Edit(ctx context.Context, userID int64, search string, newTitle string, newDueDate *time.Time, newRemindAt *time.Time) (string, error) {
+	loc := s.loc(ctx)
 	todo, err := s.repo.FindBySearch(ctx, userID, search)
 	if err != nil {
 		return "", err
@@ -93,17 +191,28 @@ func (s *Service) Edit(ctx context.Context, userID int64, search string, newTitl
 	if err := s.repo.Update(ctx, todo.ID, title, dueDate); err != nil {
 		return "", err
 	}
+	conversation.Touch(ctx, conversation.KindTodo, todo.ID, title)
 
 	resp := fmt.Sprintf("✏️ Todo diupdate: \"%s\"", title)
 	if dueDate != nil {
-		resp += fmt.Sprintf("\n📅 Deadline: %s", dueDate.In(s.timezone).Format("2 Jan 2006"))
+		resp += fmt.Sprintf("\n📅 Deadline: %s", dueDate.In(loc).Format("2 Jan 2006"))
+	}
+
+	if newDueDate != nil {
+		n, err := s.rescheduleRelative(ctx, todo.ID, *newDueDate)
+		if err != nil {
+			return "", fmt.Errorf("reschedule relative reminders: %w", err)
+		}
+		if n > 0 {
+			resp += fmt.Sprintf("\n⏰ %d reminder sebelum deadline ikut dijadwalkan ulang", n)
+		}
 	}
 
 	if newRemindAt != nil {
 		if err := s.reminderRepo.UpsertByTodoID(ctx, todo.ID, *newRemindAt); err != nil {
 			return "", fmt.Errorf("upsert reminder: %w", err)
 		}
-		resp += fmt.Sprintf("\n⏰ Reminder diupdate: %s", newRemindAt.In(s.timezone).Format("2 Jan 2006 15:04 WIB"))
+		resp += fmt.Sprintf("\n⏰ Reminder diupdate: %s", newRemindAt.In(loc).Format("2 Jan 2006 15:04 MST"))
 	}
 
 	return resp, nil
@@ -125,15 +234,163 @@ func (s *Service) Delete(ctx context.Context, userID int64, search string) (stri
 	return fmt.Sprintf("🗑️ Todo dihapus: \"%s\"", todo.Title), nil
 }
 
+// PreviewClearAll describes what ClearAll would delete. ok is false when
+// there is nothing to delete, in which case msg is the final answer.
+func (s *Service) PreviewClearAll(ctx context.Context, userID int64) (msg string, ok bool, err error) {
+	n, err := s.repo.CountActive(ctx, userID)
+	if err != nil {
+		return "", false, err
+	}
+	if n == 0 {
+		return "ℹ️ Tidak ada todo yang perlu dihapus.", false, nil
+	}
+	return fmt.Sprintf("⚠️ %d todo akan dihapus dari daftar.", n), true, nil
+}
+
 func (s *Service) ClearAll(ctx context.Context, userID int64) (string, error) {
-	n, err := s.repo.DeleteAll(ctx, userID)
+	ids, err := s.repo.DeleteAll(ctx, userID)
 	if err != nil {
 		return "", err
 	}
-	if n == 0 {
+	if len(ids) == 0 {
 		return "ℹ️ Tidak ada todo yang perlu dihapus.", nil
 	}
-	return fmt.Sprintf("🗑️ %d todo dihapus dari daftar.", n), nil
+	return fmt.Sprintf("🗑️ %d todo dipindah ke sampah.\nKetik \"undo\" untuk membatalkan.", len(ids)), nil
+}
+
+// UndoClear takes todos trashed by ClearAll back out of the trash, given the
+// undo journal payload.
+func (s *Service) UndoClear(ctx context.Context, userID int64, payload []byte) (string, error) {
+	var ids []int
+	if err := json.Unmarshal(payload, &ids); err != nil {
+		return "", fmt.Errorf("unmarshal todo ids: %w", err)
+	}
+	n, err := s.repo.Restore(ctx, userID, ids)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("↩️ %d todo dikembalikan.", n), nil
+}
+
+func (s *Service) ListTrash(ctx context.Context, userID int64) ([]TrashedTodo, error) {
+	return s.repo.ListTrash(ctx, userID)
+}
+
+// RestoreBySearch takes a todo or goal out of the trash. Its reminders and
+// project link were never removed, so they come back with it.
+func (s *Service) RestoreBySearch(ctx context.Context, userID int64, search string) (string, error) {
+	todo, err := s.repo.FindTrashedBySearch(ctx, userID, search)
+	if err != nil {
+		return "", err
+	}
+	if todo == nil {
+		return fmt.Sprintf("❌ Todo \"%s\" tidak ada di sampah.", search), nil
+	}
+
+	if _, err := s.repo.Restore(ctx, userID, []int{todo.ID}); err != nil {
+		return "", err
+	}
+	conversation.Touch(ctx, conversation.KindTodo, todo.ID, todo.Title)
+
+	return fmt.Sprintf("♻️ Todo dipulihkan: \"%s\"", todo.Title), nil
+}
+
+// SetReminderCatchUp sets what happens to a todo's reminders when they are
+// missed during downtime: reminder.CatchUpFire or reminder.CatchUpSkip.
+func (s *Service) SetReminderCatchUp(ctx context.Context, userID int64, search, policy string) (string, error) {
+	todo, err := s.repo.FindBySearch(ctx, userID, search)
+	if err != nil {
+		return "", err
+	}
+	if todo == nil {
+		return fmt.Sprintf("❌ Todo \"%s\" tidak ditemukan.", search), nil
+	}
+
+	n, err := s.reminderRepo.SetCatchUp(ctx, todo.ID, policy)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return fmt.Sprintf("ℹ️ Todo \"%s\" tidak punya reminder aktif.", todo.Title), nil
+	}
+	conversation.Touch(ctx, conversation.KindTodo, todo.ID, todo.Title)
+
+	if policy == reminder.CatchUpSkip {
+		return fmt.Sprintf("⏭ Reminder \"%s\" akan dilewati jika terlewat.", todo.Title), nil
+	}
+	return fmt.Sprintf("⏰ Reminder \"%s\" tetap dikirim walau terlambat.", todo.Title), nil
+}
+
+// AddReminder adds reminders to an existing todo: one at remindAt (recurring
+// when recurring is set) and one per lead time relative to its due date.
+func (s *Service) AddReminder(ctx context.Context, userID int64, search string, remindAt *time.Time, recurring string, leads []time.Duration) (string, error) {
+	loc := s.loc(ctx)
+	todo, err := s.repo.FindBySearch(ctx, userID, search)
+	if err != nil {
+		return "", err
+	}
+	if todo == nil {
+		return fmt.Sprintf("❌ Todo \"%s\" tidak ditemukan.", search), nil
+	}
+	if len(leads) > 0 && todo.DueDate == nil {
+		return fmt.Sprintf("ℹ️ Todo \"%s\" belum punya deadline. Set deadline dulu, misal \"edit todo %s deadline jumat\".", todo.Title, todo.Title), nil
+	}
+	if remindAt == nil && len(leads) == 0 {
+		return "❌ Sebutkan kapan reminder dikirim, misal \"jam 9 besok\" atau \"1 jam sebelum deadline\".", nil
+	}
+
+	resp := fmt.Sprintf("⏰ Reminder ditambahkan ke \"%s\"", todo.Title)
+	if remindAt != nil {
+		if err := s.reminderRepo.Create(ctx, todo.ID, *remindAt, recurring != "", recurring); err != nil {
+			return "", err
+		}
+		resp += fmt.Sprintf("\n⏰ Reminder: %s", remindAt.In(loc).Format("2 Jan 2006 15:04 MST"))
+		if rule, err := recurrence.Parse(recurring); err == nil {
+			resp += fmt.Sprintf(" (🔁 %s)", rule.Describe())
+		}
+	}
+	if len(leads) > 0 {
+		lines, err := s.addRelativeReminders(ctx, todo.ID, *todo.DueDate, leads)
+		if err != nil {
+			return "", err
+		}
+		resp += lines
+	}
+	conversation.Touch(ctx, conversation.KindTodo, todo.ID, todo.Title)
+
+	return resp, nil
+}
+
+// SetReminderNag makes a todo's reminders repeat until acknowledged, or stops
+// that when nag is nil.
+func (s *Service) SetReminderNag(ctx context.Context, userID int64, search string, nag *reminder.Nag) (string, error) {
+	todo, err := s.repo.FindBySearch(ctx, userID, search)
+	if err != nil {
+		return "", err
+	}
+	if todo == nil {
+		return fmt.Sprintf("❌ Todo \"%s\" tidak ditemukan.", search), nil
+	}
+
+	n, err := s.reminderRepo.SetNag(ctx, todo.ID, nag)
+	if err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return fmt.Sprintf("ℹ️ Todo \"%s\" tidak punya reminder aktif.", todo.Title), nil
+	}
+	conversation.Touch(ctx, conversation.KindTodo, todo.ID, todo.Title)
+
+	if nag == nil {
+		return fmt.Sprintf("🔕 Reminder \"%s\" tidak diulang lagi.", todo.Title), nil
+	}
+	return fmt.Sprintf("🔂 Reminder \"%s\" diulang %s sampai kamu tandai selesai atau tekan 👍 Oke.", todo.Title, nag.Describe()), nil
+}
+
+// PurgeTrash permanently deletes todos that have been in the trash longer
+// than retention.
+func (s *Service) PurgeTrash(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
 }
 
 func (s *Service) CleanupCompletedTodos(ctx context.Context) error {
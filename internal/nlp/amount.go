@@ -0,0 +1,66 @@
+package nlp
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	amountWithUnit  = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)\s*(rb|ribu|k|jt|juta)$`)
+	amountPlain     = regexp.MustCompile(`^\d{1,3}(?:[.,]\d{3})+$|^\d+$`)
+	amountTrailing  = regexp.MustCompile(`(?i)^(.*?)\s+((?:rp\.?\s*)?\d+(?:[.,]\d+)*\s*(?:rb|ribu|k|jt|juta)?)$`)
+	amountUnitScale = map[string]float64{
+		"rb":   1_000,
+		"ribu": 1_000,
+		"k":    1_000,
+		"jt":   1_000_000,
+		"juta": 1_000_000,
+	}
+)
+
+// ParseAmount converts Indonesian currency shorthand into rupiah.
+// Accepted forms: "35rb", "35 ribu", "35k", "1.5jt", "1,5 juta", "1juta", "20000", "20.000", "Rp 20.000".
+func ParseAmount(s string) (int64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "rp.")
+	s = strings.TrimPrefix(s, "rp")
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+
+	if m := amountWithUnit.FindStringSubmatch(s); m != nil {
+		f, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", "."), 64)
+		if err != nil {
+			return 0, false
+		}
+		return int64(math.Round(f * amountUnitScale[m[2]])), true
+	}
+
+	if amountPlain.MatchString(s) {
+		digits := strings.NewReplacer(".", "", ",", "").Replace(s)
+		n, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return n, true
+	}
+
+	return 0, false
+}
+
+// splitTrailingAmount splits "makan siang 35rb" into ("makan siang", 35000).
+// ok is false when the text does not end with a valid amount.
+func splitTrailingAmount(s string) (string, int64, bool) {
+	m := amountTrailing.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return s, 0, false
+	}
+	amount, ok := ParseAmount(m[2])
+	if !ok || amount <= 0 {
+		return s, 0, false
+	}
+	return strings.TrimSpace(m[1]), amount, true
+}
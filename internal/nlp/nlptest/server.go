@@ -0,0 +1,134 @@
+// Package nlptest provides an in-process fake of the Anthropic Messages API so
+// nlp.Service can be exercised offline:
+//
+//	srv := nlptest.NewServer()
+//	defer srv.Close()
+//	srv.Enqueue(nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "beli susu"}})
+//	svc := nlp.NewService("test", loc, srv.Options()...)
+package nlptest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// ToolCall is one tool_use block the fake model will return.
+type ToolCall struct {
+	Name  string
+	Input any
+}
+
+type response struct {
+	status int
+	blocks []map[string]any
+}
+
+// Server replays queued responses in FIFO order and records every request body.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	queue    []response
+	requests []json.RawMessage
+}
+
+func NewServer() *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// Options returns the client options that point nlp.NewService at this server.
+func (s *Server) Options() []option.RequestOption {
+	return []option.RequestOption{
+		option.WithBaseURL(s.URL),
+		option.WithMaxRetries(0),
+	}
+}
+
+// Enqueue adds a response containing the given tool calls.
+func (s *Server) Enqueue(calls ...ToolCall) {
+	blocks := make([]map[string]any, 0, len(calls))
+	for i, c := range calls {
+		input := c.Input
+		if input == nil {
+			input = map[string]any{}
+		}
+		blocks = append(blocks, map[string]any{
+			"type":  "tool_use",
+			"id":    fmt.Sprintf("toolu_%02d", i),
+			"name":  c.Name,
+			"input": input,
+		})
+	}
+	s.push(response{status: http.StatusOK, blocks: blocks})
+}
+
+// EnqueueText adds a response with plain prose and no tool call.
+func (s *Server) EnqueueText(text string) {
+	s.push(response{status: http.StatusOK, blocks: []map[string]any{{"type": "text", "text": text}}})
+}
+
+// EnqueueError adds an API error response with the given HTTP status.
+func (s *Server) EnqueueError(status int) {
+	s.push(response{status: status})
+}
+
+// Requests returns the raw JSON bodies received so far.
+func (s *Server) Requests() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.requests...)
+}
+
+func (s *Server) push(r response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, r)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.requests = append(s.requests, body)
+	if len(s.queue) == 0 {
+		s.mu.Unlock()
+		http.Error(w, `{"type":"error","error":{"type":"api_error","message":"nlptest: no queued response"}}`, http.StatusInternalServerError)
+		return
+	}
+	resp := s.queue[0]
+	s.queue = s.queue[1:]
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if resp.status != http.StatusOK {
+		w.WriteHeader(resp.status)
+		fmt.Fprintf(w, `{"type":"error","error":{"type":"api_error","message":"nlptest: status %d"}}`, resp.status)
+		return
+	}
+
+	stopReason := "end_turn"
+	for _, b := range resp.blocks {
+		if b["type"] == "tool_use" {
+			stopReason = "tool_use"
+			break
+		}
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":            "msg_nlptest",
+		"type":          "message",
+		"role":          "assistant",
+		"model":         "claude-haiku-4-5-20251001",
+		"content":       resp.blocks,
+		"stop_reason":   stopReason,
+		"stop_sequence": nil,
+		"usage":         map[string]any{"input_tokens": 0, "output_tokens": 0},
+	})
+}
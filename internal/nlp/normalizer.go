@@ -0,0 +1,336 @@
+package nlp
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/recurrence"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+)
+
+// RejectionError means an intent could not be normalized safely and must not
+// be routed. Message is shown to the user as-is.
+type RejectionError struct {
+	Field   string
+	Message string
+}
+
+func (e *RejectionError) Error() string {
+	return fmt.Sprintf("reject %s: %s", e.Field, e.Message)
+}
+
+func reject(field, format string, args ...any) *RejectionError {
+	return &RejectionError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// defaultReminderHour is used for recurring reminders given without a time
+// when the context carries no user settings.
+const defaultReminderHour = 7
+
+// Normalizer re-derives the deterministic parts of a ParsedIntent (relative
+// dates, recurrence rules, currency amounts) in Go instead of trusting the
+// model's arithmetic. Every change it makes is reported back as a correction.
+type Normalizer struct {
+	timezone *time.Location
+	holidays recurrence.Holidays
+	now      func() time.Time
+}
+
+func NewNormalizer(timezone *time.Location, holidays recurrence.Holidays) *Normalizer {
+	return &Normalizer{timezone: timezone, holidays: holidays, now: time.Now}
+}
+
+var (
+	amountToken = regexp.MustCompile(`(?i)(?:rp\.?\s*)?\d+(?:[.,]\d+)?\s*(?:rb|ribu|k|jt|juta)\b|rp\.?\s*\d+(?:[.,]\d{3})*|\b\d{1,3}(?:\.\d{3})+\b`)
+
+	// itemClause starts a bulk segment that qualifies the item before it
+	// rather than being an item of its own.
+	itemClause = regexp.MustCompile(`(?i)^(?:ingetin|ingatkan|ingatin|remind|deadline|jam|pukul)\b`)
+
+	// reminderClause and deadlineClause start the part of a segment that
+	// dates the reminder or the deadline: in "laporan deadline jumat depan,
+	// ingetin besok jam 9" besok is for the reminder only.
+	reminderClause = regexp.MustCompile(`(?i)\b(?:ingetin|ingatkan|ingatin|remind)\b`)
+	deadlineClause = regexp.MustCompile(`(?i)\b(?:deadline|tenggat|jatuh tempo)\b`)
+
+	relativeDayWords = []struct {
+		pattern *regexp.Regexp
+		// unless marks a use of the word that is not relative to today.
+		unless *regexp.Regexp
+		days   int
+		label  string
+	}{
+		{regexp.MustCompile(`(?i)\bhari ini\b`), nil, 0, "hari ini"},
+		{regexp.MustCompile(`(?i)\bbesok\b`), nil, 1, "besok"},
+		{regexp.MustCompile(`(?i)\blusa\b`), nil, 2, "lusa"},
+		// "hari minggu depan" and "senin minggu depan" name a day of next
+		// week, not today in a week.
+		{regexp.MustCompile(`(?i)\bminggu depan\b`), regexp.MustCompile(`(?i)\b(?:hari|senin|selasa|rabu|kamis|jumat|jum'at|sabtu|minggu)\s+minggu depan\b`), 7, "minggu depan"},
+	}
+)
+
+// Normalize validates and corrects in place, using the timezone and default
+// reminder hour of the user in ctx. It returns human-readable corrections
+// (in Indonesian) or a *RejectionError.
+func (n *Normalizer) Normalize(ctx context.Context, in *ParsedIntent) ([]string, error) {
+	loc := settings.Location(ctx, n.timezone)
+	var corrections []string
+	segment := n.segmentFor(in)
+
+	if in.Amount != 0 || in.Intent == "add_expense" {
+		c, err := n.normalizeAmount(in, segment)
+		if err != nil {
+			return nil, err
+		}
+		corrections = append(corrections, c...)
+	}
+
+	if in.Date != "" {
+		if _, err := in.ParseDate(loc); err != nil {
+			return nil, reject("date", "Tanggal \"%s\" tidak valid.", in.Date)
+		}
+	}
+
+	if in.Recurring != "" {
+		rule, err := NormalizeRecurring(in.Recurring)
+		if err != nil {
+			return nil, err
+		}
+		in.Recurring = rule
+	}
+
+	if len(in.LeadTimes) > 0 && in.DueDate == "" && (in.Intent == "add_todo" || in.Intent == "add_goal") {
+		return nil, reject("lead_times", "Reminder sebelum deadline butuh deadline. Contoh: \"tambah todo %s deadline jumat, ingetin 1 hari sebelumnya\".",
+			firstNonEmpty(in.Title, "laporan"))
+	}
+
+	c, err := n.normalizeDates(in, segment, loc, settings.ReminderHour(ctx, defaultReminderHour))
+	if err != nil {
+		return nil, err
+	}
+	corrections = append(corrections, c...)
+
+	return corrections, nil
+}
+
+// NormalizeRecurring validates a recurrence rule (an RFC 5545 RRULE, or one
+// of the older short forms such as monthly:5) and returns its canonical form.
+func NormalizeRecurring(rule string) (string, error) {
+	r, err := recurrence.Parse(rule)
+	if err != nil {
+		return "", reject("recurring", "Format pengulangan \"%s\" tidak dikenali.", rule)
+	}
+	return r.String(), nil
+}
+
+// segmentFor returns the part of a bulk message that belongs to this intent,
+// falling back to the whole message when it cannot be located.
+func (n *Normalizer) segmentFor(in *ParsedIntent) string {
+	anchor := firstNonEmpty(in.Title, in.Description, in.Search, in.Name)
+	segments := splitBulk(in.Raw)
+	if anchor == "" || len(segments) <= 1 {
+		return in.Raw
+	}
+	anchor = strings.ToLower(anchor)
+	for i, seg := range segments {
+		if !strings.Contains(strings.ToLower(seg), anchor) {
+			continue
+		}
+		// "bayar pajak, ingetin besok jam 10" is one item.
+		for _, next := range segments[i+1:] {
+			if !itemClause.MatchString(next) {
+				break
+			}
+			seg += ", " + next
+		}
+		return seg
+	}
+	return in.Raw
+}
+
+func (n *Normalizer) normalizeAmount(in *ParsedIntent, segment string) ([]string, error) {
+	var corrections []string
+	tokens := amountToken.FindAllString(segment, -1)
+	if len(tokens) == 1 {
+		if amount, ok := ParseAmount(tokens[0]); ok && amount != in.Amount {
+			corrections = append(corrections, fmt.Sprintf("Nominal \"%s\" dibaca ulang: %s → %s",
+				strings.TrimSpace(tokens[0]), formatNumber(in.Amount), formatNumber(amount)))
+			in.Amount = amount
+		}
+	}
+	if in.Amount < 0 || (in.Intent == "add_expense" && in.Amount == 0) {
+		return nil, reject("amount", "Nominal untuk \"%s\" tidak valid. Contoh: \"catat %s 35rb\".",
+			in.Description, firstNonEmpty(in.Description, "makan siang"))
+	}
+	return corrections, nil
+}
+
+func (n *Normalizer) normalizeDates(in *ParsedIntent, segment string, loc *time.Location, reminderHour int) ([]string, error) {
+	var corrections []string
+	now := n.now().In(loc)
+
+	remindAt, err := in.ParseRemindAt(loc)
+	if err != nil {
+		return nil, reject("remind_at", "Waktu reminder \"%s\" tidak valid.", in.RemindAt)
+	}
+	dueDate, err := in.ParseDueDate(loc)
+	if err != nil {
+		return nil, reject("due_date", "Deadline \"%s\" tidak valid.", in.DueDate)
+	}
+
+	// Relative day words only apply to one-off dates, each to the field of
+	// its clause.
+	if in.Recurring == "" {
+		deadline, reminder, item := dateClauses(segment)
+		relativeDay := func(clause string) (time.Time, string, bool) {
+			offset, label, ok := relativeOffset(clause)
+			if !ok {
+				offset, label, ok = relativeOffset(item)
+			}
+			return time.Date(now.Year(), now.Month(), now.Day()+offset, 0, 0, 0, 0, loc), label, ok
+		}
+		if want, label, ok := relativeDay(deadline); ok && dueDate != nil && !sameDay(*dueDate, want) {
+			fixed := moveToDay(*dueDate, want)
+			corrections = append(corrections, fmt.Sprintf("Deadline \"%s\" dikoreksi ke %s", label, fixed.Format("2006-01-02")))
+			in.DueDate = formatLike(in.DueDate, fixed)
+		}
+		if want, label, ok := relativeDay(reminder); ok && remindAt != nil && !sameDay(*remindAt, want) {
+			fixed := moveToDay(*remindAt, want)
+			corrections = append(corrections, fmt.Sprintf("Reminder \"%s\" dikoreksi ke %s", label, fixed.Format("2006-01-02 15:04")))
+			remindAt = &fixed
+			in.RemindAt = fixed.Format(time.RFC3339)
+		}
+	}
+
+	if in.Recurring != "" {
+		rule, err := recurrence.Parse(in.Recurring)
+		if err != nil {
+			return nil, reject("recurring", "Format pengulangan \"%s\" tidak dikenali.", in.Recurring)
+		}
+		// A new rule for an existing reminder keeps its time of day, which
+		// only the reminder service knows.
+		if remindAt == nil && in.Intent == "edit_reminder" {
+			return corrections, nil
+		}
+		hour, minute := reminderHour, 0
+		if remindAt != nil {
+			hour, minute = remindAt.Hour(), remindAt.Minute()
+		}
+		if remindAt == nil || !isOccurrence(rule, *remindAt, loc, n.holidays) || !remindAt.After(now) {
+			// The series starts today at the requested time; its first
+			// occurrence still to come becomes remind_at.
+			start := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+			next, ok := rule.After(start, now, loc, n.holidays)
+			if !ok {
+				return nil, reject("recurring", "Pengulangan \"%s\" tidak punya jadwal berikutnya.", rule.Describe())
+			}
+			if remindAt != nil {
+				corrections = append(corrections, fmt.Sprintf("Reminder berulang dijadwalkan ulang ke %s", next.Format("2006-01-02 15:04")))
+			}
+			remindAt = &next
+			in.RemindAt = next.Format(time.RFC3339)
+		}
+		in.Reminder = true
+		return corrections, nil
+	}
+
+	if remindAt != nil {
+		if !remindAt.After(now) {
+			return nil, reject("remind_at", "Waktu reminder %s sudah lewat. Sebutkan waktu yang akan datang.",
+				remindAt.Format("2006-01-02 15:04"))
+		}
+		in.Reminder = true
+	}
+
+	return corrections, nil
+}
+
+// isOccurrence reports whether t is an occurrence of rule in a series
+// starting at t, i.e. whether t itself matches the rule.
+func isOccurrence(rule *recurrence.Rule, t time.Time, loc *time.Location, holidays recurrence.Holidays) bool {
+	first, ok := rule.After(t, t.Add(-time.Nanosecond), loc, holidays)
+	return ok && first.Equal(t)
+}
+
+// dateClauses splits a segment into its deadline clauses, its reminder
+// clauses and the item before them, which dates whichever clause names no
+// day itself.
+func dateClauses(segment string) (deadline, reminder, item string) {
+	type mark struct {
+		at       int
+		deadline bool
+	}
+	var marks []mark
+	for _, m := range deadlineClause.FindAllStringIndex(segment, -1) {
+		marks = append(marks, mark{m[0], true})
+	}
+	for _, m := range reminderClause.FindAllStringIndex(segment, -1) {
+		marks = append(marks, mark{m[0], false})
+	}
+	if len(marks) == 0 {
+		return "", "", segment
+	}
+	slices.SortFunc(marks, func(a, b mark) int { return a.at - b.at })
+
+	var deadlines, reminders []string
+	for i, m := range marks {
+		end := len(segment)
+		if i+1 < len(marks) {
+			end = marks[i+1].at
+		}
+		if m.deadline {
+			deadlines = append(deadlines, segment[m.at:end])
+		} else {
+			reminders = append(reminders, segment[m.at:end])
+		}
+	}
+	return strings.Join(deadlines, " "), strings.Join(reminders, " "), segment[:marks[0].at]
+}
+
+func relativeOffset(segment string) (int, string, bool) {
+	found := -1
+	label := ""
+	for _, w := range relativeDayWords {
+		if w.pattern.MatchString(segment) && (w.unless == nil || !w.unless.MatchString(segment)) {
+			if found >= 0 {
+				return 0, "", false // ambiguous, e.g. "besok atau lusa"
+			}
+			found, label = w.days, w.label
+		}
+	}
+	return found, label, found >= 0
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
+
+func moveToDay(t, day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+}
+
+// formatLike writes t back in the same layout (date-only or RFC3339) as original.
+func formatLike(original string, t time.Time) string {
+	if len(original) == len("2006-01-02") {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatNumber(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,245 @@
+package nlp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testNormalizer(t *testing.T) *Normalizer {
+	t.Helper()
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := NewNormalizer(loc, nil)
+	// Friday, the "today" of the system prompt examples.
+	n.now = func() time.Time { return time.Date(2026, 2, 13, 10, 0, 0, 0, loc) }
+	return n
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          ParsedIntent
+		want        ParsedIntent
+		corrections []string
+	}{
+		{
+			name: "amount kept",
+			in:   ParsedIntent{Intent: "add_expense", Description: "bensin", Amount: 50000, Raw: "catat bensin 50rb"},
+			want: ParsedIntent{Intent: "add_expense", Description: "bensin", Amount: 50000, Raw: "catat bensin 50rb"},
+		},
+		{
+			name:        "amount re-read from the message",
+			in:          ParsedIntent{Intent: "add_expense", Description: "makan siang", Amount: 3500, Raw: "catat makan siang 35rb"},
+			want:        ParsedIntent{Intent: "add_expense", Description: "makan siang", Amount: 35000, Raw: "catat makan siang 35rb"},
+			corrections: []string{`Nominal "35rb" dibaca ulang: 3500 → 35000`},
+		},
+		{
+			name:        "amount re-read from its bulk segment",
+			in:          ParsedIntent{Intent: "add_expense", Description: "bensin", Amount: 5000, Raw: "catat makan siang 35rb, bensin 50rb, parkir 5rb"},
+			want:        ParsedIntent{Intent: "add_expense", Description: "bensin", Amount: 50000, Raw: "catat makan siang 35rb, bensin 50rb, parkir 5rb"},
+			corrections: []string{`Nominal "50rb" dibaca ulang: 5000 → 50000`},
+		},
+		{
+			name:        "decimal amount",
+			in:          ParsedIntent{Intent: "set_budget", Category: "Makan", Amount: 1000000, Raw: "budget makan 1,5jt"},
+			want:        ParsedIntent{Intent: "set_budget", Category: "Makan", Amount: 1500000, Raw: "budget makan 1,5jt"},
+			corrections: []string{`Nominal "1,5jt" dibaca ulang: 1000000 → 1500000`},
+		},
+		{
+			name: "amount kept when the message has several",
+			in:   ParsedIntent{Intent: "transfer", Account: "BCA", ToAccount: "gopay", Amount: 200000, Raw: "topup gopay 200rb dari BCA 5jt"},
+			want: ParsedIntent{Intent: "transfer", Account: "BCA", ToAccount: "gopay", Amount: 200000, Raw: "topup gopay 200rb dari BCA 5jt"},
+		},
+		{
+			name:        "remind_at moved to besok",
+			in:          ParsedIntent{Intent: "add_todo", Title: "bayar pajak", Reminder: true, RemindAt: "2026-02-13T10:00:00", Raw: "tambah todo bayar pajak, ingetin besok jam 10"},
+			want:        ParsedIntent{Intent: "add_todo", Title: "bayar pajak", Reminder: true, RemindAt: "2026-02-14T10:00:00+07:00", Raw: "tambah todo bayar pajak, ingetin besok jam 10"},
+			corrections: []string{`Reminder "besok" dikoreksi ke 2026-02-14 10:00`},
+		},
+		{
+			name:        "due_date moved to lusa",
+			in:          ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-14", Raw: "tambah todo laporan deadline lusa"},
+			want:        ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-15", Raw: "tambah todo laporan deadline lusa"},
+			corrections: []string{`Deadline "lusa" dikoreksi ke 2026-02-15`},
+		},
+		{
+			name:        "due_date read from its bulk segment",
+			in:          ParsedIntent{Intent: "add_todo", Title: "cuci mobil", DueDate: "2026-02-14", Raw: "tambah todo laporan besok dan cuci mobil lusa"},
+			want:        ParsedIntent{Intent: "add_todo", Title: "cuci mobil", DueDate: "2026-02-15", Raw: "tambah todo laporan besok dan cuci mobil lusa"},
+			corrections: []string{`Deadline "lusa" dikoreksi ke 2026-02-15`},
+		},
+		{
+			name: "due_date kept when the words are ambiguous",
+			in:   ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-14", Raw: "tambah todo laporan deadline besok atau lusa"},
+			want: ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-14", Raw: "tambah todo laporan deadline besok atau lusa"},
+		},
+		{
+			name: "besok dates the reminder, not the deadline",
+			in:   ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-20", RemindAt: "2026-02-14T09:00:00", Raw: "tambah todo laporan deadline jumat depan, ingetin besok jam 9"},
+			want: ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-20", Reminder: true, RemindAt: "2026-02-14T09:00:00", Raw: "tambah todo laporan deadline jumat depan, ingetin besok jam 9"},
+		},
+		{
+			name:        "besok corrects the reminder only",
+			in:          ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-20", RemindAt: "2026-02-20T09:00:00", Raw: "tambah todo laporan deadline jumat depan, ingetin besok jam 9"},
+			want:        ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-20", Reminder: true, RemindAt: "2026-02-14T09:00:00+07:00", Raw: "tambah todo laporan deadline jumat depan, ingetin besok jam 9"},
+			corrections: []string{`Reminder "besok" dikoreksi ke 2026-02-14 09:00`},
+		},
+		{
+			name: "hari minggu depan is a day, not a week from today",
+			in:   ParsedIntent{Intent: "create_reminder", Title: "gereja", RemindAt: "2026-02-15T08:00:00", Raw: "ingetin gereja hari minggu depan jam 8"},
+			want: ParsedIntent{Intent: "create_reminder", Title: "gereja", Reminder: true, RemindAt: "2026-02-15T08:00:00", Raw: "ingetin gereja hari minggu depan jam 8"},
+		},
+		{
+			name:        "due_date moved to minggu depan",
+			in:          ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-19", Raw: "tambah todo laporan deadline minggu depan"},
+			want:        ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-20", Raw: "tambah todo laporan deadline minggu depan"},
+			corrections: []string{`Deadline "minggu depan" dikoreksi ke 2026-02-20`},
+		},
+		{
+			name: "future remind_at turns the reminder on",
+			in:   ParsedIntent{Intent: "create_reminder", Title: "minum obat", RemindAt: "2026-02-13T21:00:00", Raw: "ingetin minum obat jam 9 malam"},
+			want: ParsedIntent{Intent: "create_reminder", Title: "minum obat", Reminder: true, RemindAt: "2026-02-13T21:00:00", Raw: "ingetin minum obat jam 9 malam"},
+		},
+		{
+			name:        "recurring remind_at in the past moves to the next occurrence",
+			in:          ParsedIntent{Intent: "create_reminder", Title: "bayar wifi", RemindAt: "2026-02-05T07:00:00", Recurring: "FREQ=MONTHLY;BYMONTHDAY=5", Raw: "ingetin bayar wifi tiap tanggal 5"},
+			want:        ParsedIntent{Intent: "create_reminder", Title: "bayar wifi", Reminder: true, RemindAt: "2026-03-05T07:00:00+07:00", Recurring: "FREQ=MONTHLY;BYMONTHDAY=5", Raw: "ingetin bayar wifi tiap tanggal 5"},
+			corrections: []string{"Reminder berulang dijadwalkan ulang ke 2026-03-05 07:00"},
+		},
+		{
+			name: "recurring without remind_at starts at the reminder hour",
+			in:   ParsedIntent{Intent: "create_reminder", Title: "bayar listrik", Recurring: "FREQ=MONTHLY;BYMONTHDAY=17", Raw: "ingetin bayar listrik setiap tanggal 17"},
+			want: ParsedIntent{Intent: "create_reminder", Title: "bayar listrik", Reminder: true, RemindAt: "2026-02-17T07:00:00+07:00", Recurring: "FREQ=MONTHLY;BYMONTHDAY=17", Raw: "ingetin bayar listrik setiap tanggal 17"},
+		},
+		{
+			name: "legacy recurrence is canonicalized",
+			in:   ParsedIntent{Intent: "create_reminder", Title: "bayar wifi", RemindAt: "2026-03-05T07:00:00", Recurring: "monthly:5", Raw: "ingetin bayar wifi tiap tanggal 5"},
+			want: ParsedIntent{Intent: "create_reminder", Title: "bayar wifi", Reminder: true, RemindAt: "2026-03-05T07:00:00", Recurring: "FREQ=MONTHLY;BYMONTHDAY=5", Raw: "ingetin bayar wifi tiap tanggal 5"},
+		},
+		{
+			name: "recurring edit keeps the time of the reminder",
+			in:   ParsedIntent{Intent: "edit_reminder", Search: "bayar wifi", Recurring: "FREQ=MONTHLY;BYMONTHDAY=10", Raw: "ubah reminder bayar wifi jadi tiap tanggal 10"},
+			want: ParsedIntent{Intent: "edit_reminder", Search: "bayar wifi", Recurring: "FREQ=MONTHLY;BYMONTHDAY=10", Raw: "ubah reminder bayar wifi jadi tiap tanggal 10"},
+		},
+		{
+			name: "lead times with a deadline",
+			in:   ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-13T17:00:00+07:00", LeadTimes: []string{"-1h", "-1d"}, Raw: "tambah todo laporan deadline jumat jam 17, ingetin 1 jam dan 1 hari sebelumnya"},
+			want: ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "2026-02-13T17:00:00+07:00", LeadTimes: []string{"-1h", "-1d"}, Raw: "tambah todo laporan deadline jumat jam 17, ingetin 1 jam dan 1 hari sebelumnya"},
+		},
+		{
+			name: "lead times on an existing todo",
+			in:   ParsedIntent{Intent: "add_reminder", Search: "beli kado", LeadTimes: []string{"-2d"}, Raw: "ingetin beli kado 2 hari sebelum deadline"},
+			want: ParsedIntent{Intent: "add_reminder", Search: "beli kado", LeadTimes: []string{"-2d"}, Raw: "ingetin beli kado 2 hari sebelum deadline"},
+		},
+	}
+
+	n := testNormalizer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := tt.in
+			corrections, err := n.Normalize(context.Background(), &in)
+			if err != nil {
+				t.Fatalf("Normalize: %v", err)
+			}
+			if !reflect.DeepEqual(in, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", in, tt.want)
+			}
+			if !reflect.DeepEqual(corrections, tt.corrections) {
+				t.Errorf("corrections = %q, want %q", corrections, tt.corrections)
+			}
+		})
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    ParsedIntent
+		field string
+	}{
+		{"expense without amount", ParsedIntent{Intent: "add_expense", Description: "bensin", Raw: "catat bensin"}, "amount"},
+		{"negative amount", ParsedIntent{Intent: "pay_debt", Person: "Budi", Amount: -50000, Raw: "Budi bayar"}, "amount"},
+		{"invalid date", ParsedIntent{Intent: "delete_expense", Search: "beli kecap", Date: "14 feb", Raw: "hapus beli kecap 14 feb"}, "date"},
+		{"invalid recurrence", ParsedIntent{Intent: "create_reminder", Title: "bayar wifi", Recurring: "kadang-kadang", Raw: "ingetin bayar wifi kadang-kadang"}, "recurring"},
+		{"lead times without a deadline", ParsedIntent{Intent: "add_todo", Title: "laporan", LeadTimes: []string{"-1h"}, Raw: "tambah todo laporan, ingetin 1 jam sebelumnya"}, "lead_times"},
+		{"lead times on a goal without a deadline", ParsedIntent{Intent: "add_goal", Title: "deploy", LeadTimes: []string{"-1d"}, Raw: "tambah goal deploy, ingetin sehari sebelumnya"}, "lead_times"},
+		{"invalid remind_at", ParsedIntent{Intent: "create_reminder", Title: "minum obat", RemindAt: "jam 9", Raw: "ingetin minum obat jam 9"}, "remind_at"},
+		{"remind_at in the past", ParsedIntent{Intent: "create_reminder", Title: "minum obat", RemindAt: "2026-02-13T09:00:00", Raw: "ingetin minum obat jam 9"}, "remind_at"},
+		{"invalid due_date", ParsedIntent{Intent: "add_todo", Title: "laporan", DueDate: "jumat", Raw: "tambah todo laporan deadline jumat"}, "due_date"},
+	}
+
+	n := testNormalizer(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := tt.in
+			_, err := n.Normalize(context.Background(), &in)
+			var rejection *RejectionError
+			if !errors.As(err, &rejection) {
+				t.Fatalf("err = %v, want a *RejectionError", err)
+			}
+			if rejection.Field != tt.field {
+				t.Errorf("rejected field = %q, want %q", rejection.Field, tt.field)
+			}
+			if rejection.Message == "" {
+				t.Error("rejection has no message for the user")
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"35rb", 35000, true},
+		{"35 ribu", 35000, true},
+		{"35k", 35000, true},
+		{"1.5jt", 1500000, true},
+		{"1,5 juta", 1500000, true},
+		{"1juta", 1000000, true},
+		{"20000", 20000, true},
+		{"20.000", 20000, true},
+		{"Rp 20.000", 20000, true},
+		{"Rp.20.000", 20000, true},
+		{"", 0, false},
+		{"Budi", 0, false},
+		{"2.5", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseAmount(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseAmount(%q) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseLeadTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"-30m", -30 * time.Minute, false},
+		{"-1h", -time.Hour, false},
+		{"-1d", -24 * time.Hour, false},
+		{"-2w", -14 * 24 * time.Hour, false},
+		{"1h", -time.Hour, false},
+		{"+30m", 30 * time.Minute, false},
+		{"1 jam", 0, true},
+		{"-1y", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLeadTime(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLeadTime(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package nlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// ErrNoMatch is returned by a parser that does not recognise the message,
+// signalling that the next parser in a chain should be tried.
+var ErrNoMatch = errors.New("no matching pattern")
+
+// IntentParser turns a raw user message into one or more intents.
+// conv may be nil when there is no recent conversation.
+type IntentParser interface {
+	Parse(ctx context.Context, userMessage string, conv *Conversation) ([]ParsedIntent, error)
+}
+
+// ChainParser tries each parser in order and returns the first usable result.
+// A result is usable when it contains at least one intent other than "unknown".
+type ChainParser struct {
+	parsers []IntentParser
+}
+
+func NewChainParser(parsers ...IntentParser) *ChainParser {
+	return &ChainParser{parsers: parsers}
+}
+
+func (c *ChainParser) Parse(ctx context.Context, userMessage string, conv *Conversation) ([]ParsedIntent, error) {
+	var fallback []ParsedIntent
+	var lastErr error
+
+	for _, p := range c.parsers {
+		intents, err := p.Parse(ctx, userMessage, conv)
+		if err != nil {
+			if !errors.Is(err, ErrNoMatch) {
+				slog.Warn("intent parser failed, trying next", "parser", fmt.Sprintf("%T", p), "error", err)
+				lastErr = err
+			}
+			continue
+		}
+		if allUnknown(intents) {
+			if fallback == nil {
+				fallback = intents
+			}
+			continue
+		}
+		return intents, nil
+	}
+
+	if fallback != nil {
+		return fallback, nil
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return []ParsedIntent{{Intent: "unknown", Raw: userMessage}}, nil
+}
+
+func allUnknown(intents []ParsedIntent) bool {
+	for _, in := range intents {
+		if in.Intent != "unknown" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,497 @@
+package nlp
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RuleParser is a deterministic, offline parser for the most common Indonesian
+// command patterns. It never guesses: anything involving dates, times,
+// recurrence or other ambiguity is left to the next parser via ErrNoMatch.
+type RuleParser struct {
+	rules []rule
+}
+
+type rule struct {
+	pattern *regexp.Regexp
+	build   func(m []string, raw string) ([]ParsedIntent, bool)
+}
+
+var (
+	// temporalWords marks messages that need date/time understanding.
+	temporalWords = regexp.MustCompile(`(?i)\b(besok|lusa|kemarin|nanti|jam|pukul|tanggal|tgl|tiap|setiap|depan|deadline|ingetin|ingatkan|remind|senin|selasa|rabu|kamis|jumat|sabtu|minggu|jan|januari|feb|februari|mar|maret|apr|april|mei|jun|juni|jul|juli|agu|agustus|sep|september|okt|oktober|nov|november|des|desember)\b|\d{1,2}:\d{2}`)
+
+	bulkSeparator = regexp.MustCompile(`(?i)\s*,\s*(?:dan\s+)?|\s+dan\s+`)
+
+	// decimalComma matches "1,5" so it is not mistaken for a bulk separator.
+	decimalComma = regexp.MustCompile(`(\d),(\d)`)
+
+	// referenceWords marks follow-ups that point at an earlier message. Only
+	// "-nya" on words that name a field or an entity points back ("jamnya",
+	// "remindernya"); "tanya", "punya" or "hanya" do not.
+	referenceWords = regexp.MustCompile(`(?i)\b(yang tadi|tadi|itu|barusan|terakhir|nya|semuanya|(?:jam|tanggal|tgl|hari|waktu|deadline|judul|nama|harga|nominal|jumlah|kategori|akun|todo|goal|project|reminder|pengingat|pengeluaran|pemasukan|hutang|utang)nya)\b`)
+
+	// idRef matches a bare "id 123" answer to a disambiguation prompt.
+	idRef = regexp.MustCompile(`(?i)^id\s+#?(\d+)$`)
+
+	// incomeWords starts an item of "catat ..." that is money coming in,
+	// e.g. "catat gaji 8jt".
+	incomeWords = regexp.MustCompile(`(?i)^(?:gaji|gajian|bonus|thr|pemasukan|pendapatan|dividen|komisi|honor|cashback|refund)\b`)
+
+	// incomeSource splits "transfer 500rb dari Budi" at "dari".
+	incomeSource = regexp.MustCompile(`(?i)^(.+?)\s+dari\s+(.+)$`)
+
+	// paidWith splits "bensin 50rb pakai gopay" at the account paid from.
+	paidWith = regexp.MustCompile(`(?i)^(.+?)\s+(?:pakai|pake|via)\s+(\S+)$`)
+
+	// paidInto splits "gaji 8jt masuk ke BCA" at the account paid into.
+	paidInto = regexp.MustCompile(`(?i)^(.+?)\s+(?:masuk\s+)?ke\s+(\S+)$`)
+
+	// notPerson rules out words that start a debt sentence but are not a
+	// name, e.g. "aku bayar 50rb" or "udah bayar 50rb".
+	notPerson = regexp.MustCompile(`(?i)^(?:aku|saya|gue|gw|kamu|dia|udah|sudah|belum|baru|mau|hutang|utang|piutang|yang|ini|itu)$`)
+
+	// commandItem marks a bulk item that is a command of its own, e.g. the
+	// second half of "hapus todo A dan selesaikan todo B".
+	commandItem = regexp.MustCompile(`(?i)^\S+\s+(?:todo|reminder|goal|pengeluaran|pemasukan)\b`)
+
+	unpaidWords = regexp.MustCompile(`(?i)\b(hutang|belum bayar|belum lunas|cicilan)\b`)
+
+	extraSpaces = regexp.MustCompile(`\s+`)
+)
+
+func NewRuleParser() *RuleParser {
+	return &RuleParser{rules: []rule{
+		{regexp.MustCompile(`(?i)^(?:tambah|tambahin|tambahkan|buat|bikin)\s+todo\s*:?\s+(.+)$`), buildAddTodo},
+		{regexp.MustCompile(`(?i)^(?:done|selesai|selesaikan|selesaiin)\s+(?:todo\s+)?(.+)$`), buildTodoAction("complete_todo")},
+		{regexp.MustCompile(`(?i)^hapus\s+todo\s+(.+)$`), buildTodoAction("delete_todo")},
+		{regexp.MustCompile(`(?i)^hapus\s+reminder\s+#?(\d+)$`), buildReminderRef("delete_reminder")},
+		{regexp.MustCompile(`(?i)^hapus\s+reminder\s+(.+)$`), buildTodoAction("delete_reminder")},
+		{regexp.MustCompile(`(?i)^(?:jeda|pause)\s+reminder\s+#?(\d+)$`), buildReminderRef("pause_reminder")},
+		{regexp.MustCompile(`(?i)^(?:jeda|pause)\s+reminder\s+(.+)$`), buildTodoAction("pause_reminder")},
+		{regexp.MustCompile(`(?i)^(?:lanjutkan|lanjutin|resume)\s+reminder\s+#?(\d+)$`), buildReminderRef("resume_reminder")},
+		{regexp.MustCompile(`(?i)^(?:lanjutkan|lanjutin|resume)\s+reminder\s+(.+)$`), buildTodoAction("resume_reminder")},
+		{regexp.MustCompile(`(?i)^(?:stop|hentikan|batalkan)\s+reminder\s+#?(\d+)$`), buildReminderRef("cancel_reminder")},
+		{regexp.MustCompile(`(?i)^(?:stop|hentikan|batalkan)\s+reminder\s+(.+)$`), buildTodoAction("cancel_reminder")},
+		// Skipping by title usually names which occurrence ("bulan ini"),
+		// so only the ID form is handled here.
+		{regexp.MustCompile(`(?i)^(?:skip|lewati)\s+reminder\s+#?(\d+)$`), buildReminderRef("skip_reminder")},
+		{regexp.MustCompile(`(?i)^(?:pulihkan|pulihin|restore)\s+(?:todo\s+)?(.+)$`), buildTodoAction("restore_todo")},
+		{regexp.MustCompile(`(?i)^(?:catat|catet)\s+pemasukan\s+(.+)$`), buildAddIncome},
+		{regexp.MustCompile(`(?i)^(?:terima|nerima|dapat|dapet)\s+(.+)$`), buildAddIncome},
+		{regexp.MustCompile(`(?i)^(?:catat|catet)\s+(?:pengeluaran\s+)?(.+)$`), buildAddExpense},
+		{regexp.MustCompile(`(?i)^hapus\s+pemasukan\s+(.+)$`), buildDeleteIncome},
+		// Listing comes first: "cek hutang Budi" would otherwise read as Budi
+		// lending something called "hutang".
+		{regexp.MustCompile(`(?i)^siapa\s+(?:saja\s+|aja\s+)?yang\s+(?:hutang|utang|ngutang|pinjam)\s+(?:ke|sama)\s+(?:aku|saya|gue|gw)$`), fixedIntent(ParsedIntent{Intent: "list_debt", Direction: "they_owe"})},
+		{regexp.MustCompile(`(?i)^(?:(?:list|daftar|lihat|cek)\s+)?(?:hutang[\s-]+piutang|hutang)$`), fixedIntent(ParsedIntent{Intent: "list_debt"})},
+		{regexp.MustCompile(`(?i)^(?:(?:list|daftar|lihat|cek)\s+)?piutang$`), fixedIntent(ParsedIntent{Intent: "list_debt", Direction: "they_owe"})},
+		{regexp.MustCompile(`(?i)^(?:(?:list|daftar|lihat|cek)\s+)?(?:hutangku|hutang\s+(?:aku|saya|gue|gw))$`), fixedIntent(ParsedIntent{Intent: "list_debt", Direction: "i_owe"})},
+		{regexp.MustCompile(`(?i)^(?:(?:list|daftar|lihat|cek)\s+)?(?:hutang|utang)\s+(?:ke\s+|sama\s+|dengan\s+)?(\S+)$`), buildDebtHistory},
+		{regexp.MustCompile(`(?i)^(?:aku|saya|gue|gw)\s+(?:pinjam|minjem|hutang|utang|ngutang)\s+(?:ke|sama|dari)\s+(\S+)\s+(\S+)$`), buildDebt("add_debt", "i_owe", 1, 2)},
+		{regexp.MustCompile(`(?i)^(?:aku|saya|gue|gw)\s+(?:pinjam|minjem|hutang|utang|ngutang)\s+(\S+)\s+(?:ke|sama|dari)\s+(\S+)$`), buildDebt("add_debt", "i_owe", 2, 1)},
+		{regexp.MustCompile(`(?i)^(?:pinjamkan|pinjamin|pinjemin|minjemin)\s+(\S+)\s+(\S+)$`), buildDebt("add_debt", "they_owe", 1, 2)},
+		{regexp.MustCompile(`(?i)^(\S+)\s+(?:pinjam|minjem|hutang|utang|ngutang)\s+(\S+)$`), buildDebt("add_debt", "they_owe", 1, 2)},
+		{regexp.MustCompile(`(?i)^(?:aku|saya|gue|gw)\s+(?:bayar|balikin|kembalikan|cicil|nyicil)\s+(?:hutang\s+)?(?:ke\s+)?(\S+)\s+(\S+)$`), buildDebt("pay_debt", "i_owe", 1, 2)},
+		{regexp.MustCompile(`(?i)^(?:bayar|lunasi|lunaskan)\s+hutang\s+(?:ke|sama)\s+(\S+)(?:\s+(\S+))?$`), buildDebt("pay_debt", "i_owe", 1, 2)},
+		{regexp.MustCompile(`(?i)^(\S+)\s+(?:bayar|balikin|kembalikan|cicil|nyicil)\s+(\S+)$`), buildDebt("pay_debt", "they_owe", 1, 2)},
+		{regexp.MustCompile(`(?i)^(?:lunasi|lunaskan|bayar hutang)\s+(.+)$`), buildPayExpense},
+		{regexp.MustCompile(`(?i)^(?:sisa|lihat|cek|list|daftar)\s+budget$`), fixedIntent(ParsedIntent{Intent: "show_budget"})},
+		{regexp.MustCompile(`(?i)^hapus\s+budget\s+(\S+)$`), buildDeleteBudget},
+		{regexp.MustCompile(`(?i)^(?:atur\s+|set\s+)?budget\s+(.+?)(?:\s+(?:per\s+bulan|sebulan|/\s*bulan))?$`), buildSetBudget},
+		{regexp.MustCompile(`(?i)^saldo\s+awal\s+(\S+)\s+(\S+)$`), buildSetAccount},
+		{regexp.MustCompile(`(?i)^(?:cek\s+|lihat\s+)?saldo(?:\s+(\S+))?$`), buildShowBalance},
+		{regexp.MustCompile(`(?i)^(?:topup|top\s+up|isi)\s+(\S+)\s+(\S+)\s+dari\s+(\S+)$`), buildTopUp},
+		{regexp.MustCompile(`(?i)^(?:transfer|tf|pindah|pindahkan|pindahin)\s+(\S+)\s+dari\s+(\S+)\s+ke\s+(\S+)$`), buildTransfer},
+		{regexp.MustCompile(`(?i)^(?:ubah|ganti)\s+kategori\s+(.+?)\s+(?:jadi|ke)\s+(\S+)$`), buildSetExpenseCategory},
+		{regexp.MustCompile(`(?i)^(?:list|daftar|lihat|tampilkan|cek)\s+todo(?:\s+(.+))?$`), buildListTodo},
+		{regexp.MustCompile(`(?i)^(?:list|daftar|lihat|tampilkan|cek)\s+pengeluaran(?:\s+(.+))?$`), buildListPeriod("list_expense")},
+		{regexp.MustCompile(`(?i)^pengeluaran(?:\s+(.+))?$`), buildListPeriod("list_expense")},
+		{regexp.MustCompile(`(?i)^(?:list|daftar|lihat|tampilkan|cek)\s+pemasukan(?:\s+(.+))?$`), buildListPeriod("list_income")},
+		{regexp.MustCompile(`(?i)^pemasukan(?:\s+(.+))?$`), buildListPeriod("list_income")},
+		{regexp.MustCompile(`(?i)^semua\s+pengeluaran$`), fixedIntent(ParsedIntent{Intent: "list_expense", Filter: "all"})},
+		{regexp.MustCompile(`(?i)^(?:(?:list|daftar|lihat|tampilkan|cek)\s+)?(?:sampah|trash)(?:\s+todo)?$`), fixedIntent(ParsedIntent{Intent: "list_trash"})},
+		{regexp.MustCompile(`(?i)^(?:list|daftar|lihat|tampilkan|cek)\s+reminder$`), fixedIntent(ParsedIntent{Intent: "list_reminder"})},
+		{regexp.MustCompile(`(?i)^(?:list|daftar|lihat|tampilkan|cek)\s+(?:project|projek)$`), fixedIntent(ParsedIntent{Intent: "list_project"})},
+		{regexp.MustCompile(`(?i)^(?:daily\s+)?(?:briefing|rangkuman)$`), fixedIntent(ParsedIntent{Intent: "daily_briefing"})},
+		{regexp.MustCompile(`(?i)^(?:help|bantuan)$`), fixedIntent(ParsedIntent{Intent: "help"})},
+		{regexp.MustCompile(`(?i)^(?:settings?|pengaturan|setelan)$`), fixedIntent(ParsedIntent{Intent: "show_settings"})},
+		{regexp.MustCompile(`(?i)^(matikan|nonaktifkan|nyalakan|aktifkan|hidupkan)\s+(.+)$`), buildToggleSetting},
+		{regexp.MustCompile(`(?i)^(?:ya|iya|yes|y|lanjut|lanjutkan|ok|oke)$`), fixedIntent(ParsedIntent{Intent: "confirm"})},
+		{regexp.MustCompile(`(?i)^(?:tidak|gak|nggak|enggak|no|batal|jangan)$`), fixedIntent(ParsedIntent{Intent: "cancel"})},
+		{regexp.MustCompile(`(?i)^(?:undo|batalkan|urungkan)$`), fixedIntent(ParsedIntent{Intent: "undo"})},
+	}}
+}
+
+func (p *RuleParser) Parse(_ context.Context, userMessage string, conv *Conversation) ([]ParsedIntent, error) {
+	text := strings.TrimSpace(extraSpaces.ReplaceAllString(userMessage, " "))
+	text = strings.TrimRight(text, ".!")
+
+	// Follow-ups need conversation context the rules can't resolve.
+	if conv != nil && referenceWords.MatchString(text) {
+		return nil, ErrNoMatch
+	}
+
+	for _, r := range p.rules {
+		m := r.pattern.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		intents, ok := r.build(m, userMessage)
+		if !ok || len(intents) == 0 {
+			return nil, ErrNoMatch
+		}
+		return intents, nil
+	}
+	return nil, ErrNoMatch
+}
+
+func fixedIntent(in ParsedIntent) func([]string, string) ([]ParsedIntent, bool) {
+	return func(_ []string, raw string) ([]ParsedIntent, bool) {
+		out := in
+		out.Raw = raw
+		return []ParsedIntent{out}, true
+	}
+}
+
+func splitBulk(s string) []string {
+	s = decimalComma.ReplaceAllString(s, "$1\x00$2")
+	var items []string
+	for _, part := range bulkSeparator.Split(s, -1) {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, strings.ReplaceAll(part, "\x00", ","))
+		}
+	}
+	return items
+}
+
+func buildAddTodo(m []string, raw string) ([]ParsedIntent, bool) {
+	if temporalWords.MatchString(m[1]) {
+		return nil, false
+	}
+	var intents []ParsedIntent
+	for _, title := range splitBulk(m[1]) {
+		if commandItem.MatchString(title) {
+			return nil, false
+		}
+		intents = append(intents, ParsedIntent{Intent: "add_todo", Title: title, Raw: raw})
+	}
+	return intents, true
+}
+
+func buildTodoAction(intent string) func([]string, string) ([]ParsedIntent, bool) {
+	return func(m []string, raw string) ([]ParsedIntent, bool) {
+		lower := strings.ToLower(m[1])
+		// Goals and "semua" (bulk clear) need project/confirmation context.
+		if strings.HasPrefix(lower, "goal") || strings.HasPrefix(lower, "semua") || temporalWords.MatchString(lower) {
+			return nil, false
+		}
+		var intents []ParsedIntent
+		for _, search := range splitBulk(m[1]) {
+			if commandItem.MatchString(search) {
+				return nil, false
+			}
+			intents = append(intents, ParsedIntent{Intent: intent, Search: search, Raw: raw})
+		}
+		return intents, true
+	}
+}
+
+func buildReminderRef(intent string) func([]string, string) ([]ParsedIntent, bool) {
+	return func(m []string, raw string) ([]ParsedIntent, bool) {
+		id, err := strconv.Atoi(m[1])
+		if err != nil || id <= 0 {
+			return nil, false
+		}
+		return []ParsedIntent{{Intent: intent, ReminderID: id, Raw: raw}}, true
+	}
+}
+
+func buildAddExpense(m []string, raw string) ([]ParsedIntent, bool) {
+	if temporalWords.MatchString(m[1]) {
+		return nil, false
+	}
+	var intents []ParsedIntent
+	for _, item := range splitBulk(m[1]) {
+		if incomeWords.MatchString(item) {
+			in, ok := parseIncome(item, raw)
+			if !ok {
+				return nil, false
+			}
+			intents = append(intents, in)
+			continue
+		}
+		var account string
+		if m := paidWith.FindStringSubmatch(item); m != nil {
+			item, account = m[1], m[2]
+		}
+		desc, amount, ok := splitTrailingAmount(item)
+		if !ok {
+			return nil, false
+		}
+		isPaid := true
+		if unpaidWords.MatchString(desc) {
+			isPaid = false
+			desc = strings.TrimSpace(extraSpaces.ReplaceAllString(unpaidWords.ReplaceAllString(desc, ""), " "))
+		}
+		if desc == "" {
+			return nil, false
+		}
+		intents = append(intents, ParsedIntent{
+			Intent:      "add_expense",
+			Description: desc,
+			Amount:      amount,
+			IsPaid:      &isPaid,
+			Account:     account,
+			Raw:         raw,
+		})
+	}
+	return intents, true
+}
+
+func buildAddIncome(m []string, raw string) ([]ParsedIntent, bool) {
+	if temporalWords.MatchString(m[1]) {
+		return nil, false
+	}
+	var intents []ParsedIntent
+	for _, item := range splitBulk(m[1]) {
+		in, ok := parseIncome(item, raw)
+		if !ok {
+			return nil, false
+		}
+		intents = append(intents, in)
+	}
+	return intents, true
+}
+
+// parseIncome reads "gaji 8jt", "transfer 500rb dari Budi" or "gaji 8jt
+// masuk ke BCA".
+func parseIncome(item, raw string) (ParsedIntent, bool) {
+	var source, account string
+	if m := paidInto.FindStringSubmatch(item); m != nil {
+		item, account = m[1], m[2]
+	}
+	if m := incomeSource.FindStringSubmatch(item); m != nil {
+		item, source = m[1], strings.TrimSpace(m[2])
+	}
+	desc, amount, ok := splitTrailingAmount(item)
+	if !ok || desc == "" {
+		return ParsedIntent{}, false
+	}
+	return ParsedIntent{Intent: "add_income", Description: desc, Amount: amount, Source: source, Account: account, Raw: raw}, true
+}
+
+func buildSetAccount(m []string, raw string) ([]ParsedIntent, bool) {
+	amount, ok := ParseAmount(m[2])
+	if !ok {
+		return nil, false
+	}
+	return []ParsedIntent{{Intent: "set_account", Account: m[1], Amount: amount, Raw: raw}}, true
+}
+
+func buildShowBalance(m []string, raw string) ([]ParsedIntent, bool) {
+	return []ParsedIntent{{Intent: "show_balance", Account: m[1], Raw: raw}}, true
+}
+
+// buildTopUp reads "topup gopay 200rb dari BCA".
+func buildTopUp(m []string, raw string) ([]ParsedIntent, bool) {
+	amount, ok := ParseAmount(m[2])
+	if !ok {
+		return nil, false
+	}
+	return []ParsedIntent{{Intent: "transfer", Account: m[3], ToAccount: m[1], Amount: amount, Raw: raw}}, true
+}
+
+// buildTransfer reads "transfer 1jt dari BCA ke gopay".
+func buildTransfer(m []string, raw string) ([]ParsedIntent, bool) {
+	amount, ok := ParseAmount(m[1])
+	if !ok {
+		return nil, false
+	}
+	return []ParsedIntent{{Intent: "transfer", Account: m[2], ToAccount: m[3], Amount: amount, Raw: raw}}, true
+}
+
+// buildDebt reads a debt or a payment on one; the groups at personIdx and
+// amountIdx hold the name and the amount. An amount may be left out only
+// when paying, to pay off all of it.
+func buildDebt(intent, direction string, personIdx, amountIdx int) func([]string, string) ([]ParsedIntent, bool) {
+	return func(m []string, raw string) ([]ParsedIntent, bool) {
+		person := m[personIdx]
+		if notPerson.MatchString(person) {
+			return nil, false
+		}
+		var amount int64
+		if m[amountIdx] != "" || intent == "add_debt" {
+			var ok bool
+			if amount, ok = ParseAmount(m[amountIdx]); !ok {
+				return nil, false
+			}
+		}
+		return []ParsedIntent{{Intent: intent, Person: person, Amount: amount, Direction: direction, Raw: raw}}, true
+	}
+}
+
+func buildDebtHistory(m []string, raw string) ([]ParsedIntent, bool) {
+	if notPerson.MatchString(m[1]) {
+		return nil, false
+	}
+	if _, ok := ParseAmount(m[1]); ok {
+		return nil, false
+	}
+	return []ParsedIntent{{Intent: "list_debt", Person: m[1], Raw: raw}}, true
+}
+
+func buildDeleteIncome(m []string, raw string) ([]ParsedIntent, bool) {
+	if temporalWords.MatchString(m[1]) {
+		return nil, false
+	}
+	var intents []ParsedIntent
+	for _, item := range splitBulk(m[1]) {
+		in := ParsedIntent{Intent: "delete_income", Search: item, Raw: raw}
+		if m := idRef.FindStringSubmatch(item); m != nil {
+			in.Search = ""
+			in.IncomeID, _ = strconv.Atoi(m[1])
+		} else if search, amount, ok := splitTrailingAmount(item); ok {
+			in.Search = search
+			in.Amount = amount
+		}
+		intents = append(intents, in)
+	}
+	return intents, true
+}
+
+func buildPayExpense(m []string, raw string) ([]ParsedIntent, bool) {
+	if temporalWords.MatchString(m[1]) {
+		return nil, false
+	}
+	var intents []ParsedIntent
+	for _, item := range splitBulk(m[1]) {
+		in := ParsedIntent{Intent: "pay_expense", Search: item, Raw: raw}
+		if m := idRef.FindStringSubmatch(item); m != nil {
+			in.Search = ""
+			in.ExpenseID, _ = strconv.Atoi(m[1])
+		} else if search, amount, ok := splitTrailingAmount(item); ok {
+			in.Search = search
+			in.Amount = amount
+		}
+		intents = append(intents, in)
+	}
+	return intents, true
+}
+
+func buildSetExpenseCategory(m []string, raw string) ([]ParsedIntent, bool) {
+	if temporalWords.MatchString(m[1]) {
+		return nil, false
+	}
+	// An unknown category is passed through so the reply can list the
+	// valid ones.
+	item := m[1]
+	in := ParsedIntent{Intent: "set_expense_category", Search: item, Category: m[2], Raw: raw}
+	if c, ok := expenseCategory(m[2]); ok {
+		in.Category = c
+	}
+	if m := idRef.FindStringSubmatch(item); m != nil {
+		in.Search = ""
+		in.ExpenseID, _ = strconv.Atoi(m[1])
+	} else if search, amount, ok := splitTrailingAmount(item); ok {
+		in.Search = search
+		in.Amount = amount
+	}
+	return []ParsedIntent{in}, true
+}
+
+func buildSetBudget(m []string, raw string) ([]ParsedIntent, bool) {
+	var intents []ParsedIntent
+	for _, item := range splitBulk(m[1]) {
+		name, amount, ok := splitTrailingAmount(item)
+		if !ok {
+			return nil, false
+		}
+		category, ok := expenseCategory(name)
+		if !ok {
+			return nil, false
+		}
+		intents = append(intents, ParsedIntent{Intent: "set_budget", Category: category, Amount: amount, Raw: raw})
+	}
+	return intents, true
+}
+
+func buildDeleteBudget(m []string, raw string) ([]ParsedIntent, bool) {
+	category, ok := expenseCategory(m[1])
+	if !ok {
+		return nil, false
+	}
+	return []ParsedIntent{{Intent: "delete_budget", Category: category, Raw: raw}}, true
+}
+
+// expenseCategory returns the default expense category named s, ignoring
+// case.
+func expenseCategory(s string) (string, bool) {
+	for _, c := range expenseCategories {
+		if strings.EqualFold(c, strings.TrimSpace(s)) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+func buildListTodo(m []string, raw string) ([]ParsedIntent, bool) {
+	var filter string
+	switch strings.ToLower(strings.TrimSpace(m[1])) {
+	case "", "semua", "all":
+		filter = "all"
+	case "hari ini", "today":
+		filter = "today"
+	case "pending", "belum selesai":
+		filter = "pending"
+	default:
+		return nil, false
+	}
+	return []ParsedIntent{{Intent: "list_todo", Filter: filter, Raw: raw}}, true
+}
+
+// buildListPeriod builds list_expense or list_income from a period such as
+// "hari ini" or "semua".
+func buildListPeriod(intent string) func([]string, string) ([]ParsedIntent, bool) {
+	return func(m []string, raw string) ([]ParsedIntent, bool) {
+		var filter string
+		switch strings.ToLower(strings.TrimSpace(m[1])) {
+		case "", "bulan ini":
+			filter = "this_month"
+		case "hari ini":
+			filter = "today"
+		case "minggu ini":
+			filter = "this_week"
+		case "semua":
+			filter = "all"
+		default:
+			return nil, false
+		}
+		return []ParsedIntent{{Intent: intent, Filter: filter, Raw: raw}}, true
+	}
+}
+
+var scheduledMessageNames = map[string]string{
+	"briefing":           "briefing",
+	"daily briefing":     "briefing",
+	"rangkuman":          "briefing",
+	"overdue":            "overdue",
+	"pengingat overdue":  "overdue",
+	"laporan bulanan":    "monthly_report",
+	"laporan":            "monthly_report",
+	"jam tenang":         "quiet_hours",
+	"jangan ganggu":      "dnd",
+	"mode jangan ganggu": "dnd",
+}
+
+func buildToggleSetting(m []string, raw string) ([]ParsedIntent, bool) {
+	setting, ok := scheduledMessageNames[strings.ToLower(strings.TrimSpace(m[2]))]
+	if !ok {
+		return nil, false
+	}
+	verb := strings.ToLower(m[1])
+	enabled := verb != "matikan" && verb != "nonaktifkan"
+	return []ParsedIntent{{Intent: "update_setting", Setting: setting, Enabled: &enabled, Raw: raw}}, true
+}
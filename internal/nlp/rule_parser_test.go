@@ -0,0 +1,170 @@
+package nlp
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestRuleParserPromptExamples runs the examples of the LLM system prompt
+// through the rule parser. Those it cannot read safely must be left to the
+// LLM (ErrNoMatch) rather than parsed differently.
+func TestRuleParserPromptExamples(t *testing.T) {
+	paid := true
+	tests := []struct {
+		msg  string
+		want []ParsedIntent // nil means ErrNoMatch
+	}{
+		{"tambah todo beli susu, beli roti, dan beli kopi", []ParsedIntent{
+			{Intent: "add_todo", Title: "beli susu"},
+			{Intent: "add_todo", Title: "beli roti"},
+			{Intent: "add_todo", Title: "beli kopi"},
+		}},
+		{"hapus todo beli susu dan selesaikan todo beli roti", nil},
+		{"done makan mie dan cuci piring", []ParsedIntent{
+			{Intent: "complete_todo", Search: "makan mie"},
+			{Intent: "complete_todo", Search: "cuci piring"},
+		}},
+		{"edit todo beli susu jadi beli madu", nil},
+		{"kosongkan todo", nil},
+		{"buat done semua todo", nil},
+		{"lihat goals Laundry App", nil},
+		{"progress Laundry App", nil},
+		{"list project", []ParsedIntent{{Intent: "list_project"}}},
+		{"tambah goal di Laundry App: wireframe, database, deploy", nil},
+		{"hapus goal wireframe dan database dari Laundry App", nil},
+		{"done goal wireframe", nil},
+		{"selesaikan goal wireframe di Laundry App", nil},
+		{"catat makan siang 35rb, bensin 50rb, parkir 5rb", []ParsedIntent{
+			{Intent: "add_expense", Description: "makan siang", Amount: 35000, IsPaid: &paid},
+			{Intent: "add_expense", Description: "bensin", Amount: 50000, IsPaid: &paid},
+			{Intent: "add_expense", Description: "parkir", Amount: 5000, IsPaid: &paid},
+		}},
+		{"catat makan siang 35rb dan bensin 50rb", []ParsedIntent{
+			{Intent: "add_expense", Description: "makan siang", Amount: 35000, IsPaid: &paid},
+			{Intent: "add_expense", Description: "bensin", Amount: 50000, IsPaid: &paid},
+		}},
+		{"hapus pengeluaran parkir dan bensin", nil},
+		{"lunasi beli kecap", []ParsedIntent{{Intent: "pay_expense", Search: "beli kecap"}}},
+		{"lunasi beli kecap 20rb", []ParsedIntent{{Intent: "pay_expense", Search: "beli kecap", Amount: 20000}}},
+		{"hapus beli kecap 14 feb", nil},
+		{"hapus id 123", nil},
+		{"ganti nama bensin jadi bensin motor", nil},
+		{"tandai beli kecap 20rb sudah lunas", nil},
+		{"edit id 456 jadi bensin motor", nil},
+		{"kosongkan februari 2026", nil},
+		{"catat bensin 50rb", []ParsedIntent{{Intent: "add_expense", Description: "bensin", Amount: 50000, IsPaid: &paid}}},
+		{"catat kado ulang tahun 200rb", []ParsedIntent{{Intent: "add_expense", Description: "kado ulang tahun", Amount: 200000, IsPaid: &paid}}},
+		{"ubah kategori kado jadi Belanja", []ParsedIntent{{Intent: "set_expense_category", Search: "kado", Category: "Belanja"}}},
+		{"ubah kategori id 456 jadi Transport", []ParsedIntent{{Intent: "set_expense_category", ExpenseID: 456, Category: "Transport"}}},
+		{"budget makan 2jt per bulan", []ParsedIntent{{Intent: "set_budget", Category: "Makan", Amount: 2000000}}},
+		{"budget makan 2jt, transport 800rb", []ParsedIntent{
+			{Intent: "set_budget", Category: "Makan", Amount: 2000000},
+			{Intent: "set_budget", Category: "Transport", Amount: 800000},
+		}},
+		{"sisa budget", []ParsedIntent{{Intent: "show_budget"}}},
+		{"catat gaji 8jt", []ParsedIntent{{Intent: "add_income", Description: "gaji", Amount: 8000000}}},
+		{"terima transfer 500rb dari Budi", []ParsedIntent{{Intent: "add_income", Description: "transfer", Amount: 500000, Source: "Budi"}}},
+		{"catat bensin 50rb pakai gopay", []ParsedIntent{{Intent: "add_expense", Description: "bensin", Amount: 50000, IsPaid: &paid, Account: "gopay"}}},
+		{"saldo awal BCA 5jt", []ParsedIntent{{Intent: "set_account", Account: "BCA", Amount: 5000000}}},
+		{"topup gopay 200rb dari BCA", []ParsedIntent{{Intent: "transfer", Account: "BCA", ToAccount: "gopay", Amount: 200000}}},
+		{"saldo gopay", []ParsedIntent{{Intent: "show_balance", Account: "gopay"}}},
+		{"Budi pinjam 200rb", []ParsedIntent{{Intent: "add_debt", Person: "Budi", Amount: 200000, Direction: "they_owe"}}},
+		{"aku hutang ke Sari 1jt", []ParsedIntent{{Intent: "add_debt", Person: "Sari", Amount: 1000000, Direction: "i_owe"}}},
+		{"Budi bayar 50rb", []ParsedIntent{{Intent: "pay_debt", Person: "Budi", Amount: 50000, Direction: "they_owe"}}},
+		{"siapa saja yang hutang ke aku", []ParsedIntent{{Intent: "list_debt", Direction: "they_owe"}}},
+		{"pemasukan bulan ini", []ParsedIntent{{Intent: "list_income", Filter: "this_month"}}},
+		{"ingetin minum obat jam 9", nil},
+		{"ingetin bayar wifi tiap tanggal 5", nil},
+		{"ingetin bayar listrik setiap tanggal 17", nil},
+		{"ingetin bayar wifi tiap tanggal 5 dan bayar listrik tiap tanggal 17", nil},
+		{"ingetin standup tiap hari kerja jam 9", nil},
+		{"ingetin gajian tiap tanggal 25, kalau libur maju ke hari kerja sebelumnya", nil},
+		{"tambah todo bayar pajak, ingetin besok jam 10", nil},
+		{"tambah todo laporan deadline jumat jam 17, ingetin 1 jam dan 1 hari sebelumnya", nil},
+		{"ingetin beli kado 2 hari sebelum deadline", nil},
+		{"hapus reminder #12", []ParsedIntent{{Intent: "delete_reminder", ReminderID: 12}}},
+		{"hapus reminder minum obat", []ParsedIntent{{Intent: "delete_reminder", Search: "minum obat"}}},
+		{"ubah reminder minum obat jadi jam 8", nil},
+		{"ubah reminder bayar wifi jadi tiap tanggal 10", nil},
+		{"jeda reminder bayar wifi", []ParsedIntent{{Intent: "pause_reminder", Search: "bayar wifi"}}},
+		{"lanjutkan reminder bayar wifi", []ParsedIntent{{Intent: "resume_reminder", Search: "bayar wifi"}}},
+		{"stop reminder bayar wifi", []ParsedIntent{{Intent: "cancel_reminder", Search: "bayar wifi"}}},
+		{"skip reminder bayar wifi bulan ini", nil},
+		{"list reminder", []ParsedIntent{{Intent: "list_reminder"}}},
+	}
+
+	p := NewRuleParser()
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			got, err := p.Parse(context.Background(), tt.msg, nil)
+			if tt.want == nil {
+				if !errors.Is(err, ErrNoMatch) {
+					t.Fatalf("got %+v, %v; want ErrNoMatch", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			for i := range tt.want {
+				tt.want[i].Raw = tt.msg
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleParserDebtListing(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want ParsedIntent
+	}{
+		{"cek hutang Budi", ParsedIntent{Intent: "list_debt", Person: "Budi"}},
+		{"lihat utang Sari", ParsedIntent{Intent: "list_debt", Person: "Sari"}},
+		{"list hutang Budi", ParsedIntent{Intent: "list_debt", Person: "Budi"}},
+		{"hutang ke Budi", ParsedIntent{Intent: "list_debt", Person: "Budi"}},
+		{"lihat hutang piutang", ParsedIntent{Intent: "list_debt"}},
+		{"cek hutang aku", ParsedIntent{Intent: "list_debt", Direction: "i_owe"}},
+		{"piutang", ParsedIntent{Intent: "list_debt", Direction: "they_owe"}},
+	}
+
+	p := NewRuleParser()
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			got, err := p.Parse(context.Background(), tt.msg, nil)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			tt.want.Raw = tt.msg
+			if len(got) != 1 || !reflect.DeepEqual(got[0], tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleParserSkipsReferencesWithConversation(t *testing.T) {
+	p := NewRuleParser()
+	conv := &Conversation{Turns: []Turn{{Role: "user", Text: "tambah todo beli susu"}}}
+	for _, msg := range []string{"hapus todo yang tadi", "hapus todonya", "ganti judulnya jadi beli roti"} {
+		if _, err := p.Parse(context.Background(), msg, conv); !errors.Is(err, ErrNoMatch) {
+			t.Errorf("%q: err = %v, want ErrNoMatch", msg, err)
+		}
+	}
+
+	// Words that merely end in "-nya" are not references.
+	for _, msg := range []string{"tambah todo tanya pak budi", "tambah todo balikin buku punya sari", "tambah todo hanya beli susu"} {
+		got, err := p.Parse(context.Background(), msg, conv)
+		if err != nil {
+			t.Errorf("%q: Parse: %v", msg, err)
+			continue
+		}
+		if len(got) != 1 || got[0].Intent != "add_todo" {
+			t.Errorf("%q: got %+v, want one add_todo", msg, got)
+		}
+	}
+}
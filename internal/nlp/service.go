@@ -2,7 +2,7 @@ package nlp
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -10,167 +10,307 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
 )
 
 type Service struct {
 	client   anthropic.Client
+	tools    []map[string]any
 	timezone *time.Location
 }
 
-func NewService(apiKey string, timezone *time.Location) *Service {
-	client := anthropic.NewClient(option.WithAPIKey(apiKey))
+// NewService creates the LLM-backed parser. Extra options (e.g. option.WithBaseURL)
+// are passed to the Anthropic client, which lets it run against a local mock.
+func NewService(apiKey string, timezone *time.Location, opts ...option.RequestOption) *Service {
+	client := anthropic.NewClient(append([]option.RequestOption{option.WithAPIKey(apiKey)}, opts...)...)
 	return &Service{
 		client:   client,
+		tools:    toolDefinitions(),
 		timezone: timezone,
 	}
 }
 
-func (s *Service) Parse(ctx context.Context, userMessage string) ([]ParsedIntent, error) {
-	now := time.Now().In(s.timezone)
+func (s *Service) Parse(ctx context.Context, userMessage string, conv *Conversation) ([]ParsedIntent, error) {
+	loc := settings.Location(ctx, s.timezone)
+	now := time.Now().In(loc)
 	tomorrow := now.AddDate(0, 0, 1)
 	dayAfterTomorrow := now.AddDate(0, 0, 2)
 
-	systemPrompt := fmt.Sprintf(`Kamu adalah parser untuk personal assistant bot. Tugas kamu HANYA mengubah pesan user menjadi JSON array.
+	systemPrompt := fmt.Sprintf(`Kamu adalah parser untuk personal assistant bot. Tugas kamu HANYA mengubah pesan user menjadi panggilan tool.
 
 Hari ini: %s
 Timezone: %s
 
 RULES:
-- Output HANYA JSON array (selalu dalam bentuk array [...]), tanpa markdown, tanpa penjelasan
-- Jika user melakukan 1 aksi, return array dengan 1 elemen. Jika multiple aksi, return array dengan banyak elemen
-- Format tanggal: due_date = "YYYY-MM-DD", remind_at = "YYYY-MM-DDTHH:MM:SS+07:00" (RFC3339 dengan timezone Asia/Jakarta)
-- Jika user sebut tanggal tanpa jam, default jam 07:00 WIB
+- Setiap aksi = 1 panggilan tool. Jika user melakukan beberapa aksi, panggil tool beberapa kali (boleh tool yang sama)
+- Jangan menulis teks atau penjelasan, hanya panggilan tool
+- Format tanggal: due_date = "YYYY-MM-DD", remind_at = "YYYY-MM-DDTHH:MM:SS" (jam lokal user di timezone %s, TANPA offset)
+- Jika user sebut tanggal tanpa jam, default jam %02d:00 %s
 - Jika user menyebut jam/waktu, SELALU set reminder=true dan remind_at dengan waktu tersebut
+- "ingetin X ..." tanpa kata "todo" dan tanpa deadline = create_reminder (reminder mandiri), BUKAN add_todo
 - "besok" = %s
 - "lusa" = %s
 - Nominal uang: "35rb" = 35000, "1.5jt" = 1500000, "1juta" = 1000000
 - "minggu depan" = 7 hari dari sekarang
 - "bulan depan" = 1 bulan dari sekarang, gunakan hari terakhir bulan tersebut untuk due_date jika tidak spesifik
-- Format recurring: "daily" | "weekly:MON" | "monthly:DD" | "yearly:MM-DD"
-  - "daily" = setiap hari, "weekly:MON" = setiap Senin (MON/TUE/WED/THU/FRI/SAT/SUN)
-  - "monthly:5" = setiap tanggal 5, "monthly:17" = setiap tanggal 17
-  - "yearly:3-15" = setiap 15 Maret, "yearly:12-25" = setiap 25 Desember
-- Jika user sebut "tiap tanggal X" atau "setiap tanggal X": set recurring="monthly:X"
+- Format recurring: RRULE RFC 5545 tanpa DTSTART (jam diambil dari remind_at):
+  - "setiap hari" = "FREQ=DAILY", "setiap hari kerja" = "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;X-NONWORKDAY=SKIP" (tanggal merah & cuti bersama ikut dilewati)
+  - "setiap Senin" = "FREQ=WEEKLY;BYDAY=MO" (MO/TU/WE/TH/FR/SA/SU), "tiap 2 minggu hari Jumat" = "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR"
+  - "tiap tanggal 5" = "FREQ=MONTHLY;BYMONTHDAY=5", "tiap akhir bulan" = "FREQ=MONTHLY;BYMONTHDAY=-1"
+  - "tiap Senin pertama" = "FREQ=MONTHLY;BYDAY=1MO", "tiap Jumat terakhir" = "FREQ=MONTHLY;BYDAY=-1FR"
+  - "tiap 15 Maret" = "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=15"
+  - "sampai 31 Des" → tambahkan ";UNTIL=20261231", "sebanyak 5 kali" → tambahkan ";COUNT=5"
+  - "kecuali 25 Des" → tambahkan baris baru "\nEXDATE:20261225"
+  - Hari libur (akhir pekan, libur nasional, cuti bersama): "kalau libur, majukan ke hari kerja sebelumnya" → tambahkan ";X-NONWORKDAY=PREV", "kalau libur, mundur ke hari kerja berikutnya" → ";X-NONWORKDAY=NEXT", "lewati kalau libur" → ";X-NONWORKDAY=SKIP". X-NONWORKDAY butuh BYDAY atau BYMONTHDAY dan tanpa INTERVAL
+- Jika user sebut "tiap tanggal X" atau "setiap tanggal X": set recurring="FREQ=MONTHLY;BYMONTHDAY=X"
 - Jika remind_at untuk recurring sudah lewat hari ini, gunakan occurrence BERIKUTNYA sebagai remind_at (contoh: hari ini 19 Feb, user minta "tiap tanggal 17" → remind_at = 17 Maret)
-- Jika tidak bisa parsing, return: [{"intent": "unknown", "raw": "<pesan asli>"}]
+- Jika tidak bisa parsing, panggil tool unknown dengan raw = pesan asli
 
 CONTOH BULK:
-- "tambah todo beli susu, beli roti, dan beli kopi" → 3 elemen add_todo
+- "tambah todo beli susu, beli roti, dan beli kopi" → 3 panggilan add_todo
 - "hapus todo beli susu dan selesaikan todo beli roti" → 1 delete_todo + 1 complete_todo
-- "done makan mie dan cuci piring" → 2 elemen complete_todo (search="makan mie", search="cuci piring")
-- "edit todo beli susu jadi beli madu" → 1 elemen edit_todo dengan search="beli susu", title="beli madu"
-- "kosongkan todo" → 1 elemen clear_todo (tanpa nama spesifik = hapus semua)
-- "buat done semua todo" → 1 elemen clear_todo HANYA jika tidak ada nama spesifik yang disebutkan
+- "done makan mie dan cuci piring" → 2 panggilan complete_todo (search="makan mie", search="cuci piring")
+- "edit todo beli susu jadi beli madu" → 1 panggilan edit_todo dengan search="beli susu", title="beli madu"
+- "kosongkan todo" → 1 panggilan clear_todo (tanpa nama spesifik = hapus semua)
+- "buat done semua todo" → 1 panggilan clear_todo HANYA jika tidak ada nama spesifik yang disebutkan
 - "lihat goals Laundry App" → show_project dengan project="Laundry App"
 - "progress Laundry App" → show_project dengan project="Laundry App"
 - "list project" → list_project (tanpa project field)
-- "tambah goal di Laundry App: wireframe, database, deploy" → 3 elemen add_goal dengan project="Laundry App"
-- "hapus goal wireframe dan database dari Laundry App" → 2 elemen delete_goal dengan project="Laundry App"
+- "tambah goal di Laundry App: wireframe, database, deploy" → 3 panggilan add_goal dengan project="Laundry App"
+- "hapus goal wireframe dan database dari Laundry App" → 2 panggilan delete_goal dengan project="Laundry App"
 - "done goal wireframe" → complete_goal dengan project="" (kosong, jika user tidak sebut project)
 - "selesaikan goal wireframe di Laundry App" → complete_goal dengan project="Laundry App", search="wireframe"
-- "catat makan siang 35rb, bensin 50rb, parkir 5rb" → 3 elemen add_expense
-- "catat makan siang 35rb dan bensin 50rb" → 2 elemen add_expense
-- "hapus pengeluaran parkir dan bensin" → 2 elemen delete_expense (search="parkir", search="bensin")
-- "lunasi beli kecap" → 1 elemen pay_expense (BUKAN add_expense)
-- "lunasi beli kecap 20rb" → 1 elemen pay_expense dengan search="beli kecap", amount=20000
-- "hapus beli kecap 14 feb" → 1 elemen delete_expense dengan search="beli kecap", date="2026-02-14"
-- "hapus id 123" → 1 elemen delete_expense dengan expense_id=123
-- "ganti nama bensin jadi bensin motor" → 1 elemen edit_expense dengan search="bensin", new_title="bensin motor"
-- "tandai beli kecap 20rb sudah lunas" → 1 elemen edit_expense dengan search="beli kecap", amount=20000, new_is_paid=true
-- "edit id 456 jadi bensin motor" → 1 elemen edit_expense dengan expense_id=456, new_title="bensin motor"
-- "kosongkan februari 2026" → 1 elemen clear_expense dengan month=2, year=2026
-- "ingetin bayar wifi tiap tanggal 5" → 1 elemen add_todo dengan title="bayar wifi", reminder=true, remind_at="2026-03-05T07:00:00+07:00" (bulan depan karena tgl 5 Feb sudah lewat), recurring="monthly:5"
-- "ingetin bayar listrik setiap tanggal 17" → 1 elemen add_todo dengan title="bayar listrik", reminder=true, remind_at="2026-03-17T07:00:00+07:00", recurring="monthly:17"
-- "ingetin bayar wifi tiap tanggal 5 dan bayar listrik tiap tanggal 17" → 2 elemen add_todo masing-masing dengan recurring berbeda
-- "list reminder" → 1 elemen list_reminder
-- "daftar reminder" → 1 elemen list_reminder
-
-INTENTS:
-- add_todo: {title, reminder?, remind_at?, recurring?, due_date?}
-- complete_todo: {search}
-- list_todo: {filter: "all"|"today"|"pending"}
-- delete_todo: {search}
-- edit_todo: {search, title?, due_date?, remind_at?}
-- clear_todo: {} (HANYA jika user ingin menghapus/mengosongkan semua todo sekaligus tanpa menyebut nama spesifik: "kosongkan todo", "hapus semua todo", "clear todo list". JANGAN gunakan ini jika user menyebut nama todo tertentu — gunakan complete_todo atau delete_todo per item)
-- add_expense: {description, amount, is_paid?} (default is_paid=true. Set is_paid=false jika user bilang "hutang", "belum bayar", "belum lunas", "cicilan". Contoh: "catat hutang sewa kos 1.5jt" → is_paid=false. JANGAN gunakan ini untuk pesan seperti "lunasi X" atau "bayar hutang X" — itu adalah pay_expense)
-- pay_expense: {search, amount?, date?} (tandai pengeluaran lunas. Contoh: "lunasi sewa kos", "lunasi beli kecap 20rb" → search="beli kecap", amount=20000. "lunasi beli kecap 14 feb" → search="beli kecap", date="2026-02-14". Jika ada nominal → isi amount. Jika ada tanggal → isi date=YYYY-MM-DD)
-- list_expense: {filter: "today"|"this_week"|"this_month"|"all"}
-- delete_expense: {search?, amount?, date?, expense_id?} (hapus pengeluaran. "hapus beli kecap 100rb" → search="beli kecap", amount=100000. "hapus beli kecap 14 feb" → search="beli kecap", date="2026-02-14". "hapus id 123" → expense_id=123)
-- edit_expense: {search?, amount?, date?, new_title?, new_is_paid?, expense_id?} (edit judul atau status pengeluaran. "ganti nama bensin jadi bensin motor" → search="bensin", new_title="bensin motor". "tandai beli kecap 20rb sudah lunas" → search="beli kecap", amount=20000, new_is_paid=true. "ubah beli kecap jadi belum lunas" → search="beli kecap", new_is_paid=false. "edit id 456 jadi bensin motor" → expense_id=456, new_title="bensin motor")
-- clear_expense: {month, year?} (hapus semua pengeluaran di bulan tertentu. month=1-12. "kosongkan februari 2026" → month=2, year=2026. "hapus semua pengeluaran februari" → month=2, year tidak diisi)
-- add_project: {name, due_date?, description?}
-- add_goal: {project, title, due_date?, reminder?, remind_at?, recurring?} (project WAJIB diisi. Jika bulk: tiap goal = 1 elemen dengan project yang sama)
-- complete_goal: {project?, search} (project boleh kosong jika user tidak menyebutkan project)
-- list_project: {} (tampilkan semua project, "list project", "project apa saja", "daftar project")
-- show_project: {project} (tampilkan detail + goals satu project. "lihat project X", "detail project X", "goals X", "progress X", "tampilkan X", "apa saja goals X" → project="X")
-- delete_project: {project}
-- delete_goal: {project?, search} (project boleh kosong jika user tidak menyebutkan project)
-- daily_briefing: {} (user minta rangkuman harian, daily briefing, "apa yang harus dikerjakan hari ini", "briefing", "rangkuman")
-- list_reminder: {} (tampilkan semua reminder aktif. "list reminder", "daftar reminder", "reminder apa saja", "reminder aktif")
-- help: {}
-- unknown: {raw}`,
+- "catat makan siang 35rb, bensin 50rb, parkir 5rb" → 3 panggilan add_expense
+- "catat makan siang 35rb dan bensin 50rb" → 2 panggilan add_expense
+- "hapus pengeluaran parkir dan bensin" → 2 panggilan delete_expense (search="parkir", search="bensin")
+- "lunasi beli kecap" → 1 panggilan pay_expense (BUKAN add_expense)
+- "lunasi beli kecap 20rb" → 1 panggilan pay_expense dengan search="beli kecap", amount=20000
+- "hapus beli kecap 14 feb" → 1 panggilan delete_expense dengan search="beli kecap", date="2026-02-14"
+- "hapus id 123" → 1 panggilan delete_expense dengan expense_id=123
+- "ganti nama bensin jadi bensin motor" → 1 panggilan edit_expense dengan search="bensin", new_title="bensin motor"
+- "tandai beli kecap 20rb sudah lunas" → 1 panggilan edit_expense dengan search="beli kecap", amount=20000, new_is_paid=true
+- "edit id 456 jadi bensin motor" → 1 panggilan edit_expense dengan expense_id=456, new_title="bensin motor"
+- "kosongkan februari 2026" → 1 panggilan clear_expense dengan month=2, year=2026
+- "catat bensin 50rb" → 1 panggilan add_expense dengan description="bensin", amount=50000, category="Transport"
+- "catat kado ulang tahun 200rb" → 1 panggilan add_expense tanpa category (tidak jelas masuk mana)
+- "ubah kategori kado jadi Belanja" → 1 panggilan set_expense_category dengan search="kado", category="Belanja"
+- "ubah kategori id 456 jadi Transport" → 1 panggilan set_expense_category dengan expense_id=456, category="Transport"
+- "budget makan 2jt per bulan" → 1 panggilan set_budget dengan category="Makan", amount=2000000
+- "budget makan 2jt, transport 800rb" → 2 panggilan set_budget
+- "sisa budget" → 1 panggilan show_budget
+- "catat gaji 8jt" → 1 panggilan add_income dengan description="gaji", amount=8000000 (BUKAN add_expense)
+- "terima transfer 500rb dari Budi" → 1 panggilan add_income dengan description="transfer", amount=500000, source="Budi"
+- "catat bensin 50rb pakai gopay" → 1 panggilan add_expense dengan description="bensin", amount=50000, account="gopay"
+- "saldo awal BCA 5jt" → 1 panggilan set_account dengan account="BCA", amount=5000000
+- "topup gopay 200rb dari BCA" → 1 panggilan transfer dengan from_account="BCA", to_account="gopay", amount=200000 (BUKAN add_expense)
+- "saldo gopay" → 1 panggilan show_balance dengan account="gopay"
+- "Budi pinjam 200rb" → 1 panggilan add_debt dengan person="Budi", amount=200000, direction=they_owe
+- "aku hutang ke Sari 1jt" → 1 panggilan add_debt dengan person="Sari", amount=1000000, direction=i_owe (BUKAN add_expense)
+- "Budi bayar 50rb" → 1 panggilan pay_debt dengan person="Budi", amount=50000, direction=they_owe
+- "siapa saja yang hutang ke aku" → 1 panggilan list_debt dengan direction=they_owe
+- "pemasukan bulan ini" → 1 panggilan list_income dengan filter="this_month"
+- "ingetin minum obat jam 9" → 1 panggilan create_reminder dengan title="minum obat", remind_at=jam 09:00 berikutnya
+- "ingetin bayar wifi tiap tanggal 5" → 1 panggilan create_reminder dengan title="bayar wifi", remind_at="2026-03-05T07:00:00" (bulan depan karena tgl 5 Feb sudah lewat), recurring="FREQ=MONTHLY;BYMONTHDAY=5"
+- "ingetin bayar listrik setiap tanggal 17" → 1 panggilan create_reminder dengan title="bayar listrik", remind_at="2026-03-17T07:00:00", recurring="FREQ=MONTHLY;BYMONTHDAY=17"
+- "ingetin bayar wifi tiap tanggal 5 dan bayar listrik tiap tanggal 17" → 2 panggilan create_reminder masing-masing dengan recurring berbeda
+- "ingetin standup tiap hari kerja jam 9" → 1 panggilan create_reminder dengan title="standup", remind_at=hari kerja berikutnya jam 09:00, recurring="FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;X-NONWORKDAY=SKIP"
+- "ingetin gajian tiap tanggal 25, kalau libur maju ke hari kerja sebelumnya" → 1 panggilan create_reminder dengan title="gajian", recurring="FREQ=MONTHLY;BYMONTHDAY=25;X-NONWORKDAY=PREV"
+- "tambah todo bayar pajak, ingetin besok jam 10" → 1 panggilan add_todo dengan title="bayar pajak", reminder=true, remind_at=besok jam 10:00
+- "tambah todo laporan deadline jumat jam 17, ingetin 1 jam dan 1 hari sebelumnya" → 1 panggilan add_todo dengan due_date=jumat, lead_times=["-1h","-1d"]
+- "ingetin beli kado 2 hari sebelum deadline" → 1 panggilan add_reminder dengan search="beli kado", lead_times=["-2d"]
+- "hapus reminder #12" → 1 panggilan delete_reminder dengan reminder_id=12
+- "hapus reminder minum obat" → 1 panggilan delete_reminder dengan search="minum obat"
+- "ubah reminder minum obat jadi jam 8" → 1 panggilan edit_reminder dengan search="minum obat", remind_at=jam 08:00 berikutnya
+- "ubah reminder bayar wifi jadi tiap tanggal 10" → 1 panggilan edit_reminder dengan search="bayar wifi", recurring="FREQ=MONTHLY;BYMONTHDAY=10" (tanpa remind_at, jamnya tetap)
+- "jeda reminder bayar wifi" → 1 panggilan pause_reminder dengan search="bayar wifi"
+- "lanjutkan reminder bayar wifi" → 1 panggilan resume_reminder dengan search="bayar wifi"
+- "stop reminder bayar wifi" → 1 panggilan cancel_reminder dengan search="bayar wifi"
+- "skip reminder bayar wifi bulan ini" → 1 panggilan skip_reminder dengan search="bayar wifi"
+- "list reminder" → 1 panggilan list_reminder
+- "daftar reminder" → 1 panggilan list_reminder`,
 		now.Format("2006-01-02 (Monday)"),
-		s.timezone.String(),
+		loc.String(),
+		loc.String(),
+		settings.ReminderHour(ctx, defaultReminderHour),
+		now.Format("MST"),
 		tomorrow.Format("2006-01-02"),
 		dayAfterTomorrow.Format("2006-01-02"),
 	)
+	systemPrompt += conversationPrompt(conv)
 
-	intents, err := s.callAPI(ctx, systemPrompt, userMessage)
+	messages := conversationMessages(conv, userMessage)
+
+	resp, err := s.callAPI(ctx, systemPrompt, messages)
 	if err != nil {
 		// Retry once
 		slog.Warn("NLP first attempt failed, retrying", "error", err)
-		intents, err = s.callAPI(ctx, systemPrompt, userMessage)
+		resp, err = s.callAPI(ctx, systemPrompt, messages)
 		if err != nil {
 			return nil, fmt.Errorf("nlp parse failed: %w", err)
 		}
 	}
+	if len(resp.rejected) == 0 {
+		return resp.intents, nil
+	}
+
+	// Keep the calls that decoded and send the rejected ones back as tool
+	// errors, so the model only has to fix what was wrong.
+	slog.Warn("NLP tool calls rejected, retrying", "error", resp.rejected[0])
+	messages = append(messages, resp.message.ToParam(), resp.toolResults())
+	retry, err := s.callAPI(ctx, systemPrompt, messages)
+	if err != nil {
+		slog.Warn("NLP retry failed", "error", err)
+		return nil, fmt.Errorf("nlp parse failed: %w", resp.rejected[0])
+	}
+	if len(retry.rejected) > 0 {
+		return nil, fmt.Errorf("nlp parse failed: %w", retry.rejected[0])
+	}
+	return resp.withRetried(retry.intents), nil
+}
+
+// apiResult is one model response: the intents of the tool calls that
+// decoded, and the errors of the ones that did not, by tool_use ID.
+// rejectedAt holds, per rejected call, how many intents came before it.
+type apiResult struct {
+	message    *anthropic.Message
+	intents    []ParsedIntent
+	rejected   []*FieldError
+	rejectedAt []int
+	errs       map[string]*FieldError
+}
+
+// withRetried puts the intents of a retry back at the places of the calls
+// they replace, one per rejected call, so "hapus todo X lalu tambah todo X"
+// keeps its order. Extra retried intents follow the last rejected call.
+func (r *apiResult) withRetried(retried []ParsedIntent) []ParsedIntent {
+	out := make([]ParsedIntent, 0, len(r.intents)+len(retried))
+	done := 0
+	for i, at := range r.rejectedAt {
+		out = append(out, r.intents[done:at]...)
+		done = at
+		if i == len(r.rejectedAt)-1 {
+			out = append(out, retried...)
+		} else if len(retried) > 0 {
+			out = append(out, retried[0])
+			retried = retried[1:]
+		}
+	}
+	return append(out, r.intents[done:]...)
+}
 
-	return intents, nil
+// toolResults answers every tool call of the response, flagging the rejected
+// ones with what was wrong.
+func (r *apiResult) toolResults() anthropic.MessageParam {
+	var blocks []anthropic.ContentBlockParamUnion
+	for _, block := range r.message.Content {
+		if block.Type != "tool_use" {
+			continue
+		}
+		if fe, ok := r.errs[block.ID]; ok {
+			blocks = append(blocks, anthropic.NewToolResultBlock(block.ID, fe.Error(), true))
+		} else {
+			blocks = append(blocks, anthropic.NewToolResultBlock(block.ID, "ok", false))
+		}
+	}
+	blocks = append(blocks, anthropic.NewTextBlock("Panggil ulang HANYA tool yang error di atas dengan input yang sudah diperbaiki."))
+	return anthropic.NewUserMessage(blocks...)
 }
 
-func (s *Service) callAPI(ctx context.Context, systemPrompt, userMessage string) ([]ParsedIntent, error) {
+func (s *Service) callAPI(ctx context.Context, systemPrompt string, messages []anthropic.MessageParam) (*apiResult, error) {
 	message, err := s.client.Messages.New(ctx, anthropic.MessageNewParams{
 		Model:     "claude-haiku-4-5-20251001",
-		MaxTokens: 512,
+		MaxTokens: 1024,
 		System: []anthropic.TextBlockParam{
 			{Text: systemPrompt},
 		},
-		Messages: []anthropic.MessageParam{
-			{
-				Role: anthropic.MessageParamRoleUser,
-				Content: []anthropic.ContentBlockParamUnion{
-					{OfRequestTextBlock: &anthropic.TextBlockParam{Text: userMessage}},
-				},
-			},
-		},
-	})
+		Messages:   messages,
+		ToolChoice: anthropic.ToolChoiceUnionParam{OfToolChoiceAny: &anthropic.ToolChoiceAnyParam{}},
+	}, option.WithJSONSet("tools", s.tools))
 	if err != nil {
 		return nil, fmt.Errorf("anthropic api call: %w", err)
 	}
 
-	if len(message.Content) == 0 {
-		return nil, fmt.Errorf("empty response from api")
+	res := &apiResult{message: message, errs: map[string]*FieldError{}}
+	for _, block := range message.Content {
+		if block.Type != "tool_use" {
+			continue
+		}
+		intent, err := decodeToolCall(block.Name, block.Input)
+		if err != nil {
+			var fe *FieldError
+			if !errors.As(err, &fe) {
+				return nil, fmt.Errorf("decode tool call: %w", err)
+			}
+			res.rejected = append(res.rejected, fe)
+			res.rejectedAt = append(res.rejectedAt, len(res.intents))
+			res.errs[block.ID] = fe
+			continue
+		}
+		res.intents = append(res.intents, intent)
 	}
 
-	text := ""
-	for _, block := range message.Content {
-		if block.Type == "text" {
-			text = block.Text
-			break
+	if len(res.intents) == 0 && len(res.rejected) == 0 {
+		return nil, fmt.Errorf("no tool call in response (stop_reason: %s)", message.StopReason)
+	}
+
+	return res, nil
+}
+
+// conversationPrompt describes recently touched entities so the model can
+// resolve references like "yang tadi" or "-nya".
+func conversationPrompt(conv *Conversation) string {
+	if conv == nil || (len(conv.Turns) == 0 && len(conv.Entities) == 0) {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\nKONTEKS PERCAKAPAN:\n")
+	b.WriteString("- Pesan sebelumnya ada di riwayat chat. Pesan TERAKHIR dari user adalah yang harus diparse\n")
+	b.WriteString("- Jika user merujuk \"yang tadi\", \"itu\", \"-nya\", \"terakhir\", gunakan entitas terbaru di bawah (paling atas = paling baru)\n")
+	b.WriteString("- Jika user hanya menjawab \"id 456\" setelah bot meminta ID, gunakan aksi dari pertanyaan bot tersebut (contoh: \"hapus id\" → delete_expense expense_id=456)\n")
+	if len(conv.Entities) > 0 {
+		b.WriteString("ENTITAS TERAKHIR:\n")
+		for _, e := range conv.Entities {
+			fmt.Fprintf(&b, "- %s #%d: %s\n", e.Kind, e.ID, e.Label)
 		}
 	}
+	return strings.TrimRight(b.String(), "\n")
+}
 
-	// Clean potential markdown wrapping
-	text = strings.TrimSpace(text)
-	text = strings.TrimPrefix(text, "```json")
-	text = strings.TrimPrefix(text, "```")
-	text = strings.TrimSuffix(text, "```")
-	text = strings.TrimSpace(text)
+// conversationMessages builds the message list: prior turns followed by the
+// new user message. The API requires alternating roles starting with "user",
+// so leading assistant turns are dropped and consecutive same-role turns merged.
+func conversationMessages(conv *Conversation, userMessage string) []anthropic.MessageParam {
+	var turns []Turn
+	if conv != nil {
+		turns = append(turns, conv.Turns...)
+	}
+	turns = append(turns, Turn{Role: "user", Text: userMessage})
 
-	var intents []ParsedIntent
-	if err := json.Unmarshal([]byte(text), &intents); err != nil {
-		return nil, fmt.Errorf("parse json response: %w (raw: %s)", err, text)
+	var merged []Turn
+	for _, t := range turns {
+		if len(merged) == 0 && t.Role != "user" {
+			continue
+		}
+		if n := len(merged); n > 0 && merged[n-1].Role == t.Role {
+			merged[n-1].Text += "\n" + t.Text
+			continue
+		}
+		merged = append(merged, t)
 	}
 
-	return intents, nil
+	messages := make([]anthropic.MessageParam, 0, len(merged))
+	for _, t := range merged {
+		role := anthropic.MessageParamRoleUser
+		if t.Role == "assistant" {
+			role = anthropic.MessageParamRoleAssistant
+		}
+		messages = append(messages, anthropic.MessageParam{
+			Role: role,
+			Content: []anthropic.ContentBlockParamUnion{
+				{OfRequestTextBlock: &anthropic.TextBlockParam{Text: t.Text}},
+			},
+		})
+	}
+	return messages
 }
@@ -0,0 +1,240 @@
+package nlp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/nlp"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/nlp/nlptest"
+)
+
+func newTestService(t *testing.T) (*nlp.Service, *nlptest.Server) {
+	t.Helper()
+	srv := nlptest.NewServer()
+	t.Cleanup(srv.Close)
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return nlp.NewService("test", loc, srv.Options()...), srv
+}
+
+func intentNames(intents []nlp.ParsedIntent) []string {
+	names := make([]string, len(intents))
+	for i, in := range intents {
+		names[i] = in.Intent
+	}
+	return names
+}
+
+// lastMessage returns the content blocks of the last message of a recorded
+// request.
+func lastMessage(t *testing.T, body json.RawMessage) []map[string]any {
+	t.Helper()
+	var req struct {
+		Messages []struct {
+			Role    string           `json:"role"`
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	if len(req.Messages) == 0 {
+		t.Fatal("request has no messages")
+	}
+	return req.Messages[len(req.Messages)-1].Content
+}
+
+func TestServiceParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		responses func(srv *nlptest.Server)
+		want      []string
+		requests  int
+		wantErr   string
+	}{
+		{
+			name: "valid tool calls",
+			responses: func(srv *nlptest.Server) {
+				srv.Enqueue(
+					nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "beli susu"}},
+					nlptest.ToolCall{Name: "add_expense", Input: map[string]any{"description": "bensin", "amount": 50000}},
+				)
+			},
+			want:     []string{"add_todo", "add_expense"},
+			requests: 1,
+		},
+		{
+			name: "api error is retried",
+			responses: func(srv *nlptest.Server) {
+				srv.EnqueueError(http.StatusInternalServerError)
+				srv.Enqueue(nlptest.ToolCall{Name: "list_todo"})
+			},
+			want:     []string{"list_todo"},
+			requests: 2,
+		},
+		{
+			name: "unknown field is retried",
+			responses: func(srv *nlptest.Server) {
+				srv.Enqueue(nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "beli susu", "priority": "high"}})
+				srv.Enqueue(nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "beli susu"}})
+			},
+			want:     []string{"add_todo"},
+			requests: 2,
+		},
+		{
+			name: "validation failure keeps the valid intents",
+			responses: func(srv *nlptest.Server) {
+				srv.Enqueue(
+					nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "beli susu"}},
+					nlptest.ToolCall{Name: "add_expense", Input: map[string]any{"description": "bensin", "amount": 0}},
+				)
+				srv.Enqueue(nlptest.ToolCall{Name: "add_expense", Input: map[string]any{"description": "bensin", "amount": 50000}})
+			},
+			want:     []string{"add_todo", "add_expense"},
+			requests: 2,
+		},
+		{
+			name: "retried intent keeps its place",
+			responses: func(srv *nlptest.Server) {
+				srv.Enqueue(
+					nlptest.ToolCall{Name: "delete_todo", Input: map[string]any{"search": "beli susu", "force": true}},
+					nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "beli susu"}},
+				)
+				srv.Enqueue(nlptest.ToolCall{Name: "delete_todo", Input: map[string]any{"search": "beli susu"}})
+			},
+			want:     []string{"delete_todo", "add_todo"},
+			requests: 2,
+		},
+		{
+			name: "retried intents fill each rejected place",
+			responses: func(srv *nlptest.Server) {
+				srv.Enqueue(
+					nlptest.ToolCall{Name: "add_expense", Input: map[string]any{"description": "bensin", "amount": 0}},
+					nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "beli susu"}},
+					nlptest.ToolCall{Name: "delete_todo", Input: map[string]any{"search": "beli roti", "force": true}},
+					nlptest.ToolCall{Name: "list_todo"},
+				)
+				srv.Enqueue(
+					nlptest.ToolCall{Name: "add_expense", Input: map[string]any{"description": "bensin", "amount": 50000}},
+					nlptest.ToolCall{Name: "delete_todo", Input: map[string]any{"search": "beli roti"}},
+				)
+			},
+			want:     []string{"add_expense", "add_todo", "delete_todo", "list_todo"},
+			requests: 2,
+		},
+		{
+			name: "still invalid after the retry",
+			responses: func(srv *nlptest.Server) {
+				srv.Enqueue(nlptest.ToolCall{Name: "add_expense", Input: map[string]any{"description": "bensin", "amount": -5}})
+				srv.Enqueue(nlptest.ToolCall{Name: "add_expense", Input: map[string]any{"description": "bensin", "amount": 0}})
+			},
+			requests: 2,
+			wantErr:  `add_expense: field "amount" must be greater than 0`,
+		},
+		{
+			name: "unknown intent after the retry",
+			responses: func(srv *nlptest.Server) {
+				srv.Enqueue(nlptest.ToolCall{Name: "add_note", Input: map[string]any{"text": "x"}})
+				srv.Enqueue(nlptest.ToolCall{Name: "add_note", Input: map[string]any{"text": "x"}})
+			},
+			requests: 2,
+			wantErr:  `add_note: field "name" is not a known intent`,
+		},
+		{
+			name: "no tool call twice",
+			responses: func(srv *nlptest.Server) {
+				srv.EnqueueText("Halo!")
+				srv.EnqueueText("Halo!")
+			},
+			requests: 2,
+			wantErr:  "no tool call in response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, srv := newTestService(t)
+			tt.responses(srv)
+
+			intents, err := svc.Parse(context.Background(), "pesan", nil)
+			if got := len(srv.Requests()); got != tt.requests {
+				t.Errorf("requests = %d, want %d", got, tt.requests)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got := strings.Join(intentNames(intents), ","); got != strings.Join(tt.want, ",") {
+				t.Errorf("intents = %s, want %s", got, strings.Join(tt.want, ","))
+			}
+		})
+	}
+}
+
+func TestServiceParseRejectedCallIsReported(t *testing.T) {
+	svc, srv := newTestService(t)
+	srv.Enqueue(
+		nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "beli susu"}},
+		nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "cuci mobil", "priority": "high"}},
+	)
+	srv.Enqueue(nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": "cuci mobil"}})
+
+	intents, err := svc.Parse(context.Background(), "tambah todo beli susu dan cuci mobil", nil)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(intents) != 2 || intents[0].Title != "beli susu" || intents[1].Title != "cuci mobil" {
+		t.Fatalf("intents = %+v, want beli susu then cuci mobil", intents)
+	}
+
+	// The retry answers both calls: the valid one as ok, the rejected one
+	// as an error naming the field.
+	requests := srv.Requests()
+	results := map[string]map[string]any{}
+	for _, block := range lastMessage(t, requests[1]) {
+		if block["type"] == "tool_result" {
+			results[block["tool_use_id"].(string)] = block
+		}
+	}
+	if len(results) != 2 {
+		t.Fatalf("tool results = %v, want 2", results)
+	}
+	if isErr, _ := results["toolu_00"]["is_error"].(bool); isErr {
+		t.Errorf("valid call reported as error: %v", results["toolu_00"])
+	}
+	rejected := results["toolu_01"]
+	if isErr, _ := rejected["is_error"].(bool); !isErr {
+		t.Errorf("rejected call not reported as error: %v", rejected)
+	}
+	content, _ := json.Marshal(rejected["content"])
+	if !strings.Contains(string(content), `field \"priority\" is not allowed`) {
+		t.Errorf("rejected content = %s, want the field error", content)
+	}
+}
+
+func TestServiceParseFieldErrorIsReturned(t *testing.T) {
+	svc, srv := newTestService(t)
+	srv.Enqueue(nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": " "}})
+	srv.Enqueue(nlptest.ToolCall{Name: "add_todo", Input: map[string]any{"title": ""}})
+
+	_, err := svc.Parse(context.Background(), "tambah todo", nil)
+	var fe *nlp.FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("err = %v, want a *FieldError", err)
+	}
+	if fe.Intent != "add_todo" || fe.Field != "title" {
+		t.Errorf("FieldError = %+v, want add_todo title", fe)
+	}
+}
@@ -0,0 +1,999 @@
+package nlp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// FieldError reports a tool input that failed decoding or validation.
+type FieldError struct {
+	Intent string
+	Field  string
+	Reason string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: field %q %s", e.Intent, e.Field, e.Reason)
+}
+
+func fieldErr(field, reason string) *FieldError {
+	return &FieldError{Field: field, Reason: reason}
+}
+
+// intentInput is implemented by every per-intent tool input type.
+type intentInput interface {
+	validate() *FieldError
+	toIntent() ParsedIntent
+}
+
+type intentTool struct {
+	name        string
+	description string
+	properties  map[string]any
+	required    []string
+	decode      func(json.RawMessage) (ParsedIntent, error)
+}
+
+// === Per-intent inputs ===
+
+type AddTodoInput struct {
+	Title     string   `json:"title"`
+	Reminder  bool     `json:"reminder,omitempty"`
+	RemindAt  string   `json:"remind_at,omitempty"`
+	Recurring string   `json:"recurring,omitempty"`
+	DueDate   string   `json:"due_date,omitempty"`
+	LeadTimes []string `json:"lead_times,omitempty"`
+	Nag       bool     `json:"nag,omitempty"`
+	NagEvery  int      `json:"nag_every,omitempty"`
+	NagMax    int      `json:"nag_max,omitempty"`
+}
+
+func (in AddTodoInput) validate() *FieldError {
+	if strings.TrimSpace(in.Title) == "" {
+		return fieldErr("title", "is required")
+	}
+	if fe := checkNag(in.NagEvery, in.NagMax); fe != nil {
+		return fe
+	}
+	return checkLeadTimes(in.LeadTimes)
+}
+
+func (in AddTodoInput) toIntent() ParsedIntent {
+	return ParsedIntent{Title: in.Title, Reminder: in.Reminder, RemindAt: in.RemindAt, Recurring: in.Recurring, DueDate: in.DueDate, LeadTimes: in.LeadTimes,
+		Nag: in.Nag, NagEvery: in.NagEvery, NagMax: in.NagMax}
+}
+
+type SearchInput struct {
+	Search string `json:"search"`
+}
+
+func (in SearchInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" {
+		return fieldErr("search", "is required")
+	}
+	return nil
+}
+
+func (in SearchInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search}
+}
+
+type ListTodoInput struct {
+	Filter string `json:"filter,omitempty"`
+}
+
+func (in ListTodoInput) validate() *FieldError {
+	return checkEnum("filter", in.Filter, "all", "today", "pending")
+}
+
+func (in ListTodoInput) toIntent() ParsedIntent {
+	return ParsedIntent{Filter: in.Filter}
+}
+
+type EditTodoInput struct {
+	Search   string `json:"search"`
+	Title    string `json:"title,omitempty"`
+	DueDate  string `json:"due_date,omitempty"`
+	RemindAt string `json:"remind_at,omitempty"`
+}
+
+func (in EditTodoInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" {
+		return fieldErr("search", "is required")
+	}
+	if in.Title == "" && in.DueDate == "" && in.RemindAt == "" {
+		return fieldErr("title", "or due_date/remind_at must be set")
+	}
+	return nil
+}
+
+func (in EditTodoInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search, Title: in.Title, DueDate: in.DueDate, RemindAt: in.RemindAt}
+}
+
+type ReminderCatchUpInput struct {
+	Search  string `json:"search"`
+	CatchUp string `json:"catch_up"`
+}
+
+func (in ReminderCatchUpInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" {
+		return fieldErr("search", "is required")
+	}
+	if in.CatchUp == "" {
+		return fieldErr("catch_up", "is required")
+	}
+	return checkEnum("catch_up", in.CatchUp, "fire", "skip")
+}
+
+func (in ReminderCatchUpInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search, CatchUp: in.CatchUp}
+}
+
+type ReminderNagInput struct {
+	Search   string `json:"search"`
+	Enabled  *bool  `json:"enabled,omitempty"`
+	NagEvery int    `json:"nag_every,omitempty"`
+	NagMax   int    `json:"nag_max,omitempty"`
+}
+
+func (in ReminderNagInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" {
+		return fieldErr("search", "is required")
+	}
+	return checkNag(in.NagEvery, in.NagMax)
+}
+
+func (in ReminderNagInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search, Nag: in.Enabled == nil || *in.Enabled, NagEvery: in.NagEvery, NagMax: in.NagMax}
+}
+
+type AddReminderInput struct {
+	Search    string   `json:"search"`
+	RemindAt  string   `json:"remind_at,omitempty"`
+	Recurring string   `json:"recurring,omitempty"`
+	LeadTimes []string `json:"lead_times,omitempty"`
+}
+
+func (in AddReminderInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" {
+		return fieldErr("search", "is required")
+	}
+	if in.RemindAt == "" && in.Recurring == "" && len(in.LeadTimes) == 0 {
+		return fieldErr("remind_at", "or recurring/lead_times must be set")
+	}
+	return checkLeadTimes(in.LeadTimes)
+}
+
+func (in AddReminderInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search, RemindAt: in.RemindAt, Recurring: in.Recurring, LeadTimes: in.LeadTimes}
+}
+
+type CreateReminderInput struct {
+	Title     string `json:"title"`
+	RemindAt  string `json:"remind_at,omitempty"`
+	Recurring string `json:"recurring,omitempty"`
+	Nag       bool   `json:"nag,omitempty"`
+	NagEvery  int    `json:"nag_every,omitempty"`
+	NagMax    int    `json:"nag_max,omitempty"`
+}
+
+func (in CreateReminderInput) validate() *FieldError {
+	if strings.TrimSpace(in.Title) == "" {
+		return fieldErr("title", "is required")
+	}
+	if in.RemindAt == "" && in.Recurring == "" {
+		return fieldErr("remind_at", "or recurring must be set")
+	}
+	return checkNag(in.NagEvery, in.NagMax)
+}
+
+func (in CreateReminderInput) toIntent() ParsedIntent {
+	return ParsedIntent{Title: in.Title, RemindAt: in.RemindAt, Recurring: in.Recurring, Nag: in.Nag, NagEvery: in.NagEvery, NagMax: in.NagMax}
+}
+
+type EditReminderInput struct {
+	ReminderID int    `json:"reminder_id,omitempty"`
+	Search     string `json:"search,omitempty"`
+	Title      string `json:"title,omitempty"`
+	RemindAt   string `json:"remind_at,omitempty"`
+	Recurring  string `json:"recurring,omitempty"`
+}
+
+func (in EditReminderInput) validate() *FieldError {
+	if in.ReminderID <= 0 && strings.TrimSpace(in.Search) == "" {
+		return fieldErr("search", "or reminder_id is required")
+	}
+	if in.Title == "" && in.RemindAt == "" && in.Recurring == "" {
+		return fieldErr("remind_at", "or title/recurring must be set")
+	}
+	return nil
+}
+
+func (in EditReminderInput) toIntent() ParsedIntent {
+	return ParsedIntent{ReminderID: in.ReminderID, Search: in.Search, Title: in.Title, RemindAt: in.RemindAt, Recurring: in.Recurring}
+}
+
+// ReminderRefInput names one reminder, by its number in the reminder list or
+// by title, for actions that need nothing else.
+type ReminderRefInput struct {
+	ReminderID int    `json:"reminder_id,omitempty"`
+	Search     string `json:"search,omitempty"`
+}
+
+func (in ReminderRefInput) validate() *FieldError {
+	if in.ReminderID <= 0 && strings.TrimSpace(in.Search) == "" {
+		return fieldErr("reminder_id", "or search is required")
+	}
+	return nil
+}
+
+func (in ReminderRefInput) toIntent() ParsedIntent {
+	return ParsedIntent{ReminderID: in.ReminderID, Search: in.Search}
+}
+
+type AddExpenseInput struct {
+	Description string `json:"description"`
+	Amount      int64  `json:"amount"`
+	IsPaid      *bool  `json:"is_paid,omitempty"`
+	Category    string `json:"category,omitempty"`
+	Account     string `json:"account,omitempty"`
+}
+
+func (in AddExpenseInput) validate() *FieldError {
+	if strings.TrimSpace(in.Description) == "" {
+		return fieldErr("description", "is required")
+	}
+	if in.Amount <= 0 {
+		return fieldErr("amount", "must be greater than 0")
+	}
+	return checkEnum("category", in.Category, expenseCategories...)
+}
+
+func (in AddExpenseInput) toIntent() ParsedIntent {
+	return ParsedIntent{Description: in.Description, Amount: in.Amount, IsPaid: in.IsPaid, Category: in.Category, Account: in.Account}
+}
+
+type PayExpenseInput struct {
+	Search    string `json:"search,omitempty"`
+	Amount    int64  `json:"amount,omitempty"`
+	Date      string `json:"date,omitempty"`
+	ExpenseID int    `json:"expense_id,omitempty"`
+}
+
+func (in PayExpenseInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" && in.ExpenseID <= 0 {
+		return fieldErr("search", "or expense_id is required")
+	}
+	if in.Amount < 0 {
+		return fieldErr("amount", "must not be negative")
+	}
+	return nil
+}
+
+func (in PayExpenseInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search, Amount: in.Amount, Date: in.Date, ExpenseID: in.ExpenseID}
+}
+
+type ListExpenseInput struct {
+	Filter string `json:"filter,omitempty"`
+}
+
+func (in ListExpenseInput) validate() *FieldError {
+	return checkEnum("filter", in.Filter, "today", "this_week", "this_month", "all")
+}
+
+func (in ListExpenseInput) toIntent() ParsedIntent {
+	return ParsedIntent{Filter: in.Filter}
+}
+
+type DeleteExpenseInput struct {
+	Search    string `json:"search,omitempty"`
+	Amount    int64  `json:"amount,omitempty"`
+	Date      string `json:"date,omitempty"`
+	ExpenseID int    `json:"expense_id,omitempty"`
+}
+
+func (in DeleteExpenseInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" && in.ExpenseID <= 0 {
+		return fieldErr("search", "or expense_id is required")
+	}
+	return nil
+}
+
+func (in DeleteExpenseInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search, Amount: in.Amount, Date: in.Date, ExpenseID: in.ExpenseID}
+}
+
+type EditExpenseInput struct {
+	Search    string `json:"search,omitempty"`
+	Amount    int64  `json:"amount,omitempty"`
+	Date      string `json:"date,omitempty"`
+	NewTitle  string `json:"new_title,omitempty"`
+	NewIsPaid *bool  `json:"new_is_paid,omitempty"`
+	ExpenseID int    `json:"expense_id,omitempty"`
+}
+
+func (in EditExpenseInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" && in.ExpenseID <= 0 {
+		return fieldErr("search", "or expense_id is required")
+	}
+	if in.NewTitle == "" && in.NewIsPaid == nil {
+		return fieldErr("new_title", "or new_is_paid must be set")
+	}
+	return nil
+}
+
+func (in EditExpenseInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search, Amount: in.Amount, Date: in.Date, NewTitle: in.NewTitle, NewIsPaid: in.NewIsPaid, ExpenseID: in.ExpenseID}
+}
+
+type SetExpenseCategoryInput struct {
+	Search    string `json:"search,omitempty"`
+	Amount    int64  `json:"amount,omitempty"`
+	Date      string `json:"date,omitempty"`
+	ExpenseID int    `json:"expense_id,omitempty"`
+	Category  string `json:"category"`
+}
+
+func (in SetExpenseCategoryInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" && in.ExpenseID <= 0 {
+		return fieldErr("search", "or expense_id is required")
+	}
+	if in.Category == "" {
+		return fieldErr("category", "is required")
+	}
+	return checkEnum("category", in.Category, expenseCategories...)
+}
+
+func (in SetExpenseCategoryInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search, Amount: in.Amount, Date: in.Date, ExpenseID: in.ExpenseID, Category: in.Category}
+}
+
+type AddIncomeInput struct {
+	Description string `json:"description"`
+	Amount      int64  `json:"amount"`
+	Source      string `json:"source,omitempty"`
+	Account     string `json:"account,omitempty"`
+}
+
+func (in AddIncomeInput) validate() *FieldError {
+	if strings.TrimSpace(in.Description) == "" {
+		return fieldErr("description", "is required")
+	}
+	if in.Amount <= 0 {
+		return fieldErr("amount", "must be greater than 0")
+	}
+	return nil
+}
+
+func (in AddIncomeInput) toIntent() ParsedIntent {
+	return ParsedIntent{Description: in.Description, Amount: in.Amount, Source: in.Source, Account: in.Account}
+}
+
+type DeleteIncomeInput struct {
+	Search   string `json:"search,omitempty"`
+	Amount   int64  `json:"amount,omitempty"`
+	IncomeID int    `json:"income_id,omitempty"`
+}
+
+func (in DeleteIncomeInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" && in.IncomeID <= 0 {
+		return fieldErr("search", "or income_id is required")
+	}
+	return nil
+}
+
+func (in DeleteIncomeInput) toIntent() ParsedIntent {
+	return ParsedIntent{Search: in.Search, Amount: in.Amount, IncomeID: in.IncomeID}
+}
+
+type SetAccountInput struct {
+	Account string `json:"account"`
+	Amount  int64  `json:"amount"`
+}
+
+func (in SetAccountInput) validate() *FieldError {
+	if strings.TrimSpace(in.Account) == "" {
+		return fieldErr("account", "is required")
+	}
+	if in.Amount < 0 {
+		return fieldErr("amount", "must not be negative")
+	}
+	return nil
+}
+
+func (in SetAccountInput) toIntent() ParsedIntent {
+	return ParsedIntent{Account: in.Account, Amount: in.Amount}
+}
+
+type TransferInput struct {
+	FromAccount string `json:"from_account"`
+	ToAccount   string `json:"to_account"`
+	Amount      int64  `json:"amount"`
+}
+
+func (in TransferInput) validate() *FieldError {
+	if strings.TrimSpace(in.FromAccount) == "" {
+		return fieldErr("from_account", "is required")
+	}
+	if strings.TrimSpace(in.ToAccount) == "" {
+		return fieldErr("to_account", "is required")
+	}
+	if in.Amount <= 0 {
+		return fieldErr("amount", "must be greater than 0")
+	}
+	return nil
+}
+
+func (in TransferInput) toIntent() ParsedIntent {
+	return ParsedIntent{Account: in.FromAccount, ToAccount: in.ToAccount, Amount: in.Amount}
+}
+
+type AccountRefInput struct {
+	Account string `json:"account,omitempty"`
+}
+
+func (in AccountRefInput) validate() *FieldError { return nil }
+
+func (in AccountRefInput) toIntent() ParsedIntent {
+	return ParsedIntent{Account: in.Account}
+}
+
+type AddDebtInput struct {
+	Person    string `json:"person"`
+	Amount    int64  `json:"amount"`
+	Direction string `json:"direction"`
+	Note      string `json:"note,omitempty"`
+	DueDate   string `json:"due_date,omitempty"`
+}
+
+func (in AddDebtInput) validate() *FieldError {
+	if strings.TrimSpace(in.Person) == "" {
+		return fieldErr("person", "is required")
+	}
+	if in.Amount <= 0 {
+		return fieldErr("amount", "must be greater than 0")
+	}
+	return checkEnum("direction", in.Direction, debtDirections...)
+}
+
+func (in AddDebtInput) toIntent() ParsedIntent {
+	return ParsedIntent{Person: in.Person, Amount: in.Amount, Direction: in.Direction, Description: in.Note, DueDate: in.DueDate}
+}
+
+type PayDebtInput struct {
+	Person    string `json:"person"`
+	Amount    int64  `json:"amount,omitempty"`
+	Direction string `json:"direction"`
+}
+
+func (in PayDebtInput) validate() *FieldError {
+	if strings.TrimSpace(in.Person) == "" {
+		return fieldErr("person", "is required")
+	}
+	if in.Amount < 0 {
+		return fieldErr("amount", "must not be negative")
+	}
+	return checkEnum("direction", in.Direction, debtDirections...)
+}
+
+func (in PayDebtInput) toIntent() ParsedIntent {
+	return ParsedIntent{Person: in.Person, Amount: in.Amount, Direction: in.Direction}
+}
+
+type ListDebtInput struct {
+	Person    string `json:"person,omitempty"`
+	Direction string `json:"direction,omitempty"`
+}
+
+func (in ListDebtInput) validate() *FieldError {
+	return checkEnum("direction", in.Direction, debtDirections...)
+}
+
+func (in ListDebtInput) toIntent() ParsedIntent {
+	return ParsedIntent{Person: in.Person, Direction: in.Direction}
+}
+
+type SetBudgetInput struct {
+	Category string `json:"category"`
+	Amount   int64  `json:"amount"`
+}
+
+func (in SetBudgetInput) validate() *FieldError {
+	if in.Category == "" {
+		return fieldErr("category", "is required")
+	}
+	if in.Amount <= 0 {
+		return fieldErr("amount", "must be greater than 0")
+	}
+	return checkEnum("category", in.Category, expenseCategories...)
+}
+
+func (in SetBudgetInput) toIntent() ParsedIntent {
+	return ParsedIntent{Category: in.Category, Amount: in.Amount}
+}
+
+type BudgetRefInput struct {
+	Category string `json:"category"`
+}
+
+func (in BudgetRefInput) validate() *FieldError {
+	if in.Category == "" {
+		return fieldErr("category", "is required")
+	}
+	return checkEnum("category", in.Category, expenseCategories...)
+}
+
+func (in BudgetRefInput) toIntent() ParsedIntent {
+	return ParsedIntent{Category: in.Category}
+}
+
+type ClearExpenseInput struct {
+	Month int `json:"month"`
+	Year  int `json:"year,omitempty"`
+}
+
+func (in ClearExpenseInput) validate() *FieldError {
+	if in.Month < 1 || in.Month > 12 {
+		return fieldErr("month", "must be between 1 and 12")
+	}
+	if in.Year != 0 && in.Year < 2000 {
+		return fieldErr("year", "must be a four-digit year")
+	}
+	return nil
+}
+
+func (in ClearExpenseInput) toIntent() ParsedIntent {
+	return ParsedIntent{Month: in.Month, Year: in.Year}
+}
+
+type AddProjectInput struct {
+	Name        string `json:"name"`
+	DueDate     string `json:"due_date,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+func (in AddProjectInput) validate() *FieldError {
+	if strings.TrimSpace(in.Name) == "" {
+		return fieldErr("name", "is required")
+	}
+	return nil
+}
+
+func (in AddProjectInput) toIntent() ParsedIntent {
+	return ParsedIntent{Name: in.Name, DueDate: in.DueDate, Description: in.Description}
+}
+
+type AddGoalInput struct {
+	Project   string   `json:"project"`
+	Title     string   `json:"title"`
+	DueDate   string   `json:"due_date,omitempty"`
+	Reminder  bool     `json:"reminder,omitempty"`
+	RemindAt  string   `json:"remind_at,omitempty"`
+	Recurring string   `json:"recurring,omitempty"`
+	LeadTimes []string `json:"lead_times,omitempty"`
+}
+
+func (in AddGoalInput) validate() *FieldError {
+	if strings.TrimSpace(in.Project) == "" {
+		return fieldErr("project", "is required")
+	}
+	if strings.TrimSpace(in.Title) == "" {
+		return fieldErr("title", "is required")
+	}
+	return checkLeadTimes(in.LeadTimes)
+}
+
+func (in AddGoalInput) toIntent() ParsedIntent {
+	return ParsedIntent{Project: in.Project, Title: in.Title, DueDate: in.DueDate, Reminder: in.Reminder, RemindAt: in.RemindAt, Recurring: in.Recurring, LeadTimes: in.LeadTimes}
+}
+
+type GoalSearchInput struct {
+	Project string `json:"project,omitempty"`
+	Search  string `json:"search"`
+}
+
+func (in GoalSearchInput) validate() *FieldError {
+	if strings.TrimSpace(in.Search) == "" {
+		return fieldErr("search", "is required")
+	}
+	return nil
+}
+
+func (in GoalSearchInput) toIntent() ParsedIntent {
+	return ParsedIntent{Project: in.Project, Search: in.Search}
+}
+
+type ProjectInput struct {
+	Project string `json:"project"`
+}
+
+func (in ProjectInput) validate() *FieldError {
+	if strings.TrimSpace(in.Project) == "" {
+		return fieldErr("project", "is required")
+	}
+	return nil
+}
+
+func (in ProjectInput) toIntent() ParsedIntent {
+	return ParsedIntent{Project: in.Project}
+}
+
+type UnknownInput struct {
+	Raw string `json:"raw,omitempty"`
+}
+
+func (in UnknownInput) validate() *FieldError { return nil }
+
+func (in UnknownInput) toIntent() ParsedIntent {
+	return ParsedIntent{Raw: in.Raw}
+}
+
+type UpdateSettingInput struct {
+	Setting  string `json:"setting"`
+	Time     string `json:"time,omitempty"`
+	EndTime  string `json:"end_time,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	Enabled  *bool  `json:"enabled,omitempty"`
+}
+
+var clockPattern = regexp.MustCompile(`^([01]?\d|2[0-3]):[0-5]\d$`)
+
+func (in UpdateSettingInput) validate() *FieldError {
+	if in.Time != "" && !clockPattern.MatchString(in.Time) {
+		return fieldErr("time", "must be HH:MM")
+	}
+	if in.EndTime != "" && !clockPattern.MatchString(in.EndTime) {
+		return fieldErr("end_time", "must be HH:MM")
+	}
+	switch in.Setting {
+	case "timezone":
+		if strings.TrimSpace(in.Timezone) == "" {
+			return fieldErr("timezone", "is required for setting timezone")
+		}
+	case "reminder_hour":
+		if in.Time == "" {
+			return fieldErr("time", "is required for setting reminder_hour")
+		}
+	case "briefing", "overdue", "monthly_report":
+		if in.Time == "" && in.Enabled == nil {
+			return fieldErr("time", "or enabled is required")
+		}
+	case "quiet_hours":
+		if (in.Time == "") != (in.EndTime == "") {
+			return fieldErr("end_time", "must be set together with time")
+		}
+		if in.Time == "" && in.Enabled == nil {
+			return fieldErr("time", "and end_time, or enabled is required")
+		}
+	case "dnd":
+		if in.Time == "" && (in.Enabled == nil || *in.Enabled) {
+			return fieldErr("time", "is required unless enabled is false")
+		}
+	default:
+		return fieldErr("setting", "is not a known setting")
+	}
+	return nil
+}
+
+func (in UpdateSettingInput) toIntent() ParsedIntent {
+	return ParsedIntent{Setting: in.Setting, Time: in.Time, EndTime: in.EndTime, Timezone: in.Timezone, Enabled: in.Enabled}
+}
+
+// NoArgsInput is used by intents that take no parameters.
+type NoArgsInput struct{}
+
+func (in NoArgsInput) validate() *FieldError { return nil }
+
+func (in NoArgsInput) toIntent() ParsedIntent { return ParsedIntent{} }
+
+// === Registry ===
+
+var intentTools = []intentTool{
+	tool[AddTodoInput]("add_todo",
+		"Tambah todo baru. Jika user menyebut jam/waktu, set reminder=true dan remind_at. Jika user minta diingatkan terus (\"ingetin terus sampai aku bilang done\"), set nag=true.",
+		props{"title": str("judul todo"), "reminder": boolean("aktifkan reminder"), "remind_at": str(remindAtDesc), "recurring": str(recurringDesc), "due_date": str(dueDateDesc), "lead_times": strList(leadTimesDesc),
+			"nag": boolean(nagDesc), "nag_every": integer(nagEveryDesc), "nag_max": integer(nagMaxDesc)},
+		"title"),
+	tool[SearchInput]("complete_todo", "Tandai todo selesai. \"done makan mie\" → search=\"makan mie\".",
+		props{"search": str("kata kunci judul todo")}, "search"),
+	tool[ListTodoInput]("list_todo", "Tampilkan daftar todo.",
+		props{"filter": enum("filter daftar todo", "all", "today", "pending")}),
+	tool[SearchInput]("delete_todo", "Hapus satu todo berdasarkan nama.",
+		props{"search": str("kata kunci judul todo")}, "search"),
+	tool[EditTodoInput]("edit_todo", "Edit todo. \"edit todo beli susu jadi beli madu\" → search=\"beli susu\", title=\"beli madu\".",
+		props{"search": str("kata kunci judul todo lama"), "title": str("judul baru"), "due_date": str(dueDateDesc), "remind_at": str(remindAtDesc)},
+		"search"),
+	tool[NoArgsInput]("clear_todo",
+		"HANYA jika user ingin menghapus/mengosongkan semua todo sekaligus tanpa menyebut nama spesifik: \"kosongkan todo\", \"hapus semua todo\". JANGAN gunakan jika user menyebut nama todo tertentu.",
+		props{}),
+	tool[NoArgsInput]("list_trash", "Tampilkan todo yang sudah dihapus (sampah): \"lihat sampah\", \"todo apa saja yang dihapus\".", props{}),
+	tool[SearchInput]("restore_todo",
+		"Kembalikan satu todo/goal dari sampah berdasarkan nama: \"pulihkan todo beli susu\" → search=\"beli susu\". Untuk membatalkan penghapusan massal terakhir tanpa nama, gunakan undo.",
+		props{"search": str("kata kunci judul todo")}, "search"),
+	tool[AddExpenseInput]("add_expense",
+		"Catat pengeluaran. Default is_paid=true. Set is_paid=false jika user bilang \"hutang\", \"belum bayar\", \"belum lunas\", \"cicilan\". JANGAN gunakan untuk \"lunasi X\" atau \"bayar hutang X\" (itu pay_expense).",
+		props{"description": str("deskripsi pengeluaran"), "amount": integer("nominal dalam rupiah, \"35rb\"=35000, \"1.5jt\"=1500000"), "is_paid": boolean("status lunas"), "category": enum(categoryDesc, expenseCategories...), "account": str(accountDesc)},
+		"description", "amount"),
+	tool[PayExpenseInput]("pay_expense",
+		"Tandai pengeluaran lunas. \"lunasi beli kecap 20rb\" → search=\"beli kecap\", amount=20000. \"lunasi beli kecap 14 feb\" → search=\"beli kecap\", date=YYYY-MM-DD. \"lunasi id 123\" → expense_id=123.",
+		props{"search": str("kata kunci deskripsi"), "amount": integer("nominal untuk membedakan"), "date": str(dateDesc), "expense_id": integer("ID pengeluaran jika disebut langsung")}),
+	tool[ListExpenseInput]("list_expense", "Tampilkan daftar pengeluaran.",
+		props{"filter": enum("periode", "today", "this_week", "this_month", "all")}),
+	tool[DeleteExpenseInput]("delete_expense",
+		"Hapus pengeluaran. \"hapus beli kecap 100rb\" → search=\"beli kecap\", amount=100000. \"hapus id 123\" → expense_id=123.",
+		props{"search": str("kata kunci deskripsi"), "amount": integer("nominal untuk membedakan"), "date": str(dateDesc), "expense_id": integer("ID pengeluaran jika disebut langsung")}),
+	tool[EditExpenseInput]("edit_expense",
+		"Edit judul atau status pengeluaran. \"ganti nama bensin jadi bensin motor\" → search=\"bensin\", new_title=\"bensin motor\". \"tandai beli kecap 20rb sudah lunas\" → search=\"beli kecap\", amount=20000, new_is_paid=true.",
+		props{"search": str("kata kunci deskripsi"), "amount": integer("nominal untuk membedakan"), "date": str(dateDesc), "new_title": str("deskripsi baru"), "new_is_paid": boolean("status lunas baru"), "expense_id": integer("ID pengeluaran jika disebut langsung")}),
+	tool[SetExpenseCategoryInput]("set_expense_category",
+		"Ubah kategori pengeluaran. \"ubah kategori bensin jadi Transport\" → search=\"bensin\", category=\"Transport\". \"ubah kategori id 123 jadi Makan\" → expense_id=123, category=\"Makan\".",
+		props{"search": str("kata kunci deskripsi"), "amount": integer("nominal untuk membedakan"), "date": str(dateDesc), "expense_id": integer("ID pengeluaran jika disebut langsung"), "category": enum("kategori baru", expenseCategories...)},
+		"category"),
+	tool[AddIncomeInput]("add_income",
+		"Catat pemasukan (uang masuk): gaji, bonus, THR, transfer masuk. \"catat gaji 8jt\" → description=\"gaji\", amount=8000000. \"terima transfer 500rb dari Budi\" → description=\"transfer\", amount=500000, source=\"Budi\". JANGAN gunakan add_expense untuk uang masuk.",
+		props{"description": str("deskripsi pemasukan"), "amount": integer("nominal dalam rupiah"), "source": str("dari siapa uangnya, jika disebut"), "account": str("akun tujuan uangnya, jika disebut: \"gaji 8jt masuk ke BCA\" → account=\"BCA\"")},
+		"description", "amount"),
+	tool[DeleteIncomeInput]("delete_income",
+		"Hapus pemasukan. \"hapus pemasukan bonus\" → search=\"bonus\". \"hapus pemasukan id 12\" → income_id=12.",
+		props{"search": str("kata kunci deskripsi atau sumber"), "amount": integer("nominal untuk membedakan"), "income_id": integer("ID pemasukan jika disebut langsung")}),
+	tool[ListExpenseInput]("list_income", "Tampilkan daftar pemasukan saja.",
+		props{"filter": enum("periode", "today", "this_week", "this_month", "all")}),
+	tool[SetAccountInput]("set_account",
+		"Buat akun (cash, rekening bank, e-wallet) atau ubah saldo awalnya. \"saldo awal BCA 5jt\" → account=\"BCA\", amount=5000000.",
+		props{"account": str("nama akun"), "amount": integer("saldo awal dalam rupiah")},
+		"account", "amount"),
+	tool[TransferInput]("transfer",
+		"Pindahkan uang antar akun sendiri; bukan pengeluaran. \"topup gopay 200rb dari BCA\" → from_account=\"BCA\", to_account=\"gopay\", amount=200000. \"tarik tunai 500rb dari BCA\" → from_account=\"BCA\", to_account=\"cash\".",
+		props{"from_account": str("akun asal"), "to_account": str("akun tujuan"), "amount": integer("nominal dalam rupiah")},
+		"from_account", "to_account", "amount"),
+	tool[AccountRefInput]("show_balance", "Tampilkan saldo akun: \"saldo\", \"cek saldo\" (semua akun), \"saldo gopay\" → account=\"gopay\".",
+		props{"account": str("nama akun; kosong untuk semua akun")}),
+	tool[AddDebtInput]("add_debt",
+		"Catat hutang piutang dengan seseorang. \"Budi pinjam 200rb\" → person=\"Budi\", amount=200000, direction=they_owe. \"aku hutang ke Sari 1jt, bayar tanggal 25\" → person=\"Sari\", amount=1000000, direction=i_owe, due_date. Untuk hutang tagihan tanpa nama orang (\"catat hutang sewa kos 1.5jt\") gunakan add_expense.",
+		props{"person": str("nama orangnya"), "amount": integer("nominal dalam rupiah"), "direction": enum(directionDesc, debtDirections...), "note": str("untuk apa, jika disebut"), "due_date": str("jatuh tempo YYYY-MM-DD, jika disebut")},
+		"person", "amount", "direction"),
+	tool[PayDebtInput]("pay_debt",
+		"Catat pembayaran hutang piutang dengan seseorang. \"Budi bayar 50rb\" → person=\"Budi\", amount=50000, direction=they_owe. \"aku bayar Sari 500rb\" → person=\"Sari\", amount=500000, direction=i_owe. \"Budi lunas\" → person=\"Budi\", direction=they_owe, tanpa amount.",
+		props{"person": str("nama orangnya"), "amount": integer("nominal yang dibayar; kosongkan jika lunas semua"), "direction": enum(directionDesc, debtDirections...)},
+		"person", "direction"),
+	tool[ListDebtInput]("list_debt",
+		"Tampilkan hutang piutang. \"hutang piutang\" → semua. \"siapa saja yang hutang ke aku\" → direction=they_owe. \"hutangku apa saja\" → direction=i_owe. \"hutang Budi\" → person=\"Budi\" (riwayat dengan satu orang).",
+		props{"person": str("nama orangnya, untuk riwayat satu orang"), "direction": enum(directionDesc, debtDirections...)}),
+	tool[SetBudgetInput]("set_budget",
+		"Atur budget bulanan per kategori. \"budget makan 2jt per bulan\" → category=\"Makan\", amount=2000000.",
+		props{"category": enum("kategori pengeluaran", expenseCategories...), "amount": integer("budget per bulan dalam rupiah")},
+		"category", "amount"),
+	tool[BudgetRefInput]("delete_budget", "Hapus budget satu kategori. \"hapus budget makan\" → category=\"Makan\".",
+		props{"category": enum("kategori pengeluaran", expenseCategories...)}, "category"),
+	tool[NoArgsInput]("show_budget", "Tampilkan sisa budget bulan ini: \"sisa budget\", \"lihat budget\", \"budget masih berapa\".", props{}),
+	tool[ClearExpenseInput]("clear_expense",
+		"Hapus semua pengeluaran di bulan tertentu. \"kosongkan februari 2026\" → month=2, year=2026. Year boleh kosong jika tidak disebut.",
+		props{"month": integer("bulan 1-12"), "year": integer("tahun, misal 2026")},
+		"month"),
+	tool[AddProjectInput]("add_project", "Buat project baru.",
+		props{"name": str("nama project"), "due_date": str(dueDateDesc), "description": str("deskripsi project")},
+		"name"),
+	tool[AddGoalInput]("add_goal",
+		"Tambah goal ke project. Jika bulk: tiap goal = 1 panggilan dengan project yang sama.",
+		props{"project": str("nama project"), "title": str("judul goal"), "due_date": str(dueDateDesc), "reminder": boolean("aktifkan reminder"), "remind_at": str(remindAtDesc), "recurring": str(recurringDesc), "lead_times": strList(leadTimesDesc)},
+		"project", "title"),
+	tool[GoalSearchInput]("complete_goal", "Tandai goal selesai. project boleh kosong jika user tidak menyebutkan project.",
+		props{"project": str("nama project"), "search": str("kata kunci judul goal")}, "search"),
+	tool[NoArgsInput]("list_project", "Tampilkan semua project: \"list project\", \"project apa saja\".", props{}),
+	tool[ProjectInput]("show_project", "Tampilkan detail + goals satu project: \"progress X\", \"goals X\", \"lihat project X\".",
+		props{"project": str("nama project")}, "project"),
+	tool[ProjectInput]("delete_project", "Hapus project beserta semua goals.",
+		props{"project": str("nama project")}, "project"),
+	tool[GoalSearchInput]("delete_goal", "Hapus goal. project boleh kosong jika user tidak menyebutkan project.",
+		props{"project": str("nama project"), "search": str("kata kunci judul goal")}, "search"),
+	tool[NoArgsInput]("daily_briefing", "Rangkuman harian: \"briefing\", \"apa yang harus dikerjakan hari ini\".", props{}),
+	tool[NoArgsInput]("list_reminder", "Tampilkan semua reminder aktif: \"list reminder\", \"reminder apa saja\".", props{}),
+	tool[ReminderNagInput]("set_reminder_nag",
+		"Ulangi reminder (todo atau reminder mandiri) sampai user menandai selesai atau menekan Oke. \"reminder minum obat ingetin terus tiap 10 menit\" → search=\"minum obat\", nag_every=10. \"reminder bayar listrik jangan diulang lagi\" → search=\"bayar listrik\", enabled=false.",
+		props{"search": str("kata kunci judul todo atau reminder"), "enabled": boolean("false untuk berhenti mengulang"), "nag_every": integer(nagEveryDesc), "nag_max": integer(nagMaxDesc)},
+		"search"),
+	tool[AddReminderInput]("add_reminder",
+		"Tambah reminder ke todo yang sudah ada; satu todo boleh punya banyak reminder. \"ingetin beli susu 1 jam dan 1 hari sebelum deadline\" → search=\"beli susu\", lead_times=[\"-1h\",\"-1d\"]. \"tambah reminder laporan jam 3 sore\" → search=\"laporan\", remind_at.",
+		props{"search": str("kata kunci judul todo"), "remind_at": str(remindAtDesc), "recurring": str(recurringDesc), "lead_times": strList(leadTimesDesc)},
+		"search"),
+	tool[CreateReminderInput]("create_reminder",
+		"Buat reminder mandiri (bukan todo) untuk hal yang cukup diingatkan: \"ingetin minum obat jam 9\" → title=\"minum obat\", remind_at. \"ingetin standup tiap hari kerja jam 9\" → title=\"standup\", recurring. Gunakan add_todo hanya jika user bilang \"todo\" atau menyebut deadline.",
+		props{"title": str("apa yang diingatkan"), "remind_at": str(remindAtDesc), "recurring": str(recurringDesc),
+			"nag": boolean(nagDesc), "nag_every": integer(nagEveryDesc), "nag_max": integer(nagMaxDesc)},
+		"title"),
+	tool[EditReminderInput]("edit_reminder",
+		"Ubah reminder (todo atau reminder mandiri): \"ubah reminder minum obat jadi jam 8\" → search=\"minum obat\", remind_at. \"ganti reminder #12 jadi tiap senin\" → reminder_id=12, recurring. \"reminder bayar wifi ubah jadi tiap tanggal 10\" → search=\"bayar wifi\", recurring (tanpa remind_at jika jam tidak disebut). \"ganti nama reminder obat jadi vitamin\" → search=\"obat\", title=\"vitamin\".",
+		props{"reminder_id": integer("nomor reminder dari daftar reminder"), "search": str("kata kunci judul reminder"), "title": str("judul baru"), "remind_at": str(remindAtDesc), "recurring": str(recurringDesc)}),
+	tool[ReminderRefInput]("delete_reminder",
+		"Hapus satu reminder: \"hapus reminder #12\" → reminder_id=12, \"hapus reminder minum obat\" → search=\"minum obat\". Todo-nya tidak ikut dihapus.",
+		props{"reminder_id": integer("nomor reminder dari daftar reminder"), "search": str("kata kunci judul reminder")}),
+	tool[ReminderRefInput]("pause_reminder",
+		"Jeda reminder sementara sampai dilanjutkan: \"jeda reminder bayar wifi\", \"pause reminder #12\", \"reminder minum obat libur dulu\".",
+		props{"reminder_id": integer("nomor reminder dari daftar reminder"), "search": str("kata kunci judul reminder atau todo-nya")}),
+	tool[ReminderRefInput]("resume_reminder",
+		"Lanjutkan reminder yang dijeda: \"lanjutkan reminder bayar wifi\", \"aktifkan lagi reminder #12\".",
+		props{"reminder_id": integer("nomor reminder dari daftar reminder"), "search": str("kata kunci judul reminder atau todo-nya")}),
+	tool[ReminderRefInput]("cancel_reminder",
+		"Hentikan reminder (termasuk seri berulangnya) untuk seterusnya; todo-nya tetap ada: \"stop reminder bayar wifi\", \"berhenti ingetin minum obat\", \"batalkan reminder #12\".",
+		props{"reminder_id": integer("nomor reminder dari daftar reminder"), "search": str("kata kunci judul reminder atau todo-nya")}),
+	tool[ReminderRefInput]("skip_reminder",
+		"Lewati satu kali kiriman berikutnya dari reminder berulang: \"skip reminder bayar wifi bulan ini\" → search=\"bayar wifi\", \"lewati reminder standup besok\" → search=\"standup\".",
+		props{"reminder_id": integer("nomor reminder dari daftar reminder"), "search": str("kata kunci judul reminder atau todo-nya")}),
+	tool[ReminderCatchUpInput]("set_reminder_catchup",
+		"Atur apa yang terjadi jika reminder (todo atau reminder mandiri) terlewat (misal bot sedang mati). \"reminder minum obat kalau kelewat skip aja\" → search=\"minum obat\", catch_up=skip. \"reminder bayar listrik tetap kirim walau telat\" → search=\"bayar listrik\", catch_up=fire.",
+		props{
+			"search":   str("kata kunci judul todo atau reminder"),
+			"catch_up": enum("fire = tetap kirim terlambat (default), skip = lewati reminder yang terlewat", "fire", "skip"),
+		}, "search", "catch_up"),
+	tool[NoArgsInput]("show_settings", "Tampilkan pengaturan user: \"pengaturan\", \"settings\".", props{}),
+	tool[UpdateSettingInput]("update_setting",
+		"Ubah pengaturan. \"ganti briefing jam 6 pagi\" → setting=briefing, time=\"06:00\". \"matikan laporan bulanan\" → setting=monthly_report, enabled=false. \"ganti timezone ke WITA\" → setting=timezone, timezone=\"WITA\". \"reminder default jam 8\" → setting=reminder_hour, time=\"08:00\". \"jangan ganggu jam 10 malam sampai 6 pagi\" → setting=quiet_hours, time=\"22:00\", end_time=\"06:00\". \"jangan ganggu sampai jam 3\" → setting=dnd, time=\"15:00\" (sekali saja, sampai jam itu berikutnya). \"matikan mode jangan ganggu\" → setting=dnd, enabled=false.",
+		props{
+			"setting":  enum("pengaturan yang diubah; briefing = daily briefing, overdue = pengingat todo lewat deadline, monthly_report = laporan pengeluaran bulanan, quiet_hours = jam tenang harian (pesan otomatis ditahan), dnd = jangan ganggu sementara sampai jam tertentu", "timezone", "reminder_hour", "briefing", "overdue", "monthly_report", "quiet_hours", "dnd"),
+			"time":     str("jam baru HH:MM (24 jam); untuk quiet_hours = jam mulai, untuk dnd = sampai jam berapa"),
+			"end_time": str("quiet_hours: jam selesai HH:MM"),
+			"timezone": str("WIB, WITA, WIT, atau nama IANA seperti Asia/Jakarta"),
+			"enabled":  boolean("nyalakan (true) atau matikan (false) pesan terjadwal"),
+		},
+		"setting"),
+	tool[NoArgsInput]("confirm", "User menyetujui aksi yang menunggu konfirmasi: \"ya\", \"iya\", \"lanjut\".", props{}),
+	tool[NoArgsInput]("cancel", "User menolak aksi yang menunggu konfirmasi: \"tidak\", \"batal\", \"jangan\".", props{}),
+	tool[NoArgsInput]("undo", "User ingin membatalkan penghapusan massal terakhir: \"undo\", \"batalkan\", \"kembalikan yang tadi dihapus\".", props{}),
+	tool[NoArgsInput]("help", "User minta bantuan.", props{}),
+	tool[UnknownInput]("unknown", "Pesan tidak bisa dipahami.",
+		props{"raw": str("pesan asli")}),
+}
+
+const (
+	remindAtDesc  = "jam lokal user tanpa offset, misal 2026-02-13T07:00:00"
+	dueDateDesc   = "YYYY-MM-DD"
+	dateDesc      = "tanggal pencatatan YYYY-MM-DD"
+	leadTimesDesc = "reminder relatif terhadap due_date: -30m, -1h, -1d, -1w (tanda minus = sebelum deadline); hanya untuk todo/goal yang punya deadline"
+	nagDesc       = "ulangi reminder sampai user menandai selesai atau menekan Oke"
+	nagEveryDesc  = "jeda antar pengulangan dalam menit (default 15)"
+	nagMaxDesc    = "maksimal pengulangan (default 8)"
+	recurringDesc = "RRULE RFC 5545, misal FREQ=DAILY | FREQ=WEEKLY;BYDAY=MO | FREQ=MONTHLY;BYMONTHDAY=-1 | FREQ=MONTHLY;BYDAY=1MO; boleh INTERVAL, COUNT, UNTIL, baris EXDATE, dan X-NONWORKDAY=SKIP|PREV|NEXT untuk hari libur"
+	categoryDesc  = "kategori pengeluaran; kosongkan jika tidak jelas"
+	directionDesc = "they_owe = orang itu hutang ke user (piutang), i_owe = user hutang ke orang itu"
+	accountDesc   = "akun pembayaran jika disebut (\"pakai gopay\", \"via BCA\"), misal \"gopay\""
+)
+
+// debtDirections are who owes whom in a debt: the person owes the user, or
+// the user owes the person.
+var debtDirections = []string{"they_owe", "i_owe"}
+
+// expenseCategories are the default expense categories seeded by the
+// migrations.
+var expenseCategories = []string{"Makan", "Transport", "Tagihan", "Belanja", "Hiburan", "Kesehatan"}
+
+type props map[string]any
+
+func str(desc string) map[string]any {
+	return map[string]any{"type": "string", "description": desc}
+}
+
+func strList(desc string) map[string]any {
+	return map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": desc}
+}
+
+func integer(desc string) map[string]any {
+	return map[string]any{"type": "integer", "description": desc}
+}
+
+func boolean(desc string) map[string]any {
+	return map[string]any{"type": "boolean", "description": desc}
+}
+
+func enum(desc string, values ...string) map[string]any {
+	return map[string]any{"type": "string", "description": desc, "enum": values}
+}
+
+func tool[T intentInput](name, description string, properties props, required ...string) intentTool {
+	return intentTool{
+		name:        name,
+		description: description,
+		properties:  properties,
+		required:    required,
+		decode: func(raw json.RawMessage) (ParsedIntent, error) {
+			var in T
+			if fe := decodeStrict(raw, &in); fe != nil {
+				fe.Intent = name
+				return ParsedIntent{}, fe
+			}
+			if fe := in.validate(); fe != nil {
+				fe.Intent = name
+				return ParsedIntent{}, fe
+			}
+			p := in.toIntent()
+			p.Intent = name
+			return p, nil
+		},
+	}
+}
+
+// toolDefinitions returns the tool definitions sent with every Messages request.
+// They are injected as raw JSON because the SDK's typed schema param cannot
+// express "required".
+func toolDefinitions() []map[string]any {
+	tools := make([]map[string]any, 0, len(intentTools))
+	for _, t := range intentTools {
+		schema := map[string]any{"type": "object", "properties": t.properties}
+		if len(t.required) > 0 {
+			schema["required"] = t.required
+		}
+		tools = append(tools, map[string]any{
+			"name":         t.name,
+			"description":  t.description,
+			"input_schema": schema,
+		})
+	}
+	return tools
+}
+
+// decodeToolCall decodes a tool_use block into a validated ParsedIntent.
+func decodeToolCall(name string, input json.RawMessage) (ParsedIntent, error) {
+	for _, t := range intentTools {
+		if t.name == name {
+			return t.decode(input)
+		}
+	}
+	return ParsedIntent{}, &FieldError{Intent: name, Field: "name", Reason: "is not a known intent"}
+}
+
+func decodeStrict(raw json.RawMessage, v any) *FieldError {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		raw = json.RawMessage("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fieldErr(typeErr.Field, fmt.Sprintf("must be %s, got %s", typeErr.Type, typeErr.Value))
+	}
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fieldErr(strings.Trim(name, `"`), "is not allowed")
+	}
+	return fieldErr("input", err.Error())
+}
+
+func checkNag(every, max int) *FieldError {
+	if every < 0 {
+		return fieldErr("nag_every", "must not be negative")
+	}
+	if max < 0 {
+		return fieldErr("nag_max", "must not be negative")
+	}
+	return nil
+}
+
+func checkLeadTimes(leads []string) *FieldError {
+	for _, l := range leads {
+		if _, err := ParseLeadTime(l); err != nil {
+			return fieldErr("lead_times", fmt.Sprintf("has invalid entry %q, use e.g. -1h or -1d", l))
+		}
+	}
+	return nil
+}
+
+func checkEnum(field, value string, allowed ...string) *FieldError {
+	if value == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+	return fieldErr(field, fmt.Sprintf("must be one of %s", strings.Join(allowed, ", ")))
+}
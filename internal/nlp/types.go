@@ -2,6 +2,8 @@ package nlp
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"time"
 )
 
@@ -26,7 +28,47 @@ type ParsedIntent struct {
 	Year        int     `json:"year,omitempty"`       // e.g. 2026, for clear_expense
 	NewTitle    string  `json:"new_title,omitempty"`  // edit_expense: new description
 	NewIsPaid   *bool   `json:"new_is_paid,omitempty"` // edit_expense: new paid status
-	ExpenseID   int     `json:"expense_id,omitempty"` // direct ID reference for delete/edit
+	ExpenseID   int     `json:"expense_id,omitempty"` // direct ID reference for pay/delete/edit
+	Category    string  `json:"category,omitempty"`   // add_expense / set_expense_category: expense category name
+	Source      string  `json:"source,omitempty"`     // add_income: who the money came from
+	IncomeID    int     `json:"income_id,omitempty"`  // direct ID reference for delete_income
+	Account     string  `json:"account,omitempty"`    // add_expense / add_income / set_account / show_balance; transfer: from account
+	ToAccount   string  `json:"to_account,omitempty"` // transfer: to account
+	Person      string  `json:"person,omitempty"`     // add_debt / pay_debt / list_debt: the other party
+	Direction   string  `json:"direction,omitempty"`  // add_debt / pay_debt / list_debt: they_owe | i_owe
+	GoalID      int     `json:"goal_id,omitempty"`    // direct ID reference set by disambiguation buttons
+	// Settings-specific fields
+	Setting     string  `json:"setting,omitempty"`    // update_setting: timezone | reminder_hour | briefing | overdue | monthly_report | quiet_hours | dnd
+	Time        string  `json:"time,omitempty"`       // update_setting: new time "HH:MM"
+	Timezone    string  `json:"timezone,omitempty"`   // update_setting: WIB/WITA/WIT or IANA name
+	Enabled     *bool   `json:"enabled,omitempty"`    // update_setting: switch a scheduled message on/off
+	EndTime     string  `json:"end_time,omitempty"`   // update_setting: end of quiet_hours "HH:MM"
+	// Reminder-specific fields
+	CatchUp     string  `json:"catch_up,omitempty"`   // set_reminder_catchup: fire | skip
+	LeadTimes   []string `json:"lead_times,omitempty"` // reminders relative to due_date, e.g. "-1h", "-1d"
+	ReminderID  int     `json:"reminder_id,omitempty"` // edit/delete/pause/resume/cancel/skip_reminder: ID from the reminder list
+	Nag         bool    `json:"nag,omitempty"`        // repeat the reminder until acknowledged
+	NagEvery    int     `json:"nag_every,omitempty"`  // minutes between repeats
+	NagMax      int     `json:"nag_max,omitempty"`    // maximum number of repeats
+}
+
+// Conversation is the recent exchange with a user, used to resolve follow-ups
+// such as "hapus yang tadi" or a bare "id 456".
+type Conversation struct {
+	Turns    []Turn
+	Entities []EntityRef
+}
+
+type Turn struct {
+	Role string // "user" or "assistant"
+	Text string
+}
+
+// EntityRef is something the bot recently touched, most recent first.
+type EntityRef struct {
+	Kind  string // "todo", "goal", "expense", "income", "project"
+	ID    int
+	Label string
 }
 
 func (p *ParsedIntent) ParseDate(loc *time.Location) (*time.Time, error) {
@@ -44,7 +86,9 @@ func (p *ParsedIntent) ParseRemindAt(loc *time.Location) (*time.Time, error) {
 	if p.RemindAt == "" {
 		return nil, nil
 	}
-	// Try RFC3339 first (e.g. 2026-02-13T23:18:00+07:00)
+	// Try RFC3339 first (e.g. 2026-02-13T23:18:00+07:00). The parser asks for
+	// local wall time without an offset, since a fixed offset is wrong for
+	// dates on the other side of a DST change.
 	if t, err := time.Parse(time.RFC3339, p.RemindAt); err == nil {
 		t = t.In(loc)
 		return &t, nil
@@ -76,3 +120,42 @@ func (p *ParsedIntent) ParseDueDate(loc *time.Location) (*time.Time, error) {
 	}
 	return &t, nil
 }
+
+var leadTimePattern = regexp.MustCompile(`^([+-]?)(\d+)([mhdw])$`)
+
+var leadTimeUnits = map[string]time.Duration{
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseLeadTime parses a reminder offset relative to a due date: "-1h" is one
+// hour before, "+30m" thirty minutes after. Without a sign it is before.
+func ParseLeadTime(s string) (time.Duration, error) {
+	m := leadTimePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("unsupported lead time format: %s", s)
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return 0, fmt.Errorf("unsupported lead time format: %s", s)
+	}
+	d := time.Duration(n) * leadTimeUnits[m[3]]
+	if m[1] != "+" {
+		d = -d
+	}
+	return d, nil
+}
+
+func (p *ParsedIntent) ParseLeadTimes() ([]time.Duration, error) {
+	leads := make([]time.Duration, 0, len(p.LeadTimes))
+	for _, s := range p.LeadTimes {
+		d, err := ParseLeadTime(s)
+		if err != nil {
+			return nil, err
+		}
+		leads = append(leads, d)
+	}
+	return leads, nil
+}
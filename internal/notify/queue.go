@@ -0,0 +1,314 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	batchSeparator = "\n\n─────────────\n\n"
+
+	// maxMessageLen is Telegram's limit on a message, in UTF-16 code units.
+	maxMessageLen = 4096
+
+	// flushLease is how long a replica has to send the messages it claimed
+	// before another may take them over.
+	flushLease = 2 * time.Minute
+)
+
+// Queue is the single way proactive messages reach users. Messages due while
+// the user is in quiet hours or do-not-disturb are held back and sent
+// together once it ends.
+type Queue struct {
+	repo        *Repository
+	bot         *tele.Bot
+	settingsSvc *settings.Service
+	interval    time.Duration
+	now         func() time.Time
+	stopCh      chan struct{}
+	once        sync.Once
+}
+
+// NewQueue flushes held-back messages every interval.
+func NewQueue(repo *Repository, bot *tele.Bot, settingsSvc *settings.Service, interval time.Duration) *Queue {
+	return &Queue{
+		repo:        repo,
+		bot:         bot,
+		settingsSvc: settingsSvc,
+		interval:    interval,
+		now:         time.Now,
+		stopCh:      make(chan struct{}),
+	}
+}
+
+// Send delivers text to the user now, or holds it back when they may not be
+// messaged and reports deferred. Send errors are returned as is, so callers
+// can retry or give up the way they would with bot.Send.
+func (q *Queue) Send(ctx context.Context, userID int64, kind, text string, markup *tele.ReplyMarkup) (deferred bool, err error) {
+	us := q.settingsSvc.Resolve(ctx, userID)
+	now := q.now()
+	if until := us.AfterQuiet(now); until.After(now) {
+		if err := q.repo.Enqueue(ctx, userID, kind, text, markup); err != nil {
+			return false, err
+		}
+		slog.Info("message held back", "user_id", userID, "kind", kind, "until", until)
+		return true, nil
+	}
+
+	_, err = q.bot.Send(&tele.User{ID: userID}, text, markup)
+	return false, err
+}
+
+func (q *Queue) Start() {
+	slog.Info("notification queue started", "interval", q.interval)
+	ticker := time.NewTicker(q.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.flush()
+		case <-q.stopCh:
+			slog.Info("notification queue stopped")
+			return
+		}
+	}
+}
+
+func (q *Queue) Stop() {
+	q.once.Do(func() { close(q.stopCh) })
+}
+
+// flush sends the held-back messages of every user who may be messaged again.
+func (q *Queue) flush() {
+	ctx := context.Background()
+	userIDs, err := q.repo.Users(ctx)
+	if err != nil {
+		slog.Error("failed to list held-back messages", "error", err)
+		return
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+	all, err := q.settingsSvc.ForUsers(ctx, userIDs)
+	if err != nil {
+		slog.Error("failed to load settings for held-back messages", "error", err)
+		return
+	}
+
+	now := q.now()
+	for _, userID := range userIDs {
+		if all[userID].AfterQuiet(now).After(now) {
+			continue
+		}
+		if err := q.flushUser(ctx, userID); err != nil {
+			slog.Error("failed to send held-back messages", "user_id", userID, "error", err)
+		}
+	}
+}
+
+// flushUser sends the user's held-back messages. Each is deleted once the
+// Telegram message that completes it is sent; on a send error the rest are
+// retried or dead-lettered.
+func (q *Queue) flushUser(ctx context.Context, userID int64) error {
+	msgs, err := q.repo.Claim(ctx, userID, flushLease)
+	if err != nil || len(msgs) == 0 {
+		return err
+	}
+
+	user := &tele.User{ID: userID}
+	sent := make(map[int]bool, len(msgs))
+	for _, o := range planBatch(msgs) {
+		if _, err := q.bot.Send(user, o.text, o.markup); err != nil {
+			var unsent []Message
+			for _, m := range msgs {
+				if !sent[m.ID] {
+					unsent = append(unsent, m)
+				}
+			}
+			q.handleSendError(ctx, userID, unsent, err)
+			return err
+		}
+		if len(o.done) == 0 {
+			continue
+		}
+		if err := q.repo.Delete(ctx, o.done); err != nil {
+			return err
+		}
+		for _, id := range o.done {
+			sent[id] = true
+		}
+	}
+	slog.Info("held-back messages sent", "user_id", userID, "count", len(msgs))
+	return nil
+}
+
+// handleSendError retries messages that failed with exponential backoff, or
+// Telegram's retry-after, and dead-letters them when the error is permanent
+// or they are out of attempts.
+func (q *Queue) handleSendError(ctx context.Context, userID int64, msgs []Message, sendErr error) {
+	ids := make([]int, len(msgs))
+	attempts := 0
+	for i, m := range msgs {
+		ids[i] = m.ID
+		attempts = max(attempts, m.Attempts+1)
+	}
+
+	f := ClassifySendError(sendErr)
+	if f.Permanent || (f.Wait == 0 && attempts >= maxFlushAttempts) {
+		slog.Error("held-back messages dead-lettered", "user_id", userID, "count", len(ids), "attempt", attempts, "permanent", f.Permanent, "error", sendErr)
+		if err := q.repo.DeadLetter(ctx, ids, sendErr); err != nil {
+			slog.Error("failed to dead-letter held-back messages", "user_id", userID, "error", err)
+		}
+		return
+	}
+
+	wait := f.Wait
+	if wait == 0 {
+		wait = Backoff(attempts)
+	}
+	slog.Warn("failed to send held-back messages, will retry", "user_id", userID, "count", len(ids), "attempt", attempts, "retry_in", wait, "error", sendErr)
+	if err := q.repo.Retry(ctx, ids, sendErr, wait); err != nil {
+		slog.Error("failed to schedule held-back message retry", "user_id", userID, "error", err)
+	}
+}
+
+// outgoing is one Telegram message of a flush. done lists the held-back
+// messages it completes: those whose text it ends.
+type outgoing struct {
+	text   string
+	markup *tele.ReplyMarkup
+	done   []int
+}
+
+// planBatch turns held-back messages into Telegram messages. Those without
+// buttons are joined into one, split to stay within maxMessageLen:
+//
+// 🌅 Selama jam tenang ada 3 pesan untukmu:
+//
+// 🔔 Reminder ...
+// ─────────────
+// ☀️ Briefing ...
+//
+// Those with buttons (a reminder's Done/Snooze/Skip, the acknowledge button
+// of a repeating one) follow, each on its own so the buttons keep working.
+func planBatch(msgs []Message) []outgoing {
+	var plain, buttoned []Message
+	for _, m := range msgs {
+		if m.Markup != nil {
+			buttoned = append(buttoned, m)
+		} else {
+			plain = append(plain, m)
+		}
+	}
+
+	var out []outgoing
+	switch len(plain) {
+	case 0:
+	case 1:
+		out = planSingle(plain[0])
+	default:
+		out = planJoined(plain)
+	}
+	for _, m := range buttoned {
+		out = append(out, planSingle(m)...)
+	}
+	return out
+}
+
+// planSingle sends a message as it was, its buttons under the last part.
+func planSingle(m Message) []outgoing {
+	parts := splitText(m.Text, maxMessageLen)
+	out := make([]outgoing, len(parts))
+	for i, p := range parts {
+		out[i].text = p
+	}
+	out[len(out)-1].markup = m.Markup
+	out[len(out)-1].done = []int{m.ID}
+	return out
+}
+
+// planJoined joins messages under a header, starting a new Telegram message
+// whenever the next part would not fit.
+func planJoined(msgs []Message) []outgoing {
+	header := fmt.Sprintf("🌅 Selama jam tenang ada %d pesan untukmu:", len(msgs))
+	// Parts are short enough to follow the header or a separator.
+	room := maxMessageLen - max(textLen(header)+2, textLen(batchSeparator))
+	var out []outgoing
+	cur := outgoing{text: header}
+	sep := "\n\n"
+	for _, m := range msgs {
+		for _, p := range splitText(m.Text, room) {
+			if textLen(cur.text)+textLen(sep)+textLen(p) > maxMessageLen {
+				out = append(out, cur)
+				cur = outgoing{text: p}
+			} else {
+				cur.text += sep + p
+			}
+			sep = batchSeparator
+		}
+		cur.done = append(cur.done, m.ID)
+	}
+	return append(out, cur)
+}
+
+// splitText cuts text into parts of at most limit, at line breaks where it
+// can.
+func splitText(text string, limit int) []string {
+	if textLen(text) <= limit {
+		return []string{text}
+	}
+	var (
+		parts []string
+		cur   string
+	)
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for textLen(line) > limit {
+			if cur != "" {
+				parts = append(parts, strings.TrimSuffix(cur, "\n"))
+				cur = ""
+			}
+			head, rest := cutText(line, limit)
+			parts = append(parts, head)
+			line = rest
+		}
+		if textLen(cur)+textLen(line) > limit {
+			parts = append(parts, strings.TrimSuffix(cur, "\n"))
+			cur = ""
+		}
+		cur += line
+	}
+	if cur != "" {
+		parts = append(parts, strings.TrimSuffix(cur, "\n"))
+	}
+	return parts
+}
+
+// cutText splits s after its first limit UTF-16 code units.
+func cutText(s string, limit int) (string, string) {
+	n := 0
+	for i, r := range s {
+		n += utf16.RuneLen(r)
+		if n > limit {
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
+
+// textLen is the length of s as Telegram counts it.
+func textLen(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
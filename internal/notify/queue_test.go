@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestPlanBatchSingleMessage(t *testing.T) {
+	markup := &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{{Text: "✅ Selesai", Data: "done"}}}}
+	got := planBatch([]Message{{ID: 7, Text: "🔔 Reminder\n\n📌 minum obat", Markup: markup}})
+	want := []outgoing{{text: "🔔 Reminder\n\n📌 minum obat", markup: markup, done: []int{7}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planBatch = %+v, want %+v", got, want)
+	}
+}
+
+func TestPlanBatchJoinsMessages(t *testing.T) {
+	got := planBatch([]Message{{ID: 1, Text: "🔔 Reminder A"}, {ID: 2, Text: "☀️ Briefing"}})
+	want := []outgoing{{
+		text: "🌅 Selama jam tenang ada 2 pesan untukmu:\n\n🔔 Reminder A" + batchSeparator + "☀️ Briefing",
+		done: []int{1, 2},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planBatch = %+v, want %+v", got, want)
+	}
+}
+
+// TestPlanBatchStaysWithinLimit holds back a night of messages, one of them
+// longer than Telegram allows on its own.
+func TestPlanBatchStaysWithinLimit(t *testing.T) {
+	msgs := []Message{{ID: 1, Text: strings.Repeat("📊 Laporan bulanan: pengeluaran makan\n", 200)}}
+	for i := 2; i <= 40; i++ {
+		msgs = append(msgs, Message{ID: i, Text: "🔔 Reminder\n\n📌 " + strings.Repeat("x", 100)})
+	}
+
+	out := planBatch(msgs)
+	if len(out) < 3 {
+		t.Fatalf("planBatch made %d messages, want the batch split", len(out))
+	}
+	var done []int
+	for i, o := range out {
+		if n := textLen(o.text); n > maxMessageLen {
+			t.Errorf("message %d is %d long", i, n)
+		}
+		if o.markup != nil {
+			t.Errorf("message %d has buttons", i)
+		}
+		done = append(done, o.done...)
+	}
+	for i, id := range done {
+		if id != i+1 {
+			t.Fatalf("done = %v, want each message once, in order", done)
+		}
+	}
+	if len(done) != len(msgs) {
+		t.Errorf("done %d messages, want %d", len(done), len(msgs))
+	}
+	if !strings.HasPrefix(out[0].text, "🌅 Selama jam tenang ada 40 pesan untukmu:") {
+		t.Errorf("first message = %.60q…, want the header", out[0].text)
+	}
+}
+
+func TestPlanBatchSplitsLongSingleMessage(t *testing.T) {
+	markup := &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{{Text: "👍 Oke", Data: "ack"}}}}
+	text := strings.Repeat("baris laporan\n", 600)
+	out := planBatch([]Message{{ID: 3, Text: text, Markup: markup}})
+	if len(out) != 3 {
+		t.Fatalf("planBatch made %d messages, want 3", len(out))
+	}
+	var joined []string
+	for i, o := range out {
+		last := i == len(out)-1
+		if (o.markup != nil) != last || (len(o.done) > 0) != last {
+			t.Errorf("message %d: markup %v, done %v; want both only on the last", i, o.markup != nil, o.done)
+		}
+		joined = append(joined, o.text)
+	}
+	if got := strings.Join(joined, "\n"); got != strings.TrimSuffix(text, "\n") {
+		t.Error("split lost text")
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		text  string
+		limit int
+		want  []string
+	}{
+		{"pendek", 10, []string{"pendek"}},
+		{"satu\ndua\ntiga", 9, []string{"satu\ndua", "tiga"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		// An emoji outside the BMP counts twice, as it does for Telegram.
+		{"📌📌📌", 4, []string{"📌📌", "📌"}},
+	}
+	for _, tt := range tests {
+		if got := splitText(tt.text, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitText(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+		}
+	}
+}
+
+// TestPlanBatchKeepsButtons sends reminders with buttons on their own after
+// the joined plain messages.
+func TestPlanBatchKeepsButtons(t *testing.T) {
+	snooze := &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{{Text: "⏰ Tunda", Data: "snooze"}}}}
+	ack := &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{{Text: "👍 Oke", Data: "ack"}}}}
+	got := planBatch([]Message{
+		{ID: 1, Text: "🔔 Reminder A", Markup: snooze},
+		{ID: 2, Text: "☀️ Briefing"},
+		{ID: 3, Text: "🔁 Reminder B", Markup: ack},
+		{ID: 4, Text: "📊 Laporan"},
+	})
+	want := []outgoing{
+		{text: "🌅 Selama jam tenang ada 2 pesan untukmu:\n\n☀️ Briefing" + batchSeparator + "📊 Laporan", done: []int{2, 4}},
+		{text: "🔔 Reminder A", markup: snooze, done: []int{1}},
+		{text: "🔁 Reminder B", markup: ack, done: []int{3}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planBatch = %+v\nwant %+v", got, want)
+	}
+
+	got = planBatch([]Message{{ID: 1, Text: "🔔 Reminder A", Markup: snooze}, {ID: 2, Text: "☀️ Briefing"}})
+	want = []outgoing{
+		{text: "☀️ Briefing", done: []int{2}},
+		{text: "🔔 Reminder A", markup: snooze, done: []int{1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("planBatch = %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,191 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	tele "gopkg.in/telebot.v4"
+)
+
+// maxFlushAttempts is how many times a held-back message is tried before it
+// is dead-lettered.
+const maxFlushAttempts = 5
+
+// Kinds of proactive messages.
+const (
+	KindReminder      = "reminder"
+	KindBriefing      = "briefing"
+	KindOverdue       = "overdue"
+	KindMonthlyReport = "monthly_report"
+	KindBudget        = "budget"
+)
+
+// Message is a proactive message held back during the user's quiet hours or
+// do-not-disturb.
+type Message struct {
+	ID        int
+	UserID    int64
+	Kind      string
+	Text      string
+	Markup    *tele.ReplyMarkup
+	Attempts  int
+	CreatedAt time.Time
+}
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Enqueue stores a message to be sent once the user may be messaged again.
+func (r *Repository) Enqueue(ctx context.Context, userID int64, kind, text string, markup *tele.ReplyMarkup) error {
+	var payload []byte
+	if markup != nil {
+		var err error
+		if payload, err = json.Marshal(markup); err != nil {
+			return fmt.Errorf("marshal outbound markup: %w", err)
+		}
+	}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO outbound_messages (user_id, kind, text, markup) VALUES ($1, $2, $3, $4)`,
+		userID, kind, text, payload,
+	)
+	if err != nil {
+		return fmt.Errorf("enqueue outbound message: %w", err)
+	}
+	return nil
+}
+
+// Users returns the users with held-back messages that are due to be sent.
+func (r *Repository) Users(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT DISTINCT user_id FROM outbound_messages
+		 WHERE dead_at IS NULL AND (retry_at IS NULL OR retry_at <= NOW())`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list outbound users: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan outbound user: %w", err)
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+// Claim leases the user's held-back messages that are due, oldest first, for
+// lease. Leased messages are skipped by other replicas until the lease
+// expires, so they are sent outside any transaction and a replica that dies
+// mid-flush only delays them. Each must end with Delete, Retry or
+// DeadLetter.
+func (r *Repository) Claim(ctx context.Context, userID int64, lease time.Duration) ([]Message, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin claim outbound: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, user_id, kind, text, markup, attempts, created_at
+		 FROM outbound_messages
+		 WHERE user_id = $1 AND dead_at IS NULL
+		   AND (retry_at IS NULL OR retry_at <= NOW())
+		   AND (claimed_until IS NULL OR claimed_until < NOW())
+		 ORDER BY id
+		 FOR UPDATE SKIP LOCKED`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("select outbound messages: %w", err)
+	}
+	var (
+		msgs []Message
+		ids  []int
+	)
+	for rows.Next() {
+		var (
+			m       Message
+			payload []byte
+		)
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Kind, &m.Text, &payload, &m.Attempts, &m.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan outbound message: %w", err)
+		}
+		if payload != nil {
+			m.Markup = &tele.ReplyMarkup{}
+			if err := json.Unmarshal(payload, m.Markup); err != nil {
+				m.Markup = nil
+			}
+		}
+		msgs = append(msgs, m)
+		ids = append(ids, m.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate outbound messages: %w", err)
+	}
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE outbound_messages SET claimed_until = NOW() + make_interval(secs => $2) WHERE id = ANY($1)`,
+		pq.Array(ids), lease.Seconds(),
+	); err != nil {
+		return nil, fmt.Errorf("lease outbound messages: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// Delete removes messages that were sent.
+func (r *Repository) Delete(ctx context.Context, ids []int) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM outbound_messages WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+		return fmt.Errorf("delete sent outbound messages: %w", err)
+	}
+	return nil
+}
+
+// Retry records a failed attempt and holds the messages back for wait.
+func (r *Repository) Retry(ctx context.Context, ids []int, sendErr error, wait time.Duration) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE outbound_messages
+		 SET attempts = attempts + 1, last_error = $2, retry_at = NOW() + make_interval(secs => $3), claimed_until = NULL
+		 WHERE id = ANY($1)`,
+		pq.Array(ids), sendErr.Error(), wait.Seconds(),
+	)
+	if err != nil {
+		return fmt.Errorf("schedule outbound retry: %w", err)
+	}
+	return nil
+}
+
+// DeadLetter gives up on messages that cannot be sent. They are kept, with
+// the error, rather than deleted: a reminder among them is already recorded
+// as delivered and would otherwise be lost without a trace.
+func (r *Repository) DeadLetter(ctx context.Context, ids []int, sendErr error) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE outbound_messages
+		 SET attempts = attempts + 1, last_error = $2, dead_at = NOW(), claimed_until = NULL
+		 WHERE id = ANY($1)`,
+		pq.Array(ids), sendErr.Error(),
+	)
+	if err != nil {
+		return fmt.Errorf("dead-letter outbound messages: %w", err)
+	}
+	return nil
+}
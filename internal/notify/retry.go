@@ -0,0 +1,52 @@
+package notify
+
+import (
+	"errors"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	retryBackoff    = time.Minute
+	maxRetryBackoff = time.Hour
+)
+
+// permanentSendErrors are Telegram errors that retrying cannot fix: the user
+// blocked the bot, deleted their account, or the chat does not exist.
+var permanentSendErrors = []error{
+	tele.ErrBlockedByUser,
+	tele.ErrUserIsDeactivated,
+	tele.ErrNotStartedByUser,
+	tele.ErrChatNotFound,
+}
+
+// SendFailure says what to do about a failed send.
+type SendFailure struct {
+	Permanent bool
+	// Wait is how long Telegram asked us to back off; zero when it did not say.
+	Wait time.Duration
+}
+
+func ClassifySendError(err error) SendFailure {
+	for _, perm := range permanentSendErrors {
+		if errors.Is(err, perm) {
+			return SendFailure{Permanent: true}
+		}
+	}
+	var flood tele.FloodError
+	if errors.As(err, &flood) {
+		return SendFailure{Wait: time.Duration(flood.RetryAfter) * time.Second}
+	}
+	return SendFailure{}
+}
+
+// Backoff returns the delay before retrying after the given attempt:
+// 1m, 2m, 4m, ... capped at an hour.
+func Backoff(attempt int) time.Duration {
+	d := retryBackoff
+	for i := 1; i < attempt && d < maxRetryBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxRetryBackoff)
+}
@@ -0,0 +1,45 @@
+package pending
+
+import (
+	"context"
+	"sync"
+)
+
+type collectorKey struct{}
+
+// Choice is one candidate row in a disambiguation prompt.
+type Choice struct {
+	ID    int
+	Label string
+}
+
+// Collector gathers the choices offered while routing one intent so the
+// handler can turn them into inline keyboard buttons.
+type Collector struct {
+	mu      sync.Mutex
+	choices []Choice
+}
+
+// WithCollector returns a context carrying a fresh Collector.
+func WithCollector(ctx context.Context) (context.Context, *Collector) {
+	c := &Collector{}
+	return context.WithValue(ctx, collectorKey{}, c), c
+}
+
+// Offer records candidates on the context's Collector. It is a no-op when the
+// context has none, so services can call it unconditionally.
+func Offer(ctx context.Context, choices ...Choice) {
+	c, ok := ctx.Value(collectorKey{}).(*Collector)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.choices = append(c.choices, choices...)
+}
+
+func (c *Collector) Choices() []Choice {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Choice(nil), c.choices...)
+}
@@ -0,0 +1,95 @@
+package pending
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Kinds of pending actions. A pick chooses one row from a disambiguation
+// prompt; a confirm approves a destructive operation.
+const (
+	KindPick    = "pick"
+	KindConfirm = "confirm"
+)
+
+// Repository stores actions waiting for the user to pick a candidate from an
+// inline keyboard. Each action expires after the TTL so stale buttons fail.
+type Repository struct {
+	db  *sql.DB
+	ttl time.Duration
+}
+
+func NewRepository(db *sql.DB, ttl time.Duration) *Repository {
+	return &Repository{db: db, ttl: ttl}
+}
+
+// Create stores a pending action and returns its ID. payload is opaque to
+// the store; candidates are the row IDs the user may pick from.
+func (r *Repository) Create(ctx context.Context, userID int64, kind string, payload []byte, candidates []int) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO pending_actions (user_id, kind, payload, candidate_ids, expires_at)
+		 VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		userID, kind, payload, pq.Array(candidates), time.Now().Add(r.ttl),
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("create pending action: %w", err)
+	}
+	return id, nil
+}
+
+// Take consumes a pending action for the chosen candidate and returns its
+// payload. It returns nil when the action is missing, expired, owned by
+// another user, or does not offer that candidate. Taking an action removes
+// it, so the other buttons of the same prompt stop working too.
+func (r *Repository) Take(ctx context.Context, userID int64, kind string, id, candidateID int) ([]byte, error) {
+	var payload []byte
+	err := r.db.QueryRowContext(ctx,
+		`DELETE FROM pending_actions
+		 WHERE id = $1 AND user_id = $2 AND kind = $3 AND expires_at > NOW() AND $4 = ANY(candidate_ids)
+		 RETURNING payload`,
+		id, userID, kind, candidateID,
+	).Scan(&payload)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("take pending action: %w", err)
+	}
+	return payload, nil
+}
+
+// TakeLatest consumes the user's most recent live action of kind, for
+// replies typed as text ("ya") instead of a button press. It returns nil when
+// there is none.
+func (r *Repository) TakeLatest(ctx context.Context, userID int64, kind string) ([]byte, error) {
+	var payload []byte
+	err := r.db.QueryRowContext(ctx,
+		`DELETE FROM pending_actions WHERE id = (
+		     SELECT id FROM pending_actions
+		     WHERE user_id = $1 AND kind = $2 AND expires_at > NOW()
+		     ORDER BY created_at DESC, id DESC LIMIT 1
+		 ) RETURNING payload`,
+		userID, kind,
+	).Scan(&payload)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("take latest pending action: %w", err)
+	}
+	return payload, nil
+}
+
+// DeleteExpired removes actions whose buttons can no longer be used.
+func (r *Repository) DeleteExpired(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM pending_actions WHERE expires_at <= NOW()`)
+	if err != nil {
+		return fmt.Errorf("delete expired pending actions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package recurrence
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var indonesianDays = [...]string{
+	"Minggu", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu",
+}
+
+var indonesianMonths = [...]string{
+	"Jan", "Feb", "Mar", "Apr", "Mei", "Jun",
+	"Jul", "Agu", "Sep", "Okt", "Nov", "Des",
+}
+
+var indonesianMonthsFull = [...]string{
+	"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember",
+}
+
+var workdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+// Label is the short kind of the rule: "Harian", "Mingguan", "Bulanan" or
+// "Tahunan".
+func (r *Rule) Label() string {
+	switch r.Freq {
+	case Daily:
+		return "Harian"
+	case Weekly:
+		return "Mingguan"
+	case Monthly:
+		return "Bulanan"
+	case Yearly:
+		return "Tahunan"
+	}
+	return ""
+}
+
+// Describe explains the rule in Indonesian, e.g. "setiap 2 minggu hari Jumat
+// sampai 31 Des 2026", "setiap Senin pertama" or "setiap tanggal 25, maju ke
+// hari kerja sebelumnya jika libur".
+func (r *Rule) Describe() string {
+	s := r.describeBase()
+	switch r.Shift {
+	case ShiftSkip:
+		s += ", kecuali hari libur"
+	case ShiftPrev:
+		s += ", maju ke hari kerja sebelumnya jika libur"
+	case ShiftNext:
+		s += ", mundur ke hari kerja berikutnya jika libur"
+	}
+	if r.until != nil {
+		s += fmt.Sprintf(" sampai %d %s %d", r.until.day, indonesianMonths[r.until.month-1], r.until.year)
+	}
+	if r.Count > 0 {
+		s += fmt.Sprintf(" (%dx lagi)", r.Count)
+	}
+	if len(r.exDates) > 0 {
+		var dates []string
+		for i, c := range r.exDates {
+			if i == 3 {
+				dates = append(dates, fmt.Sprintf("+%d lagi", len(r.exDates)-3))
+				break
+			}
+			dates = append(dates, fmt.Sprintf("%d %s", c.day, indonesianMonths[c.month-1]))
+		}
+		s += ", kecuali " + strings.Join(dates, ", ")
+	}
+	return s
+}
+
+func (r *Rule) describeBase() string {
+	switch r.Freq {
+	case Daily:
+		if r.Interval == 1 && len(r.ByDay) > 0 {
+			return "setiap " + r.describeWeekdays()
+		}
+		return every(r.Interval, "hari")
+
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return every(r.Interval, "minggu")
+		}
+		if r.Interval == 1 {
+			return "setiap " + r.describeWeekdays()
+		}
+		return every(r.Interval, "minggu") + " hari " + r.describeWeekdays()
+
+	case Monthly:
+		if slices.Equal(r.ByMonthDay, []int{-1}) && len(r.ByDay) == 0 {
+			if r.Interval == 1 {
+				return "setiap akhir bulan"
+			}
+			return every(r.Interval, "bulan") + " di akhir bulan"
+		}
+		if len(r.ByMonthDay) > 0 {
+			s := "tanggal " + describeMonthDays(r.ByMonthDay)
+			if r.Interval == 1 {
+				return "setiap " + s
+			}
+			return every(r.Interval, "bulan") + " " + s
+		}
+		if len(r.ByDay) > 0 {
+			if r.Interval == 1 {
+				return "setiap " + r.describeWeekdays()
+			}
+			return every(r.Interval, "bulan") + " " + r.describeWeekdays()
+		}
+		return every(r.Interval, "bulan")
+
+	case Yearly:
+		if len(r.ByMonth) > 0 {
+			months := make([]string, len(r.ByMonth))
+			for i, m := range r.ByMonth {
+				months[i] = indonesianMonthsFull[m-1]
+			}
+			switch {
+			case len(r.ByMonthDay) > 0 && slices.Min(r.ByMonthDay) > 0:
+				return fmt.Sprintf("setiap %s %s", describeMonthDays(r.ByMonthDay), strings.Join(months, ", "))
+			case len(r.ByMonthDay) > 0:
+				return fmt.Sprintf("setiap tanggal %s %s", describeMonthDays(r.ByMonthDay), strings.Join(months, ", "))
+			case len(r.ByDay) > 0:
+				return fmt.Sprintf("setiap %s bulan %s", r.describeWeekdays(), strings.Join(months, ", "))
+			}
+		}
+		return every(r.Interval, "tahun")
+	}
+	return ""
+}
+
+func every(n int, unit string) string {
+	if n == 1 {
+		return "setiap " + unit
+	}
+	return fmt.Sprintf("setiap %d %s", n, unit)
+}
+
+// describeWeekdays names BYDAY: "hari kerja", "Senin, Rabu", "Senin pertama".
+func (r *Rule) describeWeekdays() string {
+	plain := make([]time.Weekday, 0, len(r.ByDay))
+	for _, w := range r.ByDay {
+		if w.N == 0 {
+			plain = append(plain, w.Day)
+		}
+	}
+	if len(plain) == len(r.ByDay) {
+		sorted := slices.Sorted(slices.Values(plain))
+		switch {
+		case slices.Equal(sorted, workdays):
+			return "hari kerja"
+		case slices.Equal(sorted, []time.Weekday{time.Sunday, time.Saturday}):
+			return "akhir pekan"
+		}
+	}
+
+	names := make([]string, len(r.ByDay))
+	for i, w := range r.ByDay {
+		names[i] = indonesianDays[w.Day]
+		if w.N != 0 {
+			names[i] += " " + ordinal(w.N)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+var ordinals = [...]string{"", "pertama", "kedua", "ketiga", "keempat", "kelima"}
+
+func ordinal(n int) string {
+	switch {
+	case n == -1:
+		return "terakhir"
+	case n < 0 && -n < len(ordinals):
+		return ordinals[-n] + " dari akhir"
+	case n < 0:
+		return fmt.Sprintf("ke-%d dari akhir", -n)
+	case n < len(ordinals):
+		return ordinals[n]
+	}
+	return fmt.Sprintf("ke-%d", n)
+}
+
+func describeMonthDays(days []int) string {
+	names := make([]string, len(days))
+	for i, d := range days {
+		switch {
+		case d == -1:
+			names[i] = "terakhir"
+		case d < 0:
+			names[i] = fmt.Sprintf("ke-%d dari akhir", -d)
+		default:
+			names[i] = fmt.Sprintf("%d", d)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,287 @@
+package recurrence
+
+import (
+	"slices"
+	"time"
+)
+
+// maxPeriods bounds the search for an occurrence, so a rule that can never
+// match (BYMONTH=2;BYMONTHDAY=30) ends instead of looping forever.
+const maxPeriods = 10000
+
+// each calls yield with every occurrence at or after start, in order, until
+// yield returns false, UNTIL is passed or maxPeriods is reached. Occurrences
+// take their time of day from start. EXDATE and COUNT are not applied.
+func (r *Rule) each(start time.Time, loc *time.Location, yield func(time.Time) bool) {
+	start = start.In(loc)
+	var until time.Time
+	if r.until != nil {
+		until = r.until.in(loc)
+		if r.until.dateOnly {
+			until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+	}
+
+	for k := 0; k < maxPeriods; k++ {
+		for _, d := range r.period(start, k) {
+			t := time.Date(d.Year(), d.Month(), d.Day(), start.Hour(), start.Minute(), start.Second(), 0, loc)
+			if t.Before(start) {
+				continue
+			}
+			if r.until != nil && t.After(until) {
+				return
+			}
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// period returns the dates (at midnight UTC, only Y/M/D matter) of the kth
+// period after start, sorted.
+func (r *Rule) period(start time.Time, k int) []time.Time {
+	n := k * r.Interval
+	switch r.Freq {
+	case Daily:
+		d := date(start.Year(), start.Month(), start.Day()+n)
+		if r.matchesMonth(d) && r.matchesMonthDay(d) && r.matchesWeekday(d) {
+			return []time.Time{d}
+		}
+		return nil
+
+	case Weekly:
+		offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+		first := date(start.Year(), start.Month(), start.Day()-offset+7*n)
+		var days []time.Time
+		for i := 0; i < 7; i++ {
+			d := first.AddDate(0, 0, i)
+			want := d.Weekday() == start.Weekday()
+			if len(r.ByDay) > 0 {
+				want = r.matchesWeekday(d)
+			}
+			if want && r.matchesMonth(d) {
+				days = append(days, d)
+			}
+		}
+		return days
+
+	case Monthly:
+		first := date(start.Year(), start.Month()+time.Month(n), 1)
+		if !r.matchesMonth(first) {
+			return nil
+		}
+		return r.monthDays(first, start)
+
+	case Yearly:
+		year := start.Year() + n
+		if len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) > 0 {
+			return r.yearWeekdays(year)
+		}
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		var days []time.Time
+		for _, m := range slices.Sorted(slices.Values(months)) {
+			days = append(days, r.monthDays(date(year, m, 1), start)...)
+		}
+		return days
+	}
+	return nil
+}
+
+// monthDays expands BYMONTHDAY and BYDAY within the month of first. With
+// neither, the day of month comes from start; months without that day are
+// skipped, as RFC 5545 requires (use BYMONTHDAY=-1 for the last day).
+func (r *Rule) monthDays(first, start time.Time) []time.Time {
+	n := daysIn(first)
+	var days []int
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, md := range r.ByMonthDay {
+			d := md
+			if md < 0 {
+				d = n + 1 + md
+			}
+			if d < 1 || d > n {
+				continue
+			}
+			if len(r.ByDay) > 0 && !slices.Contains(r.byDayInMonth(first), d) {
+				continue
+			}
+			days = append(days, d)
+		}
+	case len(r.ByDay) > 0:
+		days = r.byDayInMonth(first)
+	default:
+		if start.Day() <= n {
+			days = []int{start.Day()}
+		}
+	}
+
+	slices.Sort(days)
+	days = slices.Compact(days)
+	out := make([]time.Time, len(days))
+	for i, d := range days {
+		out[i] = date(first.Year(), first.Month(), d)
+	}
+	return out
+}
+
+// byDayInMonth returns the days of first's month selected by BYDAY.
+func (r *Rule) byDayInMonth(first time.Time) []int {
+	n := daysIn(first)
+	var days []int
+	for _, w := range r.ByDay {
+		var matching []int
+		for d := 1; d <= n; d++ {
+			if date(first.Year(), first.Month(), d).Weekday() == w.Day {
+				matching = append(matching, d)
+			}
+		}
+		days = append(days, pick(matching, w.N)...)
+	}
+	return days
+}
+
+// yearWeekdays expands BYDAY over a whole year (FREQ=YEARLY without BYMONTH).
+func (r *Rule) yearWeekdays(year int) []time.Time {
+	var days []time.Time
+	for _, w := range r.ByDay {
+		var matching []time.Time
+		for d := date(year, time.January, 1); d.Year() == year; d = d.AddDate(0, 0, 1) {
+			if d.Weekday() == w.Day {
+				matching = append(matching, d)
+			}
+		}
+		days = append(days, pick(matching, w.N)...)
+	}
+	slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(days, func(a, b time.Time) bool { return a.Equal(b) })
+}
+
+// pick returns all of vs when n is 0, else the nth (negative from the end).
+func pick[T any](vs []T, n int) []T {
+	switch {
+	case n == 0:
+		return vs
+	case n > 0 && n <= len(vs):
+		return []T{vs[n-1]}
+	case n < 0 && -n <= len(vs):
+		return []T{vs[len(vs)+n]}
+	}
+	return nil
+}
+
+func (r *Rule) matchesMonth(d time.Time) bool {
+	return len(r.ByMonth) == 0 || slices.Contains(r.ByMonth, d.Month())
+}
+
+func (r *Rule) matchesMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	n := daysIn(d)
+	for _, md := range r.ByMonthDay {
+		if md == d.Day() || (md < 0 && n+1+md == d.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWeekday ignores ordinals; it is only used where they are not allowed.
+func (r *Rule) matchesWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, w := range r.ByDay {
+		if w.Day == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) excluded(t time.Time, loc *time.Location) bool {
+	for _, c := range r.exDates {
+		ex := c.in(loc)
+		if c.dateOnly {
+			if ex.Year() == t.Year() && ex.YearDay() == t.YearDay() {
+				return true
+			}
+		} else if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// After returns the first occurrence later than after of the series that
+// starts at start, or false when the series ends before that. Occurrences
+// are compared once X-NONWORKDAY has moved them off weekends and holidays.
+func (r *Rule) After(start, after time.Time, loc *time.Location, holidays Holidays) (time.Time, bool) {
+	next, _, ok := r.advance(start, after, loc, holidays)
+	return next, ok
+}
+
+// Advance moves a series whose current occurrence is current past now. It
+// returns the next occurrence and the rule to store with it: COUNT reduced by
+// the occurrences used up (skipped ones included) and past EXDATEs dropped.
+// It returns false when the series has ended.
+func (r *Rule) Advance(current, now time.Time, loc *time.Location, holidays Holidays) (time.Time, *Rule, bool) {
+	if now.Before(current) {
+		now = current
+	}
+	return r.advance(current, now, loc, holidays)
+}
+
+func (r *Rule) advance(start, after time.Time, loc *time.Location, holidays Holidays) (time.Time, *Rule, bool) {
+	var (
+		next  time.Time
+		found bool
+		used  int
+	)
+	r.each(start, loc, func(t time.Time) bool {
+		used++
+		if r.Count > 0 && used > r.Count {
+			return false
+		}
+		if r.excluded(t, loc) {
+			return true
+		}
+		// EXDATE names the date as the rule gives it; what is compared
+		// and sent is the workday it moves to.
+		shifted, ok := r.shift(t, holidays)
+		if !ok || !shifted.After(after) {
+			return true
+		}
+		next, found = shifted, true
+		return false
+	})
+	if !found {
+		return time.Time{}, nil, false
+	}
+
+	rest := r.clone()
+	if rest.Count > 0 {
+		rest.Count -= used - 1
+	}
+	rest.exDates = slices.DeleteFunc(rest.exDates, func(c civil) bool {
+		ex := c.in(loc)
+		if c.dateOnly {
+			ex = ex.AddDate(0, 0, 1)
+		}
+		return !ex.After(next)
+	})
+	return next, rest, true
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
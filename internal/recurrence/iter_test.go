@@ -0,0 +1,137 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAfter(t *testing.T) {
+	loc := jakarta(t)
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		after string // empty means just after start
+		want  string // empty means the series has ended
+	}{
+		{"daily", "FREQ=DAILY", "2026-01-01 09:00", "", "2026-01-02 09:00"},
+		{"every third day", "FREQ=DAILY;INTERVAL=3", "2026-01-01 09:00", "2026-01-05 00:00", "2026-01-07 09:00"},
+		{"every other friday", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", "2026-01-02 09:00", "", "2026-01-16 09:00"},
+		{"several weekdays", "FREQ=WEEKLY;BYDAY=MO,WE,FR", "2026-01-05 07:30", "", "2026-01-07 07:30"},
+		{"weekly without BYDAY keeps the weekday", "FREQ=WEEKLY", "2026-01-06 09:00", "", "2026-01-13 09:00"},
+		{"first monday", "FREQ=MONTHLY;BYDAY=1MO", "2026-01-05 09:00", "", "2026-02-02 09:00"},
+		{"last friday", "FREQ=MONTHLY;BYDAY=-1FR", "2026-01-30 09:00", "", "2026-02-27 09:00"},
+		{"second to last sunday", "FREQ=MONTHLY;BYDAY=-2SU", "2026-01-01 09:00", "", "2026-01-18 09:00"},
+		{"last day into february", "FREQ=MONTHLY;BYMONTHDAY=-1", "2026-01-31 09:00", "", "2026-02-28 09:00"},
+		{"last day out of february", "FREQ=MONTHLY;BYMONTHDAY=-1", "2026-02-28 09:00", "", "2026-03-31 09:00"},
+		{"last day of a leap february", "FREQ=MONTHLY;BYMONTHDAY=-1", "2028-01-31 09:00", "", "2028-02-29 09:00"},
+		{"day 31 skips short months", "FREQ=MONTHLY;BYMONTHDAY=31", "2026-01-31 09:00", "", "2026-03-31 09:00"},
+		{"monthly without BYMONTHDAY skips short months", "FREQ=MONTHLY", "2026-01-30 09:00", "", "2026-03-30 09:00"},
+		{"two days a month", "FREQ=MONTHLY;BYMONTHDAY=1,15", "2026-01-15 09:00", "", "2026-02-01 09:00"},
+		{"every other month", "FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=10", "2026-01-10 09:00", "", "2026-03-10 09:00"},
+		{"yearly", "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=15", "2026-03-15 09:00", "", "2027-03-15 09:00"},
+		{"29 february", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", "2025-03-01 09:00", "", "2028-02-29 09:00"},
+		{"thanksgiving", "FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "2026-01-01 09:00", "", "2026-11-26 09:00"},
+		{"never matches", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", "2026-01-01 09:00", "", ""},
+		{"until a date includes that day", "FREQ=DAILY;UNTIL=20260105", "2026-01-01 09:00", "2026-01-05 08:00", "2026-01-05 09:00"},
+		{"until a date ends after that day", "FREQ=DAILY;UNTIL=20260105", "2026-01-01 09:00", "2026-01-05 09:00", ""},
+		{"until a UTC time", "FREQ=DAILY;UNTIL=20260105T020000Z", "2026-01-01 09:00", "2026-01-04 10:00", "2026-01-05 09:00"},
+		{"until a UTC time excludes later ones", "FREQ=DAILY;UNTIL=20260105T015959Z", "2026-01-01 09:00", "2026-01-04 10:00", ""},
+		{"count limits the series", "FREQ=DAILY;COUNT=3", "2026-01-01 09:00", "2026-01-03 08:00", "2026-01-03 09:00"},
+		{"count ends the series", "FREQ=DAILY;COUNT=3", "2026-01-01 09:00", "2026-01-03 09:00", ""},
+		{"exdate on a date", "FREQ=WEEKLY;BYDAY=MO\nEXDATE:20260112", "2026-01-05 09:00", "", "2026-01-19 09:00"},
+		{"exdate at another time excludes nothing", "FREQ=WEEKLY;BYDAY=MO\nEXDATE:20260112T100000", "2026-01-05 09:00", "", "2026-01-12 09:00"},
+		{"exdate at the time", "FREQ=WEEKLY;BYDAY=MO\nEXDATE:20260112T090000", "2026-01-05 09:00", "", "2026-01-19 09:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := parseLocal(t, tt.start, loc)
+			after := start
+			if tt.after != "" {
+				after = parseLocal(t, tt.after, loc)
+			}
+			got, ok := r.After(start, after, loc, nil)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("After = %s, want the series ended", got.Format("2006-01-02 15:04"))
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("series ended")
+			}
+			if got.Format("2006-01-02 15:04") != tt.want {
+				t.Errorf("After = %s, want %s", got.Format("2006-01-02 15:04"), tt.want)
+			}
+		})
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	loc := jakarta(t)
+	tests := []struct {
+		name    string
+		rule    string
+		current string
+		now     string // empty means current
+		want    string // empty means the series has ended
+		rest    string
+	}{
+		{"count counts down", "FREQ=DAILY;COUNT=3", "2026-01-01 09:00", "", "2026-01-02 09:00", "FREQ=DAILY;COUNT=2"},
+		{"last of the count", "FREQ=DAILY;COUNT=1", "2026-01-01 09:00", "", "", ""},
+		{"missed occurrences use up the count", "FREQ=DAILY;COUNT=5", "2026-01-01 09:00", "2026-01-03 12:00", "2026-01-04 09:00", "FREQ=DAILY;COUNT=2"},
+		{"excluded occurrences use up the count", "FREQ=DAILY;COUNT=3\nEXDATE:20260102", "2026-01-01 09:00", "", "2026-01-03 09:00", "FREQ=DAILY;COUNT=1"},
+		{"past exdates are dropped", "FREQ=WEEKLY;BYDAY=MO\nEXDATE:20260112,20260202", "2026-01-05 09:00", "", "2026-01-19 09:00", "FREQ=WEEKLY;BYDAY=MO\nEXDATE:20260202"},
+		{"the last exdate is dropped", "FREQ=WEEKLY;BYDAY=MO\nEXDATE:20260112", "2026-01-05 09:00", "", "2026-01-19 09:00", "FREQ=WEEKLY;BYDAY=MO"},
+		{"now before current", "FREQ=DAILY", "2026-01-05 09:00", "2026-01-01 00:00", "2026-01-06 09:00", "FREQ=DAILY"},
+		{"until passed", "FREQ=WEEKLY;BYDAY=FR;UNTIL=20260110", "2026-01-09 09:00", "", "", ""},
+		{"interval keeps its phase", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", "2026-01-02 09:00", "2026-01-20 00:00", "2026-01-30 09:00", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			current := parseLocal(t, tt.current, loc)
+			now := current
+			if tt.now != "" {
+				now = parseLocal(t, tt.now, loc)
+			}
+			got, rest, ok := r.Advance(current, now, loc, nil)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("Advance = %s, want the series ended", got.Format("2006-01-02 15:04"))
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("series ended")
+			}
+			if got.Format("2006-01-02 15:04") != tt.want {
+				t.Errorf("Advance = %s, want %s", got.Format("2006-01-02 15:04"), tt.want)
+			}
+			if rest.String() != tt.rest {
+				t.Errorf("rest = %q, want %q", rest.String(), tt.rest)
+			}
+			if r.String() != tt.rule {
+				t.Errorf("rule changed to %q", r.String())
+			}
+		})
+	}
+}
+
+func parseLocal(t *testing.T, s string, loc *time.Location) time.Time {
+	t.Helper()
+	v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
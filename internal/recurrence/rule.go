@@ -0,0 +1,391 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules used
+// by reminders: FREQ (DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, COUNT, UNTIL,
+// BYDAY with ordinals, BYMONTHDAY (negative counts from the month's end),
+// BYMONTH and WKST, plus EXDATE lines. The X-NONWORKDAY extension moves or
+// drops occurrences that fall on weekends and holidays.
+//
+// A rule is stored as text, for example:
+//
+//	FREQ=MONTHLY;BYDAY=1MO
+//	FREQ=WEEKLY;INTERVAL=2;BYDAY=FR;UNTIL=20261231
+//	EXDATE:20261225
+//	FREQ=MONTHLY;BYMONTHDAY=25;X-NONWORKDAY=PREV
+//
+// There is no DTSTART: the series starts at the reminder's current time,
+// which also gives the time of day. COUNT is the number of occurrences left,
+// counting the current one. Dates in UNTIL and EXDATE are wall-clock times in
+// the user's timezone unless they end in Z.
+package recurrence
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Freq int
+
+const (
+	Daily Freq = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var freqNames = map[string]Freq{"DAILY": Daily, "WEEKLY": Weekly, "MONTHLY": Monthly, "YEARLY": Yearly}
+
+func (f Freq) String() string {
+	for name, v := range freqNames {
+		if v == f {
+			return name
+		}
+	}
+	return "UNKNOWN"
+}
+
+// WeekdayNum is a BYDAY entry: every Day of the period when N is 0, otherwise
+// the Nth one (negative counts from the end, -1 is the last).
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var dayCodes = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func parseDayCode(s string) (time.Weekday, bool) {
+	for i, code := range dayCodes {
+		if code == s {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return dayCodes[w.Day]
+	}
+	return strconv.Itoa(w.N) + dayCodes[w.Day]
+}
+
+// civil is a date, or date and time, without a zone. It is placed in the
+// user's timezone when the rule is evaluated, except UTC values ("...Z").
+type civil struct {
+	year                 int
+	month                time.Month
+	day                  int
+	hour, minute, second int
+	dateOnly             bool
+	utc                  bool
+}
+
+func parseCivil(s string) (civil, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		return civil{
+			year: t.Year(), month: t.Month(), day: t.Day(),
+			hour: t.Hour(), minute: t.Minute(), second: t.Second(),
+			dateOnly: layout == "20060102",
+			utc:      strings.HasSuffix(layout, "Z"),
+		}, nil
+	}
+	return civil{}, fmt.Errorf("invalid date %q", s)
+}
+
+func (c civil) in(loc *time.Location) time.Time {
+	if c.utc {
+		return time.Date(c.year, c.month, c.day, c.hour, c.minute, c.second, 0, time.UTC).In(loc)
+	}
+	return time.Date(c.year, c.month, c.day, c.hour, c.minute, c.second, 0, loc)
+}
+
+func (c civil) String() string {
+	s := fmt.Sprintf("%04d%02d%02d", c.year, c.month, c.day)
+	if c.dateOnly {
+		return s
+	}
+	s += fmt.Sprintf("T%02d%02d%02d", c.hour, c.minute, c.second)
+	if c.utc {
+		s += "Z"
+	}
+	return s
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       Freq
+	Interval   int
+	Count      int // occurrences left including the current one; 0 means no limit
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	WeekStart  time.Weekday
+	Shift      Shift
+
+	until   *civil
+	exDates []civil
+}
+
+var (
+	legacyWeekly  = regexp.MustCompile(`^weekly:([a-z]+)$`)
+	legacyMonthly = regexp.MustCompile(`^monthly:(\d{1,2})$`)
+	legacyYearly  = regexp.MustCompile(`^yearly:(\d{1,2})-(\d{1,2})$`)
+	byDayEntry    = regexp.MustCompile(`^([+-]?\d{1,2})?([A-Z]{2})$`)
+)
+
+var legacyDays = map[string]string{
+	"mon": "MO", "senin": "MO",
+	"tue": "TU", "selasa": "TU",
+	"wed": "WE", "rabu": "WE",
+	"thu": "TH", "kamis": "TH",
+	"fri": "FR", "jumat": "FR",
+	"sat": "SA", "sabtu": "SA",
+	"sun": "SU", "minggu": "SU",
+}
+
+// Parse reads a rule. The short forms used before RRULE support (daily,
+// weekly:MON, monthly:5, yearly:3-15) are accepted and converted.
+func Parse(s string) (*Rule, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty rule")
+	}
+	if legacy, ok := fromLegacy(strings.ToLower(s)); ok {
+		s = legacy
+	}
+
+	r := &Rule{Interval: 1, WeekStart: time.Monday}
+	haveRRule := false
+	for _, line := range strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		upper := strings.ToUpper(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(upper, "EXDATE"):
+			_, values, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("EXDATE without value")
+			}
+			for _, v := range strings.Split(values, ",") {
+				c, err := parseCivil(strings.TrimSpace(v))
+				if err != nil {
+					return nil, fmt.Errorf("EXDATE: %w", err)
+				}
+				r.exDates = append(r.exDates, c)
+			}
+		case strings.HasPrefix(upper, "RRULE:") || strings.HasPrefix(upper, "FREQ="):
+			if haveRRule {
+				return nil, fmt.Errorf("more than one RRULE")
+			}
+			haveRRule = true
+			if err := r.parseRRule(strings.TrimPrefix(upper, "RRULE:")); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("unsupported line %q", line)
+		}
+	}
+	if !haveRRule {
+		return nil, fmt.Errorf("missing RRULE")
+	}
+	return r, nil
+}
+
+func fromLegacy(s string) (string, bool) {
+	if s == "daily" {
+		return "FREQ=DAILY", true
+	}
+	if m := legacyWeekly.FindStringSubmatch(s); m != nil {
+		if code, ok := legacyDays[m[1]]; ok {
+			return "FREQ=WEEKLY;BYDAY=" + code, true
+		}
+	}
+	if m := legacyMonthly.FindStringSubmatch(s); m != nil {
+		// monthly:31 meant the last day of every month, as migration 011
+		// converts it.
+		if day, _ := strconv.Atoi(m[1]); day == 31 {
+			return "FREQ=MONTHLY;BYMONTHDAY=-1", true
+		}
+		return "FREQ=MONTHLY;BYMONTHDAY=" + m[1], true
+	}
+	if m := legacyYearly.FindStringSubmatch(s); m != nil {
+		return "FREQ=YEARLY;BYMONTH=" + m[1] + ";BYMONTHDAY=" + m[2], true
+	}
+	return "", false
+}
+
+func (r *Rule) parseRRule(s string) error {
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return fmt.Errorf("invalid part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			f, ok := freqNames[value]
+			if !ok {
+				return fmt.Errorf("unsupported FREQ %s", value)
+			}
+			r.Freq = f
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid INTERVAL %s", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid COUNT %s", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			c, err := parseCivil(value)
+			if err != nil {
+				return fmt.Errorf("UNTIL: %w", err)
+			}
+			r.until = &c
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				m := byDayEntry.FindStringSubmatch(v)
+				if m == nil {
+					return fmt.Errorf("invalid BYDAY %s", v)
+				}
+				day, ok := parseDayCode(m[2])
+				if !ok {
+					return fmt.Errorf("invalid BYDAY %s", v)
+				}
+				w := WeekdayNum{Day: day}
+				if m[1] != "" {
+					w.N, _ = strconv.Atoi(m[1])
+					if w.N == 0 || w.N < -53 || w.N > 53 {
+						return fmt.Errorf("invalid BYDAY %s", v)
+					}
+				}
+				r.ByDay = append(r.ByDay, w)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return fmt.Errorf("invalid BYMONTHDAY %s", v)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(v)
+				if err != nil || n < 1 || n > 12 {
+					return fmt.Errorf("invalid BYMONTH %s", v)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			day, ok := parseDayCode(value)
+			if !ok {
+				return fmt.Errorf("invalid WKST %s", value)
+			}
+			r.WeekStart = day
+		case "X-NONWORKDAY":
+			shift, ok := shiftNames[value]
+			if !ok {
+				return fmt.Errorf("invalid X-NONWORKDAY %s", value)
+			}
+			r.Shift = shift
+		default:
+			return fmt.Errorf("unsupported %s", key)
+		}
+	}
+
+	if r.Freq == 0 {
+		return fmt.Errorf("missing FREQ")
+	}
+	if r.Count > 0 && r.until != nil {
+		return fmt.Errorf("COUNT and UNTIL are exclusive")
+	}
+	for _, w := range r.ByDay {
+		if w.N == 0 {
+			continue
+		}
+		if r.Freq != Monthly && r.Freq != Yearly {
+			return fmt.Errorf("BYDAY ordinals need FREQ=MONTHLY or YEARLY")
+		}
+		if (r.Freq == Monthly || len(r.ByMonth) > 0) && (w.N < -5 || w.N > 5) {
+			return fmt.Errorf("invalid BYDAY %s", w)
+		}
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq == Weekly {
+		return fmt.Errorf("BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+	return r.checkShift()
+}
+
+// String returns the rule in the form it is stored.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq.String()}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.until != nil {
+		parts = append(parts, "UNTIL="+r.until.String())
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, w := range r.ByDay {
+			days[i] = w.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+dayCodes[r.WeekStart])
+	}
+	if r.Shift != ShiftNone {
+		parts = append(parts, "X-NONWORKDAY="+r.Shift.String())
+	}
+
+	s := strings.Join(parts, ";")
+	if len(r.exDates) > 0 {
+		dates := make([]string, len(r.exDates))
+		for i, c := range r.exDates {
+			dates[i] = c.String()
+		}
+		s += "\nEXDATE:" + strings.Join(dates, ",")
+	}
+	return s
+}
+
+func joinInts[T ~int](vs []T) string {
+	s := make([]string, len(vs))
+	for i, v := range vs {
+		s[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(s, ",")
+}
+
+// clone returns a copy that shares no slices with r.
+func (r *Rule) clone() *Rule {
+	c := *r
+	c.ByDay = slices.Clone(r.ByDay)
+	c.ByMonthDay = slices.Clone(r.ByMonthDay)
+	c.ByMonth = slices.Clone(r.ByMonth)
+	c.exDates = slices.Clone(r.exDates)
+	return &c
+}
@@ -0,0 +1,122 @@
+package recurrence
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLegacy(t *testing.T) {
+	tests := map[string]string{
+		"daily":        "FREQ=DAILY",
+		"weekly:mon":   "FREQ=WEEKLY;BYDAY=MO",
+		"weekly:jumat": "FREQ=WEEKLY;BYDAY=FR",
+		"Weekly:Senin": "FREQ=WEEKLY;BYDAY=MO",
+		"monthly:5":    "FREQ=MONTHLY;BYMONTHDAY=5",
+		"monthly:05":   "FREQ=MONTHLY;BYMONTHDAY=5",
+		"monthly:30":   "FREQ=MONTHLY;BYMONTHDAY=30",
+		"monthly:31":   "FREQ=MONTHLY;BYMONTHDAY=-1", // as migration 011 converts it
+		"yearly:3-15":  "FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=15",
+	}
+	for legacy, want := range tests {
+		r, err := Parse(legacy)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", legacy, err)
+			continue
+		}
+		if got := r.String(); got != want {
+			t.Errorf("Parse(%q) = %s, want %s", legacy, got, want)
+		}
+	}
+}
+
+// TestStringRoundTrip parses rules in their stored form and writes them back
+// unchanged.
+func TestStringRoundTrip(t *testing.T) {
+	rules := []string{
+		"FREQ=DAILY",
+		"FREQ=DAILY;INTERVAL=3",
+		"FREQ=DAILY;COUNT=10",
+		"FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		"FREQ=WEEKLY;INTERVAL=2;UNTIL=20261231;BYDAY=FR",
+		"FREQ=WEEKLY;BYDAY=TU;WKST=SU",
+		"FREQ=MONTHLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=-1FR",
+		"FREQ=MONTHLY;BYMONTHDAY=-1",
+		"FREQ=MONTHLY;BYMONTHDAY=1,15",
+		"FREQ=MONTHLY;BYMONTHDAY=25;X-NONWORKDAY=PREV",
+		"FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=15",
+		"FREQ=YEARLY;BYMONTH=11;BYDAY=4TH",
+		"FREQ=DAILY;UNTIL=20260301T170000Z",
+		"FREQ=WEEKLY;BYDAY=MO\nEXDATE:20261225,20270101T090000",
+	}
+	for _, s := range rules {
+		r, err := Parse(s)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", s, err)
+			continue
+		}
+		if got := r.String(); got != s {
+			t.Errorf("Parse(%q).String() = %q", s, got)
+		}
+		again, err := Parse(r.String())
+		if err != nil || !reflect.DeepEqual(again, r) {
+			t.Errorf("reparsing %q = %+v, %v; want %+v", s, again, err, r)
+		}
+	}
+}
+
+func TestParseNormalizes(t *testing.T) {
+	tests := map[string]string{
+		"RRULE:FREQ=WEEKLY;BYDAY=MO":                   "FREQ=WEEKLY;BYDAY=MO",
+		"freq=monthly;bymonthday=5":                    "FREQ=MONTHLY;BYMONTHDAY=5",
+		"FREQ=DAILY;INTERVAL=1":                        "FREQ=DAILY",
+		"FREQ=WEEKLY;WKST=MO;BYDAY=FR":                 "FREQ=WEEKLY;BYDAY=FR",
+		"FREQ=DAILY\r\nEXDATE:20260301":                "FREQ=DAILY\nEXDATE:20260301",
+		"  FREQ=MONTHLY;BYDAY=+2TU  ":                  "FREQ=MONTHLY;BYDAY=2TU",
+		"RRULE:BYDAY=MO;FREQ=WEEKLY;X-NONWORKDAY=SKIP": "FREQ=WEEKLY;BYDAY=MO;X-NONWORKDAY=SKIP",
+	}
+	for in, want := range tests {
+		r, err := Parse(in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", in, err)
+			continue
+		}
+		if got := r.String(); got != want {
+			t.Errorf("Parse(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	rules := []string{
+		"",
+		"weekly:funday",
+		"monthly",
+		"EXDATE:20260101",
+		"FREQ=HOURLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20261231",
+		"FREQ=DAILY;UNTIL=2026-12-31",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=MONTHLY;BYDAY=6MO",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYMONTHDAY=5",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=DAILY;BYSETPOS=1",
+		"FREQ=DAILY;X-NONWORKDAY=LATER",
+		"FREQ=MONTHLY;INTERVAL=2;BYMONTHDAY=1;X-NONWORKDAY=NEXT",
+		"FREQ=MONTHLY;X-NONWORKDAY=NEXT",
+		"FREQ=DAILY\nFREQ=WEEKLY",
+		"FREQ=DAILY\nEXDATE",
+		"FREQ=DAILY\nDTSTART:20260101",
+	}
+	for _, s := range rules {
+		if r, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", s, r)
+		}
+	}
+}
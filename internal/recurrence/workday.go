@@ -0,0 +1,100 @@
+package recurrence
+
+import (
+	"fmt"
+	"time"
+)
+
+// Shift is what a rule does with an occurrence on a day off: a weekend, or a
+// holiday of the calendar the rule is evaluated with. It is stored as the
+// X-NONWORKDAY part of the RRULE, e.g.
+//
+//	FREQ=MONTHLY;BYMONTHDAY=25;X-NONWORKDAY=PREV
+type Shift int
+
+const (
+	ShiftNone Shift = iota
+	ShiftSkip       // SKIP: drop the occurrence
+	ShiftPrev       // PREV: move it to the workday before
+	ShiftNext       // NEXT: move it to the workday after
+)
+
+var shiftNames = map[string]Shift{"SKIP": ShiftSkip, "PREV": ShiftPrev, "NEXT": ShiftNext}
+
+func (s Shift) String() string {
+	for name, v := range shiftNames {
+		if v == s {
+			return name
+		}
+	}
+	return ""
+}
+
+// maxShiftDays bounds the search for a workday; the longest run of days off
+// (Lebaran with its cuti bersama and a weekend on each side) is well below.
+const maxShiftDays = 31
+
+// Holidays tells which dates are days off besides weekends.
+type Holidays interface {
+	IsHoliday(year int, month time.Month, day int) bool
+}
+
+// IsWorkday reports whether the date of t is neither a weekend nor a holiday
+// of holidays. With nil holidays only weekends are days off.
+func IsWorkday(t time.Time, holidays Holidays) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	return holidays == nil || !holidays.IsHoliday(t.Year(), t.Month(), t.Day())
+}
+
+// shift applies the rule's Shift to an occurrence, keeping its time of day.
+// It returns false when the occurrence is dropped.
+func (r *Rule) shift(t time.Time, holidays Holidays) (time.Time, bool) {
+	if r.Shift == ShiftNone || IsWorkday(t, holidays) {
+		return t, true
+	}
+	step := 0
+	switch r.Shift {
+	case ShiftPrev:
+		step = -1
+	case ShiftNext:
+		step = 1
+	default:
+		return time.Time{}, false
+	}
+	for i := 0; i < maxShiftDays; i++ {
+		t = t.AddDate(0, 0, step)
+		if IsWorkday(t, holidays) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// checkShift makes sure every occurrence's date comes from the rule itself
+// rather than from the series start. A shifted occurrence becomes the start
+// of the series from then on, so a date taken from it would drift.
+func (r *Rule) checkShift() error {
+	if r.Shift == ShiftNone {
+		return nil
+	}
+	if r.Interval > 1 {
+		return fmt.Errorf("X-NONWORKDAY needs INTERVAL=1")
+	}
+	switch r.Freq {
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return fmt.Errorf("X-NONWORKDAY with FREQ=WEEKLY needs BYDAY")
+		}
+	case Monthly:
+		if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 {
+			return fmt.Errorf("X-NONWORKDAY with FREQ=MONTHLY needs BYDAY or BYMONTHDAY")
+		}
+	case Yearly:
+		if len(r.ByDay) == 0 && (len(r.ByMonth) == 0 || len(r.ByMonthDay) == 0) {
+			return fmt.Errorf("X-NONWORKDAY with FREQ=YEARLY needs BYDAY, or BYMONTH and BYMONTHDAY")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/holiday"
+)
+
+// bundledHolidays is the calendar shipped with the bot. Idul Fitri keeps
+// 2025-03-28..04-07 and 2026-03-18..24 off, weekends included.
+func bundledHolidays(t *testing.T) *holiday.Calendar {
+	t.Helper()
+	cal, err := holiday.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cal
+}
+
+func jakarta(t *testing.T) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestShift(t *testing.T) {
+	cal := bundledHolidays(t)
+	loc := jakarta(t)
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name     string
+		shift    Shift
+		holidays Holidays
+		in       string
+		want     string // empty means dropped
+	}{
+		{"next over Idul Fitri 2025", ShiftNext, cal, "2025-04-01 09:00", "2025-04-08 09:00"},
+		{"prev over Idul Fitri and Nyepi 2025", ShiftPrev, cal, "2025-04-01 09:00", "2025-03-27 09:00"},
+		{"skip on Idul Fitri 2025", ShiftSkip, cal, "2025-04-01 09:00", ""},
+		{"next from the last day off 2025", ShiftNext, cal, "2025-04-07 17:30", "2025-04-08 17:30"},
+		{"next over Idul Fitri 2026", ShiftNext, cal, "2026-03-20 08:00", "2026-03-25 08:00"},
+		{"prev over Idul Fitri and Nyepi 2026", ShiftPrev, cal, "2026-03-24 08:00", "2026-03-17 08:00"},
+		{"skip on cuti bersama 2026", ShiftSkip, cal, "2026-03-23 08:00", ""},
+		{"workday stays", ShiftNext, cal, "2025-04-08 09:00", "2025-04-08 09:00"},
+		{"no shift on a holiday", ShiftNone, cal, "2025-04-01 09:00", "2025-04-01 09:00"},
+		{"fixed holiday", ShiftNext, cal, "2025-05-01 09:00", "2025-05-02 09:00"},
+		{"without a calendar holidays are workdays", ShiftNext, nil, "2025-04-01 09:00", "2025-04-01 09:00"},
+		{"without a calendar weekends are still off", ShiftNext, nil, "2025-04-05 09:00", "2025-04-07 09:00"},
+		{"prev over a weekend", ShiftPrev, nil, "2025-04-06 09:00", "2025-04-04 09:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Rule{Shift: tt.shift}
+			got, ok := r.shift(at(tt.in), tt.holidays)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("shift = %s, want dropped", got)
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("dropped")
+			}
+			if got.Location() != loc || got.Format("2006-01-02 15:04") != tt.want {
+				t.Errorf("shift = %s, want %s in %s", got, tt.want, loc)
+			}
+		})
+	}
+}
+
+func TestAfterWithNonWorkday(t *testing.T) {
+	cal := bundledHolidays(t)
+	loc := jakarta(t)
+	at := func(s string) time.Time {
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		rule  string
+		start string
+		after string
+		want  string
+	}{
+		{"FREQ=MONTHLY;BYMONTHDAY=1;X-NONWORKDAY=NEXT", "2025-03-01 09:00", "2025-03-15 00:00", "2025-04-08 09:00"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1;X-NONWORKDAY=PREV", "2025-03-01 09:00", "2025-03-15 00:00", "2025-03-27 09:00"},
+		// The April occurrence moves before after, so May's is next: 1 May
+		// is a holiday too.
+		{"FREQ=MONTHLY;BYMONTHDAY=1;X-NONWORKDAY=PREV", "2025-03-01 09:00", "2025-03-28 00:00", "2025-04-30 09:00"},
+		// April, May (Hari Buruh) and June (Pancasila, a Sunday) are dropped.
+		{"FREQ=MONTHLY;BYMONTHDAY=1;X-NONWORKDAY=SKIP", "2025-03-01 09:00", "2025-03-15 00:00", "2025-07-01 09:00"},
+		{"FREQ=MONTHLY;BYMONTHDAY=20;X-NONWORKDAY=NEXT", "2026-02-20 08:00", "2026-02-21 00:00", "2026-03-25 08:00"},
+		{"FREQ=MONTHLY;BYMONTHDAY=20;X-NONWORKDAY=PREV", "2026-02-20 08:00", "2026-02-21 00:00", "2026-03-17 08:00"},
+		{"FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;X-NONWORKDAY=SKIP", "2026-03-16 09:00", "2026-03-17 10:00", "2026-03-25 09:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule+" after "+tt.after, func(t *testing.T) {
+			r, err := Parse(tt.rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := r.After(at(tt.start), at(tt.after), loc, cal)
+			if !ok {
+				t.Fatal("series ended")
+			}
+			if got.Format("2006-01-02 15:04") != tt.want {
+				t.Errorf("After = %s, want %s", got.Format("2006-01-02 15:04"), tt.want)
+			}
+		})
+	}
+}
+
+// TestAdvanceFromShiftedOccurrence continues a series stored at a shifted
+// occurrence: the next date still comes from BYMONTHDAY.
+func TestAdvanceFromShiftedOccurrence(t *testing.T) {
+	cal := bundledHolidays(t)
+	loc := jakarta(t)
+	r, err := Parse("FREQ=MONTHLY;BYMONTHDAY=1;X-NONWORKDAY=NEXT")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := time.Date(2025, 4, 8, 9, 0, 0, 0, loc)
+	got, _, ok := r.Advance(current, current, loc, cal)
+	if !ok {
+		t.Fatal("series ended")
+	}
+	// 1 May is Hari Buruh.
+	if want := time.Date(2025, 5, 2, 9, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("Advance = %s, want %s", got, want)
+	}
+}
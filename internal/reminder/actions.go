@@ -0,0 +1,79 @@
+package reminder
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/recurrence"
+	tele "gopkg.in/telebot.v4"
+)
+
+// ActionUnique is the callback endpoint for the buttons on a fired reminder.
+// The button data is "<action>|<reminder id>".
+const ActionUnique = "rem"
+
+const (
+	ActionDone           = "done"
+	ActionSnooze10m      = "s10m"
+	ActionSnooze1h       = "s1h"
+	ActionSnoozeTomorrow = "stmr"
+	ActionSkip           = "skip"
+	ActionAck            = "ack" // stop nagging without completing the todo
+)
+
+// notificationMarkup builds the one-tap buttons attached to a fired reminder.
+// Skip is only offered for recurring reminders, Oke only for nagging ones.
+func notificationMarkup(r ReminderWithTodo) *tele.ReplyMarkup {
+	m := &tele.ReplyMarkup{}
+	id := strconv.Itoa(r.ID)
+
+	first := m.Row(m.Data("✅ Done", ActionUnique, ActionDone, id))
+	if r.NagEveryMinutes != nil {
+		first = append(first, m.Data("👍 Oke", ActionUnique, ActionAck, id))
+	}
+	rows := []tele.Row{
+		first,
+		m.Row(
+			m.Data("💤 10m", ActionUnique, ActionSnooze10m, id),
+			m.Data("💤 1j", ActionUnique, ActionSnooze1h, id),
+			m.Data("💤 Besok", ActionUnique, ActionSnoozeTomorrow, id),
+		),
+	}
+	if r.IsRecurring && r.RecurrenceRule != nil {
+		rows = append(rows, m.Row(m.Data("⏭ Skip", ActionUnique, ActionSkip, id)))
+	}
+	m.Inline(rows...)
+	return m
+}
+
+// SnoozeUntil returns when a snoozed reminder should fire again, or false
+// when action is not a snooze action.
+func SnoozeUntil(action string, now time.Time) (time.Time, bool) {
+	switch action {
+	case ActionSnooze10m:
+		return now.Add(10 * time.Minute), true
+	case ActionSnooze1h:
+		return now.Add(time.Hour), true
+	case ActionSnoozeTomorrow:
+		return now.AddDate(0, 0, 1), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// NextOccurrence returns where a recurring reminder moves after the
+// occurrence at current: the first occurrence after now, with the rule
+// updated for the occurrences used up. It is nil when the series has ended.
+// Occurrences follow the wall clock in loc, across DST changes, and are
+// moved off the days off of holidays as the rule says.
+func NextOccurrence(current time.Time, rule string, loc *time.Location, now time.Time, holidays recurrence.Holidays) (*Next, error) {
+	r, err := recurrence.Parse(rule)
+	if err != nil {
+		return nil, err
+	}
+	at, rest, ok := r.Advance(current, now, loc, holidays)
+	if !ok {
+		return nil, nil
+	}
+	return &Next{RemindAt: at, Rule: rest.String()}, nil
+}
@@ -0,0 +1,78 @@
+package reminder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+)
+
+// userLocation resolves a timezone the way services do: from the settings of
+// the user in the context.
+func userLocation(t *testing.T, timezone string) *time.Location {
+	t.Helper()
+	ctx := settings.WithUser(context.Background(), &settings.Settings{Timezone: timezone})
+	loc := settings.Location(ctx, time.UTC)
+	if loc.String() != timezone {
+		t.Fatalf("location = %s, want %s", loc, timezone)
+	}
+	return loc
+}
+
+func TestNextOccurrenceAcrossDST(t *testing.T) {
+	tests := []struct {
+		name     string
+		timezone string
+		rule     string
+		current  string // local wall clock
+		now      string // local wall clock; empty means just after current
+		want     string // local wall clock
+		// elapsed is the real time between the occurrences; 0 skips the
+		// check for a repeated hour, whose offset time.Date picks.
+		elapsed time.Duration
+	}{
+		{"daily over spring-forward", "America/New_York", "FREQ=DAILY", "2026-03-07 09:00", "", "2026-03-08 09:00", 23 * time.Hour},
+		{"daily over fall-back", "America/New_York", "FREQ=DAILY", "2026-10-31 09:00", "", "2026-11-01 09:00", 25 * time.Hour},
+		{"weekly over spring-forward", "America/New_York", "FREQ=WEEKLY;BYDAY=SU", "2026-03-01 09:00", "", "2026-03-08 09:00", 7*24*time.Hour - time.Hour},
+		{"monthly over spring-forward", "America/New_York", "FREQ=MONTHLY;BYMONTHDAY=8", "2026-02-08 09:00", "", "2026-03-08 09:00", 28*24*time.Hour - time.Hour},
+		{"repeated hour on fall-back", "America/New_York", "FREQ=DAILY", "2026-10-31 01:30", "", "2026-11-01 01:30", 0},
+		{"catch-up over spring-forward", "America/New_York", "FREQ=DAILY", "2026-03-06 09:00", "2026-03-09 12:00", "2026-03-10 09:00", 4*24*time.Hour - time.Hour},
+		{"daily over spring-forward", "Europe/Berlin", "FREQ=DAILY", "2026-03-28 08:00", "", "2026-03-29 08:00", 23 * time.Hour},
+		{"daily over fall-back", "Europe/Berlin", "FREQ=DAILY", "2026-10-24 08:00", "", "2026-10-25 08:00", 25 * time.Hour},
+		{"weekly over fall-back", "Europe/Berlin", "FREQ=WEEKLY;BYDAY=SU", "2026-10-18 20:00", "", "2026-10-25 20:00", 7*24*time.Hour + time.Hour},
+		{"repeated hour on fall-back", "Europe/Berlin", "FREQ=DAILY", "2026-10-24 02:30", "", "2026-10-25 02:30", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.timezone+" "+tt.name, func(t *testing.T) {
+			loc := userLocation(t, tt.timezone)
+			parse := func(s string) time.Time {
+				v, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return v
+			}
+			current := parse(tt.current)
+			now := current.Add(time.Minute)
+			if tt.now != "" {
+				now = parse(tt.now)
+			}
+
+			next, err := NextOccurrence(current.UTC(), tt.rule, loc, now.UTC(), nil)
+			if err != nil {
+				t.Fatalf("NextOccurrence: %v", err)
+			}
+			if next == nil {
+				t.Fatal("series ended")
+			}
+			if got := next.RemindAt.In(loc).Format("2006-01-02 15:04"); got != tt.want {
+				t.Errorf("next = %s, want %s", got, tt.want)
+			}
+			if got := next.RemindAt.Sub(current); tt.elapsed != 0 && got != tt.elapsed {
+				t.Errorf("elapsed = %v, want %v", got, tt.elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package reminder
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Delivery statuses recorded in reminder_deliveries.
+const (
+	DeliveryClaimed = "claimed" // a scheduler holds the lease and is sending
+	DeliverySent    = "sent"
+	DeliverySkipped = "skipped" // missed during downtime and dropped by the catch-up policy
+	DeliveryRetry   = "retry"   // the send failed; the reminder is claimable again after a backoff
+	DeliveryDead    = "dead"    // dead letter: gave up, the reminder is parked until requeued
+)
+
+// Claim is a due reminder leased to one scheduler, with the delivery record
+// for this occurrence (reminder ID + scheduled time).
+type Claim struct {
+	ReminderWithTodo
+	DeliveryID int
+	Attempts   int
+	// AlreadySent means this occurrence was delivered before, so it must be
+	// advanced without sending again.
+	AlreadySent bool
+	// Nag means the claim is a repeat of an occurrence already sent (see
+	// Reminder.NagEveryMinutes), not a new occurrence.
+	Nag bool
+}
+
+// scheduledFor is the time the delivery record is keyed on.
+func (c *Claim) scheduledFor() time.Time {
+	if c.Nag {
+		return *c.NagAt
+	}
+	return c.RemindAt
+}
+
+// ClaimDue leases up to limit due reminders to worker, including repeats of
+// nagging reminders whose todo is not done yet. Rows are locked with
+// SKIP LOCKED and leased ones are skipped, so concurrent schedulers (several
+// replicas, or a slow tick overlapping the next) never claim the same
+// reminder. The lease is measured on the database clock so replicas with
+// skewed clocks agree.
+func (r *Repository) ClaimDue(ctx context.Context, worker string, lease time.Duration, limit int) ([]Claim, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin claim reminders: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT r.id, r.todo_id, r.remind_at, r.is_recurring, r.recurrence_rule, r.last_fired_at, r.is_active, r.catch_up, r.offset_minutes,
+		        r.nag_every_minutes, r.nag_max, r.nag_at, r.nag_count, r.nag_for, r.paused_at, r.created_at,
+		        COALESCE(t.title, r.title), COALESCE(t.user_id, r.user_id),
+		        NOT (r.remind_at <= NOW() AND r.is_active) AS nag
+		 FROM reminders r
+		 LEFT JOIN todos t ON t.id = r.todo_id
+		 WHERE ((r.remind_at <= NOW() AND r.is_active = TRUE) OR (r.nag_at <= NOW() AND t.is_completed IS NOT TRUE))
+		   AND t.deleted_at IS NULL AND r.paused_at IS NULL
+		   AND (r.claimed_until IS NULL OR r.claimed_until < NOW())
+		   AND NOT EXISTS (
+		       SELECT 1 FROM reminder_deliveries d WHERE d.reminder_id = r.id AND d.status = 'dead'
+		   )
+		 ORDER BY CASE WHEN r.remind_at <= NOW() AND r.is_active THEN r.remind_at ELSE r.nag_at END ASC
+		 LIMIT $1
+		 FOR UPDATE OF r SKIP LOCKED`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("select due reminders: %w", err)
+	}
+	var claims []Claim
+	for rows.Next() {
+		var c Claim
+		err := rows.Scan(
+			&c.ID, &c.TodoID, &c.RemindAt, &c.IsRecurring, &c.RecurrenceRule,
+			&c.LastFiredAt, &c.IsActive, &c.CatchUp, &c.OffsetMinutes,
+			&c.NagEveryMinutes, &c.NagMax, &c.NagAt, &c.NagCount, &c.NagFor, &c.PausedAt, &c.CreatedAt,
+			&c.TodoTitle, &c.TodoUserID, &c.Nag,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan due reminder: %w", err)
+		}
+		claims = append(claims, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(claims) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]int, len(claims))
+	for i, c := range claims {
+		ids[i] = c.ID
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE reminders SET claimed_until = NOW() + make_interval(secs => $1) WHERE id = ANY($2)`,
+		lease.Seconds(), pq.Array(ids),
+	); err != nil {
+		return nil, fmt.Errorf("lease reminders: %w", err)
+	}
+
+	for i := range claims {
+		c := &claims[i]
+		var status string
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO reminder_deliveries (reminder_id, scheduled_for, status, claimed_by)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (reminder_id, scheduled_for) DO UPDATE SET
+			     status = CASE WHEN reminder_deliveries.status = $5 THEN reminder_deliveries.status ELSE EXCLUDED.status END,
+			     attempts = reminder_deliveries.attempts + CASE WHEN reminder_deliveries.status = $5 THEN 0 ELSE 1 END,
+			     claimed_by = EXCLUDED.claimed_by,
+			     claimed_at = NOW()
+			 RETURNING id, status, attempts`,
+			c.ID, c.scheduledFor(), DeliveryClaimed, worker, DeliverySent,
+		).Scan(&c.DeliveryID, &status, &c.Attempts)
+		if err != nil {
+			return nil, fmt.Errorf("record reminder delivery: %w", err)
+		}
+		c.AlreadySent = status == DeliverySent
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// MarkSent records a claimed occurrence as delivered and advances the
+// reminder in the same transaction: to next for recurring reminders, or
+// deactivated when next is nil (one-time, or the series has ended). nagAt is
+// when the occurrence is repeated, nil to stop nagging. A nag claim only
+// counts the repeat; the reminder itself was advanced when first sent. The
+// lease is released.
+func (r *Repository) MarkSent(ctx context.Context, c *Claim, next *Next, nagAt *time.Time) error {
+	return r.finish(ctx, c, DeliverySent, next, nagAt)
+}
+
+// MarkSkipped is MarkSent for an occurrence dropped without sending. It is
+// not repeated.
+func (r *Repository) MarkSkipped(ctx context.Context, c *Claim, next *Next) error {
+	return r.finish(ctx, c, DeliverySkipped, next, nil)
+}
+
+func (r *Repository) finish(ctx context.Context, c *Claim, status string, next *Next, nagAt *time.Time) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin finish delivery: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE reminder_deliveries SET status = $1, finished_at = NOW() WHERE id = $2`,
+		status, c.DeliveryID,
+	); err != nil {
+		return fmt.Errorf("update delivery status: %w", err)
+	}
+
+	switch {
+	case c.Nag:
+		_, err = tx.ExecContext(ctx,
+			`UPDATE reminders SET nag_at = $1, nag_count = nag_count + 1, last_fired_at = NOW(), claimed_until = NULL WHERE id = $2`,
+			nagAt, c.ID,
+		)
+	case next != nil:
+		_, err = tx.ExecContext(ctx,
+			`UPDATE reminders SET remind_at = $1, recurrence_rule = $2, last_fired_at = NOW(), claimed_until = NULL,
+			     nag_at = $3, nag_count = 0, nag_for = $4
+			 WHERE id = $5`,
+			next.RemindAt, next.Rule, nagAt, c.RemindAt, c.ID,
+		)
+	default:
+		_, err = tx.ExecContext(ctx,
+			`UPDATE reminders SET is_active = FALSE, last_fired_at = NOW(), claimed_until = NULL,
+			     nag_at = $1, nag_count = 0, nag_for = $2
+			 WHERE id = $3`,
+			nagAt, c.RemindAt, c.ID,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("advance reminder: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeadLetter gives up on a claimed occurrence. The reminder is not advanced:
+// ClaimDue skips it while the dead delivery exists, so a recurring reminder
+// to a user who blocked the bot does not pile up a dead letter per occurrence.
+func (r *Repository) DeadLetter(ctx context.Context, c *Claim, sendErr error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin dead letter: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE reminder_deliveries SET status = $1, last_error = $2, finished_at = NOW() WHERE id = $3`,
+		DeliveryDead, sendErr.Error(), c.DeliveryID,
+	); err != nil {
+		return fmt.Errorf("update delivery status: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE reminders SET claimed_until = NULL WHERE id = $1`,
+		c.ID,
+	); err != nil {
+		return fmt.Errorf("release reminder claim: %w", err)
+	}
+	return tx.Commit()
+}
+
+// RetryDelivery records a failed send and keeps the reminder leased for
+// wait, after which any scheduler may claim it again.
+func (r *Repository) RetryDelivery(ctx context.Context, c *Claim, sendErr error, wait time.Duration) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin retry delivery: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE reminder_deliveries SET status = $1, last_error = $2 WHERE id = $3`,
+		DeliveryRetry, sendErr.Error(), c.DeliveryID,
+	); err != nil {
+		return fmt.Errorf("update delivery status: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE reminders SET claimed_until = NOW() + make_interval(secs => $1) WHERE id = $2`,
+		wait.Seconds(), c.ID,
+	); err != nil {
+		return fmt.Errorf("postpone reminder claim: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeadDelivery is a dead-lettered occurrence, for the admin view.
+type DeadDelivery struct {
+	ID           int
+	ReminderID   int
+	TodoTitle    string
+	UserID       int64
+	ScheduledFor time.Time
+	Attempts     int
+	LastError    *string
+	FinishedAt   time.Time
+}
+
+// ListDeadDeliveries returns all dead letters, oldest first.
+func (r *Repository) ListDeadDeliveries(ctx context.Context) ([]DeadDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT d.id, d.reminder_id, COALESCE(t.title, r.title), COALESCE(t.user_id, r.user_id), d.scheduled_for, d.attempts, d.last_error, d.finished_at
+		 FROM reminder_deliveries d
+		 JOIN reminders r ON r.id = d.reminder_id
+		 LEFT JOIN todos t ON t.id = r.todo_id
+		 WHERE d.status = $1
+		 ORDER BY d.finished_at ASC`,
+		DeliveryDead,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list dead deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []DeadDelivery
+	for rows.Next() {
+		var d DeadDelivery
+		if err := rows.Scan(&d.ID, &d.ReminderID, &d.TodoTitle, &d.UserID, &d.ScheduledFor, &d.Attempts, &d.LastError, &d.FinishedAt); err != nil {
+			return nil, fmt.Errorf("scan dead delivery: %w", err)
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+// RequeueDead puts dead letters back in the queue with a fresh attempt count,
+// all of them when ids is empty. Their reminders were never advanced, so
+// they are due again on the next tick. Returns the number requeued.
+func (r *Repository) RequeueDead(ctx context.Context, ids []int) (int, error) {
+	var (
+		res sql.Result
+		err error
+	)
+	if len(ids) == 0 {
+		res, err = r.db.ExecContext(ctx,
+			`UPDATE reminder_deliveries SET status = $1, attempts = 0, finished_at = NULL WHERE status = $2`,
+			DeliveryRetry, DeliveryDead,
+		)
+	} else {
+		res, err = r.db.ExecContext(ctx,
+			`UPDATE reminder_deliveries SET status = $1, attempts = 0, finished_at = NULL WHERE status = $2 AND id = ANY($3)`,
+			DeliveryRetry, DeliveryDead, pq.Array(ids),
+		)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("requeue dead deliveries: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("requeue dead deliveries: %w", err)
+	}
+	return int(n), nil
+}
+
+// DeleteDeliveriesBefore drops finished delivery records older than before.
+// Dead letters are kept until they are requeued.
+func (r *Repository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM reminder_deliveries WHERE finished_at IS NOT NULL AND finished_at < $1 AND status <> $2`,
+		before, DeliveryDead,
+	)
+	if err != nil {
+		return fmt.Errorf("delete old reminder deliveries: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,367 @@
+package reminder
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/notify"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+	tele "gopkg.in/telebot.v4"
+)
+
+// testDB returns a database with the migrations applied, in a schema of its
+// own that is dropped after the test. It needs TEST_DATABASE_URL, e.g.
+// postgres://postgres@localhost/bot_test?sslmode=disable.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		admin.Close()
+	})
+
+	database, err := sql.Open("postgres", withSearchPath(t, dsn, schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	dir, err := filepath.Abs("../../migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver, err := postgres.WithInstance(database, &postgres.Config{})
+	if err != nil {
+		t.Fatalf("migration driver: %v", err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://"+dir, "postgres", driver)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("run migrations: %v", err)
+	}
+	return database
+}
+
+// withSearchPath points every connection of dsn, a URL or key=value string,
+// at schema.
+func withSearchPath(t *testing.T, dsn, schema string) string {
+	t.Helper()
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " search_path=" + schema
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// sentMessage is a message the fake Telegram API received.
+type sentMessage struct {
+	UserID int64
+	Text   string
+}
+
+// fakeTelegram accepts sendMessage calls and records them.
+type fakeTelegram struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	sent []sentMessage
+}
+
+func newFakeTelegram(t *testing.T) *fakeTelegram {
+	t.Helper()
+	f := &fakeTelegram{}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req struct {
+			ChatID string `json:"chat_id"`
+			Text   string `json:"text"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil || !strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			http.Error(w, `{"ok":false,"error_code":400,"description":"Bad Request"}`, http.StatusBadRequest)
+			return
+		}
+		userID, _ := strconv.ParseInt(req.ChatID, 10, 64)
+
+		f.mu.Lock()
+		f.sent = append(f.sent, sentMessage{UserID: userID, Text: req.Text})
+		id := len(f.sent)
+		f.mu.Unlock()
+
+		fmt.Fprintf(w, `{"ok":true,"result":{"message_id":%d,"date":0,"chat":{"id":%d,"type":"private"},"text":%q}}`, id, userID, req.Text)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeTelegram) Sent() []sentMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]sentMessage(nil), f.sent...)
+}
+
+// newTestScheduler wires a scheduler the way main does, sending to tg.
+func newTestScheduler(t *testing.T, database *sql.DB, tg *fakeTelegram, worker string) *Scheduler {
+	t.Helper()
+	bot, err := tele.NewBot(tele.Settings{URL: tg.URL, Token: "test", Offline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settingsSvc := settings.NewService(settings.NewRepository(database), loc, 7)
+	queue := notify.NewQueue(notify.NewRepository(database), bot, settingsSvc, time.Minute)
+	s := NewScheduler(NewRepository(database), queue, time.Minute, time.Hour, settingsSvc, nil)
+	s.worker = worker
+	return s
+}
+
+type deliveryRow struct {
+	ReminderID   int
+	ScheduledFor time.Time
+	Status       string
+	Attempts     int
+}
+
+func deliveries(t *testing.T, database *sql.DB) []deliveryRow {
+	t.Helper()
+	rows, err := database.Query(`SELECT reminder_id, scheduled_for, status, attempts FROM reminder_deliveries ORDER BY reminder_id, scheduled_for`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var out []deliveryRow
+	for rows.Next() {
+		var d deliveryRow
+		if err := rows.Scan(&d.ReminderID, &d.ScheduledFor, &d.Status, &d.Attempts); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+type reminderState struct {
+	RemindAt time.Time
+	IsActive bool
+	Claimed  bool
+}
+
+func reminderStateOf(t *testing.T, database *sql.DB, id int) reminderState {
+	t.Helper()
+	var s reminderState
+	err := database.QueryRow(`SELECT remind_at, is_active, claimed_until IS NOT NULL FROM reminders WHERE id = $1`, id).
+		Scan(&s.RemindAt, &s.IsActive, &s.Claimed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestConcurrentTicksSendEachOccurrenceOnce(t *testing.T) {
+	database := testDB(t)
+	tg := newFakeTelegram(t)
+	repo := NewRepository(database)
+	ctx := context.Background()
+
+	due := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	type created struct {
+		id        int
+		recurring bool
+	}
+	var reminders []created
+	for i := 0; i < 30; i++ {
+		rule := ""
+		if i%3 == 0 {
+			rule = "FREQ=DAILY"
+		}
+		id, err := repo.CreateStandalone(ctx, int64(100+i%4), fmt.Sprintf("reminder %d", i), due, rule, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reminders = append(reminders, created{id, rule != ""})
+	}
+
+	// Several replicas tick at the same moment, a few times over.
+	schedulers := make([]*Scheduler, 6)
+	for i := range schedulers {
+		schedulers[i] = newTestScheduler(t, database, tg, fmt.Sprintf("worker-%d", i))
+	}
+	for round := 0; round < 3; round++ {
+		var wg sync.WaitGroup
+		for _, s := range schedulers {
+			wg.Add(1)
+			go func(s *Scheduler) {
+				defer wg.Done()
+				s.tick()
+			}(s)
+		}
+		wg.Wait()
+	}
+
+	if got := len(tg.Sent()); got != len(reminders) {
+		t.Errorf("sent %d messages, want %d", got, len(reminders))
+	}
+	perOccurrence := map[int]int{}
+	for _, d := range deliveries(t, database) {
+		if d.Status != DeliverySent || !d.ScheduledFor.Equal(due) {
+			t.Errorf("delivery %+v, want one sent delivery for %s", d, due)
+		}
+		perOccurrence[d.ReminderID]++
+	}
+	for _, r := range reminders {
+		if perOccurrence[r.id] != 1 {
+			t.Errorf("reminder %d has %d deliveries, want 1", r.id, perOccurrence[r.id])
+		}
+		st := reminderStateOf(t, database, r.id)
+		if st.Claimed {
+			t.Errorf("reminder %d still leased", r.id)
+		}
+		if r.recurring && (!st.IsActive || !st.RemindAt.After(time.Now())) {
+			t.Errorf("recurring reminder %d not advanced: %+v", r.id, st)
+		}
+		if !r.recurring && st.IsActive {
+			t.Errorf("one-time reminder %d still active", r.id)
+		}
+	}
+}
+
+func TestClaimDueSkipsLeasedReminders(t *testing.T) {
+	database := testDB(t)
+	repo := NewRepository(database)
+	ctx := context.Background()
+
+	if _, err := repo.CreateStandalone(ctx, 100, "minum obat", time.Now().Add(-time.Minute), "", nil); err != nil {
+		t.Fatal(err)
+	}
+	first, err := repo.ClaimDue(ctx, "worker-a", time.Minute, 10)
+	if err != nil || len(first) != 1 {
+		t.Fatalf("first claim = %v, %v; want 1 reminder", first, err)
+	}
+	second, err := repo.ClaimDue(ctx, "worker-b", time.Minute, 10)
+	if err != nil || len(second) != 0 {
+		t.Fatalf("second claim = %v, %v; want none while leased", second, err)
+	}
+}
+
+func TestExpiredLeaseIsSentOnce(t *testing.T) {
+	database := testDB(t)
+	tg := newFakeTelegram(t)
+	repo := NewRepository(database)
+	ctx := context.Background()
+
+	due := time.Now().Add(-5 * time.Minute).Truncate(time.Second)
+	id, err := repo.CreateStandalone(ctx, 100, "bayar wifi", due, "FREQ=DAILY", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A replica claims the reminder and dies before sending.
+	if claims, err := repo.ClaimDue(ctx, "crashed", time.Minute, 10); err != nil || len(claims) != 1 {
+		t.Fatalf("claim = %v, %v", claims, err)
+	}
+	if _, err := database.Exec(`UPDATE reminders SET claimed_until = NOW() - INTERVAL '1 second' WHERE id = $1`, id); err != nil {
+		t.Fatal(err)
+	}
+
+	newTestScheduler(t, database, tg, "survivor").tick()
+
+	if got := len(tg.Sent()); got != 1 {
+		t.Errorf("sent %d messages, want 1", got)
+	}
+	ds := deliveries(t, database)
+	if len(ds) != 1 || ds[0].Status != DeliverySent || ds[0].Attempts != 2 {
+		t.Errorf("deliveries = %+v, want one sent on the second attempt", ds)
+	}
+	if st := reminderStateOf(t, database, id); !st.RemindAt.Equal(due.AddDate(0, 0, 1)) || st.Claimed {
+		t.Errorf("reminder = %+v, want advanced to %s and released", st, due.AddDate(0, 0, 1))
+	}
+}
+
+func TestAlreadySentOccurrenceIsAdvancedWithoutSending(t *testing.T) {
+	database := testDB(t)
+	tg := newFakeTelegram(t)
+	repo := NewRepository(database)
+	ctx := context.Background()
+
+	due := time.Now().Add(-5 * time.Minute).Truncate(time.Second)
+	recurringID, err := repo.CreateStandalone(ctx, 100, "bayar wifi", due, "FREQ=DAILY", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oneTimeID, err := repo.CreateStandalone(ctx, 100, "minum obat", due, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A replica sends both and dies before advancing the reminders: the
+	// deliveries are sent, the reminders still due once the lease expires.
+	claims, err := repo.ClaimDue(ctx, "crashed", time.Minute, 10)
+	if err != nil || len(claims) != 2 {
+		t.Fatalf("claim = %v, %v", claims, err)
+	}
+	if _, err := database.Exec(`UPDATE reminder_deliveries SET status = $1`, DeliverySent); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec(`UPDATE reminders SET claimed_until = NOW() - INTERVAL '1 second'`); err != nil {
+		t.Fatal(err)
+	}
+
+	newTestScheduler(t, database, tg, "survivor").tick()
+
+	if sent := tg.Sent(); len(sent) != 0 {
+		t.Errorf("sent %+v, want nothing", sent)
+	}
+	ds := deliveries(t, database)
+	if len(ds) != 2 {
+		t.Fatalf("deliveries = %+v, want 2", ds)
+	}
+	for _, d := range ds {
+		if d.Status != DeliverySent || d.Attempts != 1 {
+			t.Errorf("delivery %+v, want sent on the first attempt", d)
+		}
+	}
+	if st := reminderStateOf(t, database, recurringID); !st.RemindAt.Equal(due.AddDate(0, 0, 1)) || !st.IsActive || st.Claimed {
+		t.Errorf("recurring reminder = %+v, want advanced to %s", st, due.AddDate(0, 0, 1))
+	}
+	if st := reminderStateOf(t, database, oneTimeID); st.IsActive || st.Claimed {
+		t.Errorf("one-time reminder = %+v, want deactivated", st)
+	}
+}
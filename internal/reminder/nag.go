@@ -0,0 +1,83 @@
+package reminder
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+)
+
+const (
+	// DefaultNagEvery and DefaultNagMax apply when the user asks to be nagged
+	// without saying how often or how many times.
+	DefaultNagEvery = 15 * time.Minute
+	DefaultNagMax   = 8
+
+	// nagEscalateAfter is the repeat from which the message turns urgent.
+	nagEscalateAfter = 3
+)
+
+// Nag asks for a reminder to be repeated every Every, at most Max times,
+// until the user acknowledges it or completes the todo.
+type Nag struct {
+	Every time.Duration
+	Max   int
+}
+
+// Describe explains the nag setting in Indonesian: "tiap 15 menit, maks 8x".
+func (n *Nag) Describe() string {
+	return fmt.Sprintf("tiap %d menit, maks %dx", int(n.Every/time.Minute), n.Max)
+}
+
+// nagLimit is how many times an occurrence of r is repeated.
+func nagLimit(r *Reminder) int {
+	if r.NagMax != nil && *r.NagMax > 0 {
+		return *r.NagMax
+	}
+	return DefaultNagMax
+}
+
+// nextNag returns when the occurrence just sent for c is repeated, or nil
+// when the reminder does not nag or has used up its repeats. Repeats count
+// from when the user can next be messaged, so an occurrence held back for
+// quiet hours is not repeated the moment they end.
+func nextNag(c *Claim, us *settings.Settings, now time.Time) *time.Time {
+	if c.NagEveryMinutes == nil || *c.NagEveryMinutes <= 0 {
+		return nil
+	}
+	repeats := 0
+	if c.Nag {
+		repeats = c.NagCount + 1
+	}
+	if repeats >= nagLimit(&c.Reminder) {
+		return nil
+	}
+	at := us.AfterQuiet(us.AfterQuiet(now).Add(time.Duration(*c.NagEveryMinutes) * time.Minute))
+	return &at
+}
+
+// formatNagNotification formats a repeat of an unacknowledged reminder. From
+// the nagEscalateAfter-th repeat on the tone gets more urgent.
+//
+// 🚨 Masih belum dikerjakan!
+//
+// 📌 Minum obat
+// 📅 Senin, 3 Mar 2026 · 08:00
+// 🔂 Pengingat ke-3 dari 8
+func formatNagNotification(r ReminderWithTodo, loc *time.Location) string {
+	n, limit := r.NagCount+1, nagLimit(&r.Reminder)
+	original := r.RemindAt
+	if r.NagFor != nil {
+		original = *r.NagFor
+	}
+
+	header := "🔔 Pengingat ulang"
+	if n >= nagEscalateAfter {
+		header = "🚨 Masih belum dikerjakan!"
+	}
+	msg := fmt.Sprintf("%s\n\n📌 %s\n📅 %s\n🔂 Pengingat ke-%d dari %d", header, r.TodoTitle, formatWhen(original, loc), n, limit)
+	if n >= limit {
+		return msg + "\n\nIni pengingat terakhir. Tekan ✅ Done kalau sudah selesai."
+	}
+	return msg + "\n\nTekan ✅ Done atau 👍 Oke supaya berhenti diingatkan."
+}
@@ -0,0 +1,47 @@
+package reminder
+
+import (
+	"fmt"
+	"time"
+)
+
+const minutesPerDay = 24 * 60
+
+// RelativeTime returns when a reminder offsetMinutes from due fires (negative
+// is before the deadline). Whole days are counted on the wall clock in loc,
+// so "1 hari sebelum" keeps its time of day across DST. A deadline without a
+// time of day is taken at hour, the user's default reminder hour.
+func RelativeTime(due time.Time, offsetMinutes int, loc *time.Location, hour int) time.Time {
+	due = due.In(loc)
+	if due.Hour() == 0 && due.Minute() == 0 {
+		due = time.Date(due.Year(), due.Month(), due.Day(), hour, 0, 0, 0, loc)
+	}
+	days, minutes := offsetMinutes/minutesPerDay, offsetMinutes%minutesPerDay
+	return due.AddDate(0, 0, days).Add(time.Duration(minutes) * time.Minute)
+}
+
+// DescribeOffset explains a lead time in Indonesian, e.g. "1 jam sebelum
+// deadline" or "2 hari sebelum deadline".
+func DescribeOffset(offsetMinutes int) string {
+	if offsetMinutes == 0 {
+		return "saat deadline"
+	}
+	dir := "sebelum"
+	m := -offsetMinutes
+	if offsetMinutes > 0 {
+		dir, m = "setelah", offsetMinutes
+	}
+
+	var amount string
+	switch {
+	case m%(7*minutesPerDay) == 0:
+		amount = fmt.Sprintf("%d minggu", m/(7*minutesPerDay))
+	case m%minutesPerDay == 0:
+		amount = fmt.Sprintf("%d hari", m/minutesPerDay)
+	case m%60 == 0:
+		amount = fmt.Sprintf("%d jam", m/60)
+	default:
+		amount = fmt.Sprintf("%d menit", m)
+	}
+	return amount + " " + dir + " deadline"
+}
@@ -5,25 +5,67 @@ import (
 	"database/sql"
 	"fmt"
 	"time"
+
+	"github.com/lib/pq"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/db"
+)
+
+// Catch-up policies: what to do with an occurrence missed by more than the
+// scheduler's catch-up threshold, e.g. because the bot was down.
+const (
+	CatchUpFire = "fire" // deliver late, in the missed-reminders digest
+	CatchUpSkip = "skip" // drop the missed occurrence silently
 )
 
 type Reminder struct {
-	ID             int
-	TodoID         int
+	ID int
+	// TodoID is nil for standalone reminders, which have their own title
+	// and owner instead of a todo's.
+	TodoID         *int
 	RemindAt       time.Time
 	IsRecurring    bool
 	RecurrenceRule *string
 	LastFiredAt    *time.Time
 	IsActive       bool
-	CreatedAt      time.Time
+	CatchUp        string
+	// OffsetMinutes is set for reminders relative to the todo's due date
+	// (-60 is one hour before); RemindAt follows when the due date moves.
+	OffsetMinutes *int
+	// NagEveryMinutes turns on nagging: after an occurrence is sent it is
+	// sent again every so often, at most NagMax times, until the todo is
+	// done or a button on the reminder is pressed.
+	NagEveryMinutes *int
+	NagMax          *int
+	NagAt           *time.Time // next repeat, nil when not nagging
+	NagCount        int        // repeats sent for the current occurrence
+	NagFor          *time.Time // the occurrence being repeated
+	// PausedAt is set while the user has paused the reminder; it stays
+	// active but is not sent until resumed.
+	PausedAt  *time.Time
+	CreatedAt time.Time
+}
+
+// Next is where a recurring reminder moves after an occurrence. Rule is the
+// recurrence rule to store with it, since COUNT and EXDATE shrink as the
+// series goes on.
+type Next struct {
+	RemindAt time.Time
+	Rule     string
 }
 
+// ReminderWithTodo is a reminder with what its notification needs: the
+// todo's title and owner, or the reminder's own for a standalone reminder.
 type ReminderWithTodo struct {
 	Reminder
 	TodoTitle  string
 	TodoUserID int64
 }
 
+// Standalone reports whether the reminder is not backed by a todo.
+func (r *Reminder) Standalone() bool {
+	return r.TodoID == nil
+}
+
 type Repository struct {
 	db *sql.DB
 }
@@ -47,61 +89,437 @@ func (r *Repository) Create(ctx context.Context, todoID int, remindAt time.Time,
 	return nil
 }
 
-func (r *Repository) GetDueReminders(ctx context.Context) ([]ReminderWithTodo, error) {
+// CreateStandalone adds a reminder that is not backed by a todo and returns
+// its ID. nag, when set, repeats each occurrence until acknowledged.
+func (r *Repository) CreateStandalone(ctx context.Context, userID int64, title string, remindAt time.Time, recurrenceRule string, nag *Nag) (int, error) {
+	var rule *string
+	if recurrenceRule != "" {
+		rule = &recurrenceRule
+	}
+	var every, limit *int
+	if nag != nil {
+		m, n := int(nag.Every/time.Minute), nag.Max
+		every, limit = &m, &n
+	}
+	var id int
+	err := r.db.QueryRowContext(ctx,
+		`INSERT INTO reminders (user_id, title, remind_at, is_recurring, recurrence_rule, nag_every_minutes, nag_max)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		userID, title, remindAt, rule != nil, rule, every, limit,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("create standalone reminder: %w", err)
+	}
+	return id, nil
+}
+
+// FindStandalone returns the user's standalone reminder whose title matches
+// search, preferring active and then recent ones, or nil when none does.
+func (r *Repository) FindStandalone(ctx context.Context, userID int64, search string) (*ReminderWithTodo, error) {
+	var rt ReminderWithTodo
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, todo_id, remind_at, is_recurring, recurrence_rule, last_fired_at, is_active, catch_up, offset_minutes,
+		        nag_every_minutes, nag_max, nag_at, nag_count, nag_for, paused_at, created_at,
+		        title, user_id
+		 FROM reminders
+		 WHERE user_id = $1 AND todo_id IS NULL AND title ILIKE '%' || $2 || '%'
+		 ORDER BY is_active DESC, created_at DESC LIMIT 1`,
+		userID, search,
+	).Scan(
+		&rt.ID, &rt.TodoID, &rt.RemindAt, &rt.IsRecurring, &rt.RecurrenceRule,
+		&rt.LastFiredAt, &rt.IsActive, &rt.CatchUp, &rt.OffsetMinutes,
+		&rt.NagEveryMinutes, &rt.NagMax, &rt.NagAt, &rt.NagCount, &rt.NagFor, &rt.PausedAt, &rt.CreatedAt,
+		&rt.TodoTitle, &rt.TodoUserID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find standalone reminder: %w", err)
+	}
+	return &rt, nil
+}
+
+// Edit moves a reminder to remindAt with recurrence rule ("" for one-time)
+// and, for a standalone reminder, renames it to title ("" keeps the title).
+// The reminder is active again if remindAt is still to come; a repeat in
+// progress is dropped.
+func (r *Repository) Edit(ctx context.Context, id int, title string, remindAt time.Time, recurrenceRule string) error {
+	var rule *string
+	if recurrenceRule != "" {
+		rule = &recurrenceRule
+	}
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE reminders SET
+		     title = CASE WHEN todo_id IS NULL AND $2 <> '' THEN $2 ELSE title END,
+		     remind_at = $3, is_recurring = $4, recurrence_rule = $5,
+		     is_active = $3 > NOW(), last_fired_at = NULL, nag_at = NULL
+		 WHERE id = $1`,
+		id, title, remindAt, rule != nil, rule,
+	)
+	if err != nil {
+		return fmt.Errorf("edit reminder: %w", err)
+	}
+	return nil
+}
+
+// CreateRelative adds a reminder offsetMinutes from its todo's due date, due
+// at remindAt (see RelativeTime). One already in the past is stored inactive
+// so it can come back if the due date moves later.
+func (r *Repository) CreateRelative(ctx context.Context, todoID, offsetMinutes int, remindAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO reminders (todo_id, remind_at, offset_minutes, is_active) VALUES ($1, $2, $3, $2 > NOW())`,
+		todoID, remindAt, offsetMinutes,
+	)
+	if err != nil {
+		return fmt.Errorf("create relative reminder: %w", err)
+	}
+	return nil
+}
+
+// ListRelative returns a todo's reminders relative to its due date, fired
+// ones included.
+func (r *Repository) ListRelative(ctx context.Context, todoID int) ([]Reminder, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT r.id, r.todo_id, r.remind_at, r.is_recurring, r.recurrence_rule, r.last_fired_at, r.is_active, r.created_at,
-		        t.title, t.user_id
+		`SELECT id, todo_id, remind_at, is_recurring, recurrence_rule, last_fired_at, is_active, catch_up, offset_minutes,
+		        nag_every_minutes, nag_max, nag_at, nag_count, nag_for, paused_at, created_at
+		 FROM reminders WHERE todo_id = $1 AND offset_minutes IS NOT NULL
+		 ORDER BY offset_minutes ASC`,
+		todoID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list relative reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var reminders []Reminder
+	for rows.Next() {
+		var rm Reminder
+		if err := rows.Scan(&rm.ID, &rm.TodoID, &rm.RemindAt, &rm.IsRecurring, &rm.RecurrenceRule, &rm.LastFiredAt, &rm.IsActive, &rm.CatchUp, &rm.OffsetMinutes,
+			&rm.NagEveryMinutes, &rm.NagMax, &rm.NagAt, &rm.NagCount, &rm.NagFor, &rm.PausedAt, &rm.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan relative reminder: %w", err)
+		}
+		reminders = append(reminders, rm)
+	}
+	return reminders, rows.Err()
+}
+
+// Reschedule moves a reminder to at, e.g. after its todo's due date changed.
+// It is active again only if at is still to come.
+func (r *Repository) Reschedule(ctx context.Context, id int, at time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE reminders SET remind_at = $1, is_active = $1 > NOW(), last_fired_at = NULL WHERE id = $2`,
+		at, id,
+	)
+	if err != nil {
+		return fmt.Errorf("reschedule reminder: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a single reminder; the todo and its other reminders stay.
+func (r *Repository) Delete(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM reminders WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete reminder: %w", err)
+	}
+	return nil
+}
+
+// FindActive returns the user's active reminders, paused ones included,
+// whose own title or todo's title matches search, soonest first.
+func (r *Repository) FindActive(ctx context.Context, userID int64, search string) ([]ReminderWithTodo, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT r.id, r.todo_id, r.remind_at, r.is_recurring, r.recurrence_rule, r.last_fired_at, r.is_active, r.catch_up, r.offset_minutes,
+		        r.nag_every_minutes, r.nag_max, r.nag_at, r.nag_count, r.nag_for, r.paused_at, r.created_at,
+		        COALESCE(t.title, r.title), COALESCE(t.user_id, r.user_id)
 		 FROM reminders r
-		 JOIN todos t ON t.id = r.todo_id
-		 WHERE r.remind_at <= NOW() AND r.is_active = TRUE
+		 LEFT JOIN todos t ON t.id = r.todo_id
+		 WHERE COALESCE(t.user_id, r.user_id) = $1 AND r.is_active = TRUE AND t.deleted_at IS NULL
+		   AND COALESCE(t.title, r.title) ILIKE '%' || $2 || '%'
 		 ORDER BY r.remind_at ASC`,
+		userID, search,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("get due reminders: %w", err)
+		return nil, fmt.Errorf("find active reminders: %w", err)
 	}
 	defer rows.Close()
 
 	var reminders []ReminderWithTodo
 	for rows.Next() {
 		var rt ReminderWithTodo
-		err := rows.Scan(
+		if err := rows.Scan(
 			&rt.ID, &rt.TodoID, &rt.RemindAt, &rt.IsRecurring, &rt.RecurrenceRule,
-			&rt.LastFiredAt, &rt.IsActive, &rt.CreatedAt,
+			&rt.LastFiredAt, &rt.IsActive, &rt.CatchUp, &rt.OffsetMinutes,
+			&rt.NagEveryMinutes, &rt.NagMax, &rt.NagAt, &rt.NagCount, &rt.NagFor, &rt.PausedAt, &rt.CreatedAt,
 			&rt.TodoTitle, &rt.TodoUserID,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan reminder: %w", err)
+		); err != nil {
+			return nil, fmt.Errorf("scan active reminder: %w", err)
 		}
 		reminders = append(reminders, rt)
 	}
 	return reminders, rows.Err()
 }
 
-func (r *Repository) UpdateRemindAt(ctx context.Context, id int, nextTime time.Time) error {
+// Pause stops a reminder from being sent, and any repeat in progress, until
+// it is resumed.
+func (r *Repository) Pause(ctx context.Context, id int) error {
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE reminders SET remind_at = $1, last_fired_at = NOW() WHERE id = $2`,
-		nextTime, id,
+		`UPDATE reminders SET paused_at = NOW(), nag_at = NULL WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("pause reminder: %w", err)
+	}
+	return nil
+}
+
+// Resume sends a paused reminder again. next, when set, moves a recurring
+// reminder past the occurrences missed while it was paused.
+func (r *Repository) Resume(ctx context.Context, id int, next *Next) error {
+	var err error
+	if next != nil {
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE reminders SET paused_at = NULL, remind_at = $1, recurrence_rule = $2 WHERE id = $3`,
+			next.RemindAt, next.Rule, id,
+		)
+	} else {
+		_, err = r.db.ExecContext(ctx, `UPDATE reminders SET paused_at = NULL WHERE id = $1`, id)
+	}
+	if err != nil {
+		return fmt.Errorf("resume reminder: %w", err)
+	}
+	return nil
+}
+
+// Cancel stops a reminder for good, ending its series. The row stays for its
+// delivery history.
+func (r *Repository) Cancel(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE reminders SET is_active = FALSE, paused_at = NULL, nag_at = NULL WHERE id = $1`,
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("cancel reminder: %w", err)
+	}
+	return nil
+}
+
+// CancelByTodoID is Cancel for every active reminder of a todo, which itself
+// stays. It returns how many reminders were cancelled.
+func (r *Repository) CancelByTodoID(ctx context.Context, todoID int) (int, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE reminders SET is_active = FALSE, paused_at = NULL, nag_at = NULL
+		 WHERE todo_id = $1 AND (is_active = TRUE OR nag_at IS NOT NULL)`,
+		todoID,
 	)
 	if err != nil {
-		return fmt.Errorf("update remind_at: %w", err)
+		return 0, fmt.Errorf("cancel reminders by todo_id: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("cancel reminders by todo_id: %w", err)
+	}
+	return int(n), nil
+}
+
+// GetForUser returns a reminder with its todo, or nil when it does not exist
+// or belongs to another user.
+func (r *Repository) GetForUser(ctx context.Context, id int, userID int64) (*ReminderWithTodo, error) {
+	var rt ReminderWithTodo
+	err := r.db.QueryRowContext(ctx,
+		`SELECT r.id, r.todo_id, r.remind_at, r.is_recurring, r.recurrence_rule, r.last_fired_at, r.is_active, r.catch_up, r.offset_minutes,
+		        r.nag_every_minutes, r.nag_max, r.nag_at, r.nag_count, r.nag_for, r.paused_at, r.created_at,
+		        COALESCE(t.title, r.title), COALESCE(t.user_id, r.user_id)
+		 FROM reminders r
+		 LEFT JOIN todos t ON t.id = r.todo_id
+		 WHERE r.id = $1 AND COALESCE(t.user_id, r.user_id) = $2 AND t.deleted_at IS NULL`,
+		id, userID,
+	).Scan(
+		&rt.ID, &rt.TodoID, &rt.RemindAt, &rt.IsRecurring, &rt.RecurrenceRule,
+		&rt.LastFiredAt, &rt.IsActive, &rt.CatchUp, &rt.OffsetMinutes,
+		&rt.NagEveryMinutes, &rt.NagMax, &rt.NagAt, &rt.NagCount, &rt.NagFor, &rt.PausedAt, &rt.CreatedAt,
+		&rt.TodoTitle, &rt.TodoUserID,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get reminder for user: %w", err)
+	}
+	return &rt, nil
+}
+
+// Snooze fires the reminder again at the given time. A one-time reminder is
+// rescheduled in place; a recurring one gets a separate one-time reminder so
+// its series keeps the original time of day.
+func (r *Repository) Snooze(ctx context.Context, rem *ReminderWithTodo, at time.Time) error {
+	var err error
+	if rem.IsRecurring {
+		_, err = r.db.ExecContext(ctx,
+			`INSERT INTO reminders (todo_id, user_id, title, remind_at)
+			 SELECT todo_id, user_id, title, $1 FROM reminders WHERE id = $2`,
+			at, rem.ID,
+		)
+	} else {
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE reminders SET remind_at = $1, is_active = TRUE, paused_at = NULL WHERE id = $2`,
+			at, rem.ID,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("snooze reminder: %w", err)
+	}
+	return nil
+}
+
+// SetCatchUp sets the catch-up policy of a todo's active reminders and returns
+// how many were changed.
+func (r *Repository) SetCatchUp(ctx context.Context, todoID int, policy string) (int, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE reminders SET catch_up = $1 WHERE todo_id = $2 AND is_active = TRUE`,
+		policy, todoID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("set reminder catch-up: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("set reminder catch-up: %w", err)
+	}
+	return int(n), nil
+}
+
+// SetNag turns nagging on for a todo's active reminders, or off when nag is
+// nil (which also stops a repeat in progress). It returns how many reminders
+// were changed.
+func (r *Repository) SetNag(ctx context.Context, todoID int, nag *Nag) (int, error) {
+	var (
+		res sql.Result
+		err error
+	)
+	if nag != nil {
+		res, err = r.db.ExecContext(ctx,
+			`UPDATE reminders SET nag_every_minutes = $1, nag_max = $2 WHERE todo_id = $3 AND is_active = TRUE`,
+			int(nag.Every/time.Minute), nag.Max, todoID,
+		)
+	} else {
+		res, err = r.db.ExecContext(ctx,
+			`UPDATE reminders SET nag_every_minutes = NULL, nag_max = NULL, nag_at = NULL
+			 WHERE todo_id = $1 AND (is_active = TRUE OR nag_at IS NOT NULL)`,
+			todoID,
+		)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("set reminder nag: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("set reminder nag: %w", err)
+	}
+	return int(n), nil
+}
+
+// SetReminderCatchUp sets the catch-up policy of a single reminder.
+func (r *Repository) SetReminderCatchUp(ctx context.Context, id int, policy string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE reminders SET catch_up = $1 WHERE id = $2`, policy, id)
+	if err != nil {
+		return fmt.Errorf("set reminder catch-up: %w", err)
 	}
 	return nil
 }
 
+// SetReminderNag is SetNag for a single reminder.
+func (r *Repository) SetReminderNag(ctx context.Context, id int, nag *Nag) error {
+	var err error
+	if nag != nil {
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE reminders SET nag_every_minutes = $1, nag_max = $2 WHERE id = $3`,
+			int(nag.Every/time.Minute), nag.Max, id,
+		)
+	} else {
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE reminders SET nag_every_minutes = NULL, nag_max = NULL, nag_at = NULL WHERE id = $1`,
+			id,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("set reminder nag: %w", err)
+	}
+	return nil
+}
+
+// StopNag stops repeating the current occurrence of a reminder, e.g. when
+// the user acknowledged it. Later occurrences still nag.
+func (r *Repository) StopNag(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE reminders SET nag_at = NULL WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("stop reminder nag: %w", err)
+	}
+	return nil
+}
+
+// Advance moves a recurring reminder to next, or deactivates it when next is
+// nil because the series has ended.
+func (r *Repository) Advance(ctx context.Context, id int, next *Next) error {
+	var err error
+	if next != nil {
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE reminders SET remind_at = $1, recurrence_rule = $2, last_fired_at = NOW() WHERE id = $3`,
+			next.RemindAt, next.Rule, id,
+		)
+	} else {
+		_, err = r.db.ExecContext(ctx,
+			`UPDATE reminders SET is_active = FALSE, last_fired_at = NOW() WHERE id = $1`,
+			id,
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("advance reminder: %w", err)
+	}
+	return nil
+}
+
+// TodoReminder is an active reminder as listed to its owner. TodoID is nil
+// for standalone reminders, whose own title is in TodoTitle.
 type TodoReminder struct {
-	TodoID         int
+	ID             int
+	TodoID         *int
 	TodoTitle      string
 	RemindAt       time.Time
 	IsRecurring    bool
 	RecurrenceRule *string
+	OffsetMinutes  *int
+	PausedAt       *time.Time
+}
+
+// ListActiveUserIDs returns the users with active standalone reminders.
+// Those of todo reminders are the todos' users.
+func (r *Repository) ListActiveUserIDs(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT DISTINCT user_id FROM reminders WHERE todo_id IS NULL AND is_active = TRUE`)
+	if err != nil {
+		return nil, fmt.Errorf("list reminder user ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
 }
 
 func (r *Repository) ListActiveByUser(ctx context.Context, userID int64) ([]TodoReminder, error) {
 	rows, err := r.db.QueryContext(ctx,
-		`SELECT r.todo_id, t.title, r.remind_at, r.is_recurring, r.recurrence_rule
+		`SELECT r.id, r.todo_id, COALESCE(t.title, r.title), r.remind_at, r.is_recurring, r.recurrence_rule, r.offset_minutes, r.paused_at
 		 FROM reminders r
-		 JOIN todos t ON t.id = r.todo_id
-		 WHERE t.user_id = $1 AND r.is_active = TRUE AND t.deleted_at IS NULL
+		 LEFT JOIN todos t ON t.id = r.todo_id
+		 WHERE COALESCE(t.user_id, r.user_id) = $1 AND r.is_active = TRUE AND t.deleted_at IS NULL
 		 ORDER BY r.remind_at ASC`,
 		userID,
 	)
@@ -113,7 +531,7 @@ func (r *Repository) ListActiveByUser(ctx context.Context, userID int64) ([]Todo
 	var reminders []TodoReminder
 	for rows.Next() {
 		var tr TodoReminder
-		err := rows.Scan(&tr.TodoID, &tr.TodoTitle, &tr.RemindAt, &tr.IsRecurring, &tr.RecurrenceRule)
+		err := rows.Scan(&tr.ID, &tr.TodoID, &tr.TodoTitle, &tr.RemindAt, &tr.IsRecurring, &tr.RecurrenceRule, &tr.OffsetMinutes, &tr.PausedAt)
 		if err != nil {
 			return nil, fmt.Errorf("scan todo reminder: %w", err)
 		}
@@ -122,10 +540,17 @@ func (r *Repository) ListActiveByUser(ctx context.Context, userID int64) ([]Todo
 	return reminders, rows.Err()
 }
 
+// UpsertByTodoID moves the todo's earliest active one-time reminder at an
+// absolute time to remindAt, or creates one. Recurring reminders and those
+// relative to the due date are left alone.
 func (r *Repository) UpsertByTodoID(ctx context.Context, todoID int, remindAt time.Time) error {
 	res, err := r.db.ExecContext(ctx,
 		`UPDATE reminders SET remind_at = $1, is_active = TRUE, last_fired_at = NULL
-		 WHERE todo_id = $2 AND is_active = TRUE`,
+		 WHERE id = (
+		     SELECT id FROM reminders
+		     WHERE todo_id = $2 AND is_active = TRUE AND is_recurring = FALSE AND offset_minutes IS NULL
+		     ORDER BY remind_at ASC LIMIT 1
+		 )`,
 		remindAt, todoID,
 	)
 	if err != nil {
@@ -145,13 +570,44 @@ func (r *Repository) UpsertByTodoID(ctx context.Context, todoID int, remindAt ti
 	return nil
 }
 
-func (r *Repository) Deactivate(ctx context.Context, id int) error {
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE reminders SET is_active = FALSE, last_fired_at = NOW() WHERE id = $1`,
-		id,
+// SnapshotByTodoIDs returns every reminder, active or not, of the given todos.
+func SnapshotByTodoIDs(ctx context.Context, q db.DBTX, todoIDs []int) ([]Reminder, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT id, todo_id, remind_at, is_recurring, recurrence_rule, last_fired_at, is_active, catch_up, offset_minutes,
+		        nag_every_minutes, nag_max, nag_at, nag_count, nag_for, paused_at, created_at
+		 FROM reminders WHERE todo_id = ANY($1)`,
+		pq.Array(todoIDs),
 	)
 	if err != nil {
-		return fmt.Errorf("deactivate reminder: %w", err)
+		return nil, fmt.Errorf("snapshot reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var reminders []Reminder
+	for rows.Next() {
+		var rm Reminder
+		if err := rows.Scan(&rm.ID, &rm.TodoID, &rm.RemindAt, &rm.IsRecurring, &rm.RecurrenceRule, &rm.LastFiredAt, &rm.IsActive, &rm.CatchUp, &rm.OffsetMinutes,
+			&rm.NagEveryMinutes, &rm.NagMax, &rm.NagAt, &rm.NagCount, &rm.NagFor, &rm.PausedAt, &rm.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan reminder snapshot: %w", err)
+		}
+		reminders = append(reminders, rm)
+	}
+	return reminders, rows.Err()
+}
+
+// RestoreSnapshot re-inserts reminders with their original IDs.
+func RestoreSnapshot(ctx context.Context, q db.DBTX, reminders []Reminder) error {
+	for _, rm := range reminders {
+		_, err := q.ExecContext(ctx,
+			`INSERT INTO reminders (id, todo_id, remind_at, is_recurring, recurrence_rule, last_fired_at, is_active, catch_up, offset_minutes,
+			                        nag_every_minutes, nag_max, paused_at, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) ON CONFLICT (id) DO NOTHING`,
+			rm.ID, rm.TodoID, rm.RemindAt, rm.IsRecurring, rm.RecurrenceRule, rm.LastFiredAt, rm.IsActive, rm.CatchUp, rm.OffsetMinutes,
+			rm.NagEveryMinutes, rm.NagMax, rm.PausedAt, rm.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("restore reminder: %w", err)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,5 @@
+package reminder
+
+// maxDeliveryAttempts is how many times an occurrence is sent before it is
+// dead-lettered. Send errors are classified with notify.ClassifySendError.
+const maxDeliveryAttempts = 6
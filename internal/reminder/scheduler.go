@@ -4,35 +4,62 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strconv"
+	"os"
 	"strings"
 	"sync"
 	"time"
 
-	tele "gopkg.in/telebot.v4"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/notify"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/recurrence"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
 )
 
+const (
+	// claimLease is how long a claimed reminder is reserved for the claiming
+	// scheduler. It must comfortably exceed the time to send one batch.
+	claimLease = 2 * time.Minute
+	claimBatch = 100
+)
+
+// Scheduler delivers due reminders. Several may run at once (one per bot
+// replica): reminders are claimed in the database before sending, and each
+// occurrence has a delivery record, so a reminder is sent by one scheduler
+// only and a sent occurrence is never sent again. Reminders go out through
+// the notification queue; one held back for quiet hours counts as sent.
 type Scheduler struct {
-	repo     *Repository
-	bot      *tele.Bot
-	interval time.Duration
-	timezone *time.Location
-	stopCh   chan struct{}
-	once     sync.Once
+	repo        *Repository
+	queue       *notify.Queue
+	interval    time.Duration
+	catchUp     time.Duration
+	settingsSvc *settings.Service
+	holidays    recurrence.Holidays
+	worker      string
+	now         func() time.Time
+	stopCh      chan struct{}
+	once        sync.Once
 }
 
-func NewScheduler(repo *Repository, bot *tele.Bot, interval time.Duration, timezone *time.Location) *Scheduler {
+// NewScheduler delivers reminders every interval. Reminders more than catchUp
+// late (the bot was down, or retries ran long) are handled by their catch-up
+// policy instead of firing one by one with stale times. Recurring reminders
+// move off the days off of holidays as their rule says.
+func NewScheduler(repo *Repository, queue *notify.Queue, interval, catchUp time.Duration, settingsSvc *settings.Service, holidays recurrence.Holidays) *Scheduler {
+	host, _ := os.Hostname()
 	return &Scheduler{
-		repo:     repo,
-		bot:      bot,
-		interval: interval,
-		timezone: timezone,
-		stopCh:   make(chan struct{}),
+		repo:        repo,
+		queue:       queue,
+		interval:    interval,
+		catchUp:     catchUp,
+		settingsSvc: settingsSvc,
+		holidays:    holidays,
+		worker:      fmt.Sprintf("%s/%d", host, os.Getpid()),
+		now:         time.Now,
+		stopCh:      make(chan struct{}),
 	}
 }
 
 func (s *Scheduler) Start() {
-	slog.Info("reminder scheduler started", "interval", s.interval)
+	slog.Info("reminder scheduler started", "interval", s.interval, "worker", s.worker)
 	ticker := time.NewTicker(s.interval)
 	defer ticker.Stop()
 
@@ -53,33 +80,156 @@ func (s *Scheduler) Stop() {
 
 func (s *Scheduler) tick() {
 	ctx := context.Background()
-	reminders, err := s.repo.GetDueReminders(ctx)
+	claims, err := s.repo.ClaimDue(ctx, s.worker, claimLease, claimBatch)
 	if err != nil {
-		slog.Error("failed to get due reminders", "error", err)
+		slog.Error("failed to claim due reminders", "error", err)
 		return
 	}
 
-	for _, r := range reminders {
-		user := &tele.User{ID: r.TodoUserID}
-		msg := formatReminderNotification(r, s.timezone)
-
-		if _, err := s.bot.Send(user, msg); err != nil {
-			slog.Error("failed to send reminder", "todo_id", r.TodoID, "user_id", r.TodoUserID, "error", err)
+	now := s.now()
+	var missedUsers []int64
+	missed := make(map[int64][]*Claim)
+	for i := range claims {
+		c := &claims[i]
+		if c.Nag {
+			s.nag(ctx, c, now)
 			continue
 		}
+		if c.AlreadySent || now.Sub(c.RemindAt) <= s.catchUp {
+			s.deliver(ctx, c, now)
+			continue
+		}
+		if c.CatchUp == CatchUpSkip {
+			s.skip(ctx, c, now)
+			continue
+		}
+		if _, ok := missed[c.TodoUserID]; !ok {
+			missedUsers = append(missedUsers, c.TodoUserID)
+		}
+		missed[c.TodoUserID] = append(missed[c.TodoUserID], c)
+	}
+	for _, userID := range missedUsers {
+		s.deliverMissed(ctx, userID, missed[userID], now)
+	}
+}
+
+// next returns where a delivered or skipped occurrence moves the reminder:
+// the next future occurrence for recurring reminders, nil when there is none.
+func (s *Scheduler) next(c *Claim, loc *time.Location, now time.Time) *Next {
+	if !c.IsRecurring || c.RecurrenceRule == nil {
+		return nil
+	}
+	next, err := NextOccurrence(c.RemindAt, *c.RecurrenceRule, loc, now, s.holidays)
+	if err != nil {
+		slog.Error("invalid recurrence rule, ending series", "id", c.ID, "rule", *c.RecurrenceRule, "error", err)
+		return nil
+	}
+	return next
+}
+
+// deliver sends one claimed reminder and records the outcome. The only window
+// for a duplicate is between Send returning and MarkSent committing; if the
+// process dies there, the lease expires and the occurrence is sent again.
+func (s *Scheduler) deliver(ctx context.Context, c *Claim, now time.Time) {
+	// Recurrences follow the owner's wall clock, so both the message
+	// and the next occurrence use their timezone.
+	us := s.settingsSvc.Resolve(ctx, c.TodoUserID)
+	loc := us.Location()
+	next := s.next(c, loc, now)
+	nagAt := nextNag(c, us, now)
+
+	if c.AlreadySent {
+		slog.Warn("reminder occurrence already sent, advancing", "id", c.ID, "delivery_id", c.DeliveryID)
+		if err := s.repo.MarkSent(ctx, c, next, nagAt); err != nil {
+			slog.Error("failed to advance reminder", "id", c.ID, "error", err)
+		}
+		return
+	}
+
+	msg := formatReminderNotification(c.ReminderWithTodo, loc)
+	deferred, err := s.queue.Send(ctx, c.TodoUserID, notify.KindReminder, msg, notificationMarkup(c.ReminderWithTodo))
+	if err != nil {
+		s.handleSendError(ctx, c, err)
+		return
+	}
 
-		slog.Info("reminder sent", "todo_id", r.TodoID, "user_id", r.TodoUserID)
-
-		if r.IsRecurring && r.RecurrenceRule != nil {
-			nextTime := calculateNext(r.RemindAt, *r.RecurrenceRule, s.timezone)
-			if err := s.repo.UpdateRemindAt(ctx, r.ID, nextTime); err != nil {
-				slog.Error("failed to update recurring reminder", "id", r.ID, "error", err)
-			}
-		} else {
-			if err := s.repo.Deactivate(ctx, r.ID); err != nil {
-				slog.Error("failed to deactivate reminder", "id", r.ID, "error", err)
-			}
+	slog.Info("reminder sent", "reminder_id", c.ID, "user_id", c.TodoUserID, "deferred", deferred)
+
+	if err := s.repo.MarkSent(ctx, c, next, nagAt); err != nil {
+		slog.Error("failed to record reminder delivery", "id", c.ID, "error", err)
+	}
+}
+
+// nag repeats an occurrence that was sent but not acknowledged yet.
+func (s *Scheduler) nag(ctx context.Context, c *Claim, now time.Time) {
+	us := s.settingsSvc.Resolve(ctx, c.TodoUserID)
+	nagAt := nextNag(c, us, now)
+
+	if !c.AlreadySent {
+		msg := formatNagNotification(c.ReminderWithTodo, us.Location())
+		if _, err := s.queue.Send(ctx, c.TodoUserID, notify.KindReminder, msg, notificationMarkup(c.ReminderWithTodo)); err != nil {
+			s.handleSendError(ctx, c, err)
+			return
 		}
+		slog.Info("reminder repeated", "reminder_id", c.ID, "user_id", c.TodoUserID, "repeat", c.NagCount+1)
+	}
+
+	if err := s.repo.MarkSent(ctx, c, nil, nagAt); err != nil {
+		slog.Error("failed to record reminder repeat", "id", c.ID, "error", err)
+	}
+}
+
+// skip drops a missed occurrence of a reminder whose policy is CatchUpSkip.
+func (s *Scheduler) skip(ctx context.Context, c *Claim, now time.Time) {
+	loc := s.settingsSvc.Resolve(ctx, c.TodoUserID).Location()
+	slog.Info("missed reminder skipped", "reminder_id", c.ID, "user_id", c.TodoUserID, "remind_at", c.RemindAt)
+	if err := s.repo.MarkSkipped(ctx, c, s.next(c, loc, now)); err != nil {
+		slog.Error("failed to record skipped reminder", "id", c.ID, "error", err)
+	}
+}
+
+// deliverMissed sends one digest for all of a user's missed reminders
+// instead of a burst of stale notifications. The outcome is recorded per
+// reminder, so a failed digest is retried like a single reminder.
+func (s *Scheduler) deliverMissed(ctx context.Context, userID int64, claims []*Claim, now time.Time) {
+	us := s.settingsSvc.Resolve(ctx, userID)
+	loc := us.Location()
+	if _, err := s.queue.Send(ctx, userID, notify.KindReminder, formatMissedDigest(claims, loc), nil); err != nil {
+		for _, c := range claims {
+			s.handleSendError(ctx, c, err)
+		}
+		return
+	}
+
+	slog.Info("missed reminders digest sent", "user_id", userID, "count", len(claims))
+
+	for _, c := range claims {
+		if err := s.repo.MarkSent(ctx, c, s.next(c, loc, now), nextNag(c, us, now)); err != nil {
+			slog.Error("failed to record reminder delivery", "id", c.ID, "error", err)
+		}
+	}
+}
+
+// handleSendError retries transient failures with exponential backoff and
+// dead-letters permanent ones or those out of attempts. Rate limits wait
+// for Telegram's retry-after and are never dead-lettered.
+func (s *Scheduler) handleSendError(ctx context.Context, c *Claim, err error) {
+	f := notify.ClassifySendError(err)
+	if f.Permanent || (f.Wait == 0 && c.Attempts >= maxDeliveryAttempts) {
+		slog.Error("reminder dead-lettered", "reminder_id", c.ID, "user_id", c.TodoUserID, "attempt", c.Attempts, "permanent", f.Permanent, "error", err)
+		if err := s.repo.DeadLetter(ctx, c, err); err != nil {
+			slog.Error("failed to dead-letter reminder", "id", c.ID, "error", err)
+		}
+		return
+	}
+
+	wait := f.Wait
+	if wait == 0 {
+		wait = notify.Backoff(c.Attempts)
+	}
+	slog.Warn("failed to send reminder, will retry", "reminder_id", c.ID, "user_id", c.TodoUserID, "attempt", c.Attempts, "retry_in", wait, "error", err)
+	if err := s.repo.RetryDelivery(ctx, c, err, wait); err != nil {
+		slog.Error("failed to schedule reminder retry", "id", c.ID, "error", err)
 	}
 }
 
@@ -92,173 +242,75 @@ var indonesianMonths = [...]string{
 	"Jul", "Agu", "Sep", "Okt", "Nov", "Des",
 }
 
-func formatReminderNotification(r ReminderWithTodo, loc *time.Location) string {
-	t := r.RemindAt.In(loc)
-	dateStr := fmt.Sprintf("%s, %d %s %d · %02d:%02d",
+// formatWhen formats a reminder time: "Senin, 3 Mar 2026 · 09:00".
+func formatWhen(t time.Time, loc *time.Location) string {
+	t = t.In(loc)
+	return fmt.Sprintf("%s, %d %s %d · %02d:%02d",
 		indonesianDays[t.Weekday()], t.Day(), indonesianMonths[t.Month()-1], t.Year(),
 		t.Hour(), t.Minute(),
 	)
-
-	if r.IsRecurring && r.RecurrenceRule != nil {
-		header := recurringHeader(*r.RecurrenceRule)
-		detail := recurringDetail(*r.RecurrenceRule, t)
-		msg := fmt.Sprintf("🔔 %s\n\n📌 %s\n📅 %s\n🔁 %s", header, r.TodoTitle, dateStr, detail)
-		msg += fmt.Sprintf("\n\nKetik \"done %s\" untuk selesaikan", r.TodoTitle)
-		return msg
-	}
-
-	msg := fmt.Sprintf("🔔 Reminder\n\n📌 %s\n📅 %s", r.TodoTitle, dateStr)
-	msg += fmt.Sprintf("\n\nKetik \"done %s\" untuk selesaikan", r.TodoTitle)
-	return msg
 }
 
-func recurringHeader(rule string) string {
-	switch {
-	case rule == "daily":
-		return "Reminder Harian"
-	case strings.HasPrefix(rule, "weekly:"):
-		return "Reminder Mingguan"
-	case strings.HasPrefix(rule, "monthly:"):
-		return "Reminder Bulanan"
-	case strings.HasPrefix(rule, "yearly:"):
-		return "Reminder Tahunan"
-	default:
-		return "Reminder"
-	}
-}
+func formatReminderNotification(r ReminderWithTodo, loc *time.Location) string {
+	dateStr := formatWhen(r.RemindAt, loc)
 
-func recurringDetail(rule string, t time.Time) string {
+	var msg string
 	switch {
-	case rule == "daily":
-		return fmt.Sprintf("Setiap hari jam %02d:%02d", t.Hour(), t.Minute())
-	case strings.HasPrefix(rule, "weekly:"):
-		dayStr := strings.TrimPrefix(rule, "weekly:")
-		dayName := indonesianDayName(dayStr)
-		return fmt.Sprintf("Setiap hari %s", dayName)
-	case strings.HasPrefix(rule, "monthly:"):
-		dateStr := strings.TrimPrefix(rule, "monthly:")
-		return fmt.Sprintf("Setiap tanggal %s", dateStr)
-	case strings.HasPrefix(rule, "yearly:"):
-		dateStr := strings.TrimPrefix(rule, "yearly:")
-		parts := strings.Split(dateStr, "-")
-		if len(parts) == 2 {
-			month, _ := strconv.Atoi(parts[0])
-			if month >= 1 && month <= 12 {
-				return fmt.Sprintf("Setiap %s %s", parts[1], indonesianMonths[month-1])
-			}
-		}
-		return "Setiap tahun"
+	case r.IsRecurring && r.RecurrenceRule != nil:
+		header := recurringHeader(*r.RecurrenceRule)
+		detail := recurringDetail(*r.RecurrenceRule)
+		msg = fmt.Sprintf("🔔 %s\n\n📌 %s\n📅 %s\n🔁 %s", header, r.TodoTitle, dateStr, detail)
+	case r.OffsetMinutes != nil:
+		msg = fmt.Sprintf("🔔 Reminder\n\n📌 %s\n📅 %s\n⏳ %s", r.TodoTitle, dateStr, DescribeOffset(*r.OffsetMinutes))
 	default:
-		return "Recurring"
+		msg = fmt.Sprintf("🔔 Reminder\n\n📌 %s\n📅 %s", r.TodoTitle, dateStr)
 	}
-}
 
-func indonesianDayName(day string) string {
-	switch strings.ToLower(day) {
-	case "mon", "senin":
-		return "Senin"
-	case "tue", "selasa":
-		return "Selasa"
-	case "wed", "rabu":
-		return "Rabu"
-	case "thu", "kamis":
-		return "Kamis"
-	case "fri", "jumat":
-		return "Jumat"
-	case "sat", "sabtu":
-		return "Sabtu"
-	case "sun", "minggu":
-		return "Minggu"
-	default:
-		return day
+	if r.NagEveryMinutes != nil {
+		msg += fmt.Sprintf("\n🔂 Diulang tiap %d menit sampai kamu tekan ✅ Done atau 👍 Oke", *r.NagEveryMinutes)
 	}
+	return msg
 }
 
-func calculateNext(current time.Time, rule string, loc *time.Location) time.Time {
-	now := time.Now().In(loc)
-	// Convert current to local timezone so hour/minute are in the user's timezone,
-	// not UTC (postgres returns TIMESTAMPTZ as UTC).
-	cur := current.In(loc)
-
-	switch {
-	case rule == "daily":
-		next := current.AddDate(0, 0, 1)
-		if next.Before(now) {
-			next = time.Date(now.Year(), now.Month(), now.Day()+1, cur.Hour(), cur.Minute(), 0, 0, loc)
-		}
-		return next
-
-	case strings.HasPrefix(rule, "weekly:"):
-		dayStr := strings.TrimPrefix(rule, "weekly:")
-		targetDay := parseDayOfWeek(dayStr)
-		next := current.AddDate(0, 0, 7)
-		// Adjust to the correct weekday
-		for next.Weekday() != targetDay {
-			next = next.AddDate(0, 0, 1)
-		}
-		if next.Before(now) {
-			next = time.Date(now.Year(), now.Month(), now.Day(), cur.Hour(), cur.Minute(), 0, 0, loc)
-			for next.Weekday() != targetDay || !next.After(now) {
-				next = next.AddDate(0, 0, 1)
-			}
-		}
-		return next
-
-	case strings.HasPrefix(rule, "monthly:"):
-		dateStr := strings.TrimPrefix(rule, "monthly:")
-		day, err := strconv.Atoi(dateStr)
-		if err != nil || day < 1 || day > 31 {
-			slog.Warn("invalid monthly recurrence rule", "rule", rule)
-			return current.AddDate(0, 0, 1)
-		}
-		next := time.Date(cur.Year(), cur.Month()+1, day, cur.Hour(), cur.Minute(), 0, 0, loc)
-		if next.Before(now) {
-			next = time.Date(now.Year(), now.Month()+1, day, cur.Hour(), cur.Minute(), 0, 0, loc)
-			if next.Before(now) {
-				next = time.Date(now.Year(), now.Month()+2, day, cur.Hour(), cur.Minute(), 0, 0, loc)
-			}
-		}
-		return next
-
-	case strings.HasPrefix(rule, "yearly:"):
-		dateStr := strings.TrimPrefix(rule, "yearly:")
-		parts := strings.Split(dateStr, "-")
-		if len(parts) == 2 {
-			month, err1 := strconv.Atoi(parts[0])
-			day, err2 := strconv.Atoi(parts[1])
-			if err1 != nil || err2 != nil || month < 1 || month > 12 || day < 1 || day > 31 {
-				slog.Warn("invalid yearly recurrence rule", "rule", rule)
-				return current.AddDate(0, 0, 1)
-			}
-			next := time.Date(cur.Year()+1, time.Month(month), day, cur.Hour(), cur.Minute(), 0, 0, loc)
-			if next.Before(now) {
-				next = time.Date(now.Year()+1, time.Month(month), day, cur.Hour(), cur.Minute(), 0, 0, loc)
-			}
-			return next
+// formatMissedDigest formats reminders missed during downtime.
+//
+// ⏰ Kamu melewatkan 2 reminder
+//
+// 📌 Beli susu
+//
+//	📅 Senin, 3 Mar 2026 · 09:00
+//
+// 📌 Standup
+//
+//	📅 Senin, 3 Mar 2026 · 10:00 🔁
+func formatMissedDigest(claims []*Claim, loc *time.Location) string {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("⏰ Kamu melewatkan %d reminder\n", len(claims)))
+	for _, c := range claims {
+		line := "   📅 " + formatWhen(c.RemindAt, loc)
+		if c.IsRecurring {
+			line += " 🔁"
 		}
+		lines = append(lines, "📌 "+c.TodoTitle, line)
 	}
+	return strings.Join(lines, "\n")
+}
 
-	// Fallback: next day
-	return current.AddDate(0, 0, 1)
+func recurringHeader(rule string) string {
+	r, err := recurrence.Parse(rule)
+	if err != nil {
+		return "Reminder"
+	}
+	return "Reminder " + r.Label()
 }
 
-func parseDayOfWeek(day string) time.Weekday {
-	switch strings.ToLower(day) {
-	case "mon", "senin":
-		return time.Monday
-	case "tue", "selasa":
-		return time.Tuesday
-	case "wed", "rabu":
-		return time.Wednesday
-	case "thu", "kamis":
-		return time.Thursday
-	case "fri", "jumat":
-		return time.Friday
-	case "sat", "sabtu":
-		return time.Saturday
-	case "sun", "minggu":
-		return time.Sunday
-	default:
-		return time.Monday
+// recurringDetail describes the rule, capitalized: "Setiap Senin pertama".
+// The time of day is already on the 📅 line.
+func recurringDetail(rule string) string {
+	r, err := recurrence.Parse(rule)
+	if err != nil {
+		return "Recurring"
 	}
+	d := r.Describe()
+	return strings.ToUpper(d[:1]) + d[1:]
 }
@@ -0,0 +1,219 @@
+package reminder
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatWhenAcrossDST(t *testing.T) {
+	tests := []struct {
+		timezone string
+		at       time.Time // UTC instant
+		want     string
+	}{
+		{"America/New_York", time.Date(2026, 3, 7, 14, 0, 0, 0, time.UTC), "Sabtu, 7 Mar 2026 · 09:00"},
+		{"America/New_York", time.Date(2026, 3, 8, 13, 0, 0, 0, time.UTC), "Minggu, 8 Mar 2026 · 09:00"},
+		{"America/New_York", time.Date(2026, 10, 31, 13, 0, 0, 0, time.UTC), "Sabtu, 31 Okt 2026 · 09:00"},
+		{"America/New_York", time.Date(2026, 11, 1, 14, 0, 0, 0, time.UTC), "Minggu, 1 Nov 2026 · 09:00"},
+		{"Europe/Berlin", time.Date(2026, 3, 28, 7, 0, 0, 0, time.UTC), "Sabtu, 28 Mar 2026 · 08:00"},
+		{"Europe/Berlin", time.Date(2026, 3, 29, 6, 0, 0, 0, time.UTC), "Minggu, 29 Mar 2026 · 08:00"},
+		{"Europe/Berlin", time.Date(2026, 10, 24, 6, 0, 0, 0, time.UTC), "Sabtu, 24 Okt 2026 · 08:00"},
+		{"Europe/Berlin", time.Date(2026, 10, 25, 7, 0, 0, 0, time.UTC), "Minggu, 25 Okt 2026 · 08:00"},
+	}
+	for _, tt := range tests {
+		loc := userLocation(t, tt.timezone)
+		if got := formatWhen(tt.at, loc); got != tt.want {
+			t.Errorf("formatWhen(%s, %s) = %q, want %q", tt.at, tt.timezone, got, tt.want)
+		}
+	}
+}
+
+func TestFormatMissedDigest(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := []*Claim{
+		{ReminderWithTodo: ReminderWithTodo{Reminder: Reminder{RemindAt: time.Date(2026, 3, 2, 2, 0, 0, 0, time.UTC), IsRecurring: true}, TodoTitle: "bayar wifi"}},
+		{ReminderWithTodo: ReminderWithTodo{Reminder: Reminder{RemindAt: time.Date(2026, 3, 2, 13, 30, 0, 0, time.UTC)}, TodoTitle: "minum obat"}},
+	}
+	want := "⏰ Kamu melewatkan 2 reminder\n" +
+		"\n" +
+		"📌 bayar wifi\n" +
+		"   📅 Senin, 2 Mar 2026 · 09:00 🔁\n" +
+		"📌 minum obat\n" +
+		"   📅 Senin, 2 Mar 2026 · 20:30"
+	if got := formatMissedDigest(claims, loc); got != want {
+		t.Errorf("formatMissedDigest =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestTickCatchUpWindow measures lateness on the scheduler clock: within
+// catchUp the reminder is sent as is, past it it goes into the digest or,
+// with CatchUpSkip, is dropped.
+func TestTickCatchUpWindow(t *testing.T) {
+	database := testDB(t)
+	repo := NewRepository(database)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		policy string
+		late   time.Duration
+		want   string // "reminder", "digest" or "skipped"
+	}{
+		{"on time", CatchUpFire, 0, "reminder"},
+		{"within the window", CatchUpFire, 59 * time.Minute, "reminder"},
+		{"at the window edge", CatchUpFire, time.Hour, "reminder"},
+		{"past the window", CatchUpFire, time.Hour + time.Minute, "digest"},
+		{"skip within the window", CatchUpSkip, 30 * time.Minute, "reminder"},
+		{"skip past the window", CatchUpSkip, 2 * time.Hour, "skipped"},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tg := newFakeTelegram(t)
+			remindAt := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+			userID := int64(100 + i)
+			id, err := repo.CreateStandalone(ctx, userID, "minum obat", remindAt, "", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := repo.SetReminderCatchUp(ctx, id, tt.policy); err != nil {
+				t.Fatal(err)
+			}
+
+			s := newTestScheduler(t, database, tg, "worker")
+			s.now = func() time.Time { return remindAt.Add(tt.late) }
+			s.tick()
+
+			sent := tg.Sent()
+			switch tt.want {
+			case "reminder":
+				if len(sent) != 1 || !strings.HasPrefix(sent[0].Text, "🔔 Reminder") {
+					t.Errorf("sent %+v, want the reminder itself", sent)
+				}
+			case "digest":
+				if len(sent) != 1 || !strings.HasPrefix(sent[0].Text, "⏰ Kamu melewatkan 1 reminder") {
+					t.Errorf("sent %+v, want a digest", sent)
+				}
+			case "skipped":
+				if len(sent) != 0 {
+					t.Errorf("sent %+v, want nothing", sent)
+				}
+			}
+
+			wantStatus := DeliverySent
+			if tt.want == "skipped" {
+				wantStatus = DeliverySkipped
+			}
+			var status string
+			if err := database.QueryRow(`SELECT status FROM reminder_deliveries WHERE reminder_id = $1`, id).Scan(&status); err != nil {
+				t.Fatal(err)
+			}
+			if status != wantStatus {
+				t.Errorf("delivery status = %s, want %s", status, wantStatus)
+			}
+			if st := reminderStateOf(t, database, id); st.IsActive || st.Claimed {
+				t.Errorf("reminder = %+v, want finished", st)
+			}
+		})
+	}
+}
+
+func TestTickSkipAdvancesRecurringReminder(t *testing.T) {
+	database := testDB(t)
+	tg := newFakeTelegram(t)
+	repo := NewRepository(database)
+	ctx := context.Background()
+
+	remindAt := time.Now().Add(-3 * time.Hour).Truncate(time.Second)
+	id, err := repo.CreateStandalone(ctx, 100, "standup", remindAt, "FREQ=DAILY", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetReminderCatchUp(ctx, id, CatchUpSkip); err != nil {
+		t.Fatal(err)
+	}
+
+	s := newTestScheduler(t, database, tg, "worker")
+	now := remindAt.Add(2 * time.Hour)
+	s.now = func() time.Time { return now }
+	s.tick()
+
+	if sent := tg.Sent(); len(sent) != 0 {
+		t.Errorf("sent %+v, want nothing", sent)
+	}
+	if ds := deliveries(t, database); len(ds) != 1 || ds[0].Status != DeliverySkipped {
+		t.Errorf("deliveries = %+v, want one skipped", ds)
+	}
+	if st := reminderStateOf(t, database, id); !st.IsActive || !st.RemindAt.Equal(remindAt.AddDate(0, 0, 1)) {
+		t.Errorf("reminder = %+v, want the next occurrence %s", st, remindAt.AddDate(0, 0, 1))
+	}
+}
+
+// TestTickGroupsMissedRemindersPerUser sends each user one digest of their
+// missed reminders, oldest first, next to those still on time.
+func TestTickGroupsMissedRemindersPerUser(t *testing.T) {
+	database := testDB(t)
+	tg := newFakeTelegram(t)
+	repo := NewRepository(database)
+	ctx := context.Background()
+
+	now := time.Now().Truncate(time.Second)
+	create := func(userID int64, title string, ago time.Duration, rule, policy string) *Claim {
+		t.Helper()
+		remindAt := now.Add(-ago)
+		id, err := repo.CreateStandalone(ctx, userID, title, remindAt, rule, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.SetReminderCatchUp(ctx, id, policy); err != nil {
+			t.Fatal(err)
+		}
+		c := &Claim{}
+		c.ID, c.RemindAt, c.IsRecurring, c.TodoTitle, c.TodoUserID = id, remindAt, rule != "", title, userID
+		return c
+	}
+	onTime := create(100, "bayar wifi", 10*time.Minute, "", CatchUpFire)
+	lunch := create(100, "makan siang", 3*time.Hour, "", CatchUpFire)
+	meeting := create(100, "rapat", 2*time.Hour, "", CatchUpFire)
+	stretch := create(100, "peregangan", 4*time.Hour, "", CatchUpSkip)
+	water := create(200, "minum air", 5*time.Hour, "", CatchUpFire)
+	standup := create(200, "standup", 26*time.Hour, "FREQ=DAILY", CatchUpFire)
+
+	s := newTestScheduler(t, database, tg, "worker")
+	s.now = func() time.Time { return now }
+	s.tick()
+
+	loc, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := tg.Sent()
+	if len(sent) != 3 {
+		t.Fatalf("sent %d messages, want 3: %+v", len(sent), sent)
+	}
+	if sent[0].UserID != 100 || !strings.Contains(sent[0].Text, "📌 bayar wifi") || strings.Contains(sent[0].Text, "melewatkan") {
+		t.Errorf("first message = %+v, want the on-time reminder", sent[0])
+	}
+	wantDigests := []sentMessage{
+		{200, formatMissedDigest([]*Claim{standup, water}, loc)},
+		{100, formatMissedDigest([]*Claim{lunch, meeting}, loc)},
+	}
+	for i, want := range wantDigests {
+		if got := sent[i+1]; got != want {
+			t.Errorf("digest %d = %+v\nwant %+v", i+1, got, want)
+		}
+	}
+
+	for _, c := range []*Claim{onTime, lunch, meeting, stretch, water} {
+		if st := reminderStateOf(t, database, c.ID); st.IsActive || st.Claimed {
+			t.Errorf("%s = %+v, want finished", c.TodoTitle, st)
+		}
+	}
+	if st := reminderStateOf(t, database, standup.ID); !st.IsActive || !st.RemindAt.Equal(standup.RemindAt.AddDate(0, 0, 2)) {
+		t.Errorf("standup = %+v, want advanced to %s", st, standup.RemindAt.AddDate(0, 0, 2))
+	}
+}
@@ -0,0 +1,408 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/conversation"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/pending"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/recurrence"
+	"github.com/zhafrantharif/personal-assistant-bot/internal/settings"
+)
+
+// Service manages standalone reminders ("ingetin minum obat jam 9"): they
+// have their own title and owner and never show up as todos. Reminders of
+// todos and goals are managed through the todo and project services.
+type Service struct {
+	repo     *Repository
+	timezone *time.Location
+	holidays recurrence.Holidays
+}
+
+func NewService(repo *Repository, timezone *time.Location, holidays recurrence.Holidays) *Service {
+	return &Service{repo: repo, timezone: timezone, holidays: holidays}
+}
+
+// loc returns the timezone of the user being served.
+func (s *Service) loc(ctx context.Context) *time.Location {
+	return settings.Location(ctx, s.timezone)
+}
+
+// Add creates a standalone reminder at remindAt, recurring when recurring is
+// set. nag, when set, repeats each occurrence until acknowledged.
+func (s *Service) Add(ctx context.Context, userID int64, title string, remindAt time.Time, recurring string, nag *Nag) (string, error) {
+	id, err := s.repo.CreateStandalone(ctx, userID, title, remindAt, recurring, nag)
+	if err != nil {
+		return "", err
+	}
+	conversation.Touch(ctx, conversation.KindReminder, id, title)
+
+	resp := fmt.Sprintf("⏰ Reminder dibuat: \"%s\"\n📅 %s", title, remindAt.In(s.loc(ctx)).Format("2 Jan 2006 15:04 MST"))
+	if rule, err := recurrence.Parse(recurring); err == nil {
+		resp += fmt.Sprintf(" (🔁 %s)", rule.Describe())
+	}
+	if nag != nil {
+		resp += fmt.Sprintf("\n🔂 Diulang %s sampai kamu tekan ✅ Done atau 👍 Oke", nag.Describe())
+	}
+	return resp, nil
+}
+
+// find resolves a reminder by its ID, as shown in the reminder list, or by
+// its title or its todo's title. When several active reminders match, it
+// returns a message offering them to pick from, with action as the verb of
+// the example command; likewise when none does.
+func (s *Service) find(ctx context.Context, userID int64, reminderID int, search, action string) (*ReminderWithTodo, string, error) {
+	if reminderID > 0 {
+		rem, err := s.repo.GetForUser(ctx, reminderID, userID)
+		if err != nil {
+			return nil, "", err
+		}
+		if rem == nil {
+			return nil, fmt.Sprintf("❌ Reminder #%d tidak ditemukan.", reminderID), nil
+		}
+		return rem, "", nil
+	}
+
+	matches, err := s.repo.FindActive(ctx, userID, search)
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case len(matches) == 1:
+		return &matches[0], "", nil
+	case len(matches) > 1:
+		return nil, s.formatDisambiguation(ctx, search, matches, action), nil
+	}
+
+	// A one-time standalone reminder that already went off can still be
+	// edited or deleted.
+	rem, err := s.repo.FindStandalone(ctx, userID, search)
+	if err != nil {
+		return nil, "", err
+	}
+	if rem == nil {
+		return nil, fmt.Sprintf("❌ Reminder \"%s\" tidak ditemukan. Lihat nomornya di /reminders.", search), nil
+	}
+	return rem, "", nil
+}
+
+// formatDisambiguation lists the reminders matching search with their IDs.
+// Each match is also offered as a button choice.
+//
+// 🔍 Ada 2 reminder "bayar":
+//
+// #3 · Bayar wifi · 📅 5 Mar 07:00 · 🔁 Bulanan
+// #7 · Bayar listrik · 📅 6 Mar 16:00 · ⏸ Dijeda
+func (s *Service) formatDisambiguation(ctx context.Context, search string, matches []ReminderWithTodo, action string) string {
+	loc := s.loc(ctx)
+	lines := []string{fmt.Sprintf("🔍 Ada %d reminder \"%s\":\n", len(matches), search)}
+	for _, rem := range matches {
+		line := fmt.Sprintf("#%d · %s · 📅 %s", rem.ID, rem.TodoTitle, rem.RemindAt.In(loc).Format("2 Jan 15:04"))
+		if rem.RecurrenceRule != nil {
+			if r, err := recurrence.Parse(*rem.RecurrenceRule); err == nil {
+				line += " · 🔁 " + r.Label()
+			}
+		}
+		if rem.PausedAt != nil {
+			line += " · ⏸ Dijeda"
+		}
+		lines = append(lines, line)
+		pending.Offer(ctx, pending.Choice{
+			ID:    rem.ID,
+			Label: fmt.Sprintf("#%d · %s · %s", rem.ID, rem.TodoTitle, rem.RemindAt.In(loc).Format("2 Jan 15:04")),
+		})
+	}
+
+	lines = append(lines, "\nPilih tombol di bawah, atau sebutkan nomornya, contoh:")
+	for _, rem := range matches {
+		lines = append(lines, fmt.Sprintf("• \"%s reminder #%d\"", action, rem.ID))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Edit renames a standalone reminder and/or moves it to remindAt. recurring
+// replaces the recurrence rule when set, keeping the reminder's time of day
+// unless remindAt is given; otherwise a recurring reminder keeps its rule and
+// moves to its first occurrence at or after remindAt.
+func (s *Service) Edit(ctx context.Context, userID int64, reminderID int, search, newTitle string, remindAt *time.Time, recurring string) (string, error) {
+	loc := s.loc(ctx)
+	rem, msg, err := s.find(ctx, userID, reminderID, search, "ubah")
+	if rem == nil {
+		return msg, err
+	}
+	if newTitle != "" && !rem.Standalone() {
+		return fmt.Sprintf("ℹ️ Reminder #%d milik todo \"%s\". Ganti judulnya lewat \"edit todo %s jadi ...\".", rem.ID, rem.TodoTitle, rem.TodoTitle), nil
+	}
+	if rem.OffsetMinutes != nil && (remindAt != nil || recurring != "") {
+		return fmt.Sprintf("ℹ️ Reminder #%d mengikuti deadline \"%s\". Ubah deadline-nya, atau hapus lalu buat reminder baru.", rem.ID, rem.TodoTitle), nil
+	}
+
+	at := rem.RemindAt
+	if remindAt != nil {
+		at = *remindAt
+	}
+	rule := ""
+	if rem.RecurrenceRule != nil {
+		rule = *rem.RecurrenceRule
+	}
+	switch {
+	case recurring != "" && remindAt == nil:
+		// A new rule alone keeps the reminder's time of day and starts
+		// at its first occurrence still to come.
+		r, err := recurrence.Parse(recurring)
+		if err != nil {
+			return "", fmt.Errorf("parse recurrence rule %q: %w", recurring, err)
+		}
+		now := time.Now().In(loc)
+		t := rem.RemindAt.In(loc)
+		start := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+		next, ok := r.After(start, now, loc, s.holidays)
+		if !ok {
+			return fmt.Sprintf("ℹ️ Pengulangan \"%s\" tidak punya jadwal berikutnya.", r.Describe()), nil
+		}
+		at, rule = next, recurring
+	case recurring != "":
+		rule = recurring
+	case rule != "" && remindAt != nil:
+		r, err := recurrence.Parse(rule)
+		if err != nil {
+			return "", fmt.Errorf("parse recurrence rule of reminder %d: %w", rem.ID, err)
+		}
+		next, ok := r.After(at, at.Add(-time.Nanosecond), loc, s.holidays)
+		if !ok {
+			return "ℹ️ Seri reminder ini sudah berakhir sebelum waktu itu.", nil
+		}
+		at = next
+	}
+
+	if err := s.repo.Edit(ctx, rem.ID, newTitle, at, rule); err != nil {
+		return "", err
+	}
+
+	title := rem.TodoTitle
+	if newTitle != "" {
+		title = newTitle
+	}
+	if rem.Standalone() {
+		conversation.Touch(ctx, conversation.KindReminder, rem.ID, title)
+	}
+
+	resp := fmt.Sprintf("✏️ Reminder #%d diupdate: \"%s\"", rem.ID, title)
+	if !at.After(time.Now()) {
+		return resp + "\nℹ️ Waktunya sudah lewat, jadi tidak dikirim lagi.", nil
+	}
+	resp += fmt.Sprintf("\n📅 %s", at.In(loc).Format("2 Jan 2006 15:04 MST"))
+	if r, err := recurrence.Parse(rule); err == nil {
+		resp += fmt.Sprintf(" (🔁 %s)", r.Describe())
+	}
+	if rem.PausedAt != nil {
+		resp += fmt.Sprintf("\n⏸ Masih dijeda. Bilang \"lanjutkan reminder #%d\" untuk mengaktifkan lagi.", rem.ID)
+	}
+	return resp, nil
+}
+
+// Delete removes a reminder found by its ID or by its title or its todo's
+// title. A todo keeps its other reminders.
+func (s *Service) Delete(ctx context.Context, userID int64, reminderID int, search string) (string, error) {
+	rem, msg, err := s.find(ctx, userID, reminderID, search, "hapus")
+	if rem == nil {
+		return msg, err
+	}
+	if err := s.repo.Delete(ctx, rem.ID); err != nil {
+		return "", err
+	}
+	if rem.Standalone() {
+		return fmt.Sprintf("🗑️ Reminder #%d \"%s\" dihapus.", rem.ID, rem.TodoTitle), nil
+	}
+	conversation.Touch(ctx, conversation.KindTodo, *rem.TodoID, rem.TodoTitle)
+	return fmt.Sprintf("🗑️ Reminder #%d untuk \"%s\" dihapus.", rem.ID, rem.TodoTitle), nil
+}
+
+// Pause stops sending a reminder until it is resumed.
+func (s *Service) Pause(ctx context.Context, userID int64, reminderID int, search string) (string, error) {
+	rem, msg, err := s.find(ctx, userID, reminderID, search, "jeda")
+	if rem == nil {
+		return msg, err
+	}
+	if !rem.IsActive {
+		return fmt.Sprintf("ℹ️ Reminder #%d \"%s\" sudah tidak aktif.", rem.ID, rem.TodoTitle), nil
+	}
+	if rem.PausedAt != nil {
+		return fmt.Sprintf("ℹ️ Reminder #%d \"%s\" sudah dijeda.", rem.ID, rem.TodoTitle), nil
+	}
+	if err := s.repo.Pause(ctx, rem.ID); err != nil {
+		return "", err
+	}
+	s.touch(ctx, rem)
+	return fmt.Sprintf("⏸ Reminder #%d \"%s\" dijeda.\nBilang \"lanjutkan reminder #%d\" untuk mengaktifkan lagi.", rem.ID, rem.TodoTitle, rem.ID), nil
+}
+
+// Resume sends a paused reminder again. A recurring one skips the
+// occurrences missed while paused; a one-time one whose time has passed is
+// sent right away.
+func (s *Service) Resume(ctx context.Context, userID int64, reminderID int, search string) (string, error) {
+	loc := s.loc(ctx)
+	rem, msg, err := s.find(ctx, userID, reminderID, search, "lanjutkan")
+	if rem == nil {
+		return msg, err
+	}
+	if rem.PausedAt == nil || !rem.IsActive {
+		return fmt.Sprintf("ℹ️ Reminder #%d \"%s\" tidak sedang dijeda.", rem.ID, rem.TodoTitle), nil
+	}
+
+	now := time.Now()
+	at := rem.RemindAt
+	var next *Next
+	if rem.IsRecurring && rem.RecurrenceRule != nil && !rem.RemindAt.After(now) {
+		next, err = NextOccurrence(rem.RemindAt, *rem.RecurrenceRule, loc, now, s.holidays)
+		if err != nil {
+			return "", fmt.Errorf("next occurrence of reminder %d: %w", rem.ID, err)
+		}
+		if next == nil {
+			if err := s.repo.Cancel(ctx, rem.ID); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("ℹ️ Seri reminder #%d \"%s\" sudah berakhir selama dijeda.", rem.ID, rem.TodoTitle), nil
+		}
+		at = next.RemindAt
+	}
+	if err := s.repo.Resume(ctx, rem.ID, next); err != nil {
+		return "", err
+	}
+	s.touch(ctx, rem)
+
+	resp := fmt.Sprintf("▶️ Reminder #%d \"%s\" dilanjutkan.", rem.ID, rem.TodoTitle)
+	if !at.After(now) {
+		return resp + "\n⏰ Waktunya lewat selama dijeda, jadi dikirim sebentar lagi.", nil
+	}
+	return resp + fmt.Sprintf("\n📅 Berikutnya: %s", at.In(loc).Format("2 Jan 2006 15:04 MST")), nil
+}
+
+// Cancel stops a reminder for good, ending a recurring series; its todo, if
+// any, stays. When search matches several reminders of one todo, all of them
+// are cancelled.
+func (s *Service) Cancel(ctx context.Context, userID int64, reminderID int, search string) (string, error) {
+	if reminderID <= 0 {
+		matches, err := s.repo.FindActive(ctx, userID, search)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) > 1 && sameTodo(matches) {
+			n, err := s.repo.CancelByTodoID(ctx, *matches[0].TodoID)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("🛑 %d reminder todo \"%s\" dihentikan. Todo-nya tetap ada.", n, matches[0].TodoTitle), nil
+		}
+	}
+
+	rem, msg, err := s.find(ctx, userID, reminderID, search, "stop")
+	if rem == nil {
+		return msg, err
+	}
+	if !rem.IsActive {
+		return fmt.Sprintf("ℹ️ Reminder #%d \"%s\" sudah tidak aktif.", rem.ID, rem.TodoTitle), nil
+	}
+	if err := s.repo.Cancel(ctx, rem.ID); err != nil {
+		return "", err
+	}
+	s.touch(ctx, rem)
+
+	resp := fmt.Sprintf("🛑 Reminder #%d \"%s\" dihentikan.", rem.ID, rem.TodoTitle)
+	if !rem.Standalone() {
+		resp += " Todo-nya tetap ada."
+	}
+	return resp, nil
+}
+
+// SkipNext skips the next occurrence of a recurring reminder.
+func (s *Service) SkipNext(ctx context.Context, userID int64, reminderID int, search string) (string, error) {
+	loc := s.loc(ctx)
+	rem, msg, err := s.find(ctx, userID, reminderID, search, "skip")
+	if rem == nil {
+		return msg, err
+	}
+	if !rem.IsActive {
+		return fmt.Sprintf("ℹ️ Reminder #%d \"%s\" sudah tidak aktif.", rem.ID, rem.TodoTitle), nil
+	}
+	if !rem.IsRecurring || rem.RecurrenceRule == nil {
+		return fmt.Sprintf("ℹ️ Reminder #%d tidak berulang. Untuk membatalkannya, bilang \"stop reminder #%d\".", rem.ID, rem.ID), nil
+	}
+
+	// The occurrence to skip is the one at remind_at, or the next one to
+	// come if that has already gone by.
+	after := time.Now()
+	if rem.RemindAt.After(after) {
+		after = rem.RemindAt
+	}
+	next, err := NextOccurrence(rem.RemindAt, *rem.RecurrenceRule, loc, after, s.holidays)
+	if err != nil {
+		return "", fmt.Errorf("next occurrence of reminder %d: %w", rem.ID, err)
+	}
+	if err := s.repo.Advance(ctx, rem.ID, next); err != nil {
+		return "", err
+	}
+	s.touch(ctx, rem)
+
+	skipped := rem.RemindAt.In(loc).Format("2 Jan 2006 15:04 MST")
+	if next == nil {
+		return fmt.Sprintf("⏭ Reminder \"%s\" %s dilewati. Itu yang terakhir dari serinya.", rem.TodoTitle, skipped), nil
+	}
+	return fmt.Sprintf("⏭ Reminder \"%s\" %s dilewati.\n📅 Berikutnya: %s", rem.TodoTitle, skipped, next.RemindAt.In(loc).Format("2 Jan 2006 15:04 MST")), nil
+}
+
+// touch remembers a standalone reminder as the one just talked about.
+func (s *Service) touch(ctx context.Context, rem *ReminderWithTodo) {
+	if rem.Standalone() {
+		conversation.Touch(ctx, conversation.KindReminder, rem.ID, rem.TodoTitle)
+	}
+}
+
+// sameTodo reports whether all reminders belong to the same todo.
+func sameTodo(reminders []ReminderWithTodo) bool {
+	for _, rem := range reminders {
+		if rem.TodoID == nil || *rem.TodoID != *reminders[0].TodoID {
+			return false
+		}
+	}
+	return true
+}
+
+// SetCatchUp sets what happens to a standalone reminder missed during
+// downtime. ok is false when no standalone reminder matches search, so the
+// caller can try the user's todos instead.
+func (s *Service) SetCatchUp(ctx context.Context, userID int64, search, policy string) (msg string, ok bool, err error) {
+	rem, err := s.repo.FindStandalone(ctx, userID, search)
+	if rem == nil || err != nil {
+		return "", false, err
+	}
+	if err := s.repo.SetReminderCatchUp(ctx, rem.ID, policy); err != nil {
+		return "", false, err
+	}
+	conversation.Touch(ctx, conversation.KindReminder, rem.ID, rem.TodoTitle)
+
+	if policy == CatchUpSkip {
+		return fmt.Sprintf("⏭ Reminder \"%s\" akan dilewati jika terlewat.", rem.TodoTitle), true, nil
+	}
+	return fmt.Sprintf("⏰ Reminder \"%s\" tetap dikirim walau terlambat.", rem.TodoTitle), true, nil
+}
+
+// SetNag makes a standalone reminder repeat until acknowledged, or stops that
+// when nag is nil. ok is false when no standalone reminder matches search.
+func (s *Service) SetNag(ctx context.Context, userID int64, search string, nag *Nag) (msg string, ok bool, err error) {
+	rem, err := s.repo.FindStandalone(ctx, userID, search)
+	if rem == nil || err != nil {
+		return "", false, err
+	}
+	if err := s.repo.SetReminderNag(ctx, rem.ID, nag); err != nil {
+		return "", false, err
+	}
+	conversation.Touch(ctx, conversation.KindReminder, rem.ID, rem.TodoTitle)
+
+	if nag == nil {
+		return fmt.Sprintf("🔕 Reminder \"%s\" tidak diulang lagi.", rem.TodoTitle), true, nil
+	}
+	return fmt.Sprintf("🔂 Reminder \"%s\" diulang %s sampai kamu tekan ✅ Done atau 👍 Oke.", rem.TodoTitle, nag.Describe()), true, nil
+}
@@ -0,0 +1,38 @@
+package settings
+
+import (
+	"context"
+	"time"
+)
+
+type settingsKey struct{}
+
+// WithUser returns a context carrying the settings of the user being served,
+// so services and formatters deep in a call can use the user's timezone.
+func WithUser(ctx context.Context, s *Settings) context.Context {
+	return context.WithValue(ctx, settingsKey{}, s)
+}
+
+// FromContext returns the settings attached by WithUser, or nil.
+func FromContext(ctx context.Context) *Settings {
+	s, _ := ctx.Value(settingsKey{}).(*Settings)
+	return s
+}
+
+// Location returns the user's timezone from the context, or fallback when
+// the context carries no settings.
+func Location(ctx context.Context, fallback *time.Location) *time.Location {
+	if s := FromContext(ctx); s != nil {
+		return s.Location()
+	}
+	return fallback
+}
+
+// ReminderHour returns the user's default reminder hour from the context, or
+// fallback when the context carries no settings.
+func ReminderHour(ctx context.Context, fallback int) int {
+	if s := FromContext(ctx); s != nil {
+		return s.ReminderHour
+	}
+	return fallback
+}
@@ -0,0 +1,132 @@
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *sql.DB
+}
+
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+const selectSettings = `SELECT user_id, timezone, reminder_hour,
+		to_char(briefing_at, 'HH24:MI'), to_char(overdue_at, 'HH24:MI'), to_char(monthly_report_at, 'HH24:MI'),
+		briefing_enabled, overdue_enabled, monthly_report_enabled,
+		to_char(quiet_start, 'HH24:MI'), to_char(quiet_end, 'HH24:MI'), quiet_enabled, dnd_until
+	 FROM user_settings`
+
+// Get returns the stored settings, or nil when the user has none.
+func (r *Repository) Get(ctx context.Context, userID int64) (*Settings, error) {
+	rows, err := r.db.QueryContext(ctx, selectSettings+` WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get settings: %w", err)
+	}
+	defer rows.Close()
+
+	list, err := scanSettings(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
+// ListByUserIDs returns the stored settings of the given users. Users without
+// a row are simply missing from the result.
+func (r *Repository) ListByUserIDs(ctx context.Context, userIDs []int64) ([]Settings, error) {
+	rows, err := r.db.QueryContext(ctx, selectSettings+` WHERE user_id = ANY($1)`, pq.Array(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("list settings: %w", err)
+	}
+	defer rows.Close()
+	return scanSettings(rows)
+}
+
+// ListUserIDs returns the users with stored settings.
+func (r *Repository) ListUserIDs(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id FROM user_settings`)
+	if err != nil {
+		return nil, fmt.Errorf("list settings user ids: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (r *Repository) Save(ctx context.Context, s *Settings) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO user_settings (user_id, timezone, reminder_hour, briefing_at, overdue_at, monthly_report_at,
+		                            briefing_enabled, overdue_enabled, monthly_report_enabled,
+		                            quiet_start, quiet_end, quiet_enabled, dnd_until)
+		 VALUES ($1, $2, $3, $4::time, $5::time, $6::time, $7, $8, $9, $10::time, $11::time, $12, $13)
+		 ON CONFLICT (user_id) DO UPDATE SET
+		     timezone = EXCLUDED.timezone,
+		     reminder_hour = EXCLUDED.reminder_hour,
+		     briefing_at = EXCLUDED.briefing_at,
+		     overdue_at = EXCLUDED.overdue_at,
+		     monthly_report_at = EXCLUDED.monthly_report_at,
+		     briefing_enabled = EXCLUDED.briefing_enabled,
+		     overdue_enabled = EXCLUDED.overdue_enabled,
+		     monthly_report_enabled = EXCLUDED.monthly_report_enabled,
+		     quiet_start = EXCLUDED.quiet_start,
+		     quiet_end = EXCLUDED.quiet_end,
+		     quiet_enabled = EXCLUDED.quiet_enabled,
+		     dnd_until = EXCLUDED.dnd_until,
+		     updated_at = NOW()`,
+		s.UserID, s.Timezone, s.ReminderHour, s.BriefingAt.String(), s.OverdueAt.String(), s.MonthlyReportAt.String(),
+		s.BriefingEnabled, s.OverdueEnabled, s.MonthlyReportEnabled,
+		s.QuietStart.String(), s.QuietEnd.String(), s.QuietEnabled, s.DNDUntil,
+	)
+	if err != nil {
+		return fmt.Errorf("save settings: %w", err)
+	}
+	return nil
+}
+
+func scanSettings(rows *sql.Rows) ([]Settings, error) {
+	var list []Settings
+	for rows.Next() {
+		var s Settings
+		var briefingAt, overdueAt, monthlyAt, quietStart, quietEnd string
+		err := rows.Scan(&s.UserID, &s.Timezone, &s.ReminderHour, &briefingAt, &overdueAt, &monthlyAt,
+			&s.BriefingEnabled, &s.OverdueEnabled, &s.MonthlyReportEnabled,
+			&quietStart, &quietEnd, &s.QuietEnabled, &s.DNDUntil)
+		if err != nil {
+			return nil, fmt.Errorf("scan settings: %w", err)
+		}
+		if s.BriefingAt, err = ParseClock(briefingAt); err != nil {
+			return nil, err
+		}
+		if s.OverdueAt, err = ParseClock(overdueAt); err != nil {
+			return nil, err
+		}
+		if s.MonthlyReportAt, err = ParseClock(monthlyAt); err != nil {
+			return nil, err
+		}
+		if s.QuietStart, err = ParseClock(quietStart); err != nil {
+			return nil, err
+		}
+		if s.QuietEnd, err = ParseClock(quietEnd); err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,214 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type Service struct {
+	repo     *Repository
+	defaults Settings
+}
+
+// NewService uses timezone and reminderHour (from config) as the defaults for
+// users who never changed their settings.
+func NewService(repo *Repository, timezone *time.Location, reminderHour int) *Service {
+	return &Service{
+		repo: repo,
+		defaults: Settings{
+			Timezone:             timezone.String(),
+			ReminderHour:         reminderHour,
+			BriefingAt:           DefaultBriefingAt,
+			OverdueAt:            DefaultOverdueAt,
+			MonthlyReportAt:      DefaultMonthlyReportAt,
+			BriefingEnabled:      true,
+			OverdueEnabled:       true,
+			MonthlyReportEnabled: true,
+			QuietStart:           DefaultQuietStart,
+			QuietEnd:             DefaultQuietEnd,
+			QuietEnabled:         false,
+			loc:                  timezone,
+		},
+	}
+}
+
+// Get returns the user's settings, or the defaults when none are stored.
+func (s *Service) Get(ctx context.Context, userID int64) (*Settings, error) {
+	stored, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.complete(userID, stored), nil
+}
+
+// Resolve is Get for callers that cannot fail the request: errors are logged
+// and the defaults are used instead.
+func (s *Service) Resolve(ctx context.Context, userID int64) *Settings {
+	us, err := s.Get(ctx, userID)
+	if err != nil {
+		slog.Error("load user settings failed", "user_id", userID, "error", err)
+		return s.complete(userID, nil)
+	}
+	return us
+}
+
+// UserIDs returns the users who changed a setting.
+func (s *Service) UserIDs(ctx context.Context) ([]int64, error) {
+	return s.repo.ListUserIDs(ctx)
+}
+
+// ForUsers returns settings for each of userIDs, defaults included.
+func (s *Service) ForUsers(ctx context.Context, userIDs []int64) (map[int64]*Settings, error) {
+	list, err := s.repo.ListByUserIDs(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[int64]*Settings, len(list))
+	for i := range list {
+		stored[list[i].UserID] = &list[i]
+	}
+	out := make(map[int64]*Settings, len(userIDs))
+	for _, id := range userIDs {
+		out[id] = s.complete(id, stored[id])
+	}
+	return out, nil
+}
+
+// complete fills in defaults and resolves the timezone. A stored timezone
+// that no longer loads falls back to the default one.
+func (s *Service) complete(userID int64, stored *Settings) *Settings {
+	if stored == nil {
+		us := s.defaults
+		us.UserID = userID
+		return &us
+	}
+	loc, err := ResolveTimezone(stored.Timezone)
+	if err != nil {
+		slog.Warn("invalid stored timezone, using default", "user_id", userID, "timezone", stored.Timezone, "error", err)
+		loc = s.defaults.loc
+	}
+	stored.loc = loc
+	return stored
+}
+
+func (s *Service) update(ctx context.Context, userID int64, apply func(*Settings)) (*Settings, error) {
+	us, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	apply(us)
+	if err := s.repo.Save(ctx, us); err != nil {
+		return nil, err
+	}
+	return us, nil
+}
+
+func (s *Service) SetTimezone(ctx context.Context, userID int64, name string) (string, error) {
+	loc, err := ResolveTimezone(name)
+	if err != nil {
+		return fmt.Sprintf("❌ Timezone \"%s\" tidak dikenali. Contoh: WIB, WITA, WIT, atau Asia/Jakarta.", name), nil
+	}
+	if _, err := s.update(ctx, userID, func(us *Settings) {
+		us.Timezone = loc.String()
+		us.loc = loc
+	}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🌏 Timezone diubah ke %s (%s).", loc.String(), ZoneName(loc)), nil
+}
+
+// SetTime moves a scheduled message and switches it on.
+func (s *Service) SetTime(ctx context.Context, userID int64, m Message, at Clock) (string, error) {
+	if !validMessage(m) {
+		return fmt.Sprintf("❌ Pengaturan \"%s\" tidak dikenali.", m), nil
+	}
+	us, err := s.update(ctx, userID, func(us *Settings) {
+		us.setTime(m, at)
+		us.setEnabled(m, true)
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("⏰ %s akan dikirim jam %s %s.", m.Label(), at, ZoneName(us.Location())), nil
+}
+
+func (s *Service) SetEnabled(ctx context.Context, userID int64, m Message, enabled bool) (string, error) {
+	if !validMessage(m) {
+		return fmt.Sprintf("❌ Pengaturan \"%s\" tidak dikenali.", m), nil
+	}
+	us, err := s.update(ctx, userID, func(us *Settings) {
+		us.setEnabled(m, enabled)
+	})
+	if err != nil {
+		return "", err
+	}
+	if !enabled {
+		return fmt.Sprintf("🔕 %s dimatikan.", m.Label()), nil
+	}
+	at, _ := us.Schedule(m)
+	return fmt.Sprintf("🔔 %s dinyalakan (jam %s %s).", m.Label(), at, ZoneName(us.Location())), nil
+}
+
+// SetReminderHour changes the hour used for reminders given a date but no time.
+func (s *Service) SetReminderHour(ctx context.Context, userID int64, hour int) (string, error) {
+	if hour < 0 || hour > 23 {
+		return fmt.Sprintf("❌ Jam %d tidak valid. Gunakan 0-23.", hour), nil
+	}
+	if _, err := s.update(ctx, userID, func(us *Settings) {
+		us.ReminderHour = hour
+	}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("⏰ Jam default reminder diubah ke %02d:00.", hour), nil
+}
+
+// SetQuietHours sets the window in which proactive messages are held back
+// and switches it on.
+func (s *Service) SetQuietHours(ctx context.Context, userID int64, start, end Clock) (string, error) {
+	if start == end {
+		return "❌ Jam mulai dan selesai jam tenang tidak boleh sama.", nil
+	}
+	if _, err := s.update(ctx, userID, func(us *Settings) {
+		us.QuietStart, us.QuietEnd = start, end
+		us.QuietEnabled = true
+	}); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🌙 Jam tenang diatur %s–%s. Pesan otomatis ditahan dan dikirim sekaligus jam %s.", start, end, end), nil
+}
+
+func (s *Service) SetQuietEnabled(ctx context.Context, userID int64, enabled bool) (string, error) {
+	us, err := s.update(ctx, userID, func(us *Settings) {
+		us.QuietEnabled = enabled
+	})
+	if err != nil {
+		return "", err
+	}
+	if !enabled {
+		return "🔔 Jam tenang dimatikan.", nil
+	}
+	return fmt.Sprintf("🌙 Jam tenang dinyalakan (%s–%s).", us.QuietStart, us.QuietEnd), nil
+}
+
+// SetDND holds back proactive messages until until.
+func (s *Service) SetDND(ctx context.Context, userID int64, until time.Time) (string, error) {
+	us, err := s.update(ctx, userID, func(us *Settings) {
+		us.DNDUntil = &until
+	})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("⛔ Tidak akan diganggu sampai %s. Pesan yang masuk dikirim sekaligus setelahnya.",
+		until.In(us.Location()).Format("2 Jan 15:04 MST")), nil
+}
+
+func (s *Service) ClearDND(ctx context.Context, userID int64) (string, error) {
+	if _, err := s.update(ctx, userID, func(us *Settings) {
+		us.DNDUntil = nil
+	}); err != nil {
+		return "", err
+	}
+	return "🔔 Mode jangan ganggu dimatikan.", nil
+}
@@ -0,0 +1,205 @@
+package settings
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message is a scheduled message a user can move or switch off.
+type Message string
+
+const (
+	Briefing      Message = "briefing"
+	Overdue       Message = "overdue"
+	MonthlyReport Message = "monthly_report"
+)
+
+// Messages lists every scheduled message in display order.
+var Messages = []Message{Briefing, Overdue, MonthlyReport}
+
+// Label returns the Indonesian name shown to the user.
+func (m Message) Label() string {
+	switch m {
+	case Briefing:
+		return "Daily briefing"
+	case Overdue:
+		return "Pengingat overdue"
+	case MonthlyReport:
+		return "Laporan bulanan"
+	default:
+		return string(m)
+	}
+}
+
+// Clock is a wall-clock time of day in the user's timezone.
+type Clock struct {
+	Hour   int
+	Minute int
+}
+
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour, c.Minute)
+}
+
+// ParseClock parses "HH:MM" (seconds, if present, are ignored).
+func ParseClock(s string) (Clock, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		t, err = time.Parse("15:04:05", strings.TrimSpace(s))
+		if err != nil {
+			return Clock{}, fmt.Errorf("parse clock %q: %w", s, err)
+		}
+	}
+	return Clock{Hour: t.Hour(), Minute: t.Minute()}, nil
+}
+
+// On returns the clock time on the given day in loc.
+func (c Clock) On(day time.Time, loc *time.Location) time.Time {
+	d := day.In(loc)
+	return time.Date(d.Year(), d.Month(), d.Day(), c.Hour, c.Minute, 0, 0, loc)
+}
+
+// Default times for the scheduled messages.
+var (
+	DefaultBriefingAt      = Clock{Hour: 7, Minute: 30}
+	DefaultOverdueAt       = Clock{Hour: 19, Minute: 0}
+	DefaultMonthlyReportAt = Clock{Hour: 8, Minute: 0}
+	DefaultQuietStart      = Clock{Hour: 22, Minute: 0}
+	DefaultQuietEnd        = Clock{Hour: 6, Minute: 0}
+)
+
+// Settings are one user's preferences. Users without a stored row get the
+// service defaults.
+type Settings struct {
+	UserID               int64
+	Timezone             string
+	ReminderHour         int
+	BriefingAt           Clock
+	OverdueAt            Clock
+	MonthlyReportAt      Clock
+	BriefingEnabled      bool
+	OverdueEnabled       bool
+	MonthlyReportEnabled bool
+	// Quiet hours and do-not-disturb hold back proactive messages
+	// (reminders, follow-ups, reports) until they end.
+	QuietStart   Clock
+	QuietEnd     Clock
+	QuietEnabled bool
+	DNDUntil     *time.Time
+
+	loc *time.Location
+}
+
+// Location returns the user's timezone, resolved by the Service. Settings
+// made elsewhere resolve Timezone here, falling back to UTC.
+func (s *Settings) Location() *time.Location {
+	if s.loc != nil {
+		return s.loc
+	}
+	if loc, err := ResolveTimezone(s.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Schedule returns when m is sent and whether it is enabled.
+func (s *Settings) Schedule(m Message) (Clock, bool) {
+	switch m {
+	case Briefing:
+		return s.BriefingAt, s.BriefingEnabled
+	case Overdue:
+		return s.OverdueAt, s.OverdueEnabled
+	case MonthlyReport:
+		return s.MonthlyReportAt, s.MonthlyReportEnabled
+	default:
+		return Clock{}, false
+	}
+}
+
+// AfterQuiet returns t, or when the user may be messaged again if t falls in
+// their do-not-disturb or quiet hours. The quiet window may span midnight,
+// e.g. 22:00–06:00.
+func (s *Settings) AfterQuiet(t time.Time) time.Time {
+	if s.DNDUntil != nil && t.Before(*s.DNDUntil) {
+		t = *s.DNDUntil
+	}
+	if !s.QuietEnabled || s.QuietStart == s.QuietEnd {
+		return t
+	}
+	loc := s.Location()
+	local := t.In(loc)
+	m := local.Hour()*60 + local.Minute()
+	start := s.QuietStart.Hour*60 + s.QuietStart.Minute
+	end := s.QuietEnd.Hour*60 + s.QuietEnd.Minute
+
+	quiet := m >= start && m < end
+	if start > end {
+		quiet = m >= start || m < end
+	}
+	if !quiet {
+		return t
+	}
+	until := s.QuietEnd.On(local, loc)
+	if !until.After(local) {
+		until = s.QuietEnd.On(local.AddDate(0, 0, 1), loc)
+	}
+	return until
+}
+
+func (s *Settings) setTime(m Message, at Clock) {
+	switch m {
+	case Briefing:
+		s.BriefingAt = at
+	case Overdue:
+		s.OverdueAt = at
+	case MonthlyReport:
+		s.MonthlyReportAt = at
+	}
+}
+
+func (s *Settings) setEnabled(m Message, enabled bool) {
+	switch m {
+	case Briefing:
+		s.BriefingEnabled = enabled
+	case Overdue:
+		s.OverdueEnabled = enabled
+	case MonthlyReport:
+		s.MonthlyReportEnabled = enabled
+	}
+}
+
+func validMessage(m Message) bool {
+	for _, known := range Messages {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+// timezoneAliases maps the Indonesian zone abbreviations to IANA names.
+var timezoneAliases = map[string]string{
+	"wib":  "Asia/Jakarta",
+	"wita": "Asia/Makassar",
+	"wit":  "Asia/Jayapura",
+}
+
+// ResolveTimezone accepts WIB/WITA/WIT or an IANA name such as "Asia/Jakarta".
+func ResolveTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if alias, ok := timezoneAliases[strings.ToLower(name)]; ok {
+		name = alias
+	}
+	// LoadLocation maps "" to UTC and "Local" to the server zone; neither is
+	// something a user means.
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// ZoneName returns the zone abbreviation in effect now, e.g. "WIB".
+func ZoneName(loc *time.Location) string {
+	return time.Now().In(loc).Format("MST")
+}
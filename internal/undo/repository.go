@@ -0,0 +1,97 @@
+package undo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/zhafrantharif/personal-assistant-bot/internal/db"
+)
+
+// Kinds of destructive operations that can be reverted.
+const (
+	KindClearTodo     = "clear_todo"
+	KindClearExpense  = "clear_expense"
+	KindDeleteProject = "delete_project"
+)
+
+// Entry is a snapshot of what one destructive operation removed.
+type Entry struct {
+	ID        int
+	UserID    int64
+	Kind      string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+// Repository is a per-user undo journal. Only the latest operation is kept,
+// and it can only be reverted within the window.
+type Repository struct {
+	db     *sql.DB
+	window time.Duration
+}
+
+func NewRepository(db *sql.DB, window time.Duration) *Repository {
+	return &Repository{db: db, window: window}
+}
+
+// Record stores a snapshot for kind in q, replacing the user's previous
+// entry. Run it in the transaction that removes the rows, so a delete is
+// never committed without a way to undo it.
+func Record(ctx context.Context, q db.DBTX, userID int64, kind string, snapshot any) error {
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshal undo snapshot: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM undo_journal WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("clear previous undo entry: %w", err)
+	}
+	_, err = q.ExecContext(ctx,
+		`INSERT INTO undo_journal (user_id, kind, payload) VALUES ($1, $2, $3)`,
+		userID, kind, payload,
+	)
+	if err != nil {
+		return fmt.Errorf("insert undo entry: %w", err)
+	}
+	return nil
+}
+
+// Latest returns the user's latest entry if it is still inside the window,
+// or nil when there is nothing to undo. The entry is kept until Delete, so a
+// failed restore can be retried.
+func (r *Repository) Latest(ctx context.Context, userID int64) (*Entry, error) {
+	var e Entry
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, user_id, kind, payload, created_at FROM undo_journal
+		 WHERE user_id = $1 AND created_at >= $2
+		 ORDER BY created_at DESC LIMIT 1`,
+		userID, time.Now().Add(-r.window),
+	).Scan(&e.ID, &e.UserID, &e.Kind, &e.Payload, &e.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get undo entry: %w", err)
+	}
+	return &e, nil
+}
+
+// Delete removes an entry once it has been restored.
+func (r *Repository) Delete(ctx context.Context, id int) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM undo_journal WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete undo entry: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes entries that can no longer be undone.
+func (r *Repository) DeleteExpired(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM undo_journal WHERE created_at < $1`, time.Now().Add(-r.window))
+	if err != nil {
+		return fmt.Errorf("delete expired undo entries: %w", err)
+	}
+	return nil
+}
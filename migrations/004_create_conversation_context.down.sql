@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS conversation_entities;
+DROP TABLE IF EXISTS conversation_messages;
@@ -0,0 +1,18 @@
+CREATE TABLE conversation_messages (
+    id          SERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL,
+    role        TEXT NOT NULL,
+    content     TEXT NOT NULL,
+    created_at  TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_conversation_messages_user ON conversation_messages (user_id, created_at DESC);
+
+CREATE TABLE conversation_entities (
+    user_id     BIGINT NOT NULL,
+    kind        TEXT NOT NULL,
+    entity_id   INT NOT NULL,
+    label       TEXT NOT NULL,
+    touched_at  TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, kind, entity_id)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE pending_actions (
+    id             SERIAL PRIMARY KEY,
+    user_id        BIGINT NOT NULL,
+    payload        JSONB NOT NULL,
+    candidate_ids  INT[] NOT NULL,
+    expires_at     TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_pending_actions_expires ON pending_actions (expires_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS undo_journal;
+ALTER TABLE pending_actions DROP COLUMN kind;
@@ -0,0 +1,11 @@
+ALTER TABLE pending_actions ADD COLUMN kind TEXT NOT NULL DEFAULT 'pick';
+
+CREATE TABLE undo_journal (
+    id          SERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL,
+    kind        TEXT NOT NULL,
+    payload     JSONB NOT NULL,
+    created_at  TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_undo_journal_user ON undo_journal (user_id, created_at DESC);
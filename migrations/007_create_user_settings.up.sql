@@ -0,0 +1,12 @@
+CREATE TABLE user_settings (
+    user_id                 BIGINT PRIMARY KEY,
+    timezone                TEXT NOT NULL,
+    reminder_hour           SMALLINT NOT NULL,
+    briefing_at             TIME NOT NULL,
+    overdue_at              TIME NOT NULL,
+    monthly_report_at       TIME NOT NULL,
+    briefing_enabled        BOOLEAN NOT NULL DEFAULT TRUE,
+    overdue_enabled         BOOLEAN NOT NULL DEFAULT TRUE,
+    monthly_report_enabled  BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at              TIMESTAMPTZ DEFAULT NOW()
+);
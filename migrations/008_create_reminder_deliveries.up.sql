@@ -0,0 +1,16 @@
+ALTER TABLE reminders ADD COLUMN claimed_until TIMESTAMPTZ;
+
+CREATE TABLE reminder_deliveries (
+    id             SERIAL PRIMARY KEY,
+    reminder_id    INT NOT NULL REFERENCES reminders(id) ON DELETE CASCADE,
+    scheduled_for  TIMESTAMPTZ NOT NULL,
+    status         TEXT NOT NULL,
+    attempts       INT NOT NULL DEFAULT 1,
+    claimed_by     TEXT NOT NULL,
+    claimed_at     TIMESTAMPTZ DEFAULT NOW(),
+    finished_at    TIMESTAMPTZ,
+    last_error     TEXT,
+    UNIQUE (reminder_id, scheduled_for)
+);
+
+CREATE INDEX idx_reminder_deliveries_finished ON reminder_deliveries (finished_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reminder_deliveries_dead;
+UPDATE reminder_deliveries SET status = 'failed' WHERE status = 'dead';
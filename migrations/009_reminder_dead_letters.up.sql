@@ -0,0 +1,3 @@
+UPDATE reminder_deliveries SET status = 'dead' WHERE status = 'failed';
+
+CREATE INDEX idx_reminder_deliveries_dead ON reminder_deliveries (reminder_id) WHERE status = 'dead';
@@ -0,0 +1 @@
+ALTER TABLE reminders ADD COLUMN catch_up TEXT NOT NULL DEFAULT 'fire';
@@ -0,0 +1,18 @@
+-- Rules that use RRULE features without a short form are left as they are.
+UPDATE reminders SET recurrence_rule = 'daily'
+WHERE recurrence_rule = 'FREQ=DAILY';
+
+UPDATE reminders SET recurrence_rule = 'weekly:' ||
+    CASE substring(recurrence_rule FROM 'BYDAY=(..)$')
+        WHEN 'MO' THEN 'MON' WHEN 'TU' THEN 'TUE' WHEN 'WE' THEN 'WED' WHEN 'TH' THEN 'THU'
+        WHEN 'FR' THEN 'FRI' WHEN 'SA' THEN 'SAT' WHEN 'SU' THEN 'SUN'
+    END
+WHERE recurrence_rule ~ '^FREQ=WEEKLY;BYDAY=(MO|TU|WE|TH|FR|SA|SU)$';
+
+UPDATE reminders SET recurrence_rule = 'monthly:' ||
+    CASE substring(recurrence_rule FROM 'BYMONTHDAY=(-?\d+)$') WHEN '-1' THEN '31' ELSE substring(recurrence_rule FROM 'BYMONTHDAY=(\d+)$') END
+WHERE recurrence_rule ~ '^FREQ=MONTHLY;BYMONTHDAY=(-1|\d{1,2})$';
+
+UPDATE reminders SET recurrence_rule = 'yearly:' ||
+    substring(recurrence_rule FROM 'BYMONTH=(\d+)') || '-' || substring(recurrence_rule FROM 'BYMONTHDAY=(\d+)$')
+WHERE recurrence_rule ~ '^FREQ=YEARLY;BYMONTH=\d{1,2};BYMONTHDAY=\d{1,2}$';
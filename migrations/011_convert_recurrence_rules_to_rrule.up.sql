@@ -0,0 +1,24 @@
+UPDATE reminders SET recurrence_rule = 'FREQ=DAILY'
+WHERE lower(recurrence_rule) = 'daily';
+
+UPDATE reminders SET recurrence_rule = 'FREQ=WEEKLY;BYDAY=' ||
+    CASE lower(split_part(recurrence_rule, ':', 2))
+        WHEN 'mon' THEN 'MO' WHEN 'senin' THEN 'MO'
+        WHEN 'tue' THEN 'TU' WHEN 'selasa' THEN 'TU'
+        WHEN 'wed' THEN 'WE' WHEN 'rabu' THEN 'WE'
+        WHEN 'thu' THEN 'TH' WHEN 'kamis' THEN 'TH'
+        WHEN 'fri' THEN 'FR' WHEN 'jumat' THEN 'FR'
+        WHEN 'sat' THEN 'SA' WHEN 'sabtu' THEN 'SA'
+        WHEN 'sun' THEN 'SU' WHEN 'minggu' THEN 'SU'
+    END
+WHERE lower(recurrence_rule) ~ '^weekly:(mon|tue|wed|thu|fri|sat|sun|senin|selasa|rabu|kamis|jumat|sabtu|minggu)$';
+
+-- monthly:31 used to mean the last day of every month.
+UPDATE reminders SET recurrence_rule = 'FREQ=MONTHLY;BYMONTHDAY=' ||
+    CASE split_part(recurrence_rule, ':', 2)::int WHEN 31 THEN '-1' ELSE split_part(recurrence_rule, ':', 2)::int::text END
+WHERE lower(recurrence_rule) ~ '^monthly:\d{1,2}$';
+
+UPDATE reminders SET recurrence_rule = 'FREQ=YEARLY;BYMONTH=' ||
+    split_part(split_part(recurrence_rule, ':', 2), '-', 1)::int || ';BYMONTHDAY=' ||
+    split_part(split_part(recurrence_rule, ':', 2), '-', 2)::int
+WHERE lower(recurrence_rule) ~ '^yearly:\d{1,2}-\d{1,2}$';
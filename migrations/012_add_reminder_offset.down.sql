@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reminders_todo;
+ALTER TABLE reminders DROP COLUMN offset_minutes;
@@ -0,0 +1,3 @@
+ALTER TABLE reminders ADD COLUMN offset_minutes INT;
+
+CREATE INDEX idx_reminders_todo ON reminders (todo_id);
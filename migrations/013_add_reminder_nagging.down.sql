@@ -0,0 +1,13 @@
+ALTER TABLE user_settings
+    DROP COLUMN quiet_enabled,
+    DROP COLUMN quiet_end,
+    DROP COLUMN quiet_start;
+
+DROP INDEX IF EXISTS idx_reminders_nag;
+
+ALTER TABLE reminders
+    DROP COLUMN nag_for,
+    DROP COLUMN nag_count,
+    DROP COLUMN nag_at,
+    DROP COLUMN nag_max,
+    DROP COLUMN nag_every_minutes;
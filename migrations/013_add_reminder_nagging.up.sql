@@ -0,0 +1,14 @@
+ALTER TABLE reminders
+    ADD COLUMN nag_every_minutes INT,
+    ADD COLUMN nag_max           INT,
+    ADD COLUMN nag_at            TIMESTAMPTZ,
+    ADD COLUMN nag_count         INT NOT NULL DEFAULT 0,
+    ADD COLUMN nag_for           TIMESTAMPTZ;
+
+CREATE INDEX idx_reminders_nag ON reminders (nag_at)
+    WHERE nag_at IS NOT NULL;
+
+ALTER TABLE user_settings
+    ADD COLUMN quiet_start   TIME NOT NULL DEFAULT '22:00',
+    ADD COLUMN quiet_end     TIME NOT NULL DEFAULT '06:00',
+    ADD COLUMN quiet_enabled BOOLEAN NOT NULL DEFAULT TRUE;
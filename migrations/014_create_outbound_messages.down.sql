@@ -0,0 +1,5 @@
+ALTER TABLE user_settings
+    ALTER COLUMN quiet_enabled SET DEFAULT TRUE,
+    DROP COLUMN dnd_until;
+
+DROP TABLE IF EXISTS outbound_messages;
@@ -0,0 +1,27 @@
+CREATE TABLE outbound_messages (
+    id            SERIAL PRIMARY KEY,
+    user_id       BIGINT NOT NULL,
+    kind          TEXT NOT NULL,
+    text          TEXT NOT NULL,
+    markup        JSONB,
+    attempts      INT NOT NULL DEFAULT 0,
+    last_error    TEXT,
+    -- claimed_until leases the message to the replica sending it; retry_at
+    -- holds a failed one back; dead_at marks one given up on, kept for
+    -- inspection.
+    claimed_until TIMESTAMPTZ,
+    retry_at      TIMESTAMPTZ,
+    dead_at       TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_outbound_messages_user ON outbound_messages (user_id, id)
+    WHERE dead_at IS NULL;
+
+ALTER TABLE user_settings
+    ADD COLUMN dnd_until TIMESTAMPTZ,
+    ALTER COLUMN quiet_enabled SET DEFAULT FALSE;
+
+-- Quiet hours used to hold back only repeated reminders, so 013 turned them
+-- on for everyone. Now that they hold back every message they are opt-in.
+UPDATE user_settings SET quiet_enabled = FALSE;
@@ -0,0 +1,8 @@
+DELETE FROM reminders WHERE todo_id IS NULL;
+
+DROP INDEX IF EXISTS idx_reminders_user;
+ALTER TABLE reminders
+    DROP CONSTRAINT reminders_owner_check,
+    DROP COLUMN title,
+    DROP COLUMN user_id,
+    ALTER COLUMN todo_id SET NOT NULL;
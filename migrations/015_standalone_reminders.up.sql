@@ -0,0 +1,9 @@
+ALTER TABLE reminders
+    ALTER COLUMN todo_id DROP NOT NULL,
+    ADD COLUMN user_id BIGINT,
+    ADD COLUMN title   TEXT,
+    ADD CONSTRAINT reminders_owner_check
+        CHECK (todo_id IS NOT NULL OR (user_id IS NOT NULL AND title IS NOT NULL));
+
+CREATE INDEX idx_reminders_user ON reminders (user_id)
+    WHERE todo_id IS NULL;
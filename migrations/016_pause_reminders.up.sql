@@ -0,0 +1 @@
+ALTER TABLE reminders ADD COLUMN paused_at TIMESTAMPTZ;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS expense_category_rules;
+ALTER TABLE expenses DROP COLUMN IF EXISTS category_id;
+DROP TABLE IF EXISTS expense_categories;
@@ -0,0 +1,29 @@
+CREATE TABLE expense_categories (
+    id          SERIAL PRIMARY KEY,
+    name        TEXT NOT NULL UNIQUE,
+    emoji       TEXT NOT NULL,
+    created_at  TIMESTAMPTZ DEFAULT NOW()
+);
+
+INSERT INTO expense_categories (name, emoji) VALUES
+    ('Makan',     '🍜'),
+    ('Transport', '🚗'),
+    ('Tagihan',   '🧾'),
+    ('Belanja',   '🛍️'),
+    ('Hiburan',   '🎬'),
+    ('Kesehatan', '💊');
+
+ALTER TABLE expenses
+    ADD COLUMN category_id INT REFERENCES expense_categories(id) ON DELETE SET NULL;
+
+-- Keywords learned from the user's category corrections: an expense whose
+-- description contains keyword goes to category_id. hits counts how often
+-- the user confirmed it, so the stronger rule wins a conflict.
+CREATE TABLE expense_category_rules (
+    user_id      BIGINT NOT NULL,
+    keyword      TEXT NOT NULL,
+    category_id  INT NOT NULL REFERENCES expense_categories(id) ON DELETE CASCADE,
+    hits         INT NOT NULL DEFAULT 1,
+    updated_at   TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, keyword)
+);
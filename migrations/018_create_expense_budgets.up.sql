@@ -0,0 +1,13 @@
+-- A monthly spending limit per expense category. alerted_month and
+-- alerted_pct record the highest threshold (50, 80 or 100%) already alerted
+-- and in which month, so each one is sent once per month.
+CREATE TABLE expense_budgets (
+    user_id        BIGINT NOT NULL,
+    category_id    INT NOT NULL REFERENCES expense_categories(id) ON DELETE CASCADE,
+    amount         BIGINT NOT NULL CHECK (amount > 0),
+    alerted_month  DATE,
+    alerted_pct    INT NOT NULL DEFAULT 0,
+    created_at     TIMESTAMPTZ DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, category_id)
+);
@@ -0,0 +1,12 @@
+-- Money coming in, kept apart from expenses. source is who it came from
+-- ("terima transfer 500rb dari Budi"), when the user says.
+CREATE TABLE incomes (
+    id           SERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL,
+    description  TEXT NOT NULL,
+    amount       BIGINT NOT NULL,
+    source       TEXT,
+    recorded_at  TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_incomes_user_recorded ON incomes (user_id, recorded_at);
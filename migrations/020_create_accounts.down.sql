@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS account_transfers;
+ALTER TABLE incomes DROP COLUMN IF EXISTS account_id;
+ALTER TABLE expenses DROP COLUMN IF EXISTS account_id;
+DROP TABLE IF EXISTS accounts;
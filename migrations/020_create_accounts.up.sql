@@ -0,0 +1,27 @@
+-- Where money is paid from or lands: cash, a bank account, an e-wallet.
+-- The balance is opening_balance plus incomes, minus paid expenses, plus
+-- transfers in and minus transfers out.
+CREATE TABLE accounts (
+    id               SERIAL PRIMARY KEY,
+    user_id          BIGINT NOT NULL,
+    name             TEXT NOT NULL,
+    opening_balance  BIGINT NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_accounts_user_name ON accounts (user_id, LOWER(name));
+
+ALTER TABLE expenses
+    ADD COLUMN account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+
+ALTER TABLE incomes
+    ADD COLUMN account_id INT REFERENCES accounts(id) ON DELETE SET NULL;
+
+CREATE TABLE account_transfers (
+    id               SERIAL PRIMARY KEY,
+    user_id          BIGINT NOT NULL,
+    from_account_id  INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    to_account_id    INT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    amount           BIGINT NOT NULL CHECK (amount > 0),
+    recorded_at      TIMESTAMPTZ DEFAULT NOW()
+);
@@ -0,0 +1,16 @@
+-- Ledger of money owed between the user and other people. amount is signed
+-- from the user's side: positive when the person owes more ("Budi pinjam
+-- 200rb", "aku bayar Sari 500rb"), negative when the user does ("aku hutang
+-- ke Sari 1jt", "Budi bayar 50rb"). A person's balance is the sum.
+CREATE TABLE debt_entries (
+    id           SERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL,
+    person       TEXT NOT NULL,
+    amount       BIGINT NOT NULL CHECK (amount <> 0),
+    note         TEXT,
+    due_date     TIMESTAMPTZ,
+    reminder_id  INT REFERENCES reminders(id) ON DELETE SET NULL,
+    recorded_at  TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_debt_entries_user_person ON debt_entries (user_id, LOWER(person));